@@ -4,10 +4,7 @@ import (
 	"flag"
 	zap2 "go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
-	"io/ioutil"
-	"net/http"
 	"os"
-	"strings"
 
 	// needed for hack/update-codegen.sh
 	_ "k8s.io/code-generator"
@@ -19,9 +16,9 @@ import (
 
 	//+kubebuilder:scaffold:imports
 	"github.com/go-logr/logr"
-	"github.com/redhat-appstudio/image-controller/pkg/quay"
 	"github.com/redhat-appstudio/jvm-build-service/pkg/controller"
 	"github.com/redhat-appstudio/jvm-build-service/pkg/reconciler/util"
+	"github.com/redhat-appstudio/jvm-build-service/pkg/webhook"
 )
 
 var (
@@ -33,10 +30,14 @@ func main() {
 	var enableLeaderElection bool
 	var probeAddr string
 	var abAPIExportName string
+	var enableWebhooks bool
+	var webhookCertDir string
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.StringVar(&abAPIExportName, "api-export-name", "jvm-build-service", "The name of the jvm-build-service APIExport.")
 
+	flag.BoolVar(&enableWebhooks, "enable-webhooks", false, "Enable the validating and defaulting admission webhooks.")
+	flag.StringVar(&webhookCertDir, "webhook-cert-dir", "", "The directory that contains the webhook server key and certificate.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
@@ -57,40 +58,31 @@ func main() {
 	restConfig := ctrl.GetConfigOrDie()
 	klog.SetLogger(mainLog)
 
-	tokenPath := "/workspace/quaytoken" //#nosec
-	tokenContent, err := ioutil.ReadFile(tokenPath)
-	if err != nil {
-		mainLog.Error(err, "unable to read quay token")
-	}
-	orgPath := "/workspace/organization"
-	orgContent, err := ioutil.ReadFile(orgPath)
-	if err != nil {
-		mainLog.Error(err, "unable to read quay organization")
-	}
-	var quayClient *quay.QuayClient
-	if orgContent != nil && tokenContent != nil {
-		client := quay.NewQuayClient(&http.Client{Transport: &http.Transport{}}, strings.TrimSpace(string(tokenContent)), "https://quay.io/api/v1")
-		quayClient = &client
-	}
-
-	var mgr ctrl.Manager
 	mopts := ctrl.Options{
 		MetricsBindAddress:     metricsAddr,
 		Port:                   9443,
 		HealthProbeBindAddress: probeAddr,
 		LeaderElection:         enableLeaderElection,
 		LeaderElectionID:       "5483be8f.redhat.com",
+		CertDir:                webhookCertDir,
 	}
 
 	util.ImageTag = os.Getenv("IMAGE_TAG")
 	util.ImageRepo = os.Getenv("IMAGE_REPO")
 
-	mgr, err = controller.NewManager(restConfig, mopts, quayClient, string(orgContent))
+	mgr, err := controller.NewManager(restConfig, mopts)
 	if err != nil {
 		mainLog.Error(err, "unable to start manager")
 		os.Exit(1)
 	}
 
+	if enableWebhooks {
+		if err := webhook.SetupWebhooksWithManager(mgr); err != nil {
+			mainLog.Error(err, "unable to set up webhooks")
+			os.Exit(1)
+		}
+	}
+
 	//+kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
@@ -35,6 +35,16 @@ public class BuildRecipeInfo {
 
     int additionalMemory;
 
+    /**
+     * The timeout for the build pipeline, in the Go duration format e.g. 5h
+     */
+    String timeout;
+
+    /**
+     * The timeout for each step of the build pipeline, in the Go duration format e.g. 2h30m
+     */
+    String stepTimeout;
+
     List<AdditionalDownload> additionalDownloads = new ArrayList<>();
 
     boolean runTests;
@@ -145,6 +155,24 @@ public class BuildRecipeInfo {
         return this;
     }
 
+    public String getTimeout() {
+        return timeout;
+    }
+
+    public BuildRecipeInfo setTimeout(String timeout) {
+        this.timeout = timeout;
+        return this;
+    }
+
+    public String getStepTimeout() {
+        return stepTimeout;
+    }
+
+    public BuildRecipeInfo setStepTimeout(String stepTimeout) {
+        this.stepTimeout = stepTimeout;
+        return this;
+    }
+
     public boolean isRunTests() {
         return runTests;
     }
@@ -176,6 +204,8 @@ public class BuildRecipeInfo {
                 ", postBuildScript='" + postBuildScript + '\'' +
                 ", disableSubmodules=" + disableSubmodules +
                 ", additionalMemory=" + additionalMemory +
+                ", timeout='" + timeout + '\'' +
+                ", stepTimeout='" + stepTimeout + '\'' +
                 ", additionalDownloads=" + additionalDownloads +
                 ", additionalBuilds=" + additionalBuilds +
                 '}';
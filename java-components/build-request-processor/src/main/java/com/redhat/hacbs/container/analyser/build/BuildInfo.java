@@ -50,6 +50,8 @@ public class BuildInfo {
     List<AdditionalDownload> additionalDownloads = new ArrayList<>();
     boolean disableSubmodules;
     int additionalMemory;
+    String timeout;
+    String stepTimeout;
 
     public BuildInfo setTools(Map<String, VersionRange> tools) {
         this.tools = tools;
@@ -137,6 +139,24 @@ public class BuildInfo {
         return this;
     }
 
+    public String getTimeout() {
+        return timeout;
+    }
+
+    public BuildInfo setTimeout(String timeout) {
+        this.timeout = timeout;
+        return this;
+    }
+
+    public String getStepTimeout() {
+        return stepTimeout;
+    }
+
+    public BuildInfo setStepTimeout(String stepTimeout) {
+        this.stepTimeout = stepTimeout;
+        return this;
+    }
+
     public List<String> getRepositories() {
         return repositories;
     }
@@ -160,6 +180,8 @@ public class BuildInfo {
                 ", additionalDownloads=" + additionalDownloads +
                 ", disableSubmodules=" + disableSubmodules +
                 ", additionalMemory=" + additionalMemory +
+                ", timeout='" + timeout + '\'' +
+                ", stepTimeout='" + stepTimeout + '\'' +
                 '}';
     }
 }
@@ -299,6 +299,8 @@ public class LookupBuildInfoCommand implements Runnable {
                 info.postBuildScript = buildRecipeInfo.getPostBuildScript();
                 info.setAdditionalDownloads(buildRecipeInfo.getAdditionalDownloads());
                 info.setAdditionalMemory(buildRecipeInfo.getAdditionalMemory());
+                info.setTimeout(buildRecipeInfo.getTimeout());
+                info.setStepTimeout(buildRecipeInfo.getStepTimeout());
                 Log.infof("Got build recipe info %s", buildRecipeInfo);
             }
             ObjectMapper mapper = new ObjectMapper();
@@ -0,0 +1,167 @@
+package com.redhat.hacbs.container.analyser.build.maven;
+
+import java.io.IOException;
+import java.io.Reader;
+import java.nio.file.Files;
+import java.nio.file.Path;
+import java.util.ArrayList;
+import java.util.List;
+import java.util.Map;
+import java.util.Properties;
+import java.util.regex.Matcher;
+import java.util.regex.Pattern;
+
+import jakarta.enterprise.context.ApplicationScoped;
+
+import org.apache.maven.model.Model;
+import org.apache.maven.model.Plugin;
+import org.apache.maven.model.PluginExecution;
+import org.codehaus.plexus.util.xml.Xpp3Dom;
+
+import com.redhat.hacbs.container.analyser.build.BuildInfo;
+import com.redhat.hacbs.container.analyser.build.DiscoveryResult;
+import com.redhat.hacbs.container.analyser.location.VersionRange;
+
+import io.quarkus.logging.Log;
+
+/**
+ * Determines the Maven version from the maven wrapper, or failing that the enforcer plugin or the pom prerequisites.
+ */
+@ApplicationScoped
+public class MavenVersionDiscovery implements MavenDiscoveryTask {
+
+    static final String WRAPPER_PROPERTIES = ".mvn/wrapper/maven-wrapper.properties";
+    static final Pattern WRAPPER_DISTRIBUTION = Pattern.compile("/apache-maven/([^/]+)/");
+    static final Pattern VERSION_RANGE = Pattern.compile("[\\[(]([^\\])]*)[\\])]");
+
+    @Override
+    public DiscoveryResult discover(Model model, Path checkout) {
+        String wrapperVersion = getWrapperVersion(checkout);
+        if (wrapperVersion != null) {
+            Log.infof("Detected Maven version %s from the maven wrapper", wrapperVersion);
+            return new DiscoveryResult(
+                    Map.of(BuildInfo.MAVEN, new VersionRange(wrapperVersion, wrapperVersion, wrapperVersion)), 1);
+        }
+        String enforcerVersion = getEnforcerVersion(model);
+        if (enforcerVersion != null) {
+            VersionRange range = parseVersionRange(JavaVersionDiscovery.interpolate(enforcerVersion, model));
+            if (range != null) {
+                Log.infof("Detected Maven version range %s from the enforcer plugin", enforcerVersion);
+                return new DiscoveryResult(Map.of(BuildInfo.MAVEN, range), 1);
+            }
+        }
+        if (model.getPrerequisites() != null && model.getPrerequisites().getMaven() != null) {
+            String prerequisite = JavaVersionDiscovery.interpolate(model.getPrerequisites().getMaven(), model);
+            Log.infof("Detected Maven version %s from the prerequisites", prerequisite);
+            return new DiscoveryResult(Map.of(BuildInfo.MAVEN, new VersionRange(prerequisite, null, prerequisite)), 1);
+        }
+        return null;
+    }
+
+    static String getWrapperVersion(Path checkout) {
+        Path wrapperProperties = checkout.resolve(WRAPPER_PROPERTIES);
+        if (!Files.isRegularFile(wrapperProperties)) {
+            return null;
+        }
+        Properties properties = new Properties();
+        try (Reader reader = Files.newBufferedReader(wrapperProperties)) {
+            properties.load(reader);
+        } catch (IOException e) {
+            Log.errorf(e, "Failed to read %s", wrapperProperties);
+            return null;
+        }
+        String distributionUrl = properties.getProperty("distributionUrl");
+        if (distributionUrl == null) {
+            return null;
+        }
+        Matcher matcher = WRAPPER_DISTRIBUTION.matcher(distributionUrl);
+        return matcher.find() ? matcher.group(1) : null;
+    }
+
+    static String getEnforcerVersion(Model model) {
+        if (model.getBuild() == null) {
+            return null;
+        }
+        List<Plugin> plugins = new ArrayList<>(model.getBuild().getPlugins());
+        if (model.getBuild().getPluginManagement() != null) {
+            plugins.addAll(model.getBuild().getPluginManagement().getPlugins());
+        }
+        for (Plugin plugin : plugins) {
+            if (!"maven-enforcer-plugin".equals(plugin.getArtifactId())) {
+                continue;
+            }
+            String version = getRequireMavenVersion(plugin.getConfiguration());
+            if (version != null) {
+                return version;
+            }
+            for (PluginExecution execution : plugin.getExecutions()) {
+                version = getRequireMavenVersion(execution.getConfiguration());
+                if (version != null) {
+                    return version;
+                }
+            }
+        }
+        return null;
+    }
+
+    static String getRequireMavenVersion(Object configuration) {
+        if (!(configuration instanceof Xpp3Dom)) {
+            return null;
+        }
+        Xpp3Dom rules = ((Xpp3Dom) configuration).getChild("rules");
+        if (rules == null) {
+            return null;
+        }
+        Xpp3Dom requireMavenVersion = rules.getChild("requireMavenVersion");
+        if (requireMavenVersion == null) {
+            return null;
+        }
+        Xpp3Dom version = requireMavenVersion.getChild("version");
+        return version == null || version.getValue() == null ? null : version.getValue().trim();
+    }
+
+    /**
+     * Parses an enforcer version specification, a plain version is a minimum. The min and max are the outer bounds of
+     * the ranges, and the specification is kept as the range so the exclusive bounds and the gaps between multiple
+     * ranges are applied when the Maven version is picked from the builder image.
+     */
+    static VersionRange parseVersionRange(String spec) {
+        if (spec == null || spec.isBlank()) {
+            return null;
+        }
+        spec = spec.trim();
+        if (!spec.startsWith("[") && !spec.startsWith("(")) {
+            return new VersionRange(spec, null, spec);
+        }
+        String min = null;
+        String max = null;
+        int end = 0;
+        Matcher matcher = VERSION_RANGE.matcher(spec);
+        while (matcher.find()) {
+            String separator = spec.substring(end, matcher.start()).trim();
+            if (!separator.equals(end == 0 ? "" : ",")) {
+                Log.errorf("Invalid Maven version range %s", spec);
+                return null;
+            }
+            String[] parts = matcher.group(1).split(",", -1);
+            if (parts.length > 2) {
+                Log.errorf("Invalid Maven version range %s", spec);
+                return null;
+            }
+            if (end == 0) {
+                min = parts[0].trim().isEmpty() ? null : parts[0].trim();
+            }
+            max = parts.length < 2 ? parts[0].trim() : parts[1].trim();
+            max = max.isEmpty() ? null : max;
+            end = matcher.end();
+        }
+        if (end == 0 || end != spec.length()) {
+            Log.errorf("Invalid Maven version range %s", spec);
+            return null;
+        }
+        if (min == null && max == null) {
+            return null;
+        }
+        return new VersionRange(min, max, min != null ? min : max).setRange(spec);
+    }
+}
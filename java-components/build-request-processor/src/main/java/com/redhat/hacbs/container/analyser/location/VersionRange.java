@@ -11,6 +11,12 @@ public class VersionRange {
 
     String preferred;
 
+    /**
+     * The version specification the min and max came from, if it is a range. The min and max are the outer bounds, the
+     * specification also has the exclusive bounds and any gaps between the ranges.
+     */
+    String range;
+
     public VersionRange(String min, String max, String preferred) {
         this.min = min;
         this.max = max;
@@ -46,4 +52,13 @@ public class VersionRange {
         this.preferred = preferred;
         return this;
     }
+
+    public String getRange() {
+        return range;
+    }
+
+    public VersionRange setRange(String range) {
+        this.range = range;
+        return this;
+    }
 }
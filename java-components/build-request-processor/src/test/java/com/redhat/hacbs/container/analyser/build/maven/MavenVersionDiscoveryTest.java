@@ -0,0 +1,89 @@
+package com.redhat.hacbs.container.analyser.build.maven;
+
+import static org.junit.jupiter.api.Assertions.*;
+
+import java.io.IOException;
+import java.io.StringReader;
+import java.nio.file.Files;
+import java.nio.file.Path;
+
+import org.apache.maven.model.Model;
+import org.apache.maven.model.io.xpp3.MavenXpp3Reader;
+import org.codehaus.plexus.util.xml.pull.XmlPullParserException;
+import org.junit.jupiter.api.Test;
+import org.junit.jupiter.api.io.TempDir;
+
+import com.redhat.hacbs.container.analyser.build.DiscoveryResult;
+import com.redhat.hacbs.container.analyser.location.VersionRange;
+
+class MavenVersionDiscoveryTest {
+
+    static final String ENFORCER_POM = "<project><modelVersion>4.0.0</modelVersion>"
+            + "<prerequisites><maven>3.0</maven></prerequisites>"
+            + "<build><plugins><plugin><artifactId>maven-enforcer-plugin</artifactId><executions><execution>"
+            + "<configuration><rules><requireMavenVersion><version>[3.3.9,3.6)</version></requireMavenVersion></rules></configuration>"
+            + "</execution></executions></plugin></plugins></build></project>";
+
+    @Test
+    public void checkWrapperVersion(@TempDir Path checkout) throws IOException, XmlPullParserException {
+        Files.createDirectories(checkout.resolve(".mvn/wrapper"));
+        Files.writeString(checkout.resolve(MavenVersionDiscovery.WRAPPER_PROPERTIES),
+                "distributionUrl=https://repo.maven.apache.org/maven2/org/apache/maven/apache-maven/3.6.3/apache-maven-3.6.3-bin.zip\n");
+        DiscoveryResult dr = new MavenVersionDiscovery().discover(readModel(ENFORCER_POM), checkout);
+        VersionRange range = dr.getToolVersions().get("maven");
+        assertEquals("3.6.3", range.getMin());
+        assertEquals("3.6.3", range.getMax());
+        assertEquals("3.6.3", range.getPreferred());
+    }
+
+    @Test
+    public void checkEnforcerVersion(@TempDir Path checkout) throws IOException, XmlPullParserException {
+        DiscoveryResult dr = new MavenVersionDiscovery().discover(readModel(ENFORCER_POM), checkout);
+        VersionRange range = dr.getToolVersions().get("maven");
+        assertEquals("3.3.9", range.getMin());
+        assertEquals("3.6", range.getMax());
+        assertEquals("3.3.9", range.getPreferred());
+        assertEquals("[3.3.9,3.6)", range.getRange());
+    }
+
+    @Test
+    public void checkPrerequisites(@TempDir Path checkout) throws IOException, XmlPullParserException {
+        DiscoveryResult dr = new MavenVersionDiscovery().discover(
+                readModel("<project><prerequisites><maven>3.2.5</maven></prerequisites></project>"), checkout);
+        VersionRange range = dr.getToolVersions().get("maven");
+        assertEquals("3.2.5", range.getMin());
+        assertNull(range.getMax());
+    }
+
+    @Test
+    public void checkNoRequirement(@TempDir Path checkout) throws IOException, XmlPullParserException {
+        assertNull(new MavenVersionDiscovery().discover(readModel("<project></project>"), checkout));
+    }
+
+    @Test
+    public void checkVersionRangeParsing() {
+        assertEquals("3.5.4", MavenVersionDiscovery.parseVersionRange("[3.5.4,)").getMin());
+        assertNull(MavenVersionDiscovery.parseVersionRange("[3.5.4,)").getMax());
+        assertEquals("3.8.1", MavenVersionDiscovery.parseVersionRange("[3.8.1]").getMax());
+        assertEquals("3.9", MavenVersionDiscovery.parseVersionRange("(,3.9]").getPreferred());
+        assertNull(MavenVersionDiscovery.parseVersionRange("(,)"));
+    }
+
+    @Test
+    public void checkMultipleVersionRanges() {
+        VersionRange range = MavenVersionDiscovery.parseVersionRange("[3.0,3.6),(3.7,)");
+        assertEquals("3.0", range.getMin());
+        assertNull(range.getMax());
+        assertEquals("[3.0,3.6),(3.7,)", range.getRange());
+        range = MavenVersionDiscovery.parseVersionRange("[3.0,3.6), [3.8.1]");
+        assertEquals("3.0", range.getMin());
+        assertEquals("3.8.1", range.getMax());
+        assertNull(MavenVersionDiscovery.parseVersionRange("[3.0,3.6)x"));
+        assertNull(MavenVersionDiscovery.parseVersionRange("[3.0,3.6"));
+        assertNull(MavenVersionDiscovery.parseVersionRange("[3.0,3.5,3.6]"));
+    }
+
+    private static Model readModel(String pom) throws IOException, XmlPullParserException {
+        return new MavenXpp3Reader().read(new StringReader(pom));
+    }
+}
@@ -4,6 +4,7 @@ import java.util.ArrayList;
 import java.util.Collections;
 import java.util.List;
 import java.util.Objects;
+import java.util.Optional;
 import java.util.Set;
 import java.util.concurrent.ConcurrentHashMap;
 import java.util.function.Consumer;
@@ -17,6 +18,7 @@ import org.eclipse.microprofile.config.inject.ConfigProperty;
 import com.redhat.hacbs.artifactcache.services.RecipeManager;
 import com.redhat.hacbs.recipies.GAV;
 import com.redhat.hacbs.resources.model.v1alpha1.ArtifactBuild;
+import com.redhat.hacbs.resources.model.v1alpha1.ModelConstants;
 import com.redhat.hacbs.resources.model.v1alpha1.ScmInfo;
 
 import io.fabric8.kubernetes.client.KubernetesClient;
@@ -37,6 +39,10 @@ public class ScmLookup {
     @ConfigProperty(name = "kube.disabled", defaultValue = "false")
     boolean disabled;
 
+    //the JBSConfigs that use this cache, there can be more than one cache in a namespace
+    @ConfigProperty(name = "jbs-configs")
+    Optional<Set<String>> jbsConfigs;
+
     final List<Consumer<String>> imageDeletionListeners = Collections.synchronizedList(new ArrayList<>());
 
     private final Set<String> gavs = Collections.newSetFromMap(new ConcurrentHashMap<>());
@@ -58,7 +64,9 @@ public class ScmLookup {
 
             @Override
             public void onAdd(ArtifactBuild newObj) {
-
+                if (!usesThisCache(newObj)) {
+                    return;
+                }
                 for (var i = 0; i < 3; ++i) { //retry loop
                     if (newObj.getStatus().getState() == null || Objects.equals(newObj.getStatus().getState(), "")
                             || Objects.equals(newObj.getStatus().getState(), ArtifactBuild.NEW)) {
@@ -115,6 +123,17 @@ public class ScmLookup {
         });
     }
 
+    boolean usesThisCache(ArtifactBuild artifactBuild) {
+        if (jbsConfigs.isEmpty()) {
+            return true;
+        }
+        String jbsConfig = artifactBuild.getSpec().getJbsConfig();
+        if (jbsConfig == null || jbsConfig.isEmpty()) {
+            jbsConfig = ModelConstants.DEFAULT_JBS_CONFIG;
+        }
+        return jbsConfigs.get().contains(jbsConfig);
+    }
+
     public void addImageDeletionListener(Consumer<String> listener) {
         imageDeletionListeners.add(listener);
     }
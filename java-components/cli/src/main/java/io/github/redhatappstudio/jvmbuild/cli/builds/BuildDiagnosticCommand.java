@@ -8,6 +8,7 @@ import java.util.List;
 import java.util.Map;
 
 import com.redhat.hacbs.resources.model.v1alpha1.ArtifactBuild;
+import com.redhat.hacbs.resources.model.v1alpha1.BuildAttempt;
 import com.redhat.hacbs.resources.model.v1alpha1.DependencyBuild;
 
 import io.fabric8.kubernetes.client.KubernetesClient;
@@ -76,6 +77,7 @@ public class BuildDiagnosticCommand
                         theBuild.getSpec().getVersion() + '\n');
 
         List<String> dockerFiles = theBuild.getStatus().getDiagnosticDockerFiles();
+        List<BuildAttempt> attempts = theBuild.getStatus().getBuildAttempts();
         int failed = (theBuild.getStatus().getFailedBuildRecipes() == null ? 0
                 : theBuild.getStatus().getFailedBuildRecipes().size());
         int succeedMarker = dockerFiles.size() == failed ? -1 : dockerFiles.size() - 1;
@@ -84,7 +86,13 @@ public class BuildDiagnosticCommand
                 String fileName;
                 String javaVersion;
                 String tagName;
-                if (i == succeedMarker) {
+                if (attempts != null && attempts.size() == dockerFiles.size()) {
+                    // there is a Dockerfile for every attempt, including the retried ones
+                    BuildAttempt attempt = attempts.get(i);
+                    javaVersion = attempt.getRecipe().getJavaVersion();
+                    tagName = name + (BuildAttempt.OUTCOME_SUCCEEDED.equals(attempt.getOutcome()) ? ".succeed.jdk" : ".failed.jdk")
+                            + javaVersion;
+                } else if (i == succeedMarker) {
                     javaVersion = theBuild.getStatus()
                             .getCurrentBuildRecipe()
                             .getJavaVersion();
@@ -3,6 +3,7 @@ package com.redhat.hacbs.resources.model.v1alpha1;
 public class ArtifactBuildSpec {
 
     private String gav;
+    private String jbsConfig;
 
     public String getGav() {
         return gav;
@@ -11,4 +12,12 @@ public class ArtifactBuildSpec {
     public void setGav(String gav) {
         this.gav = gav;
     }
+
+    public String getJbsConfig() {
+        return jbsConfig;
+    }
+
+    public void setJbsConfig(String jbsConfig) {
+        this.jbsConfig = jbsConfig;
+    }
 }
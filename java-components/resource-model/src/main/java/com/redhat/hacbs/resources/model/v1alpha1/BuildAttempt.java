@@ -0,0 +1,83 @@
+package com.redhat.hacbs.resources.model.v1alpha1;
+
+import com.fasterxml.jackson.annotation.JsonIgnoreProperties;
+
+@JsonIgnoreProperties(ignoreUnknown = true)
+public class BuildAttempt {
+
+    public static final String OUTCOME_RUNNING = "Running";
+    public static final String OUTCOME_SUCCEEDED = "Succeeded";
+    public static final String OUTCOME_FAILED = "Failed";
+    public static final String OUTCOME_RETRIED = "Retried";
+
+    private String pipelineRun;
+    private BuildRecipe recipe;
+    private String outcome;
+    private String cause;
+    private String message;
+    private String imageDigest;
+    private String logArchive;
+
+    public String getPipelineRun() {
+        return pipelineRun;
+    }
+
+    public BuildAttempt setPipelineRun(String pipelineRun) {
+        this.pipelineRun = pipelineRun;
+        return this;
+    }
+
+    public BuildRecipe getRecipe() {
+        return recipe;
+    }
+
+    public BuildAttempt setRecipe(BuildRecipe recipe) {
+        this.recipe = recipe;
+        return this;
+    }
+
+    public String getOutcome() {
+        return outcome;
+    }
+
+    public BuildAttempt setOutcome(String outcome) {
+        this.outcome = outcome;
+        return this;
+    }
+
+    public String getCause() {
+        return cause;
+    }
+
+    public BuildAttempt setCause(String cause) {
+        this.cause = cause;
+        return this;
+    }
+
+    public String getMessage() {
+        return message;
+    }
+
+    public BuildAttempt setMessage(String message) {
+        this.message = message;
+        return this;
+    }
+
+    public String getImageDigest() {
+        return imageDigest;
+    }
+
+    public BuildAttempt setImageDigest(String imageDigest) {
+        this.imageDigest = imageDigest;
+        return this;
+    }
+
+    public String getLogArchive() {
+        return logArchive;
+    }
+
+    public BuildAttempt setLogArchive(String logArchive) {
+        this.logArchive = logArchive;
+        return this;
+    }
+}
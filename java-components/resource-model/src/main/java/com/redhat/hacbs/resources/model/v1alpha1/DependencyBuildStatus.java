@@ -16,6 +16,7 @@ public class DependencyBuildStatus {
 
     private String lastCompletedBuildPipelineRun;
     private List<String> diagnosticDockerFiles;
+    private List<BuildAttempt> buildAttempts;
 
     private long commitTime;
 
@@ -90,4 +91,13 @@ public class DependencyBuildStatus {
         this.diagnosticDockerFiles = diagnosticDockerFiles;
         return this;
     }
+
+    public List<BuildAttempt> getBuildAttempts() {
+        return buildAttempts;
+    }
+
+    public DependencyBuildStatus setBuildAttempts(List<BuildAttempt> buildAttempts) {
+        this.buildAttempts = buildAttempts;
+        return this;
+    }
 }
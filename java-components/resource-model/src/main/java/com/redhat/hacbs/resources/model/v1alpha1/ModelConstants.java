@@ -5,4 +5,5 @@ public class ModelConstants {
     public static final String VERSION = "v1alpha1";
     public static final String CLEAR_CACHE = GROUP + "/clear-cache";
     public static final String LAST_CLEAR_CACHE = GROUP + "/last-clear-cache";
+    public static final String DEFAULT_JBS_CONFIG = "jvm-build-config";
 }
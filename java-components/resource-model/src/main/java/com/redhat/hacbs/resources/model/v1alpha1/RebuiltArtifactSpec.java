@@ -8,6 +8,7 @@ public class RebuiltArtifactSpec {
     private String gav;
     private String image;
     private String digest;
+    private String jbsConfig;
 
     public String getGav() {
         return gav;
@@ -34,4 +35,13 @@ public class RebuiltArtifactSpec {
         this.digest = digest;
         return this;
     }
+
+    public String getJbsConfig() {
+        return jbsConfig;
+    }
+
+    public RebuiltArtifactSpec setJbsConfig(String jbsConfig) {
+        this.jbsConfig = jbsConfig;
+        return this;
+    }
 }
@@ -7,13 +7,18 @@ import (
 type ArtifactBuildSpec struct {
 	// GAV is the groupID:artifactID:version tuple seen in maven pom.xml files
 	GAV string `json:"gav,omitempty"`
+	// JBSConfig the name of the JBSConfig in the namespace the artifact is built with, the default is
+	// jvm-build-config
+	JBSConfig string `json:"jbsConfig,omitempty"`
 }
 
 type ArtifactBuildStatus struct {
-	//TODO: conditions?
-	State   string  `json:"state,omitempty"`
-	Message string  `json:"message,omitempty"`
-	SCMInfo SCMInfo `json:"scm,omitempty"`
+	// Conditions describe the lifecycle of the artifact build, State is a summary of these conditions
+	// that is retained for compatibility
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+	State      string             `json:"state,omitempty"`
+	Message    string             `json:"message,omitempty"`
+	SCMInfo    SCMInfo            `json:"scm,omitempty"`
 }
 
 //type ArtifactBuildState string
@@ -33,12 +38,43 @@ const (
 	ArtifactBuildStateComplete = "ArtifactBuildComplete"
 )
 
+const (
+	// ArtifactBuildConditionDiscovered The discovery process has determined the SCM information for the artifact
+	ArtifactBuildConditionDiscovered = "Discovered"
+	// ArtifactBuildConditionDependencyBuildLinked The artifact build is an owner of the DependencyBuild that will build it
+	ArtifactBuildConditionDependencyBuildLinked = "DependencyBuildLinked"
+	// ArtifactBuildConditionBuilt The artifact has been rebuilt and deployed
+	ArtifactBuildConditionBuilt = "Built"
+	// ArtifactBuildConditionVerified The rebuilt artifact passed verification against the upstream artifact
+	ArtifactBuildConditionVerified = "Verified"
+	// ArtifactBuildConditionContaminated The rebuilt artifact is contaminated by community dependencies
+	ArtifactBuildConditionContaminated = "Contaminated"
+
+	ArtifactBuildReasonSCMInfoFound           = "SCMInfoFound"
+	ArtifactBuildReasonSCMInfoMissing         = "SCMInfoMissing"
+	ArtifactBuildReasonInvalidGAV             = "InvalidGAV"
+	ArtifactBuildReasonDependencyBuildFound   = "DependencyBuildFound"
+	ArtifactBuildReasonDependencyBuildCreated = "DependencyBuildCreated"
+	ArtifactBuildReasonDependencyBuildShared  = "DependencyBuildShared"
+	ArtifactBuildReasonDependencyBuildMissing = "DependencyBuildMissing"
+	ArtifactBuildReasonBuilding               = "Building"
+	ArtifactBuildReasonBuildSucceeded         = "BuildSucceeded"
+	ArtifactBuildReasonBuildFailed            = "BuildFailed"
+	ArtifactBuildReasonArtifactNotDeployed    = "ArtifactNotDeployed"
+	ArtifactBuildReasonVerificationPassed     = "VerificationPassed"
+	ArtifactBuildReasonVerificationFailed     = "VerificationFailed"
+	ArtifactBuildReasonContaminated           = "ContaminatedByCommunityDependencies"
+	ArtifactBuildReasonNotContaminated        = "NotContaminated"
+	ArtifactBuildReasonRebuildRequested       = "RebuildRequested"
+)
+
 // +genclient
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 // +kubebuilder:subresource:status
 // +kubebuilder:resource:path=artifactbuilds,scope=Namespaced
 // +kubebuilder:printcolumn:name="GAV",type=string,JSONPath=`.spec.gav`
 // +kubebuilder:printcolumn:name="State",type=string,JSONPath=`.status.state`
+// +kubebuilder:printcolumn:name="JBSConfig",type=string,JSONPath=`.spec.jbsConfig`,priority=1
 // ArtifactBuild TODO provide godoc description
 type ArtifactBuild struct {
 	metav1.TypeMeta   `json:",inline"`
@@ -0,0 +1,70 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type BuildRecipeOverrideSpec struct {
+	// Match determines which builds the override applies to
+	Match BuildRecipeOverrideMatch `json:"match"`
+
+	// JavaVersion if set only builder images with this JDK version are used, regardless of the versions the
+	// build info analysis detected
+	JavaVersion string `json:"javaVersion,omitempty"`
+	// PreBuildScript replaces the pre build script from the recipe database
+	PreBuildScript string `json:"preBuildScript,omitempty"`
+	// PostBuildScript replaces the post build script from the recipe database
+	PostBuildScript string `json:"postBuildScript,omitempty"`
+	// AdditionalDownloads are added to the downloads from the recipe database
+	AdditionalDownloads []AdditionalDownload `json:"additionalDownloads,omitempty"`
+	// AdditionalMemory replaces the additional memory from the recipe database
+	AdditionalMemory int `json:"additionalMemory,omitempty"`
+	// Repositories are added to the repositories from the recipe database
+	Repositories []string `json:"repositories,omitempty"`
+	// DisableSubmodules replaces the submodule setting from the recipe database
+	DisableSubmodules *bool `json:"disableSubmodules,omitempty"`
+	// CommandLine replaces the build tool arguments of every invocation
+	CommandLine []string `json:"commandLine,omitempty"`
+	// PipelineTimeout replaces the pipeline timeout from the recipe database
+	PipelineTimeout *metav1.Duration `json:"pipelineTimeout,omitempty"`
+	// StepTimeout replaces the step timeout from the recipe database
+	StepTimeout *metav1.Duration `json:"stepTimeout,omitempty"`
+}
+
+// BuildRecipeOverrideMatch all the fields that are set must match for the override to be applied
+type BuildRecipeOverrideMatch struct {
+	// SCMURL the SCM URL of the build
+	SCMURL string `json:"scmURL,omitempty"`
+	// TagPattern a regular expression that must match the whole SCM tag
+	TagPattern string `json:"tagPattern,omitempty"`
+	// VersionPattern a regular expression that must match the whole version
+	VersionPattern string `json:"versionPattern,omitempty"`
+	// GAV the GAV of one of the artifacts that requested the build
+	GAV string `json:"gav,omitempty"`
+}
+
+type BuildRecipeOverrideStatus struct {
+}
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:resource:path=buildrecipeoverrides,scope=Namespaced
+// +kubebuilder:printcolumn:name="URL",type=string,JSONPath=`.spec.match.scmURL`
+// +kubebuilder:printcolumn:name="GAV",type=string,JSONPath=`.spec.match.gav`
+// BuildRecipeOverride A namespace local change to the build recipes of matching builds
+type BuildRecipeOverride struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   BuildRecipeOverrideSpec   `json:"spec"`
+	Status BuildRecipeOverrideStatus `json:"status,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// BuildRecipeOverrideList contains a list of BuildRecipeOverride
+type BuildRecipeOverrideList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []BuildRecipeOverride `json:"items"`
+}
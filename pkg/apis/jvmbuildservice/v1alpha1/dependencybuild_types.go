@@ -2,27 +2,77 @@ package v1alpha1
 
 import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 )
 
 const (
 	DependencyBuildStateNew          = "DependencyBuildStateNew"
 	DependencyBuildStateAnalyzeBuild = "DependencyBuildStateAnalyzeBuild"
 	DependencyBuildStateSubmitBuild  = "DependencyBuildStateSubmitBuild"
+	DependencyBuildStateQueued       = "DependencyBuildStateQueued"
 	DependencyBuildStateBuilding     = "DependencyBuildStateBuilding"
 	DependencyBuildStateComplete     = "DependencyBuildStateComplete"
 	DependencyBuildStateFailed       = "DependencyBuildStateFailed"
 	DependencyBuildStateContaminated = "DependencyBuildStateContaminated"
 )
 
+const (
+	// DependencyBuildConditionBuildInfoAnalyzed The build info lookup has determined how the project should be built
+	DependencyBuildConditionBuildInfoAnalyzed = "BuildInfoAnalyzed"
+	// DependencyBuildConditionRecipeSelected A build recipe has been selected for the current attempt
+	DependencyBuildConditionRecipeSelected = "RecipeSelected"
+	// DependencyBuildConditionPipelineSubmitted The build pipeline for the current recipe has been created
+	DependencyBuildConditionPipelineSubmitted = "PipelineSubmitted"
+	// DependencyBuildConditionSucceeded The build has completed successfully
+	DependencyBuildConditionSucceeded = "Succeeded"
+	// DependencyBuildConditionContaminated The build output is contaminated by community dependencies
+	DependencyBuildConditionContaminated = "Contaminated"
+	// DependencyBuildConditionVerified The build output passed verification against the upstream artifacts
+	DependencyBuildConditionVerified = "Verified"
+
+	DependencyBuildReasonAnalyzing             = "Analyzing"
+	DependencyBuildReasonBuildInfoFound        = "BuildInfoFound"
+	DependencyBuildReasonBuildInfoLookupFailed = "BuildInfoLookupFailed"
+	DependencyBuildReasonInvalidBuildInfo      = "InvalidBuildInfo"
+	DependencyBuildReasonBuildToolNotDetected  = "BuildToolNotDetected"
+	DependencyBuildReasonUnknownBuildTool      = "UnknownBuildTool"
+	DependencyBuildReasonRecipeSelected        = "RecipeSelected"
+	DependencyBuildReasonRecipesExhausted      = "RecipesExhausted"
+	DependencyBuildReasonPipelineRunPending    = "PipelineRunPending"
+	DependencyBuildReasonPipelineRunCreated    = "PipelineRunCreated"
+	DependencyBuildReasonBuilding              = "Building"
+	DependencyBuildReasonBuildSucceeded        = "BuildSucceeded"
+	DependencyBuildReasonRecipeFailed          = "RecipeFailed"
+	DependencyBuildReasonOOMRetry              = "OOMRetry"
+	DependencyBuildReasonCacheRestartRetry     = "CacheRestartRetry"
+	DependencyBuildReasonPipelineRunTimeout    = "PipelineRunTimeout"
+	DependencyBuildReasonTimeoutRetry          = "TimeoutRetry"
+	DependencyBuildReasonTransientFailureRetry = "TransientFailureRetry"
+	DependencyBuildReasonUnrecoverableFailure  = "UnrecoverableFailure"
+	DependencyBuildReasonContaminated          = "ContaminatedByCommunityDependencies"
+	DependencyBuildReasonNotContaminated       = "NotContaminated"
+	DependencyBuildReasonContaminantsResolved  = "ContaminantsResolved"
+	DependencyBuildReasonVerificationPassed    = "VerificationPassed"
+	DependencyBuildReasonVerificationFailed    = "VerificationFailed"
+
+	// The reasons a queued build is waiting, these are used for both the queue status and the PipelineSubmitted condition
+	DependencyBuildReasonNamespaceConcurrencyLimit = "NamespaceConcurrencyLimit"
+	DependencyBuildReasonClusterConcurrencyLimit   = "ClusterConcurrencyLimit"
+	DependencyBuildReasonFairShareExceeded         = "FairShareExceeded"
+	DependencyBuildReasonInsufficientResourceQuota = "InsufficientResourceQuota"
+	DependencyBuildReasonWaitingForEarlierBuilds   = "WaitingForEarlierBuilds"
+)
+
 type DependencyBuildSpec struct {
 	ScmInfo SCMInfo `json:"scm,omitempty"`
 	Version string  `json:"version,omitempty"`
+	// JBSConfig the name of the JBSConfig in the namespace the build uses, the default is jvm-build-config
+	JBSConfig string `json:"jbsConfig,omitempty"`
 }
 
 type DependencyBuildStatus struct {
-	// Conditions for capturing generic status
-	// NOTE: inspecting the fabric8 Status class, it looked analogous to k8s Condition,
-	// and then I took the liberty of making it an array, given best practices in the k8s/ocp ecosystems
+	// Conditions describe the lifecycle of the dependency build, State is a summary of these conditions
+	// that is retained for compatibility
 	Conditions   []metav1.Condition `json:"conditions,omitempty"`
 	State        string             `json:"state,omitempty"`
 	Message      string             `json:"message,omitempty"`
@@ -41,6 +91,93 @@ type DependencyBuildStatus struct {
 	FailedVerification            bool           `json:"failedVerification,omitempty"`
 	DiagnosticDockerFiles         []string       `json:"diagnosticDockerFiles,omitempty"`
 	PipelineRetries               int            `json:"pipelineRetries,omitempty"`
+	// RetriesByCause the number of times the build pipeline has been retried for each cause
+	RetriesByCause map[string]int `json:"retriesByCause,omitempty"`
+	// BuildInfoRetries the number of times the build info analysis pipeline has been retried after running out of memory
+	BuildInfoRetries int `json:"buildInfoRetries,omitempty"`
+	// BuildInfoAdditionalMemory the additional memory in MiB for the build info analysis pipeline
+	BuildInfoAdditionalMemory int `json:"buildInfoAdditionalMemory,omitempty"`
+	// RetryAfter the failed pipeline is not retried until this time
+	RetryAfter *metav1.Time `json:"retryAfter,omitempty"`
+	// AppliedBuildRecipeOverrides the names of the BuildRecipeOverrides that were merged into the build recipes
+	AppliedBuildRecipeOverrides []string `json:"appliedBuildRecipeOverrides,omitempty"`
+	// Queue is set while the build is waiting to be admitted, and cleared once its pipeline is submitted
+	Queue *BuildQueueStatus `json:"queue,omitempty"`
+	// BuildFailures the classified cause of each build pipeline that failed, in the order they ran
+	BuildFailures []BuildFailure `json:"buildFailures,omitempty"`
+	// BuildAttempts every build pipeline that has been run for this build, in the order they ran. This is kept after
+	// the PipelineRuns have been pruned.
+	BuildAttempts []BuildAttempt `json:"buildAttempts,omitempty"`
+	// DiscoveryLogArchives the images holding the step logs of the build info analysis pipelines, in the order they ran
+	DiscoveryLogArchives []string `json:"discoveryLogArchives,omitempty"`
+}
+
+// The outcomes of a build attempt
+const (
+	BuildAttemptOutcomeRunning   = "Running"
+	BuildAttemptOutcomeSucceeded = "Succeeded"
+	BuildAttemptOutcomeFailed    = "Failed"
+	// BuildAttemptOutcomeRetried the attempt failed, but the same recipe was tried again
+	BuildAttemptOutcomeRetried = "Retried"
+)
+
+type BuildAttempt struct {
+	// PipelineRun the name of the build pipeline
+	PipelineRun string `json:"pipelineRun"`
+	// PipelineRunUID the UID of the build pipeline
+	PipelineRunUID types.UID `json:"pipelineRunUid,omitempty"`
+	// Recipe the recipe the pipeline was built with, including any additional memory and timeouts
+	Recipe *BuildRecipe `json:"recipe,omitempty"`
+	// StartTime when the pipeline was created
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+	// CompletionTime when the pipeline finished
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+	// Outcome one of the BuildAttemptOutcome values
+	Outcome string `json:"outcome"`
+	// Cause the BuildFailureClass of a failed attempt, or the reason a retried attempt was retried
+	Cause string `json:"cause,omitempty"`
+	// Message a human readable description of the outcome
+	Message string `json:"message,omitempty"`
+	// ImageDigest the digest of the image the artifacts were deployed to, if the attempt succeeded
+	ImageDigest string `json:"imageDigest,omitempty"`
+	// LogArchive the image holding the step logs of the pipeline, referenced by digest
+	LogArchive string `json:"logArchive,omitempty"`
+}
+
+// The classes of build failure, these decide if the build is retried with the same recipe, tries the next recipe or
+// fails straight away
+const (
+	BuildFailureClassGitClone             = "GitClone"
+	BuildFailureClassDependencyResolution = "DependencyResolution"
+	BuildFailureClassCompilation          = "Compilation"
+	BuildFailureClassTest                 = "Test"
+	BuildFailureClassEnforceVersion       = "EnforceVersion"
+	BuildFailureClassDeploy               = "Deploy"
+	BuildFailureClassTimeout              = "Timeout"
+	BuildFailureClassOOM                  = "OOM"
+	BuildFailureClassUnknown              = "Unknown"
+)
+
+type BuildFailure struct {
+	// PipelineRun the name of the build pipeline that failed
+	PipelineRun string `json:"pipelineRun"`
+	// Class the kind of failure, one of the BuildFailureClass values
+	Class string `json:"class"`
+	// Step the name of the step that failed, if it is known
+	Step string `json:"step,omitempty"`
+	// Message a human readable description of the failure, such as the matching log line
+	Message string `json:"message,omitempty"`
+}
+
+type BuildQueueStatus struct {
+	// Position the position of the build in the queue for its namespace, starting at 1
+	Position int `json:"position"`
+	// Reason a machine readable reason for waiting
+	Reason string `json:"reason,omitempty"`
+	// Message a human readable description of why the build is waiting
+	Message string `json:"message,omitempty"`
+	// Since the time the build entered the queue
+	Since metav1.Time `json:"since"`
 }
 
 // +genclient
@@ -51,6 +188,8 @@ type DependencyBuildStatus struct {
 // +kubebuilder:printcolumn:name="Tag",type=string,JSONPath=`.spec.scm.tag`
 // +kubebuilder:printcolumn:name="State",type=string,JSONPath=`.status.state`
 // +kubebuilder:printcolumn:name="Message",type=string,JSONPath=`.status.message`
+// +kubebuilder:printcolumn:name="Queue Position",type=integer,JSONPath=`.status.queue.position`,priority=1
+// +kubebuilder:printcolumn:name="JBSConfig",type=string,JSONPath=`.spec.jbsConfig`,priority=1
 
 // DependencyBuild TODO provide godoc description
 type DependencyBuild struct {
@@ -84,11 +223,22 @@ type BuildRecipe struct {
 	DisableSubmodules   bool                 `json:"disableSubmodules,omitempty"`
 	AdditionalMemory    int                  `json:"additionalMemory,omitempty"`
 	Repositories        []string             `json:"repositories,omitempty"`
+	// PipelineTimeout overrides the pipeline timeout from the JBSConfig
+	PipelineTimeout *metav1.Duration `json:"pipelineTimeout,omitempty"`
+	// StepTimeout overrides the step timeout from the JBSConfig
+	StepTimeout *metav1.Duration `json:"stepTimeout,omitempty"`
 }
 type Contaminant struct {
 	GAV                   string   `json:"gav,omitempty"`
 	ContaminatedArtifacts []string `json:"contaminatedArtifacts,omitempty"`
 }
+
+const (
+	AdditionalDownloadTypeTar        = "tar"
+	AdditionalDownloadTypeExecutable = "executable"
+	AdditionalDownloadTypeRpm        = "rpm"
+)
+
 type AdditionalDownload struct {
 	Uri         string `json:"uri,omitempty"`
 	Sha256      string `json:"sha256,omitempty"`
@@ -1,6 +1,12 @@
 package v1alpha1
 
-import metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
 
 const (
 	JBSConfigName                           = "jvm-build-config"
@@ -18,6 +24,63 @@ const (
 	ConfigArtifactCacheIOThreadsDefault     = "4"
 	ConfigArtifactCacheWorkerThreadsDefault = "50"
 	ConfigArtifactCacheStorageDefault       = "10Gi"
+	// MavenRepositoryKeyPattern is the format of the keys in MavenBaseLocations, the number gives the position of the
+	// repository in the list of repositories and the suffix is the repository name
+	MavenRepositoryKeyPattern = `maven-repository-(\d+)-([\w-]+)`
+
+	// CacheLabel is set on the pods of every cache deployment, the value is the name of the deployment
+	CacheLabel = "jvmbuildservice.io/cache"
+	// DefaultBuildPolicy the cache build policy of a JBSConfig that has its own cache
+	DefaultBuildPolicy = "default"
+
+	// BuildTimeoutPolicyNextRecipe a build that times out moves on to the next recipe, this is the default
+	BuildTimeoutPolicyNextRecipe = "NextRecipe"
+	// BuildTimeoutPolicyRetryWithLongerTimeout a build that times out is retried with the same recipe and double the
+	// timeout, until the maximum pipeline timeout is reached
+	BuildTimeoutPolicyRetryWithLongerTimeout = "RetryWithLongerTimeout"
+
+	// RetryCauseCacheRestart the key in RetryPolicy.MaxRetries for builds that failed while the cache restarted, the
+	// other keys are the BuildFailureClass values
+	RetryCauseCacheRestart = "CacheRestart"
+)
+
+const (
+	// JBSConfigConditionCacheReady The cache Deployment is available and none of its pods are failing
+	JBSConfigConditionCacheReady = "CacheReady"
+	// JBSConfigConditionRegistryCredentialsReady The image registry secret exists and has a token, or rebuilds are
+	// disabled so it is not needed
+	JBSConfigConditionRegistryCredentialsReady = "RegistryCredentialsReady"
+	// JBSConfigConditionTLSReady The serving certificate of the cache and the CA bundle used by the builds exist, or
+	// TLS is disabled
+	JBSConfigConditionTLSReady = "TLSReady"
+	// JBSConfigConditionRecipeRepositoriesConfigured The additional recipe repositories and Maven repositories are
+	// valid
+	JBSConfigConditionRecipeRepositoriesConfigured = "RecipeRepositoriesConfigured"
+	// JBSConfigConditionRebuildsEnabled Rebuilds are enabled and the registry credentials are ready
+	JBSConfigConditionRebuildsEnabled = "RebuildsEnabled"
+	// JBSConfigConditionReady The cache, registry credentials, TLS and repositories are all ready. It does not depend
+	// on RebuildsEnabled, a JBSConfig that only caches artifacts is ready.
+	JBSConfigConditionReady = "Ready"
+
+	JBSConfigReasonCacheAvailable              = "CacheAvailable"
+	JBSConfigReasonCacheProgressing            = "CacheProgressing"
+	JBSConfigReasonCacheFailing                = "CacheFailing"
+	JBSConfigReasonCacheNotFound               = "CacheNotFound"
+	JBSConfigReasonInvalidCacheSettings        = "InvalidCacheSettings"
+	JBSConfigReasonCredentialsFound            = "CredentialsFound"
+	JBSConfigReasonCredentialsNotRequired      = "CredentialsNotRequired"
+	JBSConfigReasonSecretNotFound              = "SecretNotFound"
+	JBSConfigReasonTokenNotFound               = "TokenNotFound"
+	JBSConfigReasonCertificateIssued           = "CertificateIssued"
+	JBSConfigReasonCertificateNotIssued        = "CertificateNotIssued"
+	JBSConfigReasonCABundleNotInjected         = "CABundleNotInjected"
+	JBSConfigReasonTLSDisabled                 = "TLSDisabled"
+	JBSConfigReasonRepositoriesConfigured      = "RepositoriesConfigured"
+	JBSConfigReasonInvalidRepository           = "InvalidRepository"
+	JBSConfigReasonRebuildsEnabled             = "RebuildsEnabled"
+	JBSConfigReasonRebuildsDisabled            = "RebuildsDisabled"
+	JBSConfigReasonRegistryCredentialsNotReady = "RegistryCredentialsNotReady"
+	JBSConfigReasonReady                       = "Ready"
 )
 
 type JBSConfigSpec struct {
@@ -35,11 +98,20 @@ type JBSConfigSpec struct {
 	CacheSettings      CacheSettings              `json:"cacheSettings,omitempty"`
 	BuildSettings      BuildSettings              `json:"buildSettings,omitempty"`
 	RelocationPatterns []RelocationPatternElement `json:"relocationPatterns,omitempty"`
+	// How long finished builds are kept, by default nothing is cleaned up
+	Retention RetentionSettings `json:"retention,omitempty"`
+	// Where CloudEvents for the state changes of the builds in the namespace are sent, this overrides the sink in
+	// the SystemConfig
+	EventSink EventSink `json:"eventSink,omitempty"`
+	// How the images of the RebuiltArtifacts are checked, in case they are removed from the registry
+	ImageCheck ImageCheckSettings `json:"imageCheck,omitempty"`
 }
 
 type JBSConfigStatus struct {
-	Message       string         `json:"message,omitempty"`
-	ImageRegistry *ImageRegistry `json:"imageRegistry,omitempty"`
+	// Conditions the readiness of the cache, registry credentials, TLS and repositories, Ready summarises them
+	Conditions    []metav1.Condition `json:"conditions,omitempty"`
+	Message       string             `json:"message,omitempty"`
+	ImageRegistry *ImageRegistry     `json:"imageRegistry,omitempty"`
 }
 
 type CacheSettings struct {
@@ -51,6 +123,10 @@ type CacheSettings struct {
 	WorkerThreads string `json:"workerThreads,omitempty"`
 	Storage       string `json:"storage,omitempty"`
 	DisableTLS    bool   `json:"disableTLS,omitempty"`
+	// If this is true a JBSConfig other than jvm-build-config does not deploy its own cache. It uses the cache of
+	// jvm-build-config instead, with its repositories, relocation patterns and registry in a build policy named
+	// after the JBSConfig. The other cache settings are ignored, except DisableTLS which must match jvm-build-config.
+	Shared bool `json:"shared,omitempty"`
 }
 
 type BuildSettings struct {
@@ -66,7 +142,81 @@ type BuildSettings struct {
 	TaskLimitMemory string `json:"taskLimitMemory,omitempty"`
 	// The CPU limit for all other steps of a pipeline
 	TaskLimitCPU string `json:"taskLimitCPU,omitempty"`
+	// The maximum number of build pipelines that can run at the same time in the namespace, 0 means no limit.
+	// Additional builds wait in the DependencyBuildStateQueued state.
+	MaxConcurrentBuilds int `json:"maxConcurrentBuilds,omitempty"`
+	// The timeout for a build pipeline, the default is 3h. This can be overridden by the build recipe.
+	PipelineTimeout *metav1.Duration `json:"pipelineTimeout,omitempty"`
+	// The timeout for each step of a build pipeline, by default steps are only limited by the pipeline timeout.
+	// This can be overridden by the build recipe.
+	StepTimeout *metav1.Duration `json:"stepTimeout,omitempty"`
+	// What to do when a build pipeline times out, either NextRecipe or RetryWithLongerTimeout
+	// +kubebuilder:validation:Enum=NextRecipe;RetryWithLongerTimeout
+	TimeoutPolicy string `json:"timeoutPolicy,omitempty"`
+	// The longest timeout the RetryWithLongerTimeout policy will use, the default is 12h
+	MaxPipelineTimeout *metav1.Duration `json:"maxPipelineTimeout,omitempty"`
+	// How failed build and build info analysis pipelines are retried, any field that is not set uses the value
+	// from the SystemConfig
+	RetryPolicy RetryPolicy `json:"retryPolicy,omitempty"`
+	// If this is true the step logs of finished pipelines are not pushed to the image registry. By default they are
+	// archived next to the rebuilt images, so they are still available after the PipelineRuns have been pruned.
+	DisableLogArchive bool `json:"disableLogArchive,omitempty"`
+}
+
+// RetryPolicy controls how failed pipelines are retried. Fields that are not set use the SystemConfig value, and
+// then the built in default.
+type RetryPolicy struct {
+	// MaxRetries the number of times a pipeline is retried for each cause, keyed by OOM, CacheRestart, GitClone,
+	// Deploy or Timeout. The default is 3 for every cause. Timeouts are only retried with the RetryWithLongerTimeout
+	// timeout policy.
+	MaxRetries map[string]int `json:"maxRetries,omitempty"`
+	// MemoryIncrement the memory in MiB that is added the first time a pipeline runs out of memory, the default is 512
+	MemoryIncrement int `json:"memoryIncrement,omitempty"`
+	// MemoryGrowthFactor the additional memory is multiplied by this for each further retry, the default is 2
+	MemoryGrowthFactor int `json:"memoryGrowthFactor,omitempty"`
+	// MemoryCeiling a pipeline that runs out of memory is not retried once its additional memory reaches this,
+	// in MiB. The default is 2048.
+	MemoryCeiling int `json:"memoryCeiling,omitempty"`
+	// InitialBackoff the delay before the first retry, which doubles for each further retry. The default is 10s,
+	// and 0s retries straight away.
+	InitialBackoff *metav1.Duration `json:"initialBackoff,omitempty"`
+	// MaxBackoff the longest delay before a retry, the default is 10m
+	MaxBackoff *metav1.Duration `json:"maxBackoff,omitempty"`
 }
+
+// RetentionSettings controls the garbage collection of finished builds. ArtifactBuilds that another build still
+// depends on, such as the ones that resolve a contaminated DependencyBuild, are kept until they are no longer needed.
+type RetentionSettings struct {
+	// CompletedArtifactBuildTTL how long an ArtifactBuild is kept after it completed, by default it is kept forever
+	CompletedArtifactBuildTTL *metav1.Duration `json:"completedArtifactBuildTTL,omitempty"`
+	// FailedArtifactBuildTTL how long an ArtifactBuild is kept after it failed or was found to be missing, by default
+	// it is kept forever
+	FailedArtifactBuildTTL *metav1.Duration `json:"failedArtifactBuildTTL,omitempty"`
+	// PipelineRunsPerDependencyBuild the number of finished PipelineRuns that are kept for each DependencyBuild, the
+	// oldest are deleted first. 0 keeps all of them.
+	PipelineRunsPerDependencyBuild int `json:"pipelineRunsPerDependencyBuild,omitempty"`
+	// If this is true the RebuiltArtifacts are deleted with the DependencyBuild that produced them. By default they
+	// are kept, so the rebuilt artifacts are still used after the builds have been cleaned up.
+	DeleteRebuiltArtifacts bool `json:"deleteRebuiltArtifacts,omitempty"`
+}
+
+// ImageCheckSettings controls how often the images of the RebuiltArtifacts are checked against the registry, and
+// what happens when one has been removed
+type ImageCheckSettings struct {
+	// Interval how often each image is checked, the default is 24h. 0s only checks the image when the
+	// RebuiltArtifact is created or updated.
+	Interval *metav1.Duration `json:"interval,omitempty"`
+	// If this is true the ArtifactBuilds of an artifact whose image has been removed are annotated to be rebuilt, so
+	// the image is deployed again. By default the artifact is only reported as missing.
+	RebuildMissing bool `json:"rebuildMissing,omitempty"`
+}
+
+// EventSink an HTTP endpoint that receives CloudEvents
+type EventSink struct {
+	// URL the events are posted to in the structured JSON format, no events are sent if this is empty
+	URL string `json:"url,omitempty"`
+}
+
 type ImageRegistry struct {
 	Host       string `json:"host,omitempty"`
 	Port       string `json:"port,omitempty"`
@@ -98,7 +248,10 @@ type Pattern struct {
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 // +kubebuilder:subresource:status
 // +kubebuilder:resource:path=jbsconfigs,scope=Namespaced
-// +kubebuilder:printcolumn:name="Message",type=string,JSONPath=`.status.message`
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+// +kubebuilder:printcolumn:name="Reason",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].reason`
+// +kubebuilder:printcolumn:name="Rebuilds",type=string,JSONPath=`.status.conditions[?(@.type=="RebuildsEnabled")].status`
+// +kubebuilder:printcolumn:name="Message",type=string,JSONPath=`.status.message`,priority=1
 // JBSConfig TODO provide godoc description
 type JBSConfig struct {
 	metav1.TypeMeta   `json:",inline"`
@@ -108,6 +261,78 @@ type JBSConfig struct {
 	Status JBSConfigStatus `json:"status,omitempty"`
 }
 
+// JBSConfigNameOrDefault the name of the JBSConfig a build references, or the default JBSConfig if it does not
+// reference one
+func JBSConfigNameOrDefault(name string) string {
+	if name == "" {
+		return JBSConfigName
+	}
+	return name
+}
+
+// IsDefault returns true for the JBSConfig that builds use if they do not reference one. An empty JBSConfig, which
+// is what callers end up with if the JBSConfig does not exist, is treated as the default.
+func (in *JBSConfig) IsDefault() bool {
+	return in.Name == JBSConfigName || in.Name == ""
+}
+
+// SharesCache returns true if the JBSConfig uses the cache of the default JBSConfig
+func (in *JBSConfig) SharesCache() bool {
+	return !in.IsDefault() && in.Spec.CacheSettings.Shared
+}
+
+// maxCacheNameLength the cache name is a service name and label value, and the TLS service adds a -tls suffix, so it
+// has to fit in a 63 character DNS label with the suffix
+const maxCacheNameLength = 59
+
+// CacheName the name of the deployment, service and storage of the cache the builds of this JBSConfig use, the
+// default JBSConfig keeps the original names. Long names are shortened, with a hash of the JBSConfig name so they
+// stay unique.
+func (in *JBSConfig) CacheName() string {
+	if in.IsDefault() || in.SharesCache() {
+		return CacheDeploymentName
+	}
+	name := CacheDeploymentName + "-" + in.Name
+	if len(name) <= maxCacheNameLength {
+		return name
+	}
+	hash := sha256.Sum256([]byte(in.Name))
+	suffix := "-" + hex.EncodeToString(hash[:])[:8]
+	return strings.TrimRight(name[:maxCacheNameLength-len(suffix)], "-.") + suffix
+}
+
+// BuildPolicy the cache build policy the builds of this JBSConfig resolve artifacts with
+func (in *JBSConfig) BuildPolicy() string {
+	if in.SharesCache() {
+		return in.Name
+	}
+	return DefaultBuildPolicy
+}
+
+// ImageSecret the name of the secret with the image registry credentials of this JBSConfig
+func (in *JBSConfig) ImageSecret() string {
+	if in.IsDefault() {
+		return ImageSecretName
+	}
+	return ImageSecretName + "-" + in.Name
+}
+
+// TlsSecret the name of the secret with the serving certificate of the cache
+func (in *JBSConfig) TlsSecret() string {
+	if in.CacheName() == CacheDeploymentName {
+		return TlsSecretName
+	}
+	return TlsSecretName + "-" + in.Name
+}
+
+// CacheURL the URL of the cache service, without a path
+func (in *JBSConfig) CacheURL() string {
+	if in.Spec.CacheSettings.DisableTLS {
+		return "http://" + in.CacheName() + "." + in.Namespace + ".svc.cluster.local"
+	}
+	return "https://" + in.CacheName() + "-tls." + in.Namespace + ".svc.cluster.local"
+}
+
 func (in *JBSConfig) ImageRegistry() ImageRegistry {
 	ret := in.Spec.ImageRegistry
 	if in.Status.ImageRegistry == nil {
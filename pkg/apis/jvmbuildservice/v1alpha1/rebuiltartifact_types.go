@@ -4,20 +4,66 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+const (
+	// RebuiltArtifactConditionImageAvailable The image and digest the artifact was deployed to exist in the registry
+	RebuiltArtifactConditionImageAvailable = "ImageAvailable"
+	// RebuiltArtifactConditionVerified The build that produced the artifact passed verification against the upstream
+	// artifacts
+	RebuiltArtifactConditionVerified = "Verified"
+	// RebuiltArtifactConditionReady The artifact can be trusted, the image is available and the build passed verification
+	RebuiltArtifactConditionReady = "Ready"
+
+	RebuiltArtifactReasonImageFound              = "ImageFound"
+	RebuiltArtifactReasonImageMissing            = "ImageMissing"
+	RebuiltArtifactReasonImageCheckFailed        = "ImageCheckFailed"
+	RebuiltArtifactReasonVerificationPassed      = "VerificationPassed"
+	RebuiltArtifactReasonVerificationFailed      = "VerificationFailed"
+	RebuiltArtifactReasonVerificationNotReported = "VerificationNotReported"
+	RebuiltArtifactReasonDependencyBuildNotFound = "DependencyBuildNotFound"
+	RebuiltArtifactReasonReady                   = "Ready"
+	// RebuiltArtifactReasonRebuildRequested the image was missing and the ArtifactBuilds were annotated to rebuild it
+	RebuiltArtifactReasonRebuildRequested = "RebuildRequested"
+)
+
 type RebuiltArtifactSpec struct {
 	// The GAV of the rebuilt artifact
 	GAV    string `json:"gav,omitempty"`
 	Image  string `json:"image,omitempty"`
 	Digest string `json:"digest,omitempty"`
+	// JBSConfig the name of the JBSConfig of the build that produced the artifact, the default is jvm-build-config
+	JBSConfig string `json:"jbsConfig,omitempty"`
 }
 
 type RebuiltArtifactStatus struct {
+	// Conditions the availability and verification of the artifact, Ready summarises them
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+	// DependencyBuild the build that produced the artifact, as namespace/name
+	DependencyBuild string `json:"dependencyBuild,omitempty"`
+	// SCMURL the repository the artifact was built from
+	SCMURL string `json:"scmURL,omitempty"`
+	// CommitHash the commit the artifact was built from
+	CommitHash string `json:"commitHash,omitempty"`
+	// ArtifactBuilds the ArtifactBuilds in the namespace that request this artifact
+	ArtifactBuilds []string `json:"artifactBuilds,omitempty"`
+	// LastImageCheck when the image was last checked against the registry
+	LastImageCheck *metav1.Time `json:"lastImageCheck,omitempty"`
+	// SBOM the image holding the CycloneDX SBOM of the build that produced the artifact, referenced by digest. It is
+	// pushed as an OCI referrer of the artifact image.
+	SBOM string `json:"sbom,omitempty"`
+	// Provenance the image holding the signed SLSA provenance of the build that produced the artifact, referenced by
+	// digest. It is pushed as an OCI referrer of the artifact image.
+	Provenance string `json:"provenance,omitempty"`
 }
 
 // +genclient
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:subresource:status
 // +kubebuilder:resource:path=rebuiltartifacts,scope=Namespaced
 // +kubebuilder:printcolumn:name="GAV",type=string,JSONPath=`.spec.gav`
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+// +kubebuilder:printcolumn:name="Reason",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].reason`
+// +kubebuilder:printcolumn:name="Build",type=string,JSONPath=`.status.dependencyBuild`,priority=1
+// +kubebuilder:printcolumn:name="Image",type=string,JSONPath=`.spec.image`,priority=1
 // RebuiltArtifact An artifact that has been rebuilt and deployed to S3 or a Container registry
 type RebuiltArtifact struct {
 	metav1.TypeMeta   `json:",inline"`
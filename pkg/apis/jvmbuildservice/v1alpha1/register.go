@@ -42,6 +42,8 @@ func addKnownTypes(scheme *runtime.Scheme) error {
 		&JBSConfigList{},
 		&RebuiltArtifact{},
 		&RebuiltArtifactList{},
+		&BuildRecipeOverride{},
+		&BuildRecipeOverrideList{},
 	)
 	// &Condition{},
 	// &ConditionList{},
@@ -12,6 +12,26 @@ const (
 
 	OpenShiftQuota = QuotaImpl("openshift")
 	K8SQuota       = QuotaImpl("kubernetes")
+
+	// RegistryProviderQuay repositories and robot accounts are created in a Quay organization
+	RegistryProviderQuay = "quay"
+	// RegistryProviderHarbor repositories and robot accounts are created in a Harbor project for each namespace
+	RegistryProviderHarbor = "harbor"
+	// RegistryProviderStatic every namespace pushes to its own path in a registry with the same credentials, for
+	// registries that create repositories on push such as a plain OCI distribution registry
+	RegistryProviderStatic = "static"
+	// DefaultRegistryCredentialsSecret the Secret the provisioning credentials are read from if the SystemConfig does
+	// not name one
+	DefaultRegistryCredentialsSecret = "quaytoken" //#nosec
+
+	// SystemConfigConditionRegistryProvisioning Repositories can be provisioned for JBSConfigs that do not specify an
+	// image registry owner
+	SystemConfigConditionRegistryProvisioning = "RegistryProvisioningAvailable"
+
+	SystemConfigReasonProvisionerAvailable = "ProvisionerAvailable"
+	SystemConfigReasonProvisioningDisabled = "ProvisioningDisabled"
+	SystemConfigReasonCredentialsNotFound  = "CredentialsNotFound"
+	SystemConfigReasonInvalidProvisioning  = "InvalidConfiguration"
 )
 
 type QuotaImpl string
@@ -22,6 +42,76 @@ type SystemConfigSpec struct {
 	//DEPRECATED
 	Quota          QuotaImpl `json:"quota,omitempty"`
 	RecipeDatabase string    `json:"recipeDatabase,omitempty"`
+	// BuildQueue limits the number of build pipelines that run at the same time across the cluster
+	BuildQueue BuildQueueSettings `json:"buildQueue,omitempty"`
+	// RetryPolicy the defaults for the retry policy in each JBSConfig
+	RetryPolicy RetryPolicy `json:"retryPolicy,omitempty"`
+	// ArtifactSharing lets namespaces use the artifacts rebuilt by other namespaces, by default each namespace
+	// rebuilds its own
+	ArtifactSharing ArtifactSharingSettings `json:"artifactSharing,omitempty"`
+	// EventSink where CloudEvents for the state changes of builds are sent, for namespaces that do not configure
+	// their own sink
+	EventSink EventSink `json:"eventSink,omitempty"`
+	// EventSinkAllowedHosts the hosts the event sinks of JBSConfigs may send to, a leading *. matches any subdomain.
+	// A JBSConfig sink on any other host is not used, so editing a JBSConfig can't make the controller post to an
+	// arbitrary URL. The SystemConfig sink is always allowed.
+	EventSinkAllowedHosts []string `json:"eventSinkAllowedHosts,omitempty"`
+	// Provenance signs an SLSA provenance statement for every successful build, and attaches it to the image the
+	// artifacts were deployed to
+	Provenance ProvenanceSettings `json:"provenance,omitempty"`
+	// RegistryProvisioning creates an image repository and push credentials for each JBSConfig that does not
+	// specify an image registry owner
+	RegistryProvisioning RegistryProvisioningSettings `json:"registryProvisioning,omitempty"`
+}
+
+// RegistryProvisioningSettings the registry that repositories are provisioned in
+type RegistryProvisioningSettings struct {
+	// Provider the type of registry, quay, harbor or static. If this is not set Quay is used when the credentials
+	// have a Quay token, otherwise repositories are not provisioned.
+	// +kubebuilder:validation:Enum=quay;harbor;static
+	Provider string `json:"provider,omitempty"`
+	// Host the registry the images are pushed to, the default for Quay is quay.io
+	Host string `json:"host,omitempty"`
+	// APIURL the API of the registry, the default for Quay is https://quay.io/api/v1 and for Harbor it is
+	// /api/v2.0 on the host
+	APIURL string `json:"apiURL,omitempty"`
+	// Owner the Quay organization the repositories are created in, the prefix of the Harbor project of each
+	// namespace, or the path the static repositories are under
+	Owner string `json:"owner,omitempty"`
+	// Insecure the registry is accessed over plain HTTP
+	Insecure bool `json:"insecure,omitempty"`
+	// CredentialsSecret the Secret in the controller namespace the provisioner administers the registry with, the
+	// default is quaytoken. Quay uses the quaytoken and organization keys, Harbor and static registries use the
+	// username and password keys. Changes to the Secret are picked up without restarting the controller.
+	CredentialsSecret string `json:"credentialsSecret,omitempty"`
+}
+
+type ProvenanceSettings struct {
+	// SigningKeySecret the name of the Secret in the controller namespace that holds the PEM encoded private key the
+	// provenance is signed with. No provenance is produced if this is not set.
+	SigningKeySecret string `json:"signingKeySecret,omitempty"`
+	// SigningKeySecretKey the key of the private key in the Secret, defaults to private.key
+	SigningKeySecretKey string `json:"signingKeySecretKey,omitempty"`
+}
+
+// ArtifactSharingSettings the trust boundaries for sharing rebuilt artifacts between namespaces. When a verified
+// build of the same source exists in a trusted namespace an ArtifactBuild uses it instead of creating a new
+// DependencyBuild.
+type ArtifactSharingSettings struct {
+	// TrustedNamespaces the namespaces whose builds can be used by other namespaces, in order of preference.
+	// Sharing is disabled if this is empty.
+	TrustedNamespaces []string `json:"trustedNamespaces,omitempty"`
+	// ConsumerNamespaces the namespaces that can use the shared builds, if this is empty every namespace can
+	ConsumerNamespaces []string `json:"consumerNamespaces,omitempty"`
+}
+
+type BuildQueueSettings struct {
+	// MaxConcurrentBuilds the maximum number of build pipelines across all namespaces, 0 means no limit.
+	// While builds are waiting in more than one namespace each namespace gets an equal share of this limit.
+	MaxConcurrentBuilds int `json:"maxConcurrentBuilds,omitempty"`
+	// MaxConcurrentBuildsPerNamespace the highest limit a namespace can set in its JBSConfig, 0 means no limit.
+	// This is also used for namespaces that do not set a limit.
+	MaxConcurrentBuildsPerNamespace int `json:"maxConcurrentBuildsPerNamespace,omitempty"`
 }
 
 type JavaVersionInfo struct {
@@ -31,12 +121,15 @@ type JavaVersionInfo struct {
 }
 
 type SystemConfigStatus struct {
+	// Conditions the state of the cluster wide services, such as registry provisioning
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
 // +genclient
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 // +kubebuilder:subresource:status
 // +kubebuilder:resource:path=systemconfigs,scope=Cluster
+// +kubebuilder:printcolumn:name="Provisioning",type=string,JSONPath=`.status.conditions[?(@.type=="RegistryProvisioningAvailable")].reason`
 // SystemConfig TODO provide godoc description
 type SystemConfig struct {
 	metav1.TypeMeta   `json:",inline"`
@@ -47,7 +47,7 @@ func (in *ArtifactBuild) DeepCopyInto(out *ArtifactBuild) {
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	out.Spec = in.Spec
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 	return
 }
 
@@ -121,6 +121,13 @@ func (in *ArtifactBuildSpec) DeepCopy() *ArtifactBuildSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ArtifactBuildStatus) DeepCopyInto(out *ArtifactBuildStatus) {
 	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	out.SCMInfo = in.SCMInfo
 	return
 }
@@ -135,6 +142,110 @@ func (in *ArtifactBuildStatus) DeepCopy() *ArtifactBuildStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ArtifactSharingSettings) DeepCopyInto(out *ArtifactSharingSettings) {
+	*out = *in
+	if in.TrustedNamespaces != nil {
+		in, out := &in.TrustedNamespaces, &out.TrustedNamespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ConsumerNamespaces != nil {
+		in, out := &in.ConsumerNamespaces, &out.ConsumerNamespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ArtifactSharingSettings.
+func (in *ArtifactSharingSettings) DeepCopy() *ArtifactSharingSettings {
+	if in == nil {
+		return nil
+	}
+	out := new(ArtifactSharingSettings)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BuildAttempt) DeepCopyInto(out *BuildAttempt) {
+	*out = *in
+	if in.Recipe != nil {
+		in, out := &in.Recipe, &out.Recipe
+		*out = new(BuildRecipe)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BuildAttempt.
+func (in *BuildAttempt) DeepCopy() *BuildAttempt {
+	if in == nil {
+		return nil
+	}
+	out := new(BuildAttempt)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BuildFailure) DeepCopyInto(out *BuildFailure) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BuildFailure.
+func (in *BuildFailure) DeepCopy() *BuildFailure {
+	if in == nil {
+		return nil
+	}
+	out := new(BuildFailure)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BuildQueueSettings) DeepCopyInto(out *BuildQueueSettings) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BuildQueueSettings.
+func (in *BuildQueueSettings) DeepCopy() *BuildQueueSettings {
+	if in == nil {
+		return nil
+	}
+	out := new(BuildQueueSettings)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BuildQueueStatus) DeepCopyInto(out *BuildQueueStatus) {
+	*out = *in
+	in.Since.DeepCopyInto(&out.Since)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BuildQueueStatus.
+func (in *BuildQueueStatus) DeepCopy() *BuildQueueStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(BuildQueueStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *BuildRecipe) DeepCopyInto(out *BuildRecipe) {
 	*out = *in
@@ -153,6 +264,16 @@ func (in *BuildRecipe) DeepCopyInto(out *BuildRecipe) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.PipelineTimeout != nil {
+		in, out := &in.PipelineTimeout, &out.PipelineTimeout
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.StepTimeout != nil {
+		in, out := &in.StepTimeout, &out.StepTimeout
+		*out = new(v1.Duration)
+		**out = **in
+	}
 	return
 }
 
@@ -166,9 +287,165 @@ func (in *BuildRecipe) DeepCopy() *BuildRecipe {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BuildRecipeOverride) DeepCopyInto(out *BuildRecipeOverride) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	out.Status = in.Status
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BuildRecipeOverride.
+func (in *BuildRecipeOverride) DeepCopy() *BuildRecipeOverride {
+	if in == nil {
+		return nil
+	}
+	out := new(BuildRecipeOverride)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *BuildRecipeOverride) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BuildRecipeOverrideList) DeepCopyInto(out *BuildRecipeOverrideList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]BuildRecipeOverride, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BuildRecipeOverrideList.
+func (in *BuildRecipeOverrideList) DeepCopy() *BuildRecipeOverrideList {
+	if in == nil {
+		return nil
+	}
+	out := new(BuildRecipeOverrideList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *BuildRecipeOverrideList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BuildRecipeOverrideMatch) DeepCopyInto(out *BuildRecipeOverrideMatch) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BuildRecipeOverrideMatch.
+func (in *BuildRecipeOverrideMatch) DeepCopy() *BuildRecipeOverrideMatch {
+	if in == nil {
+		return nil
+	}
+	out := new(BuildRecipeOverrideMatch)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BuildRecipeOverrideSpec) DeepCopyInto(out *BuildRecipeOverrideSpec) {
+	*out = *in
+	out.Match = in.Match
+	if in.AdditionalDownloads != nil {
+		in, out := &in.AdditionalDownloads, &out.AdditionalDownloads
+		*out = make([]AdditionalDownload, len(*in))
+		copy(*out, *in)
+	}
+	if in.Repositories != nil {
+		in, out := &in.Repositories, &out.Repositories
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.DisableSubmodules != nil {
+		in, out := &in.DisableSubmodules, &out.DisableSubmodules
+		*out = new(bool)
+		**out = **in
+	}
+	if in.CommandLine != nil {
+		in, out := &in.CommandLine, &out.CommandLine
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.PipelineTimeout != nil {
+		in, out := &in.PipelineTimeout, &out.PipelineTimeout
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.StepTimeout != nil {
+		in, out := &in.StepTimeout, &out.StepTimeout
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BuildRecipeOverrideSpec.
+func (in *BuildRecipeOverrideSpec) DeepCopy() *BuildRecipeOverrideSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(BuildRecipeOverrideSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BuildRecipeOverrideStatus) DeepCopyInto(out *BuildRecipeOverrideStatus) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BuildRecipeOverrideStatus.
+func (in *BuildRecipeOverrideStatus) DeepCopy() *BuildRecipeOverrideStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(BuildRecipeOverrideStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *BuildSettings) DeepCopyInto(out *BuildSettings) {
 	*out = *in
+	if in.PipelineTimeout != nil {
+		in, out := &in.PipelineTimeout, &out.PipelineTimeout
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.StepTimeout != nil {
+		in, out := &in.StepTimeout, &out.StepTimeout
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.MaxPipelineTimeout != nil {
+		in, out := &in.MaxPipelineTimeout, &out.MaxPipelineTimeout
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	in.RetryPolicy.DeepCopyInto(&out.RetryPolicy)
 	return
 }
 
@@ -351,6 +628,44 @@ func (in *DependencyBuildStatus) DeepCopyInto(out *DependencyBuildStatus) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.RetriesByCause != nil {
+		in, out := &in.RetriesByCause, &out.RetriesByCause
+		*out = make(map[string]int, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.RetryAfter != nil {
+		in, out := &in.RetryAfter, &out.RetryAfter
+		*out = (*in).DeepCopy()
+	}
+	if in.AppliedBuildRecipeOverrides != nil {
+		in, out := &in.AppliedBuildRecipeOverrides, &out.AppliedBuildRecipeOverrides
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Queue != nil {
+		in, out := &in.Queue, &out.Queue
+		*out = new(BuildQueueStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.BuildFailures != nil {
+		in, out := &in.BuildFailures, &out.BuildFailures
+		*out = make([]BuildFailure, len(*in))
+		copy(*out, *in)
+	}
+	if in.BuildAttempts != nil {
+		in, out := &in.BuildAttempts, &out.BuildAttempts
+		*out = make([]BuildAttempt, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.DiscoveryLogArchives != nil {
+		in, out := &in.DiscoveryLogArchives, &out.DiscoveryLogArchives
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -364,6 +679,43 @@ func (in *DependencyBuildStatus) DeepCopy() *DependencyBuildStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EventSink) DeepCopyInto(out *EventSink) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EventSink.
+func (in *EventSink) DeepCopy() *EventSink {
+	if in == nil {
+		return nil
+	}
+	out := new(EventSink)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageCheckSettings) DeepCopyInto(out *ImageCheckSettings) {
+	*out = *in
+	if in.Interval != nil {
+		in, out := &in.Interval, &out.Interval
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageCheckSettings.
+func (in *ImageCheckSettings) DeepCopy() *ImageCheckSettings {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageCheckSettings)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ImageRegistry) DeepCopyInto(out *ImageRegistry) {
 	*out = *in
@@ -458,7 +810,7 @@ func (in *JBSConfigSpec) DeepCopyInto(out *JBSConfigSpec) {
 	}
 	out.ImageRegistry = in.ImageRegistry
 	out.CacheSettings = in.CacheSettings
-	out.BuildSettings = in.BuildSettings
+	in.BuildSettings.DeepCopyInto(&out.BuildSettings)
 	if in.RelocationPatterns != nil {
 		in, out := &in.RelocationPatterns, &out.RelocationPatterns
 		*out = make([]RelocationPatternElement, len(*in))
@@ -466,6 +818,9 @@ func (in *JBSConfigSpec) DeepCopyInto(out *JBSConfigSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	in.Retention.DeepCopyInto(&out.Retention)
+	out.EventSink = in.EventSink
+	in.ImageCheck.DeepCopyInto(&out.ImageCheck)
 	return
 }
 
@@ -482,6 +837,13 @@ func (in *JBSConfigSpec) DeepCopy() *JBSConfigSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *JBSConfigStatus) DeepCopyInto(out *JBSConfigStatus) {
 	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	if in.ImageRegistry != nil {
 		in, out := &in.ImageRegistry, &out.ImageRegistry
 		*out = new(ImageRegistry)
@@ -549,13 +911,29 @@ func (in *PatternElement) DeepCopy() *PatternElement {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProvenanceSettings) DeepCopyInto(out *ProvenanceSettings) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProvenanceSettings.
+func (in *ProvenanceSettings) DeepCopy() *ProvenanceSettings {
+	if in == nil {
+		return nil
+	}
+	out := new(ProvenanceSettings)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RebuiltArtifact) DeepCopyInto(out *RebuiltArtifact) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	out.Spec = in.Spec
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 	return
 }
 
@@ -629,6 +1007,22 @@ func (in *RebuiltArtifactSpec) DeepCopy() *RebuiltArtifactSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RebuiltArtifactStatus) DeepCopyInto(out *RebuiltArtifactStatus) {
 	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ArtifactBuilds != nil {
+		in, out := &in.ArtifactBuilds, &out.ArtifactBuilds
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.LastImageCheck != nil {
+		in, out := &in.LastImageCheck, &out.LastImageCheck
+		*out = (*in).DeepCopy()
+	}
 	return
 }
 
@@ -642,6 +1036,22 @@ func (in *RebuiltArtifactStatus) DeepCopy() *RebuiltArtifactStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RegistryProvisioningSettings) DeepCopyInto(out *RegistryProvisioningSettings) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RegistryProvisioningSettings.
+func (in *RegistryProvisioningSettings) DeepCopy() *RegistryProvisioningSettings {
+	if in == nil {
+		return nil
+	}
+	out := new(RegistryProvisioningSettings)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RelocationPattern) DeepCopyInto(out *RelocationPattern) {
 	*out = *in
@@ -680,6 +1090,65 @@ func (in *RelocationPatternElement) DeepCopy() *RelocationPatternElement {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RetentionSettings) DeepCopyInto(out *RetentionSettings) {
+	*out = *in
+	if in.CompletedArtifactBuildTTL != nil {
+		in, out := &in.CompletedArtifactBuildTTL, &out.CompletedArtifactBuildTTL
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.FailedArtifactBuildTTL != nil {
+		in, out := &in.FailedArtifactBuildTTL, &out.FailedArtifactBuildTTL
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RetentionSettings.
+func (in *RetentionSettings) DeepCopy() *RetentionSettings {
+	if in == nil {
+		return nil
+	}
+	out := new(RetentionSettings)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RetryPolicy) DeepCopyInto(out *RetryPolicy) {
+	*out = *in
+	if in.MaxRetries != nil {
+		in, out := &in.MaxRetries, &out.MaxRetries
+		*out = make(map[string]int, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.InitialBackoff != nil {
+		in, out := &in.InitialBackoff, &out.InitialBackoff
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.MaxBackoff != nil {
+		in, out := &in.MaxBackoff, &out.MaxBackoff
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RetryPolicy.
+func (in *RetryPolicy) DeepCopy() *RetryPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(RetryPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SCMInfo) DeepCopyInto(out *SCMInfo) {
 	*out = *in
@@ -702,7 +1171,7 @@ func (in *SystemConfig) DeepCopyInto(out *SystemConfig) {
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 	return
 }
 
@@ -767,6 +1236,17 @@ func (in *SystemConfigSpec) DeepCopyInto(out *SystemConfigSpec) {
 			(*out)[key] = val
 		}
 	}
+	out.BuildQueue = in.BuildQueue
+	in.RetryPolicy.DeepCopyInto(&out.RetryPolicy)
+	in.ArtifactSharing.DeepCopyInto(&out.ArtifactSharing)
+	out.EventSink = in.EventSink
+	if in.EventSinkAllowedHosts != nil {
+		in, out := &in.EventSinkAllowedHosts, &out.EventSinkAllowedHosts
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	out.Provenance = in.Provenance
+	out.RegistryProvisioning = in.RegistryProvisioning
 	return
 }
 
@@ -783,6 +1263,13 @@ func (in *SystemConfigSpec) DeepCopy() *SystemConfigSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SystemConfigStatus) DeepCopyInto(out *SystemConfigStatus) {
 	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	return
 }
 
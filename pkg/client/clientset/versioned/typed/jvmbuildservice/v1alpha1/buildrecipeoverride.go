@@ -0,0 +1,194 @@
+/*
+Copyright 2021-2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+// Code generated by client-gen. DO NOT EDIT.
+
+package v1alpha1
+
+import (
+	"context"
+	"time"
+
+	v1alpha1 "github.com/redhat-appstudio/jvm-build-service/pkg/apis/jvmbuildservice/v1alpha1"
+	scheme "github.com/redhat-appstudio/jvm-build-service/pkg/client/clientset/versioned/scheme"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	rest "k8s.io/client-go/rest"
+)
+
+// BuildRecipeOverridesGetter has a method to return a BuildRecipeOverrideInterface.
+// A group's client should implement this interface.
+type BuildRecipeOverridesGetter interface {
+	BuildRecipeOverrides(namespace string) BuildRecipeOverrideInterface
+}
+
+// BuildRecipeOverrideInterface has methods to work with BuildRecipeOverride resources.
+type BuildRecipeOverrideInterface interface {
+	Create(ctx context.Context, buildRecipeOverride *v1alpha1.BuildRecipeOverride, opts v1.CreateOptions) (*v1alpha1.BuildRecipeOverride, error)
+	Update(ctx context.Context, buildRecipeOverride *v1alpha1.BuildRecipeOverride, opts v1.UpdateOptions) (*v1alpha1.BuildRecipeOverride, error)
+	UpdateStatus(ctx context.Context, buildRecipeOverride *v1alpha1.BuildRecipeOverride, opts v1.UpdateOptions) (*v1alpha1.BuildRecipeOverride, error)
+	Delete(ctx context.Context, name string, opts v1.DeleteOptions) error
+	DeleteCollection(ctx context.Context, opts v1.DeleteOptions, listOpts v1.ListOptions) error
+	Get(ctx context.Context, name string, opts v1.GetOptions) (*v1alpha1.BuildRecipeOverride, error)
+	List(ctx context.Context, opts v1.ListOptions) (*v1alpha1.BuildRecipeOverrideList, error)
+	Watch(ctx context.Context, opts v1.ListOptions) (watch.Interface, error)
+	Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts v1.PatchOptions, subresources ...string) (result *v1alpha1.BuildRecipeOverride, err error)
+	BuildRecipeOverrideExpansion
+}
+
+// buildRecipeOverrides implements BuildRecipeOverrideInterface
+type buildRecipeOverrides struct {
+	client rest.Interface
+	ns     string
+}
+
+// newBuildRecipeOverrides returns a BuildRecipeOverrides
+func newBuildRecipeOverrides(c *JvmbuildserviceV1alpha1Client, namespace string) *buildRecipeOverrides {
+	return &buildRecipeOverrides{
+		client: c.RESTClient(),
+		ns:     namespace,
+	}
+}
+
+// Get takes name of the buildRecipeOverride, and returns the corresponding buildRecipeOverride object, and an error if there is any.
+func (c *buildRecipeOverrides) Get(ctx context.Context, name string, options v1.GetOptions) (result *v1alpha1.BuildRecipeOverride, err error) {
+	result = &v1alpha1.BuildRecipeOverride{}
+	err = c.client.Get().
+		Namespace(c.ns).
+		Resource("buildrecipeoverrides").
+		Name(name).
+		VersionedParams(&options, scheme.ParameterCodec).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// List takes label and field selectors, and returns the list of BuildRecipeOverrides that match those selectors.
+func (c *buildRecipeOverrides) List(ctx context.Context, opts v1.ListOptions) (result *v1alpha1.BuildRecipeOverrideList, err error) {
+	var timeout time.Duration
+	if opts.TimeoutSeconds != nil {
+		timeout = time.Duration(*opts.TimeoutSeconds) * time.Second
+	}
+	result = &v1alpha1.BuildRecipeOverrideList{}
+	err = c.client.Get().
+		Namespace(c.ns).
+		Resource("buildrecipeoverrides").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Timeout(timeout).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// Watch returns a watch.Interface that watches the requested buildRecipeOverrides.
+func (c *buildRecipeOverrides) Watch(ctx context.Context, opts v1.ListOptions) (watch.Interface, error) {
+	var timeout time.Duration
+	if opts.TimeoutSeconds != nil {
+		timeout = time.Duration(*opts.TimeoutSeconds) * time.Second
+	}
+	opts.Watch = true
+	return c.client.Get().
+		Namespace(c.ns).
+		Resource("buildrecipeoverrides").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Timeout(timeout).
+		Watch(ctx)
+}
+
+// Create takes the representation of a buildRecipeOverride and creates it.  Returns the server's representation of the buildRecipeOverride, and an error, if there is any.
+func (c *buildRecipeOverrides) Create(ctx context.Context, buildRecipeOverride *v1alpha1.BuildRecipeOverride, opts v1.CreateOptions) (result *v1alpha1.BuildRecipeOverride, err error) {
+	result = &v1alpha1.BuildRecipeOverride{}
+	err = c.client.Post().
+		Namespace(c.ns).
+		Resource("buildrecipeoverrides").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Body(buildRecipeOverride).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// Update takes the representation of a buildRecipeOverride and updates it. Returns the server's representation of the buildRecipeOverride, and an error, if there is any.
+func (c *buildRecipeOverrides) Update(ctx context.Context, buildRecipeOverride *v1alpha1.BuildRecipeOverride, opts v1.UpdateOptions) (result *v1alpha1.BuildRecipeOverride, err error) {
+	result = &v1alpha1.BuildRecipeOverride{}
+	err = c.client.Put().
+		Namespace(c.ns).
+		Resource("buildrecipeoverrides").
+		Name(buildRecipeOverride.Name).
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Body(buildRecipeOverride).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+func (c *buildRecipeOverrides) UpdateStatus(ctx context.Context, buildRecipeOverride *v1alpha1.BuildRecipeOverride, opts v1.UpdateOptions) (result *v1alpha1.BuildRecipeOverride, err error) {
+	result = &v1alpha1.BuildRecipeOverride{}
+	err = c.client.Put().
+		Namespace(c.ns).
+		Resource("buildrecipeoverrides").
+		Name(buildRecipeOverride.Name).
+		SubResource("status").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Body(buildRecipeOverride).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// Delete takes name of the buildRecipeOverride and deletes it. Returns an error if one occurs.
+func (c *buildRecipeOverrides) Delete(ctx context.Context, name string, opts v1.DeleteOptions) error {
+	return c.client.Delete().
+		Namespace(c.ns).
+		Resource("buildrecipeoverrides").
+		Name(name).
+		Body(&opts).
+		Do(ctx).
+		Error()
+}
+
+// DeleteCollection deletes a collection of objects.
+func (c *buildRecipeOverrides) DeleteCollection(ctx context.Context, opts v1.DeleteOptions, listOpts v1.ListOptions) error {
+	var timeout time.Duration
+	if listOpts.TimeoutSeconds != nil {
+		timeout = time.Duration(*listOpts.TimeoutSeconds) * time.Second
+	}
+	return c.client.Delete().
+		Namespace(c.ns).
+		Resource("buildrecipeoverrides").
+		VersionedParams(&listOpts, scheme.ParameterCodec).
+		Timeout(timeout).
+		Body(&opts).
+		Do(ctx).
+		Error()
+}
+
+// Patch applies the patch and returns the patched buildRecipeOverride.
+func (c *buildRecipeOverrides) Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts v1.PatchOptions, subresources ...string) (result *v1alpha1.BuildRecipeOverride, err error) {
+	result = &v1alpha1.BuildRecipeOverride{}
+	err = c.client.Patch(pt).
+		Namespace(c.ns).
+		Resource("buildrecipeoverrides").
+		Name(name).
+		SubResource(subresources...).
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Body(data).
+		Do(ctx).
+		Into(result)
+	return
+}
@@ -0,0 +1,141 @@
+/*
+Copyright 2021-2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+// Code generated by client-gen. DO NOT EDIT.
+
+package fake
+
+import (
+	"context"
+
+	v1alpha1 "github.com/redhat-appstudio/jvm-build-service/pkg/apis/jvmbuildservice/v1alpha1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	labels "k8s.io/apimachinery/pkg/labels"
+	schema "k8s.io/apimachinery/pkg/runtime/schema"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	testing "k8s.io/client-go/testing"
+)
+
+// FakeBuildRecipeOverrides implements BuildRecipeOverrideInterface
+type FakeBuildRecipeOverrides struct {
+	Fake *FakeJvmbuildserviceV1alpha1
+	ns   string
+}
+
+var buildrecipeoverridesResource = schema.GroupVersionResource{Group: "jvmbuildservice.io", Version: "v1alpha1", Resource: "buildrecipeoverrides"}
+
+var buildrecipeoverridesKind = schema.GroupVersionKind{Group: "jvmbuildservice.io", Version: "v1alpha1", Kind: "BuildRecipeOverride"}
+
+// Get takes name of the buildRecipeOverride, and returns the corresponding buildRecipeOverride object, and an error if there is any.
+func (c *FakeBuildRecipeOverrides) Get(ctx context.Context, name string, options v1.GetOptions) (result *v1alpha1.BuildRecipeOverride, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewGetAction(buildrecipeoverridesResource, c.ns, name), &v1alpha1.BuildRecipeOverride{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.BuildRecipeOverride), err
+}
+
+// List takes label and field selectors, and returns the list of BuildRecipeOverrides that match those selectors.
+func (c *FakeBuildRecipeOverrides) List(ctx context.Context, opts v1.ListOptions) (result *v1alpha1.BuildRecipeOverrideList, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewListAction(buildrecipeoverridesResource, buildrecipeoverridesKind, c.ns, opts), &v1alpha1.BuildRecipeOverrideList{})
+
+	if obj == nil {
+		return nil, err
+	}
+
+	label, _, _ := testing.ExtractFromListOptions(opts)
+	if label == nil {
+		label = labels.Everything()
+	}
+	list := &v1alpha1.BuildRecipeOverrideList{ListMeta: obj.(*v1alpha1.BuildRecipeOverrideList).ListMeta}
+	for _, item := range obj.(*v1alpha1.BuildRecipeOverrideList).Items {
+		if label.Matches(labels.Set(item.Labels)) {
+			list.Items = append(list.Items, item)
+		}
+	}
+	return list, err
+}
+
+// Watch returns a watch.Interface that watches the requested buildRecipeOverrides.
+func (c *FakeBuildRecipeOverrides) Watch(ctx context.Context, opts v1.ListOptions) (watch.Interface, error) {
+	return c.Fake.
+		InvokesWatch(testing.NewWatchAction(buildrecipeoverridesResource, c.ns, opts))
+
+}
+
+// Create takes the representation of a buildRecipeOverride and creates it.  Returns the server's representation of the buildRecipeOverride, and an error, if there is any.
+func (c *FakeBuildRecipeOverrides) Create(ctx context.Context, buildRecipeOverride *v1alpha1.BuildRecipeOverride, opts v1.CreateOptions) (result *v1alpha1.BuildRecipeOverride, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewCreateAction(buildrecipeoverridesResource, c.ns, buildRecipeOverride), &v1alpha1.BuildRecipeOverride{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.BuildRecipeOverride), err
+}
+
+// Update takes the representation of a buildRecipeOverride and updates it. Returns the server's representation of the buildRecipeOverride, and an error, if there is any.
+func (c *FakeBuildRecipeOverrides) Update(ctx context.Context, buildRecipeOverride *v1alpha1.BuildRecipeOverride, opts v1.UpdateOptions) (result *v1alpha1.BuildRecipeOverride, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewUpdateAction(buildrecipeoverridesResource, c.ns, buildRecipeOverride), &v1alpha1.BuildRecipeOverride{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.BuildRecipeOverride), err
+}
+
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+func (c *FakeBuildRecipeOverrides) UpdateStatus(ctx context.Context, buildRecipeOverride *v1alpha1.BuildRecipeOverride, opts v1.UpdateOptions) (*v1alpha1.BuildRecipeOverride, error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewUpdateSubresourceAction(buildrecipeoverridesResource, "status", c.ns, buildRecipeOverride), &v1alpha1.BuildRecipeOverride{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.BuildRecipeOverride), err
+}
+
+// Delete takes name of the buildRecipeOverride and deletes it. Returns an error if one occurs.
+func (c *FakeBuildRecipeOverrides) Delete(ctx context.Context, name string, opts v1.DeleteOptions) error {
+	_, err := c.Fake.
+		Invokes(testing.NewDeleteActionWithOptions(buildrecipeoverridesResource, c.ns, name, opts), &v1alpha1.BuildRecipeOverride{})
+
+	return err
+}
+
+// DeleteCollection deletes a collection of objects.
+func (c *FakeBuildRecipeOverrides) DeleteCollection(ctx context.Context, opts v1.DeleteOptions, listOpts v1.ListOptions) error {
+	action := testing.NewDeleteCollectionAction(buildrecipeoverridesResource, c.ns, listOpts)
+
+	_, err := c.Fake.Invokes(action, &v1alpha1.BuildRecipeOverrideList{})
+	return err
+}
+
+// Patch applies the patch and returns the patched buildRecipeOverride.
+func (c *FakeBuildRecipeOverrides) Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts v1.PatchOptions, subresources ...string) (result *v1alpha1.BuildRecipeOverride, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewPatchSubresourceAction(buildrecipeoverridesResource, c.ns, name, pt, data, subresources...), &v1alpha1.BuildRecipeOverride{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.BuildRecipeOverride), err
+}
@@ -31,6 +31,10 @@ func (c *FakeJvmbuildserviceV1alpha1) ArtifactBuilds(namespace string) v1alpha1.
 	return &FakeArtifactBuilds{c, namespace}
 }
 
+func (c *FakeJvmbuildserviceV1alpha1) BuildRecipeOverrides(namespace string) v1alpha1.BuildRecipeOverrideInterface {
+	return &FakeBuildRecipeOverrides{c, namespace}
+}
+
 func (c *FakeJvmbuildserviceV1alpha1) DependencyBuilds(namespace string) v1alpha1.DependencyBuildInterface {
 	return &FakeDependencyBuilds{c, namespace}
 }
@@ -19,6 +19,8 @@ package v1alpha1
 
 type ArtifactBuildExpansion interface{}
 
+type BuildRecipeOverrideExpansion interface{}
+
 type DependencyBuildExpansion interface{}
 
 type JBSConfigExpansion interface{}
@@ -28,6 +28,7 @@ import (
 type JvmbuildserviceV1alpha1Interface interface {
 	RESTClient() rest.Interface
 	ArtifactBuildsGetter
+	BuildRecipeOverridesGetter
 	DependencyBuildsGetter
 	JBSConfigsGetter
 	RebuiltArtifactsGetter
@@ -43,6 +44,10 @@ func (c *JvmbuildserviceV1alpha1Client) ArtifactBuilds(namespace string) Artifac
 	return newArtifactBuilds(c, namespace)
 }
 
+func (c *JvmbuildserviceV1alpha1Client) BuildRecipeOverrides(namespace string) BuildRecipeOverrideInterface {
+	return newBuildRecipeOverrides(c, namespace)
+}
+
 func (c *JvmbuildserviceV1alpha1Client) DependencyBuilds(namespace string) DependencyBuildInterface {
 	return newDependencyBuilds(c, namespace)
 }
@@ -54,6 +54,8 @@ func (f *sharedInformerFactory) ForResource(resource schema.GroupVersionResource
 	// Group=jvmbuildservice.io, Version=v1alpha1
 	case v1alpha1.SchemeGroupVersion.WithResource("artifactbuilds"):
 		return &genericInformer{resource: resource.GroupResource(), informer: f.Jvmbuildservice().V1alpha1().ArtifactBuilds().Informer()}, nil
+	case v1alpha1.SchemeGroupVersion.WithResource("buildrecipeoverrides"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Jvmbuildservice().V1alpha1().BuildRecipeOverrides().Informer()}, nil
 	case v1alpha1.SchemeGroupVersion.WithResource("dependencybuilds"):
 		return &genericInformer{resource: resource.GroupResource(), informer: f.Jvmbuildservice().V1alpha1().DependencyBuilds().Informer()}, nil
 	case v1alpha1.SchemeGroupVersion.WithResource("jbsconfigs"):
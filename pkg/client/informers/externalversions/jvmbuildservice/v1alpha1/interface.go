@@ -25,6 +25,8 @@ import (
 type Interface interface {
 	// ArtifactBuilds returns a ArtifactBuildInformer.
 	ArtifactBuilds() ArtifactBuildInformer
+	// BuildRecipeOverrides returns a BuildRecipeOverrideInformer.
+	BuildRecipeOverrides() BuildRecipeOverrideInformer
 	// DependencyBuilds returns a DependencyBuildInformer.
 	DependencyBuilds() DependencyBuildInformer
 	// JBSConfigs returns a JBSConfigInformer.
@@ -51,6 +53,11 @@ func (v *version) ArtifactBuilds() ArtifactBuildInformer {
 	return &artifactBuildInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
 }
 
+// BuildRecipeOverrides returns a BuildRecipeOverrideInformer.
+func (v *version) BuildRecipeOverrides() BuildRecipeOverrideInformer {
+	return &buildRecipeOverrideInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
+}
+
 // DependencyBuilds returns a DependencyBuildInformer.
 func (v *version) DependencyBuilds() DependencyBuildInformer {
 	return &dependencyBuildInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
@@ -0,0 +1,98 @@
+/*
+Copyright 2021-2022 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+// Code generated by lister-gen. DO NOT EDIT.
+
+package v1alpha1
+
+import (
+	v1alpha1 "github.com/redhat-appstudio/jvm-build-service/pkg/apis/jvmbuildservice/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+)
+
+// BuildRecipeOverrideLister helps list BuildRecipeOverrides.
+// All objects returned here must be treated as read-only.
+type BuildRecipeOverrideLister interface {
+	// List lists all BuildRecipeOverrides in the indexer.
+	// Objects returned here must be treated as read-only.
+	List(selector labels.Selector) (ret []*v1alpha1.BuildRecipeOverride, err error)
+	// BuildRecipeOverrides returns an object that can list and get BuildRecipeOverrides.
+	BuildRecipeOverrides(namespace string) BuildRecipeOverrideNamespaceLister
+	BuildRecipeOverrideListerExpansion
+}
+
+// buildRecipeOverrideLister implements the BuildRecipeOverrideLister interface.
+type buildRecipeOverrideLister struct {
+	indexer cache.Indexer
+}
+
+// NewBuildRecipeOverrideLister returns a new BuildRecipeOverrideLister.
+func NewBuildRecipeOverrideLister(indexer cache.Indexer) BuildRecipeOverrideLister {
+	return &buildRecipeOverrideLister{indexer: indexer}
+}
+
+// List lists all BuildRecipeOverrides in the indexer.
+func (s *buildRecipeOverrideLister) List(selector labels.Selector) (ret []*v1alpha1.BuildRecipeOverride, err error) {
+	err = cache.ListAll(s.indexer, selector, func(m interface{}) {
+		ret = append(ret, m.(*v1alpha1.BuildRecipeOverride))
+	})
+	return ret, err
+}
+
+// BuildRecipeOverrides returns an object that can list and get BuildRecipeOverrides.
+func (s *buildRecipeOverrideLister) BuildRecipeOverrides(namespace string) BuildRecipeOverrideNamespaceLister {
+	return buildRecipeOverrideNamespaceLister{indexer: s.indexer, namespace: namespace}
+}
+
+// BuildRecipeOverrideNamespaceLister helps list and get BuildRecipeOverrides.
+// All objects returned here must be treated as read-only.
+type BuildRecipeOverrideNamespaceLister interface {
+	// List lists all BuildRecipeOverrides in the indexer for a given namespace.
+	// Objects returned here must be treated as read-only.
+	List(selector labels.Selector) (ret []*v1alpha1.BuildRecipeOverride, err error)
+	// Get retrieves the BuildRecipeOverride from the indexer for a given namespace and name.
+	// Objects returned here must be treated as read-only.
+	Get(name string) (*v1alpha1.BuildRecipeOverride, error)
+	BuildRecipeOverrideNamespaceListerExpansion
+}
+
+// buildRecipeOverrideNamespaceLister implements the BuildRecipeOverrideNamespaceLister
+// interface.
+type buildRecipeOverrideNamespaceLister struct {
+	indexer   cache.Indexer
+	namespace string
+}
+
+// List lists all BuildRecipeOverrides in the indexer for a given namespace.
+func (s buildRecipeOverrideNamespaceLister) List(selector labels.Selector) (ret []*v1alpha1.BuildRecipeOverride, err error) {
+	err = cache.ListAllByNamespace(s.indexer, s.namespace, selector, func(m interface{}) {
+		ret = append(ret, m.(*v1alpha1.BuildRecipeOverride))
+	})
+	return ret, err
+}
+
+// Get retrieves the BuildRecipeOverride from the indexer for a given namespace and name.
+func (s buildRecipeOverrideNamespaceLister) Get(name string) (*v1alpha1.BuildRecipeOverride, error) {
+	obj, exists, err := s.indexer.GetByKey(s.namespace + "/" + name)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, errors.NewNotFound(v1alpha1.Resource("buildrecipeoverride"), name)
+	}
+	return obj.(*v1alpha1.BuildRecipeOverride), nil
+}
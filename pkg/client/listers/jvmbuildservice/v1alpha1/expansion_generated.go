@@ -25,6 +25,14 @@ type ArtifactBuildListerExpansion interface{}
 // ArtifactBuildNamespaceLister.
 type ArtifactBuildNamespaceListerExpansion interface{}
 
+// BuildRecipeOverrideListerExpansion allows custom methods to be added to
+// BuildRecipeOverrideLister.
+type BuildRecipeOverrideListerExpansion interface{}
+
+// BuildRecipeOverrideNamespaceListerExpansion allows custom methods to be added to
+// BuildRecipeOverrideNamespaceLister.
+type BuildRecipeOverrideNamespaceListerExpansion interface{}
+
 // DependencyBuildListerExpansion allows custom methods to be added to
 // DependencyBuildLister.
 type DependencyBuildListerExpansion interface{}
@@ -0,0 +1,247 @@
+package cloudevents
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/redhat-appstudio/jvm-build-service/pkg/apis/jvmbuildservice/v1alpha1"
+	"github.com/redhat-appstudio/jvm-build-service/pkg/reconciler/systemconfig"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	SpecVersion = "1.0"
+	ContentType = "application/cloudevents+json"
+
+	// queueSize the number of events waiting to be delivered, further events are dropped until there is space
+	queueSize = 1000
+	// workers the number of events that are delivered at the same time, so one slow sink does not hold up the rest
+	workers = 4
+
+	maxAttempts    = 5
+	initialBackoff = time.Second
+	maxBackoff     = 30 * time.Second
+	requestTimeout = 10 * time.Second
+)
+
+// Event a CloudEvent in the structured JSON format
+type Event struct {
+	SpecVersion     string      `json:"specversion"`
+	ID              string      `json:"id"`
+	Source          string      `json:"source"`
+	Type            string      `json:"type"`
+	Subject         string      `json:"subject,omitempty"`
+	Time            time.Time   `json:"time"`
+	DataContentType string      `json:"datacontenttype,omitempty"`
+	Data            interface{} `json:"data,omitempty"`
+}
+
+// Publisher delivers events to the sink configured for their namespace. Events are queued and sent in the
+// background, so publishing never blocks the informers or reconcilers.
+type Publisher struct {
+	client     client.Client
+	httpClient *http.Client
+	queue      chan queuedEvent
+	backoff    time.Duration
+	log        logr.Logger
+	// elected is closed once this replica is the leader, only the leader delivers the queued events
+	elected <-chan struct{}
+	// dropped the number of events that did not fit in the queue, the next log message reports them
+	dropped     int
+	droppedLock sync.Mutex
+}
+
+type queuedEvent struct {
+	namespace string
+	// jbsConfig the JBSConfig the object references, empty for the default one
+	jbsConfig string
+	event     Event
+}
+
+func NewPublisher(client client.Client, elected <-chan struct{}) *Publisher {
+	return &Publisher{
+		client:     client,
+		httpClient: &http.Client{Timeout: requestTimeout},
+		queue:      make(chan queuedEvent, queueSize),
+		backoff:    initialBackoff,
+		log:        ctrl.Log.WithName("cloudevents"),
+		elected:    elected,
+	}
+}
+
+// NewEvent creates an event about an object in a namespace, the subject is the resource and name of the object
+func NewEvent(eventType string, namespace string, subject string, data interface{}) Event {
+	return Event{
+		SpecVersion:     SpecVersion,
+		ID:              string(uuid.NewUUID()),
+		Source:          "/jvm-build-service/namespaces/" + namespace,
+		Type:            eventType,
+		Subject:         subject,
+		Time:            time.Now().UTC(),
+		DataContentType: "application/json",
+		Data:            data,
+	}
+}
+
+// Publish queues the event for delivery to the sink of the JBSConfig, if the queue is full the event is dropped.
+// Events are only queued on the leader, the other replicas never deliver them, and a replica that becomes the leader
+// must not send the state changes it saw before.
+func (p *Publisher) Publish(namespace string, jbsConfig string, event Event) {
+	select {
+	case <-p.elected:
+	default:
+		return
+	}
+	select {
+	case p.queue <- queuedEvent{namespace: namespace, jbsConfig: jbsConfig, event: event}:
+	default:
+		p.droppedLock.Lock()
+		defer p.droppedLock.Unlock()
+		p.dropped++
+		if p.dropped == 1 {
+			p.log.Info("the event queue is full, dropping events", "type", event.Type, "subject", event.Subject)
+		}
+	}
+}
+
+// Start delivers the queued events until the context is done, it is run by the manager
+func (p *Publisher) Start(ctx context.Context) error {
+	wg := sync.WaitGroup{}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case queued := <-p.queue:
+					p.deliver(ctx, queued)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	return nil
+}
+
+// NeedLeaderElection only the leader publishes events, as the other replicas see the same state changes
+func (p *Publisher) NeedLeaderElection() bool {
+	return true
+}
+
+func (p *Publisher) deliver(ctx context.Context, queued queuedEvent) {
+	p.droppedLock.Lock()
+	if p.dropped > 0 {
+		p.log.Info("events were dropped as the queue was full", "count", p.dropped)
+		p.dropped = 0
+	}
+	p.droppedLock.Unlock()
+
+	sink, err := p.sink(ctx, queued.namespace, queued.jbsConfig)
+	if err != nil {
+		p.log.Error(err, "failed to find the event sink", "namespace", queued.namespace)
+		return
+	}
+	if sink == "" {
+		return
+	}
+	body, err := json.Marshal(queued.event)
+	if err != nil {
+		p.log.Error(err, "failed to encode the event", "type", queued.event.Type)
+		return
+	}
+	backoff := p.backoff
+	for attempt := 1; ; attempt++ {
+		retry, err := p.send(ctx, sink, body)
+		if err == nil {
+			return
+		}
+		if !retry || attempt == maxAttempts {
+			p.log.Error(err, "failed to deliver the event", "sink", sink, "type", queued.event.Type, "subject", queued.event.Subject, "attempts", attempt)
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+// send posts the event, and reports if a failure is worth retrying
+func (p *Publisher) send(ctx context.Context, sink string, body []byte) (bool, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, sink, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	request.Header.Set("Content-Type", ContentType)
+	response, err := p.httpClient.Do(request)
+	if err != nil {
+		return true, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode >= 200 && response.StatusCode < 300 {
+		return false, nil
+	}
+	err = fmt.Errorf("the event sink returned %s", response.Status)
+	return response.StatusCode >= 500 || response.StatusCode == http.StatusTooManyRequests, err
+}
+
+// sink the sink of the JBSConfig, or the SystemConfig sink if the JBSConfig does not set one. A JBSConfig sink is
+// only used if its host is allowed by the SystemConfig.
+func (p *Publisher) sink(ctx context.Context, namespace string, jbsConfigName string) (string, error) {
+	jbsConfig := v1alpha1.JBSConfig{}
+	err := p.client.Get(ctx, types.NamespacedName{Namespace: namespace, Name: v1alpha1.JBSConfigNameOrDefault(jbsConfigName)}, &jbsConfig)
+	if err != nil && !errors.IsNotFound(err) {
+		return "", err
+	}
+	systemConfig := v1alpha1.SystemConfig{}
+	err = p.client.Get(ctx, types.NamespacedName{Name: systemconfig.SystemConfigKey}, &systemConfig)
+	if err != nil && !errors.IsNotFound(err) {
+		return "", err
+	}
+	if jbsConfig.Spec.EventSink.URL == "" {
+		return systemConfig.Spec.EventSink.URL, nil
+	}
+	sinkURL, err := url.Parse(jbsConfig.Spec.EventSink.URL)
+	if err != nil {
+		return "", err
+	}
+	if !allowedHost(sinkURL.Hostname(), systemConfig.Spec.EventSinkAllowedHosts) {
+		return "", fmt.Errorf("the event sink host %s of JBSConfig %s/%s is not in the SystemConfig eventSinkAllowedHosts", sinkURL.Hostname(), namespace, jbsConfig.Name)
+	}
+	return jbsConfig.Spec.EventSink.URL, nil
+}
+
+// allowedHost checks the host against the allowed hosts, a pattern starting with *. matches any subdomain
+func allowedHost(host string, allowed []string) bool {
+	host = strings.ToLower(host)
+	for _, pattern := range allowed {
+		pattern = strings.ToLower(pattern)
+		if strings.HasPrefix(pattern, "*.") {
+			if strings.HasSuffix(host, pattern[1:]) {
+				return true
+			}
+		} else if host == pattern {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,222 @@
+package cloudevents
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/redhat-appstudio/jvm-build-service/pkg/apis/jvmbuildservice/v1alpha1"
+	"github.com/redhat-appstudio/jvm-build-service/pkg/reconciler/systemconfig"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// sink records the events it receives, and fails the first requests with the given status codes
+type sink struct {
+	lock     sync.Mutex
+	failures []int
+	requests int
+	events   []Event
+}
+
+func (s *sink) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.requests++
+	if len(s.failures) > 0 {
+		w.WriteHeader(s.failures[0])
+		s.failures = s.failures[1:]
+		return
+	}
+	if r.Header.Get("Content-Type") != ContentType {
+		w.WriteHeader(http.StatusUnsupportedMediaType)
+		return
+	}
+	body, _ := io.ReadAll(r.Body)
+	event := Event{}
+	if err := json.Unmarshal(body, &event); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	s.events = append(s.events, event)
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (s *sink) received() ([]Event, int) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]Event{}, s.events...), s.requests
+}
+
+func setupPublisher(objs ...runtimeclient.Object) *Publisher {
+	scheme := runtime.NewScheme()
+	_ = v1alpha1.AddToScheme(scheme)
+	client := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
+	elected := make(chan struct{})
+	close(elected)
+	publisher := NewPublisher(client, elected)
+	publisher.backoff = time.Millisecond
+	return publisher
+}
+
+func systemConfigWithSink(url string) *v1alpha1.SystemConfig {
+	return &v1alpha1.SystemConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: systemconfig.SystemConfigKey},
+		Spec:       v1alpha1.SystemConfigSpec{EventSink: v1alpha1.EventSink{URL: url}},
+	}
+}
+
+func jbsConfigWithSink(url string) *v1alpha1.JBSConfig {
+	return &v1alpha1.JBSConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: v1alpha1.JBSConfigName, Namespace: metav1.NamespaceDefault},
+		Spec:       v1alpha1.JBSConfigSpec{EventSink: v1alpha1.EventSink{URL: url}},
+	}
+}
+
+func TestPublisher(t *testing.T) {
+	ctx := context.TODO()
+	event := NewEvent(ArtifactBuildCompleteEvent, metav1.NamespaceDefault, "artifactbuilds/test", ArtifactBuildData{Name: "test", GAV: "com.test:test:1.0"})
+
+	t.Run("Test event is retried until it is delivered", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		s := &sink{failures: []int{http.StatusServiceUnavailable, http.StatusTooManyRequests}}
+		server := httptest.NewServer(s)
+		defer server.Close()
+		publisher := setupPublisher(systemConfigWithSink(server.URL))
+		publisher.deliver(ctx, queuedEvent{namespace: metav1.NamespaceDefault, event: event})
+		events, requests := s.received()
+		g.Expect(requests).Should(Equal(3))
+		g.Expect(events).Should(HaveLen(1))
+		g.Expect(events[0].SpecVersion).Should(Equal(SpecVersion))
+		g.Expect(events[0].ID).Should(Equal(event.ID))
+		g.Expect(events[0].Type).Should(Equal(ArtifactBuildCompleteEvent))
+		g.Expect(events[0].Source).Should(Equal("/jvm-build-service/namespaces/default"))
+		g.Expect(events[0].Subject).Should(Equal("artifactbuilds/test"))
+		g.Expect(events[0].Data).Should(HaveKeyWithValue("gav", "com.test:test:1.0"))
+	})
+	t.Run("Test client errors are not retried", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		s := &sink{failures: []int{http.StatusBadRequest}}
+		server := httptest.NewServer(s)
+		defer server.Close()
+		publisher := setupPublisher(systemConfigWithSink(server.URL))
+		publisher.deliver(ctx, queuedEvent{namespace: metav1.NamespaceDefault, event: event})
+		events, requests := s.received()
+		g.Expect(requests).Should(Equal(1))
+		g.Expect(events).Should(BeEmpty())
+	})
+	t.Run("Test delivery gives up after the maximum attempts", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		s := &sink{failures: []int{500, 500, 500, 500, 500, 500}}
+		server := httptest.NewServer(s)
+		defer server.Close()
+		publisher := setupPublisher(systemConfigWithSink(server.URL))
+		publisher.deliver(ctx, queuedEvent{namespace: metav1.NamespaceDefault, event: event})
+		_, requests := s.received()
+		g.Expect(requests).Should(Equal(maxAttempts))
+	})
+	t.Run("Test the JBSConfig sink is used for its namespace", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		system := &sink{}
+		systemServer := httptest.NewServer(system)
+		defer systemServer.Close()
+		namespace := &sink{}
+		namespaceServer := httptest.NewServer(namespace)
+		defer namespaceServer.Close()
+		systemConfig := systemConfigWithSink(systemServer.URL)
+		systemConfig.Spec.EventSinkAllowedHosts = []string{"127.0.0.1"}
+		publisher := setupPublisher(systemConfig, jbsConfigWithSink(namespaceServer.URL))
+		publisher.deliver(ctx, queuedEvent{namespace: metav1.NamespaceDefault, event: event})
+		publisher.deliver(ctx, queuedEvent{namespace: "other", event: event})
+		events, _ := namespace.received()
+		g.Expect(events).Should(HaveLen(1))
+		events, _ = system.received()
+		g.Expect(events).Should(HaveLen(1))
+	})
+	t.Run("Test the sink of a named JBSConfig is used for the objects that reference it", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		namespace := &sink{}
+		namespaceServer := httptest.NewServer(namespace)
+		defer namespaceServer.Close()
+		team := &sink{}
+		teamServer := httptest.NewServer(team)
+		defer teamServer.Close()
+		teamConfig := jbsConfigWithSink(teamServer.URL)
+		teamConfig.Name = "team"
+		systemConfig := systemConfigWithSink("")
+		systemConfig.Spec.EventSinkAllowedHosts = []string{"127.0.0.1"}
+		publisher := setupPublisher(systemConfig, jbsConfigWithSink(namespaceServer.URL), teamConfig)
+		publisher.deliver(ctx, queuedEvent{namespace: metav1.NamespaceDefault, event: event})
+		publisher.deliver(ctx, queuedEvent{namespace: metav1.NamespaceDefault, jbsConfig: "team", event: event})
+		publisher.deliver(ctx, queuedEvent{namespace: metav1.NamespaceDefault, jbsConfig: "team", event: event})
+		events, _ := namespace.received()
+		g.Expect(events).Should(HaveLen(1))
+		events, _ = team.received()
+		g.Expect(events).Should(HaveLen(2))
+	})
+	t.Run("Test a JBSConfig sink on a host that is not allowed is not used", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		system := &sink{}
+		systemServer := httptest.NewServer(system)
+		defer systemServer.Close()
+		namespace := &sink{}
+		namespaceServer := httptest.NewServer(namespace)
+		defer namespaceServer.Close()
+		systemConfig := systemConfigWithSink(systemServer.URL)
+		systemConfig.Spec.EventSinkAllowedHosts = []string{"*.example.com"}
+		publisher := setupPublisher(systemConfig, jbsConfigWithSink(namespaceServer.URL))
+		_, err := publisher.sink(ctx, metav1.NamespaceDefault, "")
+		g.Expect(err).Should(HaveOccurred())
+		publisher.deliver(ctx, queuedEvent{namespace: metav1.NamespaceDefault, event: event})
+		_, requests := namespace.received()
+		g.Expect(requests).Should(Equal(0))
+		_, requests = system.received()
+		g.Expect(requests).Should(Equal(0))
+	})
+	t.Run("Test allowed event sink hosts", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		allowed := []string{"events.example.com", "*.example.org"}
+		g.Expect(allowedHost("events.example.com", allowed)).Should(BeTrue())
+		g.Expect(allowedHost("Events.Example.com", allowed)).Should(BeTrue())
+		g.Expect(allowedHost("other.example.com", allowed)).Should(BeFalse())
+		g.Expect(allowedHost("hooks.team.example.org", allowed)).Should(BeTrue())
+		g.Expect(allowedHost("example.org", allowed)).Should(BeFalse())
+		g.Expect(allowedHost("badexample.org", allowed)).Should(BeFalse())
+		g.Expect(allowedHost("events.example.com", nil)).Should(BeFalse())
+	})
+	t.Run("Test nothing is sent without a sink", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		publisher := setupPublisher()
+		_, err := publisher.sink(ctx, metav1.NamespaceDefault, "")
+		g.Expect(err).ShouldNot(HaveOccurred())
+		publisher.deliver(ctx, queuedEvent{namespace: metav1.NamespaceDefault, event: event})
+	})
+	t.Run("Test events are dropped when the queue is full", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		publisher := setupPublisher()
+		for i := 0; i < queueSize+10; i++ {
+			publisher.Publish(metav1.NamespaceDefault, "", event)
+		}
+		g.Expect(publisher.queue).Should(HaveLen(queueSize))
+		g.Expect(publisher.dropped).Should(Equal(10))
+	})
+	t.Run("Test events are not queued before the replica is elected", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		publisher := setupPublisher()
+		elected := make(chan struct{})
+		publisher.elected = elected
+		publisher.Publish(metav1.NamespaceDefault, "", event)
+		g.Expect(publisher.queue).Should(BeEmpty())
+		close(elected)
+		publisher.Publish(metav1.NamespaceDefault, "", event)
+		g.Expect(publisher.queue).Should(HaveLen(1))
+	})
+}
@@ -0,0 +1,178 @@
+package cloudevents
+
+import (
+	"context"
+	"time"
+
+	"github.com/redhat-appstudio/jvm-build-service/pkg/apis/jvmbuildservice/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	toolscache "k8s.io/client-go/tools/cache"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+)
+
+const (
+	ArtifactBuildDiscoveredEvent     = "io.jvmbuildservice.artifactbuild.discovered"
+	ArtifactBuildMissingEvent        = "io.jvmbuildservice.artifactbuild.missing"
+	ArtifactBuildBuildingEvent       = "io.jvmbuildservice.artifactbuild.building"
+	ArtifactBuildCompleteEvent       = "io.jvmbuildservice.artifactbuild.complete"
+	ArtifactBuildFailedEvent         = "io.jvmbuildservice.artifactbuild.failed"
+	DependencyBuildContaminatedEvent = "io.jvmbuildservice.dependencybuild.contaminated"
+	DependencyBuildExhaustedEvent    = "io.jvmbuildservice.dependencybuild.exhausted"
+	RebuiltArtifactCreatedEvent      = "io.jvmbuildservice.rebuiltartifact.created"
+)
+
+var artifactBuildEvents = map[string]string{
+	v1alpha1.ArtifactBuildStateMissing:  ArtifactBuildMissingEvent,
+	v1alpha1.ArtifactBuildStateBuilding: ArtifactBuildBuildingEvent,
+	v1alpha1.ArtifactBuildStateComplete: ArtifactBuildCompleteEvent,
+	v1alpha1.ArtifactBuildStateFailed:   ArtifactBuildFailedEvent,
+}
+
+var dependencyBuildEvents = map[string]string{
+	v1alpha1.DependencyBuildStateContaminated: DependencyBuildContaminatedEvent,
+	v1alpha1.DependencyBuildStateFailed:       DependencyBuildExhaustedEvent,
+}
+
+// ArtifactBuildData the data of the ArtifactBuild events
+type ArtifactBuildData struct {
+	Name    string `json:"name"`
+	GAV     string `json:"gav"`
+	State   string `json:"state"`
+	Message string `json:"message,omitempty"`
+	SCMURL  string `json:"scmURL,omitempty"`
+	Tag     string `json:"tag,omitempty"`
+}
+
+// DependencyBuildData the data of the DependencyBuild events
+type DependencyBuildData struct {
+	Name         string   `json:"name"`
+	SCMURL       string   `json:"scmURL"`
+	Tag          string   `json:"tag"`
+	Path         string   `json:"path,omitempty"`
+	State        string   `json:"state"`
+	Message      string   `json:"message,omitempty"`
+	Contaminants []string `json:"contaminants,omitempty"`
+}
+
+// RebuiltArtifactData the data of the RebuiltArtifact events
+type RebuiltArtifactData struct {
+	Name   string `json:"name"`
+	GAV    string `json:"gav"`
+	Image  string `json:"image"`
+	Digest string `json:"digest,omitempty"`
+}
+
+// SetupPublisherWithManager publishes the state changes seen by the shared informers, so an event is only sent once
+// the new state has been stored
+func SetupPublisherWithManager(mgr ctrl.Manager) error {
+	publisher := NewPublisher(mgr.GetClient(), mgr.Elected())
+	if err := mgr.Add(publisher); err != nil {
+		return err
+	}
+	return watch(mgr.GetCache(), &transitions{publisher: publisher, started: time.Now().Truncate(time.Second)})
+}
+
+func watch(informers cache.Informers, t *transitions) error {
+	abInformer, err := informers.GetInformer(context.Background(), &v1alpha1.ArtifactBuild{})
+	if err != nil {
+		return err
+	}
+	if _, err := abInformer.AddEventHandler(toolscache.ResourceEventHandlerFuncs{UpdateFunc: t.artifactBuildUpdated}); err != nil {
+		return err
+	}
+	dbInformer, err := informers.GetInformer(context.Background(), &v1alpha1.DependencyBuild{})
+	if err != nil {
+		return err
+	}
+	if _, err := dbInformer.AddEventHandler(toolscache.ResourceEventHandlerFuncs{UpdateFunc: t.dependencyBuildUpdated}); err != nil {
+		return err
+	}
+	raInformer, err := informers.GetInformer(context.Background(), &v1alpha1.RebuiltArtifact{})
+	if err != nil {
+		return err
+	}
+	_, err = raInformer.AddEventHandler(toolscache.ResourceEventHandlerFuncs{AddFunc: t.rebuiltArtifactAdded})
+	return err
+}
+
+type publisher interface {
+	Publish(namespace string, jbsConfig string, event Event)
+}
+
+type transitions struct {
+	publisher publisher
+	// started objects created before this were already there when the informer started, so are not new
+	started time.Time
+}
+
+func (t *transitions) artifactBuildUpdated(oldObj, newObj interface{}) {
+	old, ok := oldObj.(*v1alpha1.ArtifactBuild)
+	if !ok {
+		return
+	}
+	abr, ok := newObj.(*v1alpha1.ArtifactBuild)
+	if !ok {
+		return
+	}
+	data := ArtifactBuildData{
+		Name:    abr.Name,
+		GAV:     abr.Spec.GAV,
+		State:   abr.Status.State,
+		Message: abr.Status.Message,
+		SCMURL:  abr.Status.SCMInfo.SCMURL,
+		Tag:     abr.Status.SCMInfo.Tag,
+	}
+	subject := "artifactbuilds/" + abr.Name
+	if !meta.IsStatusConditionTrue(old.Status.Conditions, v1alpha1.ArtifactBuildConditionDiscovered) &&
+		meta.IsStatusConditionTrue(abr.Status.Conditions, v1alpha1.ArtifactBuildConditionDiscovered) {
+		t.publisher.Publish(abr.Namespace, abr.Spec.JBSConfig, NewEvent(ArtifactBuildDiscoveredEvent, abr.Namespace, subject, data))
+	}
+	if old.Status.State == abr.Status.State {
+		return
+	}
+	if eventType, ok := artifactBuildEvents[abr.Status.State]; ok {
+		t.publisher.Publish(abr.Namespace, abr.Spec.JBSConfig, NewEvent(eventType, abr.Namespace, subject, data))
+	}
+}
+
+func (t *transitions) dependencyBuildUpdated(oldObj, newObj interface{}) {
+	old, ok := oldObj.(*v1alpha1.DependencyBuild)
+	if !ok {
+		return
+	}
+	db, ok := newObj.(*v1alpha1.DependencyBuild)
+	if !ok || old.Status.State == db.Status.State {
+		return
+	}
+	eventType, ok := dependencyBuildEvents[db.Status.State]
+	if !ok {
+		return
+	}
+	data := DependencyBuildData{
+		Name:    db.Name,
+		SCMURL:  db.Spec.ScmInfo.SCMURL,
+		Tag:     db.Spec.ScmInfo.Tag,
+		Path:    db.Spec.ScmInfo.Path,
+		State:   db.Status.State,
+		Message: db.Status.Message,
+	}
+	for _, i := range db.Status.Contaminants {
+		data.Contaminants = append(data.Contaminants, i.GAV)
+	}
+	t.publisher.Publish(db.Namespace, db.Spec.JBSConfig, NewEvent(eventType, db.Namespace, "dependencybuilds/"+db.Name, data))
+}
+
+func (t *transitions) rebuiltArtifactAdded(obj interface{}) {
+	ra, ok := obj.(*v1alpha1.RebuiltArtifact)
+	if !ok || ra.CreationTimestamp.Time.Before(t.started) {
+		return
+	}
+	data := RebuiltArtifactData{
+		Name:   ra.Name,
+		GAV:    ra.Spec.GAV,
+		Image:  ra.Spec.Image,
+		Digest: ra.Spec.Digest,
+	}
+	t.publisher.Publish(ra.Namespace, ra.Spec.JBSConfig, NewEvent(RebuiltArtifactCreatedEvent, ra.Namespace, "rebuiltartifacts/"+ra.Name, data))
+}
@@ -0,0 +1,96 @@
+package cloudevents
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/redhat-appstudio/jvm-build-service/pkg/apis/jvmbuildservice/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type recordingPublisher struct {
+	events []Event
+}
+
+func (r *recordingPublisher) Publish(namespace string, jbsConfig string, event Event) {
+	r.events = append(r.events, event)
+}
+
+func (r *recordingPublisher) types() []string {
+	ret := []string{}
+	for _, i := range r.events {
+		ret = append(ret, i.Type)
+	}
+	return ret
+}
+
+func abr(state string, discovered metav1.ConditionStatus) *v1alpha1.ArtifactBuild {
+	ret := &v1alpha1.ArtifactBuild{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: metav1.NamespaceDefault},
+		Spec:       v1alpha1.ArtifactBuildSpec{GAV: "com.test:test:1.0"},
+		Status:     v1alpha1.ArtifactBuildStatus{State: state},
+	}
+	if discovered != "" {
+		ret.Status.Conditions = []metav1.Condition{{Type: v1alpha1.ArtifactBuildConditionDiscovered, Status: discovered}}
+	}
+	return ret
+}
+
+func db(state string) *v1alpha1.DependencyBuild {
+	return &v1alpha1.DependencyBuild{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: metav1.NamespaceDefault},
+		Spec:       v1alpha1.DependencyBuildSpec{ScmInfo: v1alpha1.SCMInfo{SCMURL: "https://github.com/test/test.git", Tag: "1.0"}},
+		Status: v1alpha1.DependencyBuildStatus{
+			State:        state,
+			Contaminants: []v1alpha1.Contaminant{{GAV: "com.other:other:1.0"}},
+		},
+	}
+}
+
+func TestTransitions(t *testing.T) {
+	t.Run("Test artifact build transitions", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		publisher := &recordingPublisher{}
+		transitions := &transitions{publisher: publisher}
+		transitions.artifactBuildUpdated(abr(v1alpha1.ArtifactBuildStateDiscovering, ""), abr(v1alpha1.ArtifactBuildStateBuilding, metav1.ConditionTrue))
+		g.Expect(publisher.types()).Should(Equal([]string{ArtifactBuildDiscoveredEvent, ArtifactBuildBuildingEvent}))
+		transitions.artifactBuildUpdated(abr(v1alpha1.ArtifactBuildStateBuilding, metav1.ConditionTrue), abr(v1alpha1.ArtifactBuildStateBuilding, metav1.ConditionTrue))
+		g.Expect(publisher.events).Should(HaveLen(2))
+		transitions.artifactBuildUpdated(abr(v1alpha1.ArtifactBuildStateBuilding, metav1.ConditionTrue), abr(v1alpha1.ArtifactBuildStateComplete, metav1.ConditionTrue))
+		g.Expect(publisher.events).Should(HaveLen(3))
+		g.Expect(publisher.events[2].Type).Should(Equal(ArtifactBuildCompleteEvent))
+		g.Expect(publisher.events[2].Subject).Should(Equal("artifactbuilds/test"))
+		g.Expect(publisher.events[2].Data.(ArtifactBuildData).GAV).Should(Equal("com.test:test:1.0"))
+		transitions.artifactBuildUpdated(abr(v1alpha1.ArtifactBuildStateDiscovering, metav1.ConditionUnknown), abr(v1alpha1.ArtifactBuildStateMissing, metav1.ConditionFalse))
+		g.Expect(publisher.events[3].Type).Should(Equal(ArtifactBuildMissingEvent))
+	})
+	t.Run("Test dependency build transitions", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		publisher := &recordingPublisher{}
+		transitions := &transitions{publisher: publisher}
+		transitions.dependencyBuildUpdated(db(v1alpha1.DependencyBuildStateNew), db(v1alpha1.DependencyBuildStateBuilding))
+		g.Expect(publisher.events).Should(BeEmpty())
+		transitions.dependencyBuildUpdated(db(v1alpha1.DependencyBuildStateBuilding), db(v1alpha1.DependencyBuildStateContaminated))
+		g.Expect(publisher.types()).Should(Equal([]string{DependencyBuildContaminatedEvent}))
+		g.Expect(publisher.events[0].Data.(DependencyBuildData).Contaminants).Should(Equal([]string{"com.other:other:1.0"}))
+		transitions.dependencyBuildUpdated(db(v1alpha1.DependencyBuildStateSubmitBuild), db(v1alpha1.DependencyBuildStateFailed))
+		g.Expect(publisher.events[1].Type).Should(Equal(DependencyBuildExhaustedEvent))
+	})
+	t.Run("Test only new rebuilt artifacts are published", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		publisher := &recordingPublisher{}
+		started := time.Now().Truncate(time.Second)
+		transitions := &transitions{publisher: publisher, started: started}
+		ra := &v1alpha1.RebuiltArtifact{
+			ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: metav1.NamespaceDefault, CreationTimestamp: metav1.NewTime(started.Add(-time.Hour))},
+			Spec:       v1alpha1.RebuiltArtifactSpec{GAV: "com.test:test:1.0", Image: "quay.io/test/test:1.0"},
+		}
+		transitions.rebuiltArtifactAdded(ra)
+		g.Expect(publisher.events).Should(BeEmpty())
+		ra.CreationTimestamp = metav1.NewTime(started)
+		transitions.rebuiltArtifactAdded(ra)
+		g.Expect(publisher.types()).Should(Equal([]string{RebuiltArtifactCreatedEvent}))
+		g.Expect(publisher.events[0].Data.(RebuiltArtifactData).Image).Should(Equal("quay.io/test/test:1.0"))
+	})
+}
@@ -3,7 +3,7 @@ package controller
 import (
 	"context"
 	"fmt"
-	"github.com/redhat-appstudio/image-controller/pkg/quay"
+	"github.com/redhat-appstudio/jvm-build-service/pkg/cloudevents"
 	"github.com/redhat-appstudio/jvm-build-service/pkg/metrics"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/selection"
@@ -13,7 +13,10 @@ import (
 	"github.com/redhat-appstudio/jvm-build-service/pkg/reconciler/artifactbuild"
 	"github.com/redhat-appstudio/jvm-build-service/pkg/reconciler/dependencybuild"
 	"github.com/redhat-appstudio/jvm-build-service/pkg/reconciler/jbsconfig"
+	"github.com/redhat-appstudio/jvm-build-service/pkg/reconciler/rebuiltartifact"
+	"github.com/redhat-appstudio/jvm-build-service/pkg/reconciler/retention"
 	"github.com/redhat-appstudio/jvm-build-service/pkg/reconciler/systemconfig"
+	"github.com/redhat-appstudio/jvm-build-service/pkg/registry"
 	spi "github.com/redhat-appstudio/service-provider-integration-operator/api/v1beta1"
 	pipelinev1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
 
@@ -35,7 +38,7 @@ var (
 	controllerLog = ctrl.Log.WithName("controller")
 )
 
-func NewManager(cfg *rest.Config, options ctrl.Options, quayClient *quay.QuayClient, quayOrgName string) (ctrl.Manager, error) {
+func NewManager(cfg *rest.Config, options ctrl.Options) (ctrl.Manager, error) {
 
 	// we have seen in e2e testing that this path can get invoked prior to the TaskRun CRD getting generated,
 	// and controller-runtime does not retry on missing CRDs.
@@ -103,7 +106,7 @@ func NewManager(cfg *rest.Config, options ctrl.Options, quayClient *quay.QuayCli
 	ourPipelines.Add(*requirement)
 	//we only want to watch the runs we create
 	cachePods := labels.NewSelector()
-	cacheRequirement, lerr := labels.NewRequirement("app", selection.Equals, []string{v1alpha1.CacheDeploymentName})
+	cacheRequirement, lerr := labels.NewRequirement(v1alpha1.CacheLabel, selection.Exists, []string{})
 	if lerr != nil {
 		return nil, lerr
 	}
@@ -131,14 +134,30 @@ func NewManager(cfg *rest.Config, options ctrl.Options, quayClient *quay.QuayCli
 		return nil, err
 	}
 
-	if err := systemconfig.SetupNewReconcilerWithManager(mgr); err != nil {
+	//the SystemConfig reconciler configures the registry provisioner that the JBSConfig reconciler uses
+	provisioner := &registry.ProvisionerRef{}
+	if err := systemconfig.SetupNewReconcilerWithManager(mgr, provisioner); err != nil {
 		return nil, err
 	}
 
-	if err := jbsconfig.SetupNewReconcilerWithManager(mgr, spiPresent, quayClient, quayOrgName); err != nil {
+	if err := jbsconfig.SetupNewReconcilerWithManager(mgr, spiPresent, provisioner); err != nil {
 		return nil, err
 	}
 
-	metrics.InitPrometheus(mgr.GetClient())
+	if err := retention.SetupNewReconcilerWithManager(mgr); err != nil {
+		return nil, err
+	}
+
+	if err := rebuiltartifact.SetupNewReconcilerWithManager(mgr); err != nil {
+		return nil, err
+	}
+
+	if err := cloudevents.SetupPublisherWithManager(mgr); err != nil {
+		return nil, err
+	}
+
+	if err := metrics.InitPrometheus(mgr.GetCache()); err != nil {
+		return nil, err
+	}
 	return mgr, nil
 }
@@ -0,0 +1,139 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/redhat-appstudio/jvm-build-service/pkg/apis/jvmbuildservice/v1alpha1"
+)
+
+const (
+	ToolLabel string = "tool"
+	JDKLabel  string = "jdk"
+
+	DiscoveryDurationMetric           string = "stonesoup_jvmbuildservice_artifactbuild_discovery_duration_seconds"
+	BuildInfoDurationMetric           string = "stonesoup_jvmbuildservice_dependencybuild_build_info_duration_seconds"
+	BuildPipelineRunDurationMetric    string = "stonesoup_jvmbuildservice_build_pipelinerun_duration_seconds"
+	ArtifactBuildTimeToCompleteMetric string = "stonesoup_jvmbuildservice_artifactbuild_time_to_complete_seconds"
+	RecipeAttemptsMetric              string = "stonesoup_jvmbuildservice_recipe_attempts_total"
+	OOMRetriesMetric                  string = "stonesoup_jvmbuildservice_oom_retries_total"
+	CacheRestartRetriesMetric         string = "stonesoup_jvmbuildservice_cache_restart_retries_total"
+	ContaminationMetric               string = "stonesoup_jvmbuildservice_contaminated_builds_total"
+	VerificationFailuresMetric        string = "stonesoup_jvmbuildservice_verification_failures_total"
+	RebuiltArtifactsCreatedMetric     string = "stonesoup_jvmbuildservice_rebuiltartifacts_created_total"
+)
+
+// The build lifecycle metrics are recorded by the reconcilers as the builds change state. Unlike the state gauges
+// the counters are not affected by objects being pruned, so they show how much the service has been used for the
+// life of the cluster. Discovery and build information analysis happen before a recipe is chosen, so they have
+// empty tool and jdk labels.
+var (
+	buildLabels = []string{NamespaceLabel, ToolLabel, JDKLabel}
+
+	discoveryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    DiscoveryDurationMetric,
+		Help:    "Time taken to discover the source of an ArtifactBuild.",
+		Buckets: prometheus.ExponentialBuckets(1, 2, 12),
+	}, buildLabels)
+	buildInfoDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    BuildInfoDurationMetric,
+		Help:    "Duration of the build information analysis PipelineRuns.",
+		Buckets: prometheus.ExponentialBuckets(5, 2, 10),
+	}, buildLabels)
+	buildPipelineRunDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    BuildPipelineRunDurationMetric,
+		Help:    "Duration of the build PipelineRuns.",
+		Buckets: prometheus.ExponentialBuckets(30, 2, 10),
+	}, buildLabels)
+	artifactBuildTimeToComplete = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    ArtifactBuildTimeToCompleteMetric,
+		Help:    "Time from an ArtifactBuild being created until its artifact has been rebuilt.",
+		Buckets: prometheus.ExponentialBuckets(60, 2, 12),
+	}, buildLabels)
+	recipeAttempts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: RecipeAttemptsMetric,
+		Help: "Number of build PipelineRuns started.",
+	}, buildLabels)
+	oomRetries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: OOMRetriesMetric,
+		Help: "Number of pipelines retried with more memory after running out of memory.",
+	}, buildLabels)
+	cacheRestartRetries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: CacheRestartRetriesMetric,
+		Help: "Number of builds retried because the cache was restarted while they were running.",
+	}, buildLabels)
+	contaminations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: ContaminationMetric,
+		Help: "Number of builds that were found to be contaminated with community dependencies.",
+	}, buildLabels)
+	verificationFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: VerificationFailuresMetric,
+		Help: "Number of builds where the rebuilt artifacts did not match the upstream artifacts.",
+	}, buildLabels)
+	rebuiltArtifactsCreated = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: RebuiltArtifactsCreatedMetric,
+		Help: "Number of RebuiltArtifacts created.",
+	}, buildLabels)
+
+	buildCollectors = []prometheus.Collector{
+		discoveryDuration,
+		buildInfoDuration,
+		buildPipelineRunDuration,
+		artifactBuildTimeToComplete,
+		recipeAttempts,
+		oomRetries,
+		cacheRestartRetries,
+		contaminations,
+		verificationFailures,
+		rebuiltArtifactsCreated,
+	}
+)
+
+func recipeLabels(namespace string, recipe *v1alpha1.BuildRecipe) prometheus.Labels {
+	labels := prometheus.Labels{NamespaceLabel: namespace, ToolLabel: "", JDKLabel: ""}
+	if recipe != nil {
+		labels[ToolLabel] = recipe.Tool
+		labels[JDKLabel] = recipe.JavaVersion
+	}
+	return labels
+}
+
+func ObserveDiscoveryDuration(namespace string, duration time.Duration) {
+	discoveryDuration.With(recipeLabels(namespace, nil)).Observe(duration.Seconds())
+}
+
+func ObserveBuildInfoDuration(namespace string, duration time.Duration) {
+	buildInfoDuration.With(recipeLabels(namespace, nil)).Observe(duration.Seconds())
+}
+
+func ObserveBuildPipelineRunDuration(namespace string, recipe *v1alpha1.BuildRecipe, duration time.Duration) {
+	buildPipelineRunDuration.With(recipeLabels(namespace, recipe)).Observe(duration.Seconds())
+}
+
+func ObserveArtifactBuildTimeToComplete(namespace string, recipe *v1alpha1.BuildRecipe, duration time.Duration) {
+	artifactBuildTimeToComplete.With(recipeLabels(namespace, recipe)).Observe(duration.Seconds())
+}
+
+func RecipeAttempted(namespace string, recipe *v1alpha1.BuildRecipe) {
+	recipeAttempts.With(recipeLabels(namespace, recipe)).Inc()
+}
+
+func OOMRetried(namespace string, recipe *v1alpha1.BuildRecipe) {
+	oomRetries.With(recipeLabels(namespace, recipe)).Inc()
+}
+
+func CacheRestartRetried(namespace string, recipe *v1alpha1.BuildRecipe) {
+	cacheRestartRetries.With(recipeLabels(namespace, recipe)).Inc()
+}
+
+func BuildContaminated(namespace string, recipe *v1alpha1.BuildRecipe) {
+	contaminations.With(recipeLabels(namespace, recipe)).Inc()
+}
+
+func VerificationFailed(namespace string, recipe *v1alpha1.BuildRecipe) {
+	verificationFailures.With(recipeLabels(namespace, recipe)).Inc()
+}
+
+func RebuiltArtifactCreated(namespace string, recipe *v1alpha1.BuildRecipe) {
+	rebuiltArtifactsCreated.With(recipeLabels(namespace, recipe)).Inc()
+}
@@ -6,62 +6,131 @@ import (
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/redhat-appstudio/jvm-build-service/pkg/apis/jvmbuildservice/v1alpha1"
+	toolscache "k8s.io/client-go/tools/cache"
 
-	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/metrics"
 )
 
 const (
 	StateLabel                 string = "state"
+	NamespaceLabel             string = "namespace"
+	KindLabel                  string = "kind"
 	ArtifactBuildTotalMetric   string = "stonesoup_jvmbuildservice_artifactbuilds_total_by_state_count"
 	DependencyBuildTotalMetric string = "stonesoup_jvmbuildservice_dependencybuilds_total_by_state_count"
+	ScrapeErrorsMetric         string = "stonesoup_jvmbuildservice_metrics_scrape_errors_total"
 )
 
 var (
 	artifactBuildDesc   *prometheus.Desc
 	dependencyBuildDesc *prometheus.Desc
+	scrapeErrors        *prometheus.CounterVec
 	registered          = false
 	sc                  buildContCollector
 	regLock             = sync.Mutex{}
 )
 
-func InitPrometheus(client client.Client) {
+// InitPrometheus registers the metrics, the state gauges are kept up to date from the events of the shared
+// informers, so a scrape does not have to list every object in the cluster
+func InitPrometheus(informers cache.Informers) error {
 	regLock.Lock()
 	defer regLock.Unlock()
 
 	if registered {
-		return
+		return nil
 	}
 
+	abInformer, err := informers.GetInformer(context.Background(), &v1alpha1.ArtifactBuild{})
+	if err != nil {
+		return err
+	}
+	dbInformer, err := informers.GetInformer(context.Background(), &v1alpha1.DependencyBuild{})
+	if err != nil {
+		return err
+	}
+	if err := register(abInformer, dbInformer); err != nil {
+		return err
+	}
 	registered = true
+	return nil
+}
 
-	labels := []string{StateLabel}
+func register(abInformer cache.Informer, dbInformer cache.Informer) error {
+	labels := []string{NamespaceLabel, StateLabel}
 	artifactBuildDesc = prometheus.NewDesc(ArtifactBuildTotalMetric,
 		"Number of total ArtifactBuilds by state.",
 		labels,
 		nil)
 	dependencyBuildDesc = prometheus.NewDesc(DependencyBuildTotalMetric,
-		"Number of total ArtifactBuilds by state.",
+		"Number of total DependencyBuilds by state.",
 		labels,
 		nil)
-
-	//TODO based on our openshift builds experience, we have talked about the notion of tracking adoption
-	// of various stonesoup features (i.e. product mgmt is curious how much has feature X been used for the life of this cluster),
-	// or even stonesoup "overall", based on PipelineRun counts that are incremented
-	// each time a PipelineRun comes through the reconciler for the first time (i.e. we label the PipelineRun as
-	// part of bumping the metric so we only bump once), and then this metric is immune to PipelineRuns getting pruned.
-	// i.e. newStat = prometheus.NewGaugeVec(...) and then newStat.Inc() if first time through
-	// Conversely, for "devops" concerns, the collections of existing PipelineRuns is typically more of what is needed.
+	scrapeErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: ScrapeErrorsMetric,
+		Help: "Number of scrapes where the objects of a kind could not be counted, as their informer had not synced.",
+	}, []string{KindLabel})
 
 	sc = buildContCollector{
-		client: client,
+		artifactBuilds: newStateTracker("ArtifactBuild", artifactBuildDesc, artifactBuildState, []string{
+			v1alpha1.ArtifactBuildStateNew,
+			v1alpha1.ArtifactBuildStateDiscovering,
+			v1alpha1.ArtifactBuildStateMissing,
+			v1alpha1.ArtifactBuildStateComplete,
+			v1alpha1.ArtifactBuildStateFailed,
+			v1alpha1.ArtifactBuildStateBuilding,
+		}),
+		dependencyBuilds: newStateTracker("DependencyBuild", dependencyBuildDesc, dependencyBuildState, []string{
+			v1alpha1.DependencyBuildStateNew,
+			v1alpha1.DependencyBuildStateAnalyzeBuild,
+			v1alpha1.DependencyBuildStateSubmitBuild,
+			v1alpha1.DependencyBuildStateQueued,
+			v1alpha1.DependencyBuildStateBuilding,
+			v1alpha1.DependencyBuildStateContaminated,
+			v1alpha1.DependencyBuildStateComplete,
+			v1alpha1.DependencyBuildStateFailed,
+		}),
+	}
+	sc.artifactBuilds.synced = abInformer.HasSynced
+	sc.dependencyBuilds.synced = dbInformer.HasSynced
+	if _, err := abInformer.AddEventHandler(sc.artifactBuilds); err != nil {
+		return err
+	}
+	if _, err := dbInformer.AddEventHandler(sc.dependencyBuilds); err != nil {
+		return err
+	}
+
+	metrics.Registry.MustRegister(&sc, scrapeErrors)
+	metrics.Registry.MustRegister(buildCollectors...)
+	return nil
+}
+
+func artifactBuildState(obj interface{}) (string, string, string, bool) {
+	ab, ok := obj.(*v1alpha1.ArtifactBuild)
+	if !ok {
+		return "", "", "", false
 	}
+	state := ab.Status.State
+	if state == "" {
+		state = v1alpha1.ArtifactBuildStateNew
+	}
+	return ab.Namespace, ab.Name, state, true
+}
 
-	metrics.Registry.MustRegister(&sc)
+func dependencyBuildState(obj interface{}) (string, string, string, bool) {
+	db, ok := obj.(*v1alpha1.DependencyBuild)
+	if !ok {
+		return "", "", "", false
+	}
+	state := db.Status.State
+	if state == "" {
+		state = v1alpha1.DependencyBuildStateNew
+	}
+	return db.Namespace, db.Name, state, true
 }
 
 type buildContCollector struct {
-	client client.Client
+	artifactBuilds   *stateTracker
+	dependencyBuilds *stateTracker
 }
 
 func (sc *buildContCollector) Describe(ch chan<- *prometheus.Desc) {
@@ -70,58 +139,109 @@ func (sc *buildContCollector) Describe(ch chan<- *prometheus.Desc) {
 }
 
 func (sc *buildContCollector) Collect(ch chan<- prometheus.Metric) {
-	abs := &v1alpha1.ArtifactBuildList{}
+	sc.artifactBuilds.collect(ch)
+	sc.dependencyBuilds.collect(ch)
+}
 
-	err := sc.client.List(context.Background(), abs)
-	if err != nil {
-		//TODO add log / event
-		return
-	}
-	byState := map[string]int{
-		v1alpha1.ArtifactBuildStateNew:         0,
-		v1alpha1.ArtifactBuildStateDiscovering: 0,
-		v1alpha1.ArtifactBuildStateMissing:     0,
-		v1alpha1.ArtifactBuildStateComplete:    0,
-		v1alpha1.ArtifactBuildStateFailed:      0,
-		v1alpha1.ArtifactBuildStateBuilding:    0,
+type objectKey struct {
+	namespace string
+	name      string
+}
+
+// stateTracker counts the objects of a kind by namespace and state, it is updated by the informer events
+type stateTracker struct {
+	lock    sync.Mutex
+	kind    string
+	desc    *prometheus.Desc
+	stateOf func(obj interface{}) (namespace string, name string, state string, ok bool)
+	states  []string
+	synced  func() bool
+	// objects the last known state of each object, so an update or delete can move it out of that state
+	objects map[objectKey]string
+	// counts the number of objects in each state, by namespace
+	counts map[string]map[string]int
+}
+
+func newStateTracker(kind string, desc *prometheus.Desc, stateOf func(obj interface{}) (string, string, string, bool), states []string) *stateTracker {
+	return &stateTracker{
+		kind:    kind,
+		desc:    desc,
+		stateOf: stateOf,
+		states:  states,
+		objects: map[objectKey]string{},
+		counts:  map[string]map[string]int{},
 	}
+}
 
-	for _, i := range abs.Items {
-		state := i.Status.State
-		if state == "" {
-			state = v1alpha1.ArtifactBuildStateNew
-		}
-		byState[state]++
+func (t *stateTracker) OnAdd(obj interface{}) {
+	t.set(obj)
+}
+
+func (t *stateTracker) OnUpdate(oldObj, newObj interface{}) {
+	t.set(newObj)
+}
+
+func (t *stateTracker) OnDelete(obj interface{}) {
+	if tombstone, ok := obj.(toolscache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
 	}
-	for k, v := range byState {
-		ch <- prometheus.MustNewConstMetric(artifactBuildDesc, prometheus.GaugeValue, float64(v), k)
+	namespace, name, _, ok := t.stateOf(obj)
+	if !ok {
+		return
 	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.remove(objectKey{namespace: namespace, name: name})
+}
 
-	dbs := &v1alpha1.DependencyBuildList{}
+func (t *stateTracker) set(obj interface{}) {
+	namespace, name, state, ok := t.stateOf(obj)
+	if !ok {
+		return
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	key := objectKey{namespace: namespace, name: name}
+	if existing, found := t.objects[key]; found && existing == state {
+		return
+	}
+	t.remove(key)
+	t.objects[key] = state
+	if t.counts[namespace] == nil {
+		t.counts[namespace] = map[string]int{}
+	}
+	t.counts[namespace][state]++
+}
 
-	err = sc.client.List(context.Background(), dbs)
-	if err != nil {
-		//TODO add log / event
+// remove must be called with the lock held, namespaces without any objects are dropped so they do not report
+// zero forever
+func (t *stateTracker) remove(key objectKey) {
+	state, found := t.objects[key]
+	if !found {
 		return
 	}
-	byState = map[string]int{
-		v1alpha1.DependencyBuildStateNew:          0,
-		v1alpha1.DependencyBuildStateAnalyzeBuild: 0,
-		v1alpha1.DependencyBuildStateSubmitBuild:  0,
-		v1alpha1.DependencyBuildStateBuilding:     0,
-		v1alpha1.DependencyBuildStateContaminated: 0,
-		v1alpha1.DependencyBuildStateComplete:     0,
-		v1alpha1.DependencyBuildStateFailed:       0,
+	delete(t.objects, key)
+	byState := t.counts[key.namespace]
+	byState[state]--
+	if byState[state] <= 0 {
+		delete(byState, state)
 	}
+	if len(byState) == 0 {
+		delete(t.counts, key.namespace)
+	}
+}
 
-	for _, i := range dbs.Items {
-		state := i.Status.State
-		if state == "" {
-			state = v1alpha1.DependencyBuildStateNew
-		}
-		byState[state]++
+func (t *stateTracker) collect(ch chan<- prometheus.Metric) {
+	if t.synced != nil && !t.synced() {
+		//the counts are incomplete until the informer has synced, so nothing is reported rather than a wrong number
+		scrapeErrors.WithLabelValues(t.kind).Inc()
+		return
 	}
-	for k, v := range byState {
-		ch <- prometheus.MustNewConstMetric(dependencyBuildDesc, prometheus.GaugeValue, float64(v), k)
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	for namespace, byState := range t.counts {
+		for _, state := range t.states {
+			ch <- prometheus.MustNewConstMetric(t.desc, prometheus.GaugeValue, float64(byState[state]), namespace, state)
+		}
 	}
 }
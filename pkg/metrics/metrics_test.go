@@ -1,18 +1,17 @@
 package metrics
 
 import (
-	"context"
+	"testing"
+	"time"
+
 	"github.com/redhat-appstudio/jvm-build-service/pkg/apis/jvmbuildservice/v1alpha1"
 	controllerruntime "sigs.k8s.io/controller-runtime"
-	"testing"
 
 	. "github.com/onsi/gomega"
 
 	pmodel "github.com/prometheus/client_model/go"
 
-	"k8s.io/apimachinery/pkg/runtime"
-	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
-	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	toolscache "k8s.io/client-go/tools/cache"
 	crmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
 )
 
@@ -20,51 +19,133 @@ import (
 // counters as global, makes vetting precise metric counts across multiple tests problematic;
 // so we have cover our various "scenarios" under one test method
 
-func gatherMetrics(g *WithT) []*pmodel.Metric {
+// fakeInformer delivers the events to the handler directly
+type fakeInformer struct {
+	handler toolscache.ResourceEventHandler
+	synced  bool
+}
+
+func (f *fakeInformer) AddEventHandler(handler toolscache.ResourceEventHandler) (toolscache.ResourceEventHandlerRegistration, error) {
+	f.handler = handler
+	return nil, nil
+}
+
+func (f *fakeInformer) AddEventHandlerWithResyncPeriod(handler toolscache.ResourceEventHandler, resyncPeriod time.Duration) (toolscache.ResourceEventHandlerRegistration, error) {
+	return f.AddEventHandler(handler)
+}
+
+func (f *fakeInformer) RemoveEventHandler(handle toolscache.ResourceEventHandlerRegistration) error {
+	return nil
+}
+
+func (f *fakeInformer) AddIndexers(indexers toolscache.Indexers) error {
+	return nil
+}
+
+func (f *fakeInformer) HasSynced() bool {
+	return f.synced
+}
+
+func gatherMetrics(g *WithT, name string) []*pmodel.Metric {
 	metrics, err := crmetrics.Registry.Gather()
 	g.Expect(err).NotTo(HaveOccurred())
 	for _, metricFamily := range metrics {
-		switch metricFamily.GetName() {
-		case ArtifactBuildTotalMetric:
-			// call
+		if metricFamily.GetName() == name {
 			return metricFamily.GetMetric()
 		}
 	}
 	return nil
 }
 
-func TestMetrics(t *testing.T) {
-	g := NewGomegaWithT(t)
-	scheme := runtime.NewScheme()
-	_ = v1alpha1.AddToScheme(scheme)
-	//TODO fill starting objects as needed
-	objs := []runtimeclient.Object{}
-	client := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
-	InitPrometheus(client)
-
-	//TODO add various tests where you then call
-	metric := gatherMetrics(g)
-	for _, m := range metric {
-		g.Expect(m.GetGauge().GetValue()).Should(Equal(0.0))
+func labelValue(m *pmodel.Metric, name string) string {
+	for _, l := range m.GetLabel() {
+		if l.GetName() == name {
+			return l.GetValue()
+		}
 	}
-	ab := v1alpha1.ArtifactBuild{
+	return ""
+}
+
+func gaugeValue(g *WithT, namespace string, state string) float64 {
+	for _, m := range gatherMetrics(g, ArtifactBuildTotalMetric) {
+		if labelValue(m, NamespaceLabel) == namespace && labelValue(m, StateLabel) == state {
+			return m.GetGauge().GetValue()
+		}
+	}
+	return -1
+}
+
+func artifactBuild(namespace string, state string) *v1alpha1.ArtifactBuild {
+	return &v1alpha1.ArtifactBuild{
 		Spec: v1alpha1.ArtifactBuildSpec{
 			GAV: "com.test:test:1.0",
 		},
 		ObjectMeta: controllerruntime.ObjectMeta{
 			Name:      "test",
-			Namespace: "test",
+			Namespace: namespace,
 		},
-		Status: v1alpha1.ArtifactBuildStatus{State: v1alpha1.ArtifactBuildStateComplete},
+		Status: v1alpha1.ArtifactBuildStatus{State: state},
 	}
-	g.Expect(client.Create(context.TODO(), &ab)).Should(Succeed())
-	metric = gatherMetrics(g)
+}
+
+func TestMetrics(t *testing.T) {
+	g := NewGomegaWithT(t)
+	abInformer := &fakeInformer{}
+	dbInformer := &fakeInformer{synced: true}
+	g.Expect(register(abInformer, dbInformer)).Should(Succeed())
+
+	//nothing is reported until the informer has synced
+	g.Expect(gatherMetrics(g, ArtifactBuildTotalMetric)).Should(BeEmpty())
+	errors := gatherMetrics(g, ScrapeErrorsMetric)
+	g.Expect(errors).Should(HaveLen(1))
+	g.Expect(labelValue(errors[0], KindLabel)).Should(Equal("ArtifactBuild"))
+	//every gather is a scrape, so the earlier one has been counted
+	g.Expect(errors[0].GetCounter().GetValue()).Should(BeNumerically(">=", 1))
+
+	abInformer.synced = true
+	g.Expect(gatherMetrics(g, ArtifactBuildTotalMetric)).Should(BeEmpty())
+	abInformer.handler.OnAdd(artifactBuild("test", v1alpha1.ArtifactBuildStateComplete))
+	metric := gatherMetrics(g, ArtifactBuildTotalMetric)
 	g.Expect(len(metric)).Should(Equal(6))
 	for _, m := range metric {
-		if *m.GetLabel()[0].Value == v1alpha1.ArtifactBuildStateComplete {
+		g.Expect(labelValue(m, NamespaceLabel)).Should(Equal("test"))
+		if labelValue(m, StateLabel) == v1alpha1.ArtifactBuildStateComplete {
 			g.Expect(m.GetGauge().GetValue()).Should(Equal(1.0))
 		} else {
 			g.Expect(m.GetGauge().GetValue()).Should(Equal(0.0))
 		}
 	}
+
+	//the same object is only counted once, in its latest state
+	abInformer.handler.OnAdd(artifactBuild("other", ""))
+	abInformer.handler.OnUpdate(artifactBuild("other", ""), artifactBuild("other", v1alpha1.ArtifactBuildStateBuilding))
+	abInformer.handler.OnUpdate(artifactBuild("other", v1alpha1.ArtifactBuildStateBuilding), artifactBuild("other", v1alpha1.ArtifactBuildStateBuilding))
+	g.Expect(gatherMetrics(g, ArtifactBuildTotalMetric)).Should(HaveLen(12))
+	g.Expect(gaugeValue(g, "other", v1alpha1.ArtifactBuildStateNew)).Should(Equal(0.0))
+	g.Expect(gaugeValue(g, "other", v1alpha1.ArtifactBuildStateBuilding)).Should(Equal(1.0))
+	g.Expect(gaugeValue(g, "test", v1alpha1.ArtifactBuildStateComplete)).Should(Equal(1.0))
+
+	//deleted objects are removed, along with namespaces that have nothing left
+	abInformer.handler.OnDelete(toolscache.DeletedFinalStateUnknown{Key: "other/test", Obj: artifactBuild("other", v1alpha1.ArtifactBuildStateBuilding)})
+	g.Expect(gatherMetrics(g, ArtifactBuildTotalMetric)).Should(HaveLen(6))
+	g.Expect(gaugeValue(g, "other", v1alpha1.ArtifactBuildStateBuilding)).Should(Equal(-1.0))
+
+	//the build metrics are labelled with the recipe
+	recipe := &v1alpha1.BuildRecipe{Tool: "maven", JavaVersion: "17"}
+	RecipeAttempted("test", recipe)
+	ObserveBuildPipelineRunDuration("test", recipe, 90*time.Second)
+	ObserveDiscoveryDuration("test", time.Second)
+	attempts := gatherMetrics(g, RecipeAttemptsMetric)
+	g.Expect(attempts).Should(HaveLen(1))
+	g.Expect(labelValue(attempts[0], NamespaceLabel)).Should(Equal("test"))
+	g.Expect(labelValue(attempts[0], ToolLabel)).Should(Equal("maven"))
+	g.Expect(labelValue(attempts[0], JDKLabel)).Should(Equal("17"))
+	g.Expect(attempts[0].GetCounter().GetValue()).Should(Equal(1.0))
+	durations := gatherMetrics(g, BuildPipelineRunDurationMetric)
+	g.Expect(durations).Should(HaveLen(1))
+	g.Expect(durations[0].GetHistogram().GetSampleCount()).Should(Equal(uint64(1)))
+	g.Expect(durations[0].GetHistogram().GetSampleSum()).Should(Equal(90.0))
+	discovery := gatherMetrics(g, DiscoveryDurationMetric)
+	g.Expect(discovery).Should(HaveLen(1))
+	g.Expect(labelValue(discovery[0], ToolLabel)).Should(Equal(""))
 }
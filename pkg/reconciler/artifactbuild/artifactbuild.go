@@ -12,7 +12,10 @@ import (
 	"unicode"
 
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/record"
@@ -23,6 +26,7 @@ import (
 
 	"github.com/go-logr/logr"
 	"github.com/redhat-appstudio/jvm-build-service/pkg/apis/jvmbuildservice/v1alpha1"
+	"github.com/redhat-appstudio/jvm-build-service/pkg/metrics"
 	pipelinev1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
 )
 
@@ -35,6 +39,8 @@ const (
 	DependencyBuildIdLabel                  = "jvmbuildservice.io/dependencybuild-id"
 	ArtifactBuildIdLabel                    = "jvmbuildservice.io/abr-id"
 	PipelineRunLabel                        = "jvmbuildservice.io/pipelinerun"
+	// JBSConfigLabel is set on the PipelineRuns of a DependencyBuild that references a JBSConfig
+	JBSConfigLabel = "jvmbuildservice.io/jbsconfig"
 
 	PipelineResultScmUrl      = "scm-url"
 	PipelineResultScmTag      = "scm-tag"
@@ -81,16 +87,6 @@ func (r *ReconcileArtifactBuild) Reconcile(ctx context.Context, request reconcil
 	//if !clusterSet {
 	//	log.Info("cluster is not set in context", request.String())
 
-	jbsConfig := &v1alpha1.JBSConfig{}
-	err := r.client.Get(ctx, types.NamespacedName{Namespace: request.Namespace, Name: v1alpha1.JBSConfigName}, jbsConfig)
-	if err != nil && !errors.IsNotFound(err) {
-		return reconcile.Result{}, err
-	}
-	//if rebuilds are not enabled we don't do anything here
-	if !jbsConfig.Spec.EnableRebuilds {
-		return reconcile.Result{}, nil
-	}
-
 	abr := v1alpha1.ArtifactBuild{}
 	abrerr := r.client.Get(ctx, request.NamespacedName, &abr)
 	if abrerr != nil {
@@ -126,6 +122,23 @@ func (r *ReconcileArtifactBuild) Reconcile(ctx context.Context, request reconcil
 		return ctrl.Result{}, nil
 	}
 
+	//the object is handled with the JBSConfig it references
+	jbsConfigName := abr.Spec.JBSConfig
+	if dberr == nil {
+		jbsConfigName = db.Spec.JBSConfig
+	} else if prerr == nil {
+		jbsConfigName = pr.Labels[JBSConfigLabel]
+	}
+	jbsConfig := &v1alpha1.JBSConfig{}
+	err := r.client.Get(ctx, types.NamespacedName{Namespace: request.Namespace, Name: v1alpha1.JBSConfigNameOrDefault(jbsConfigName)}, jbsConfig)
+	if err != nil && !errors.IsNotFound(err) {
+		return reconcile.Result{}, err
+	}
+	//if rebuilds are not enabled we don't do anything here
+	if !jbsConfig.Spec.EnableRebuilds {
+		return reconcile.Result{}, nil
+	}
+
 	switch {
 	case dberr == nil:
 		log = log.WithValues("kind", "DependencyBuild", "db-scm-url", db.Spec.ScmInfo.SCMURL, "db-scm-tag", db.Spec.ScmInfo.Tag)
@@ -134,7 +147,7 @@ func (r *ReconcileArtifactBuild) Reconcile(ctx context.Context, request reconcil
 
 	case prerr == nil:
 		log = log.WithValues("kind", "PipelineRun")
-		return r.handlePipelineRunReceived(ctx, log, &pr)
+		return r.handlePipelineRunReceived(ctx, log, &pr, jbsConfigName)
 
 	case abrerr == nil:
 		log = log.WithValues("kind", "ArtifactBuild", "ab-gav", abr.Spec.GAV)
@@ -176,7 +189,7 @@ func (r *ReconcileArtifactBuild) Reconcile(ctx context.Context, request reconcil
 //	return len(logicalcluster.From(object).String()) > 0
 //}
 
-func (r *ReconcileArtifactBuild) handlePipelineRunReceived(ctx context.Context, log logr.Logger, pr *pipelinev1beta1.PipelineRun) (reconcile.Result, error) {
+func (r *ReconcileArtifactBuild) handlePipelineRunReceived(ctx context.Context, log logr.Logger, pr *pipelinev1beta1.PipelineRun, jbsConfigName string) (reconcile.Result, error) {
 
 	if pr.DeletionTimestamp != nil {
 		//always remove the finalizer if it is deleted
@@ -191,7 +204,7 @@ func (r *ReconcileArtifactBuild) handlePipelineRunReceived(ctx context.Context,
 	if pr.Status.PipelineResults != nil {
 		for _, prRes := range pr.Status.PipelineResults {
 			if prRes.Name == PipelineResultJavaCommunityDependencies {
-				return reconcile.Result{}, r.handleCommunityDependencies(ctx, strings.Split(prRes.Value.StringVal, ","), pr.Namespace, log)
+				return reconcile.Result{}, r.handleCommunityDependencies(ctx, strings.Split(prRes.Value.StringVal, ","), pr.Namespace, jbsConfigName, log)
 			}
 		}
 	}
@@ -255,17 +268,15 @@ func (r *ReconcileArtifactBuild) handleDependencyBuildReceived(ctx context.Conte
 				//no need to retry it would just result in an infinite loop
 				return reconcile.Result{}, nil
 			}
-			oldState := abr.Status.State
+			oldStatus := abr.Status.DeepCopy()
 			switch db.Status.State {
 			case v1alpha1.DependencyBuildStateFailed:
-			case v1alpha1.DependencyBuildStateContaminated:
-				abr.Status.State = v1alpha1.ArtifactBuildStateFailed
 			case v1alpha1.DependencyBuildStateComplete:
 				return r.handleDependencyBuildSuccess(ctx, db, &abr)
 			default:
-				abr.Status.State = v1alpha1.ArtifactBuildStateBuilding
+				applyDependencyBuildState(&abr, db)
 			}
-			if oldState != abr.Status.State {
+			if !equality.Semantic.DeepEqual(oldStatus, &abr.Status) {
 				err = r.client.Status().Update(ctx, &abr)
 				if err != nil {
 					return reconcile.Result{}, err
@@ -291,22 +302,40 @@ func (r *ReconcileArtifactBuild) handleStateDiscovering(ctx context.Context, log
 		len(abr.Status.Message) == 0 {
 		return reconcile.Result{}, nil
 	}
+	version, err := gavVersion(abr.Spec.GAV)
+	if err != nil {
+		//the webhook rejects these, but it is not always enabled
+		abr.Status.State = v1alpha1.ArtifactBuildStateMissing
+		setCondition(abr, v1alpha1.ArtifactBuildConditionDiscovered, metav1.ConditionFalse, v1alpha1.ArtifactBuildReasonInvalidGAV, err.Error())
+		return reconcile.Result{}, r.client.Status().Update(ctx, abr)
+	}
+	//the duration is recorded once the status update succeeds, so a conflict does not record it twice
+	discoveryDuration := time.Since(discoveryStartTime(abr))
+	recordDiscovery := func(result reconcile.Result, err error) (reconcile.Result, error) {
+		if err == nil {
+			metrics.ObserveDiscoveryDuration(abr.Namespace, discoveryDuration)
+		}
+		return result, err
+	}
 	if len(abr.Status.SCMInfo.SCMURL) == 0 || len(abr.Status.SCMInfo.Tag) == 0 {
 		//discovery failed
 		abr.Status.State = v1alpha1.ArtifactBuildStateMissing
-		return reconcile.Result{}, r.client.Status().Update(ctx, abr)
+		setCondition(abr, v1alpha1.ArtifactBuildConditionDiscovered, metav1.ConditionFalse, v1alpha1.ArtifactBuildReasonSCMInfoMissing, abr.Status.Message)
+		return recordDiscovery(reconcile.Result{}, r.client.Status().Update(ctx, abr))
 	}
+	setCondition(abr, v1alpha1.ArtifactBuildConditionDiscovered, metav1.ConditionTrue, v1alpha1.ArtifactBuildReasonSCMInfoFound, fmt.Sprintf("found %s at tag %s", abr.Status.SCMInfo.SCMURL, abr.Status.SCMInfo.Tag))
 
 	//now lets look for an existing dependencybuild object
-	depId := hashString(abr.Status.SCMInfo.SCMURL + abr.Status.SCMInfo.Tag + abr.Status.SCMInfo.Path)
+	depId := dependencyBuildId(abr)
 	db := &v1alpha1.DependencyBuild{}
 	dbKey := types.NamespacedName{Namespace: abr.Namespace, Name: depId}
-	err := r.client.Get(ctx, dbKey, db)
+	err = r.client.Get(ctx, dbKey, db)
 
 	switch {
 	case err == nil:
 		//move the state to building
 		abr.Status.State = v1alpha1.ArtifactBuildStateBuilding
+		setCondition(abr, v1alpha1.ArtifactBuildConditionDependencyBuildLinked, metav1.ConditionTrue, v1alpha1.ArtifactBuildReasonDependencyBuildFound, "linked to existing DependencyBuild "+db.Name)
 		//build already exists, add us to the owner references
 		found := false
 		for _, or := range db.OwnerReferences {
@@ -325,16 +354,20 @@ func (r *ReconcileArtifactBuild) handleStateDiscovering(ctx context.Context, log
 		}
 
 		//if the build is done update our state accordingly
-		switch db.Status.State {
-		case v1alpha1.DependencyBuildStateComplete:
-			return r.handleDependencyBuildSuccess(ctx, db, abr)
-		case v1alpha1.DependencyBuildStateContaminated, v1alpha1.DependencyBuildStateFailed:
-			abr.Status.State = v1alpha1.ArtifactBuildStateFailed
+		if db.Status.State == v1alpha1.DependencyBuildStateComplete {
+			return recordDiscovery(r.handleDependencyBuildSuccess(ctx, db, abr))
 		}
-		if err := r.client.Status().Update(ctx, abr); err != nil {
+		applyDependencyBuildState(abr, db)
+		return recordDiscovery(reconcile.Result{}, r.client.Status().Update(ctx, abr))
+	case errors.IsNotFound(err):
+		//if a trusted namespace has already built it we use that build
+		sharedDb, sharedRa, err := r.findSharedDependencyBuild(ctx, log, abr, depId)
+		if err != nil {
 			return reconcile.Result{}, err
 		}
-	case errors.IsNotFound(err):
+		if sharedDb != nil {
+			return recordDiscovery(r.linkSharedDependencyBuild(ctx, log, abr, sharedDb, sharedRa))
+		}
 		//move the state to building
 		abr.Status.State = v1alpha1.ArtifactBuildStateBuilding
 
@@ -346,6 +379,8 @@ func (r *ReconcileArtifactBuild) handleStateDiscovering(ctx context.Context, log
 		}
 		//TODO: do we in fact need to put depId through GenerateName sanitation algorithm for the name? label value restrictions are more stringent than obj name
 		db.Name = depId
+		setCondition(abr, v1alpha1.ArtifactBuildConditionDependencyBuildLinked, metav1.ConditionTrue, v1alpha1.ArtifactBuildReasonDependencyBuildCreated, "created DependencyBuild "+db.Name)
+		setCondition(abr, v1alpha1.ArtifactBuildConditionBuilt, metav1.ConditionUnknown, v1alpha1.ArtifactBuildReasonBuilding, "waiting for DependencyBuild "+db.Name)
 		if err := controllerutil.SetOwnerReference(abr, db, r.scheme); err != nil {
 			return reconcile.Result{}, err
 		}
@@ -356,8 +391,8 @@ func (r *ReconcileArtifactBuild) handleStateDiscovering(ctx context.Context, log
 			CommitHash: abr.Status.SCMInfo.CommitHash,
 			Path:       abr.Status.SCMInfo.Path,
 			Private:    abr.Status.SCMInfo.Private,
-		}, Version: abr.Spec.GAV[strings.LastIndex(abr.Spec.GAV, ":")+1:]}
-		if err := r.client.Status().Update(ctx, abr); err != nil {
+		}, Version: version, JBSConfig: abr.Spec.JBSConfig}
+		if _, err := recordDiscovery(reconcile.Result{}, r.client.Status().Update(ctx, abr)); err != nil {
 			return reconcile.Result{}, err
 		}
 		return reconcile.Result{}, r.client.Create(ctx, db)
@@ -366,23 +401,101 @@ func (r *ReconcileArtifactBuild) handleStateDiscovering(ctx context.Context, log
 		log.Error(err, "for artifactbuild %s:%s", abr.Namespace, abr.Name)
 		return reconcile.Result{}, err
 	}
-
-	return reconcile.Result{}, nil
-
 }
 
 func (r *ReconcileArtifactBuild) handleDependencyBuildSuccess(ctx context.Context, db *v1alpha1.DependencyBuild, abr *v1alpha1.ArtifactBuild) (reconcile.Result, error) {
+	setCondition(abr, v1alpha1.ArtifactBuildConditionContaminated, metav1.ConditionFalse, v1alpha1.ArtifactBuildReasonNotContaminated, "")
 	for _, i := range db.Status.DeployedArtifacts {
 		if i == abr.Spec.GAV {
+			newlyCompleted := abr.Status.State != v1alpha1.ArtifactBuildStateComplete
 			abr.Status.State = v1alpha1.ArtifactBuildStateComplete
-			return reconcile.Result{}, r.client.Status().Update(ctx, abr)
+			setCondition(abr, v1alpha1.ArtifactBuildConditionBuilt, metav1.ConditionTrue, v1alpha1.ArtifactBuildReasonBuildSucceeded, "artifact was deployed by DependencyBuild "+db.Name)
+			if db.Status.FailedVerification {
+				setCondition(abr, v1alpha1.ArtifactBuildConditionVerified, metav1.ConditionFalse, v1alpha1.ArtifactBuildReasonVerificationFailed, "DependencyBuild "+db.Name+" failed artifact verification")
+			} else {
+				setCondition(abr, v1alpha1.ArtifactBuildConditionVerified, metav1.ConditionTrue, v1alpha1.ArtifactBuildReasonVerificationPassed, "")
+			}
+			if err := r.client.Status().Update(ctx, abr); err != nil {
+				return reconcile.Result{}, err
+			}
+			//only recorded once the update succeeds, a conflict would otherwise record it again
+			if newlyCompleted {
+				metrics.ObserveArtifactBuildTimeToComplete(abr.Namespace, db.Status.CurrentBuildRecipe, time.Since(abr.CreationTimestamp.Time))
+			}
+			return reconcile.Result{}, nil
 		}
 	}
 	abr.Status.Message = "Discovered dependency build did not deploy this artifact, check SCM information is correct"
 	abr.Status.State = v1alpha1.ArtifactBuildStateFailed
+	setCondition(abr, v1alpha1.ArtifactBuildConditionBuilt, metav1.ConditionFalse, v1alpha1.ArtifactBuildReasonArtifactNotDeployed, abr.Status.Message)
 	return reconcile.Result{}, r.client.Status().Update(ctx, abr)
 }
 
+// applyDependencyBuildState updates the state and conditions of an ArtifactBuild to reflect a DependencyBuild
+// that is not complete
+func applyDependencyBuildState(abr *v1alpha1.ArtifactBuild, db *v1alpha1.DependencyBuild) {
+	switch db.Status.State {
+	case v1alpha1.DependencyBuildStateContaminated:
+		abr.Status.State = v1alpha1.ArtifactBuildStateFailed
+		var contaminants []string
+		for _, i := range db.Status.Contaminants {
+			contaminants = append(contaminants, i.GAV)
+		}
+		setCondition(abr, v1alpha1.ArtifactBuildConditionContaminated, metav1.ConditionTrue, v1alpha1.ArtifactBuildReasonContaminated, "contaminated by "+strings.Join(contaminants, ","))
+		setCondition(abr, v1alpha1.ArtifactBuildConditionBuilt, metav1.ConditionFalse, v1alpha1.ArtifactBuildReasonBuildFailed, "DependencyBuild "+db.Name+" is contaminated")
+	case v1alpha1.DependencyBuildStateFailed:
+		abr.Status.State = v1alpha1.ArtifactBuildStateFailed
+		setCondition(abr, v1alpha1.ArtifactBuildConditionBuilt, metav1.ConditionFalse, v1alpha1.ArtifactBuildReasonBuildFailed, "DependencyBuild "+db.Name+" failed")
+	default:
+		abr.Status.State = v1alpha1.ArtifactBuildStateBuilding
+		setCondition(abr, v1alpha1.ArtifactBuildConditionBuilt, metav1.ConditionUnknown, v1alpha1.ArtifactBuildReasonBuilding, "waiting for DependencyBuild "+db.Name)
+	}
+}
+
+// gavVersion returns the version of a GAV in the form groupId:artifactId[:packaging[:classifier]]:version
+func gavVersion(gav string) (string, error) {
+	parts := strings.Split(gav, ":")
+	if len(parts) < 3 || len(parts) > 5 {
+		return "", fmt.Errorf("GAV %q is not in the form groupId:artifactId[:packaging[:classifier]]:version", gav)
+	}
+	for _, part := range parts {
+		if len(strings.TrimSpace(part)) == 0 {
+			return "", fmt.Errorf("GAV %q has an empty groupId, artifactId or version", gav)
+		}
+	}
+	return parts[len(parts)-1], nil
+}
+
+// discoveryStartTime discovery starts when the ArtifactBuild is created, or again when a rebuild was requested
+func discoveryStartTime(abr *v1alpha1.ArtifactBuild) time.Time {
+	discovered := meta.FindStatusCondition(abr.Status.Conditions, v1alpha1.ArtifactBuildConditionDiscovered)
+	if discovered != nil && discovered.Reason == v1alpha1.ArtifactBuildReasonRebuildRequested {
+		return discovered.LastTransitionTime.Time
+	}
+	return abr.CreationTimestamp.Time
+}
+
+// setCondition records a condition on the ArtifactBuild, the transition time is only changed if the status changes
+func setCondition(abr *v1alpha1.ArtifactBuild, conditionType string, status metav1.ConditionStatus, reason string, message string) {
+	meta.SetStatusCondition(&abr.Status.Conditions, metav1.Condition{
+		Type:               conditionType,
+		Status:             status,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: abr.Generation,
+	})
+}
+
+// dependencyBuildId the name of the DependencyBuild that builds the artifact. The builds of a JBSConfig other than the
+// default one deploy to a different registry, so they are kept apart.
+func dependencyBuildId(abr *v1alpha1.ArtifactBuild) string {
+	hashInput := abr.Status.SCMInfo.SCMURL + abr.Status.SCMInfo.Tag + abr.Status.SCMInfo.Path
+	if jbsConfigName := v1alpha1.JBSConfigNameOrDefault(abr.Spec.JBSConfig); jbsConfigName != v1alpha1.JBSConfigName {
+		hashInput += "/" + jbsConfigName
+	}
+	return hashString(hashInput)
+}
+
 func hashString(hashInput string) string {
 	hash := md5.Sum([]byte(hashInput)) //#nosec
 	depId := hex.EncodeToString(hash[:])
@@ -427,7 +540,7 @@ func (r *ReconcileArtifactBuild) handleStateComplete(ctx context.Context, log lo
 }
 
 func (r *ReconcileArtifactBuild) handleStateBuilding(ctx context.Context, log logr.Logger, abr *v1alpha1.ArtifactBuild) (reconcile.Result, error) {
-	depId := hashString(abr.Status.SCMInfo.SCMURL + abr.Status.SCMInfo.Tag + abr.Status.SCMInfo.Path)
+	depId := dependencyBuildId(abr)
 	db := &v1alpha1.DependencyBuild{}
 	dbKey := types.NamespacedName{Namespace: abr.Namespace, Name: depId}
 	err := r.client.Get(ctx, dbKey, db)
@@ -439,6 +552,7 @@ func (r *ReconcileArtifactBuild) handleStateBuilding(ctx context.Context, log lo
 		//move back to new and start again
 		r.eventRecorder.Eventf(abr, corev1.EventTypeWarning, "MissingDependencyBuild", "The ArtifactBuild %s/%s in state %s was missing a DependencyBuild", abr.Namespace, abr.Name, abr.Status.State)
 		abr.Status.State = v1alpha1.ArtifactBuildStateNew
+		setCondition(abr, v1alpha1.ArtifactBuildConditionDependencyBuildLinked, metav1.ConditionFalse, v1alpha1.ArtifactBuildReasonDependencyBuildMissing, "DependencyBuild "+depId+" was not found")
 		return reconcile.Result{}, r.client.Status().Update(ctx, abr)
 	default:
 		log.Error(err, "for artifactbuild %s:%s", abr.Namespace, abr.Name)
@@ -463,16 +577,11 @@ func (r *ReconcileArtifactBuild) handleStateBuilding(ctx context.Context, log lo
 	}
 
 	//if the build is done update our state accordingly
-	switch db.Status.State {
-	case v1alpha1.DependencyBuildStateComplete:
+	if db.Status.State == v1alpha1.DependencyBuildStateComplete {
 		return r.handleDependencyBuildSuccess(ctx, db, abr)
-	case v1alpha1.DependencyBuildStateContaminated, v1alpha1.DependencyBuildStateFailed:
-		abr.Status.State = v1alpha1.ArtifactBuildStateFailed
-		return reconcile.Result{}, r.client.Status().Update(ctx, abr)
-	default:
-		abr.Status.State = v1alpha1.ArtifactBuildStateBuilding
-		return reconcile.Result{}, r.client.Status().Update(ctx, abr)
 	}
+	applyDependencyBuildState(abr, db)
+	return reconcile.Result{}, r.client.Status().Update(ctx, abr)
 }
 
 func (r *ReconcileArtifactBuild) handleRebuild(ctx context.Context, abr *v1alpha1.ArtifactBuild) (reconcile.Result, error) {
@@ -480,7 +589,7 @@ func (r *ReconcileArtifactBuild) handleRebuild(ctx context.Context, abr *v1alpha
 	//and delete it if it exists
 	if len(abr.Status.SCMInfo.SCMURL) > 0 {
 		//now lets look for an existing dependencybuild object
-		depId := hashString(abr.Status.SCMInfo.SCMURL + abr.Status.SCMInfo.Tag + abr.Status.SCMInfo.Path)
+		depId := dependencyBuildId(abr)
 		db := &v1alpha1.DependencyBuild{}
 		dbKey := types.NamespacedName{Namespace: abr.Namespace, Name: depId}
 		err := r.client.Get(ctx, dbKey, db)
@@ -530,6 +639,10 @@ func (r *ReconcileArtifactBuild) handleRebuild(ctx context.Context, abr *v1alpha
 	abr.Status.State = v1alpha1.ArtifactBuildStateNew
 	abr.Status.SCMInfo = v1alpha1.SCMInfo{}
 	abr.Status.Message = ""
+	//the previous results no longer apply, but we keep the conditions so the rebuild is visible
+	for _, i := range abr.Status.Conditions {
+		setCondition(abr, i.Type, metav1.ConditionUnknown, v1alpha1.ArtifactBuildReasonRebuildRequested, "a rebuild was requested")
+	}
 	err := r.client.Status().Update(ctx, abr)
 	return ctrl.Result{}, err
 }
@@ -559,7 +672,7 @@ func CreateABRName(gav string) string {
 	return strings.ToLower(newName.String())
 }
 
-func (r *ReconcileArtifactBuild) handleCommunityDependencies(ctx context.Context, split []string, namespace string, log logr.Logger) error {
+func (r *ReconcileArtifactBuild) handleCommunityDependencies(ctx context.Context, split []string, namespace string, jbsConfigName string, log logr.Logger) error {
 	log.Info("Found pipeline run with community dependencies")
 	for _, gav := range split {
 		if len(gav) == 0 {
@@ -572,6 +685,7 @@ func (r *ReconcileArtifactBuild) handleCommunityDependencies(ctx context.Context
 		if err != nil {
 			if errors.IsNotFound(err) {
 				abr.Spec.GAV = gav
+				abr.Spec.JBSConfig = jbsConfigName
 				abr.Name = name
 				abr.Namespace = namespace
 				err := r.client.Create(ctx, &abr)
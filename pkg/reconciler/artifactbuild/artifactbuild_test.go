@@ -13,6 +13,7 @@ import (
 	appsv1 "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
@@ -70,6 +71,8 @@ func TestStateDiscovering(t *testing.T) {
 	fullValidation := func(client runtimeclient.Client, g *WithT) {
 		abr := getABR(client, g)
 		g.Expect(abr.Status.State).Should(Equal(v1alpha1.ArtifactBuildStateBuilding))
+		g.Expect(meta.IsStatusConditionTrue(abr.Status.Conditions, v1alpha1.ArtifactBuildConditionDiscovered)).Should(BeTrue())
+		g.Expect(meta.IsStatusConditionTrue(abr.Status.Conditions, v1alpha1.ArtifactBuildConditionDependencyBuildLinked)).Should(BeTrue())
 
 		dbList := v1alpha1.DependencyBuildList{}
 		g.Expect(client.List(context.TODO(), &dbList))
@@ -150,6 +153,184 @@ func TestStateDiscovering(t *testing.T) {
 		g.Expect(reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: metav1.NamespaceDefault, Name: "test"}}))
 		fullValidation(client, g)
 	})
+	t.Run("Invalid GAV is not built", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		setup()
+		abr := getABR(client, g)
+		abr.Spec.GAV = "com.test:test"
+		g.Expect(client.Update(ctx, abr)).Should(BeNil())
+		abr.Status.SCMInfo.Tag = "foo"
+		abr.Status.SCMInfo.SCMURL = "goo"
+		g.Expect(client.Status().Update(ctx, abr)).Should(BeNil())
+		_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: metav1.NamespaceDefault, Name: "test"}})
+		g.Expect(err).Should(BeNil())
+		abr = getABR(client, g)
+		g.Expect(abr.Status.State).Should(Equal(v1alpha1.ArtifactBuildStateMissing))
+		g.Expect(meta.FindStatusCondition(abr.Status.Conditions, v1alpha1.ArtifactBuildConditionDiscovered).Reason).Should(Equal(v1alpha1.ArtifactBuildReasonInvalidGAV))
+		dbList := v1alpha1.DependencyBuildList{}
+		g.Expect(client.List(ctx, &dbList)).Should(BeNil())
+		g.Expect(dbList.Items).Should(BeEmpty())
+	})
+}
+
+func TestNamedJBSConfig(t *testing.T) {
+	ctx := context.TODO()
+	setup := func(objs ...runtimeclient.Object) (runtimeclient.Client, *ReconcileArtifactBuild) {
+		abr := &v1alpha1.ArtifactBuild{
+			ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: metav1.NamespaceDefault},
+			Spec:       v1alpha1.ArtifactBuildSpec{GAV: gav, JBSConfig: "team-a"},
+			Status: v1alpha1.ArtifactBuildStatus{
+				State:   v1alpha1.ArtifactBuildStateDiscovering,
+				SCMInfo: v1alpha1.SCMInfo{Tag: "foo", SCMURL: "goo", SCMType: "hoo", Path: "ioo"},
+			},
+		}
+		return setupClientAndReconciler(append(objs, abr)...)
+	}
+	t.Run("DependencyBuild references the JBSConfig", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		client, reconciler := setup(&v1alpha1.JBSConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: "team-a", Namespace: metav1.NamespaceDefault},
+			Spec:       v1alpha1.JBSConfigSpec{EnableRebuilds: true},
+		})
+		g.Expect(reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: metav1.NamespaceDefault, Name: "test"}}))
+		abr := getABR(client, g)
+		g.Expect(abr.Status.State).Should(Equal(v1alpha1.ArtifactBuildStateBuilding))
+		db := v1alpha1.DependencyBuild{}
+		g.Expect(client.Get(ctx, types.NamespacedName{Namespace: metav1.NamespaceDefault, Name: dependencyBuildId(abr)}, &db)).Should(Succeed())
+		g.Expect(db.Spec.JBSConfig).Should(Equal("team-a"))
+		g.Expect(db.Name).ShouldNot(Equal(hashString(abr.Status.SCMInfo.SCMURL + abr.Status.SCMInfo.Tag + abr.Status.SCMInfo.Path)))
+	})
+	t.Run("Nothing is built without the JBSConfig", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		client, reconciler := setup()
+		g.Expect(reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: metav1.NamespaceDefault, Name: "test"}}))
+		g.Expect(getABR(client, g).Status.State).Should(Equal(v1alpha1.ArtifactBuildStateDiscovering))
+		dbList := v1alpha1.DependencyBuildList{}
+		g.Expect(client.List(ctx, &dbList)).Should(Succeed())
+		g.Expect(dbList.Items).Should(BeEmpty())
+	})
+}
+
+func TestSharedDependencyBuild(t *testing.T) {
+	ctx := context.TODO()
+	const trusted = "trusted"
+
+	setup := func(g *WithT, sharing v1alpha1.ArtifactSharingSettings, failedVerification bool) (runtimeclient.Client, *ReconcileArtifactBuild) {
+		abr := &v1alpha1.ArtifactBuild{
+			ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: metav1.NamespaceDefault},
+			Spec:       v1alpha1.ArtifactBuildSpec{GAV: gav},
+			Status: v1alpha1.ArtifactBuildStatus{
+				State:   v1alpha1.ArtifactBuildStateDiscovering,
+				SCMInfo: v1alpha1.SCMInfo{Tag: "foo", CommitHash: "abc123", SCMURL: "goo", SCMType: "hoo", Path: "ioo"},
+			},
+		}
+		db := &v1alpha1.DependencyBuild{
+			ObjectMeta: metav1.ObjectMeta{Name: hashString("goo" + "foo" + "ioo"), Namespace: trusted},
+			Spec:       v1alpha1.DependencyBuildSpec{ScmInfo: v1alpha1.SCMInfo{Tag: "foo", CommitHash: "abc123", SCMURL: "goo", SCMType: "hoo", Path: "ioo"}, Version: "1.0"},
+			Status: v1alpha1.DependencyBuildStatus{
+				State:              v1alpha1.DependencyBuildStateComplete,
+				DeployedArtifacts:  []string{gav},
+				FailedVerification: failedVerification,
+			},
+		}
+		ra := &v1alpha1.RebuiltArtifact{
+			ObjectMeta: metav1.ObjectMeta{Name: CreateABRName(gav), Namespace: trusted},
+			Spec:       v1alpha1.RebuiltArtifactSpec{GAV: gav, Image: "quay.io/trusted/artifact-deployments:foo", Digest: "sha256:12345"},
+		}
+		client, reconciler := setupClientAndReconciler(abr, db, ra)
+		systemConfig := v1alpha1.SystemConfig{}
+		g.Expect(client.Get(ctx, types.NamespacedName{Name: systemconfig.SystemConfigKey}, &systemConfig)).Should(Succeed())
+		systemConfig.Spec.ArtifactSharing = sharing
+		g.Expect(client.Update(ctx, &systemConfig)).Should(Succeed())
+		return client, reconciler
+	}
+	reconcileABR := func(g *WithT, reconciler *ReconcileArtifactBuild) {
+		_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: metav1.NamespaceDefault, Name: "test"}})
+		g.Expect(err).ShouldNot(HaveOccurred())
+	}
+	localDependencyBuilds := func(g *WithT, client runtimeclient.Client) []v1alpha1.DependencyBuild {
+		dbList := v1alpha1.DependencyBuildList{}
+		g.Expect(client.List(ctx, &dbList, runtimeclient.InNamespace(metav1.NamespaceDefault))).Should(Succeed())
+		return dbList.Items
+	}
+
+	t.Run("Verified build in a trusted namespace is used", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		client, reconciler := setup(g, v1alpha1.ArtifactSharingSettings{TrustedNamespaces: []string{trusted}}, false)
+		reconcileABR(g, reconciler)
+		abr := getABR(client, g)
+		g.Expect(abr.Status.State).Should(Equal(v1alpha1.ArtifactBuildStateComplete))
+		linked := meta.FindStatusCondition(abr.Status.Conditions, v1alpha1.ArtifactBuildConditionDependencyBuildLinked)
+		g.Expect(linked.Reason).Should(Equal(v1alpha1.ArtifactBuildReasonDependencyBuildShared))
+		g.Expect(localDependencyBuilds(g, client)).Should(BeEmpty())
+		ra := v1alpha1.RebuiltArtifact{}
+		g.Expect(client.Get(ctx, types.NamespacedName{Namespace: metav1.NamespaceDefault, Name: CreateABRName(gav)}, &ra)).Should(Succeed())
+		g.Expect(ra.Spec.Image).Should(Equal("quay.io/trusted/artifact-deployments:foo"))
+		g.Expect(ra.Spec.Digest).Should(Equal("sha256:12345"))
+		g.Expect(ra.Annotations[SharedFromAnnotation]).Should(Equal(trusted + "/" + hashString("goo"+"foo"+"ioo")))
+		g.Expect(ra.OwnerReferences).Should(BeEmpty())
+	})
+	t.Run("Sharing is disabled by default", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		client, reconciler := setup(g, v1alpha1.ArtifactSharingSettings{}, false)
+		reconcileABR(g, reconciler)
+		g.Expect(getABR(client, g).Status.State).Should(Equal(v1alpha1.ArtifactBuildStateBuilding))
+		g.Expect(localDependencyBuilds(g, client)).Should(HaveLen(1))
+	})
+	t.Run("Namespace that is not a consumer builds locally", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		client, reconciler := setup(g, v1alpha1.ArtifactSharingSettings{TrustedNamespaces: []string{trusted}, ConsumerNamespaces: []string{"other"}}, false)
+		reconcileABR(g, reconciler)
+		g.Expect(getABR(client, g).Status.State).Should(Equal(v1alpha1.ArtifactBuildStateBuilding))
+		g.Expect(localDependencyBuilds(g, client)).Should(HaveLen(1))
+	})
+	t.Run("Build that failed verification is not shared", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		client, reconciler := setup(g, v1alpha1.ArtifactSharingSettings{TrustedNamespaces: []string{trusted}}, true)
+		reconcileABR(g, reconciler)
+		g.Expect(getABR(client, g).Status.State).Should(Equal(v1alpha1.ArtifactBuildStateBuilding))
+		g.Expect(localDependencyBuilds(g, client)).Should(HaveLen(1))
+	})
+	t.Run("Build of a different commit is not shared", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		client, reconciler := setup(g, v1alpha1.ArtifactSharingSettings{TrustedNamespaces: []string{trusted}}, false)
+		db := v1alpha1.DependencyBuild{}
+		g.Expect(client.Get(ctx, types.NamespacedName{Namespace: trusted, Name: hashString("goo" + "foo" + "ioo")}, &db)).Should(Succeed())
+		db.Spec.ScmInfo.CommitHash = "def456"
+		g.Expect(client.Update(ctx, &db)).Should(Succeed())
+		reconcileABR(g, reconciler)
+		g.Expect(getABR(client, g).Status.State).Should(Equal(v1alpha1.ArtifactBuildStateBuilding))
+		g.Expect(localDependencyBuilds(g, client)).Should(HaveLen(1))
+	})
+	t.Run("Stale shared artifact is updated", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		client, reconciler := setup(g, v1alpha1.ArtifactSharingSettings{TrustedNamespaces: []string{trusted}}, false)
+		g.Expect(client.Create(ctx, &v1alpha1.RebuiltArtifact{
+			ObjectMeta: metav1.ObjectMeta{Name: CreateABRName(gav), Namespace: metav1.NamespaceDefault, Annotations: map[string]string{SharedFromAnnotation: trusted + "/old"}},
+			Spec:       v1alpha1.RebuiltArtifactSpec{GAV: gav, Image: "quay.io/trusted/artifact-deployments:old", Digest: "sha256:old"},
+		})).Should(Succeed())
+		reconcileABR(g, reconciler)
+		g.Expect(getABR(client, g).Status.State).Should(Equal(v1alpha1.ArtifactBuildStateComplete))
+		ra := v1alpha1.RebuiltArtifact{}
+		g.Expect(client.Get(ctx, types.NamespacedName{Namespace: metav1.NamespaceDefault, Name: CreateABRName(gav)}, &ra)).Should(Succeed())
+		g.Expect(ra.Spec.Image).Should(Equal("quay.io/trusted/artifact-deployments:foo"))
+		g.Expect(ra.Spec.Digest).Should(Equal("sha256:12345"))
+		g.Expect(ra.Annotations[SharedFromAnnotation]).Should(Equal(trusted + "/" + hashString("goo"+"foo"+"ioo")))
+	})
+	t.Run("Locally rebuilt artifact is kept", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		client, reconciler := setup(g, v1alpha1.ArtifactSharingSettings{TrustedNamespaces: []string{trusted}}, false)
+		g.Expect(client.Create(ctx, &v1alpha1.RebuiltArtifact{
+			ObjectMeta: metav1.ObjectMeta{Name: CreateABRName(gav), Namespace: metav1.NamespaceDefault},
+			Spec:       v1alpha1.RebuiltArtifactSpec{GAV: gav, Image: "quay.io/default/artifact-deployments:local", Digest: "sha256:local"},
+		})).Should(Succeed())
+		reconcileABR(g, reconciler)
+		g.Expect(getABR(client, g).Status.State).Should(Equal(v1alpha1.ArtifactBuildStateComplete))
+		ra := v1alpha1.RebuiltArtifact{}
+		g.Expect(client.Get(ctx, types.NamespacedName{Namespace: metav1.NamespaceDefault, Name: CreateABRName(gav)}, &ra)).Should(Succeed())
+		g.Expect(ra.Spec.Image).Should(Equal("quay.io/default/artifact-deployments:local"))
+		g.Expect(ra.Annotations).ShouldNot(HaveKey(SharedFromAnnotation))
+	})
 }
 
 func TestStateBuilding(t *testing.T) {
@@ -203,6 +384,10 @@ func TestStateBuilding(t *testing.T) {
 		g.Expect(reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: metav1.NamespaceDefault, Name: "test"}}))
 		abr = getABR(client, g)
 		g.Expect(abr.Status.State).Should(Equal(v1alpha1.ArtifactBuildStateFailed))
+		built := meta.FindStatusCondition(abr.Status.Conditions, v1alpha1.ArtifactBuildConditionBuilt)
+		g.Expect(built).ShouldNot(BeNil())
+		g.Expect(built.Status).Should(Equal(metav1.ConditionFalse))
+		g.Expect(built.Reason).Should(Equal(v1alpha1.ArtifactBuildReasonBuildFailed))
 	})
 	t.Run("Completed build", func(t *testing.T) {
 		g := NewGomegaWithT(t)
@@ -224,6 +409,9 @@ func TestStateBuilding(t *testing.T) {
 		g.Expect(reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: metav1.NamespaceDefault, Name: "test"}}))
 		abr = getABR(client, g)
 		g.Expect(abr.Status.State).Should(Equal(v1alpha1.ArtifactBuildStateComplete))
+		g.Expect(meta.IsStatusConditionTrue(abr.Status.Conditions, v1alpha1.ArtifactBuildConditionBuilt)).Should(BeTrue())
+		g.Expect(meta.IsStatusConditionTrue(abr.Status.Conditions, v1alpha1.ArtifactBuildConditionVerified)).Should(BeTrue())
+		g.Expect(meta.IsStatusConditionFalse(abr.Status.Conditions, v1alpha1.ArtifactBuildConditionContaminated)).Should(BeTrue())
 	})
 	t.Run("Failed build that is reset", func(t *testing.T) {
 		g := NewGomegaWithT(t)
@@ -267,6 +455,7 @@ func TestStateBuilding(t *testing.T) {
 		g.Expect(reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: metav1.NamespaceDefault, Name: otherName}}))
 		abr = getABR(client, g)
 		g.Expect(abr.Status.State).Should(Equal(v1alpha1.ArtifactBuildStateNew))
+		g.Expect(meta.FindStatusCondition(abr.Status.Conditions, v1alpha1.ArtifactBuildConditionBuilt).Reason).Should(Equal(v1alpha1.ArtifactBuildReasonRebuildRequested))
 		g.Expect(abr.Annotations[RebuildAnnotation]).Should(Equal("true")) //first reconcile does not remove the annotation
 		err := client.Get(ctx, types.NamespacedName{Name: db.Name, Namespace: db.Namespace}, &db)
 		g.Expect(errors.IsNotFound(err)).Should(BeTrue())
@@ -297,6 +486,7 @@ func TestStateBuilding(t *testing.T) {
 		g.Expect(reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: metav1.NamespaceDefault, Name: "test"}}))
 		abr = getABR(client, g)
 		g.Expect(abr.Status.State).Should(Equal(v1alpha1.ArtifactBuildStateFailed))
+		g.Expect(meta.IsStatusConditionTrue(abr.Status.Conditions, v1alpha1.ArtifactBuildConditionContaminated)).Should(BeTrue())
 	})
 	t.Run("Missing (deleted) build", func(t *testing.T) {
 		g := NewGomegaWithT(t)
@@ -304,6 +494,7 @@ func TestStateBuilding(t *testing.T) {
 		g.Expect(reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: metav1.NamespaceDefault, Name: "test"}}))
 		abr := getABR(client, g)
 		g.Expect(abr.Status.State).Should(Equal(v1alpha1.ArtifactBuildStateNew))
+		g.Expect(meta.IsStatusConditionFalse(abr.Status.Conditions, v1alpha1.ArtifactBuildConditionDependencyBuildLinked)).Should(BeTrue())
 	})
 }
 
@@ -0,0 +1,135 @@
+package artifactbuild
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	"github.com/redhat-appstudio/jvm-build-service/pkg/apis/jvmbuildservice/v1alpha1"
+	"github.com/redhat-appstudio/jvm-build-service/pkg/reconciler/systemconfig"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// SharedFromAnnotation is set on a RebuiltArtifact that was copied from a trusted namespace, the value is the
+// namespace and name of the DependencyBuild that produced it
+const SharedFromAnnotation = "jvmbuildservice.io/shared-from"
+
+// findSharedDependencyBuild looks in the trusted namespaces for a verified build of the same source that deployed
+// the artifact. The DependencyBuilds are named after the hash of their source, so this is the same lookup as for
+// the namespace of the ArtifactBuild.
+func (r *ReconcileArtifactBuild) findSharedDependencyBuild(ctx context.Context, log logr.Logger, abr *v1alpha1.ArtifactBuild, depId string) (*v1alpha1.DependencyBuild, *v1alpha1.RebuiltArtifact, error) {
+	systemConfig := v1alpha1.SystemConfig{}
+	err := r.client.Get(ctx, types.NamespacedName{Name: systemconfig.SystemConfigKey}, &systemConfig)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil, nil, nil
+		}
+		return nil, nil, err
+	}
+	sharing := systemConfig.Spec.ArtifactSharing
+	if len(sharing.TrustedNamespaces) == 0 || !sharingAllowed(sharing.ConsumerNamespaces, abr.Namespace) {
+		return nil, nil, nil
+	}
+	for _, namespace := range sharing.TrustedNamespaces {
+		if namespace == abr.Namespace {
+			continue
+		}
+		db := v1alpha1.DependencyBuild{}
+		err := r.client.Get(ctx, types.NamespacedName{Namespace: namespace, Name: depId}, &db)
+		if err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+			return nil, nil, err
+		}
+		if !sharedBuildUsable(&db, abr) {
+			continue
+		}
+		ra := v1alpha1.RebuiltArtifact{}
+		err = r.client.Get(ctx, types.NamespacedName{Namespace: namespace, Name: CreateABRName(abr.Spec.GAV)}, &ra)
+		if err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+			return nil, nil, err
+		}
+		log.Info("found a shared DependencyBuild", "namespace", namespace, "dependencybuild", db.Name)
+		return &db, &ra, nil
+	}
+	return nil, nil, nil
+}
+
+func sharingAllowed(consumers []string, namespace string) bool {
+	if len(consumers) == 0 {
+		return true
+	}
+	for _, i := range consumers {
+		if i == namespace {
+			return true
+		}
+	}
+	return false
+}
+
+// sharedBuildUsable only builds that completed, passed verification and are not contaminated are shared. The build
+// must also be of the commit that was discovered, the tag may have been moved since it was built.
+func sharedBuildUsable(db *v1alpha1.DependencyBuild, abr *v1alpha1.ArtifactBuild) bool {
+	if db.Status.State != v1alpha1.DependencyBuildStateComplete || db.Status.FailedVerification || len(db.Status.Contaminants) > 0 {
+		return false
+	}
+	if db.Spec.ScmInfo.CommitHash != abr.Status.SCMInfo.CommitHash {
+		return false
+	}
+	for _, i := range db.Status.DeployedArtifacts {
+		if i == abr.Spec.GAV {
+			return true
+		}
+	}
+	return false
+}
+
+// linkSharedDependencyBuild completes the ArtifactBuild using a build from a trusted namespace. The RebuiltArtifact
+// is copied into the namespace so the cache can find it. It has no owner, so it is kept if the ArtifactBuild is
+// cleaned up.
+func (r *ReconcileArtifactBuild) linkSharedDependencyBuild(ctx context.Context, log logr.Logger, abr *v1alpha1.ArtifactBuild, db *v1alpha1.DependencyBuild, shared *v1alpha1.RebuiltArtifact) (reconcile.Result, error) {
+	ra := v1alpha1.RebuiltArtifact{}
+	ra.Namespace = abr.Namespace
+	ra.Name = shared.Name
+	ra.Annotations = map[string]string{SharedFromAnnotation: db.Namespace + "/" + db.Name}
+	ra.Spec = shared.Spec
+	ra.Spec.JBSConfig = abr.Spec.JBSConfig
+	err := r.client.Create(ctx, &ra)
+	if errors.IsAlreadyExists(err) {
+		spec := ra.Spec
+		if err := r.client.Get(ctx, types.NamespacedName{Namespace: ra.Namespace, Name: ra.Name}, &ra); err != nil {
+			return reconcile.Result{}, err
+		}
+		if ra.Annotations[SharedFromAnnotation] == "" {
+			//an artifact that was already rebuilt in this namespace is kept
+			log.Info("RebuiltArtifact already exists, not replacing it with the shared artifact", "rebuiltartifact", ra.Name)
+			setCondition(abr, v1alpha1.ArtifactBuildConditionDependencyBuildLinked, metav1.ConditionTrue, v1alpha1.ArtifactBuildReasonDependencyBuildShared, "linked to DependencyBuild "+db.Name+" in trusted namespace "+db.Namespace)
+			return r.handleDependencyBuildSuccess(ctx, db, abr)
+		}
+		//an earlier copy may be of a build that has since been replaced, so it is brought up to date
+		ra.Annotations[SharedFromAnnotation] = db.Namespace + "/" + db.Name
+		ra.Spec = spec
+		log.Info("Updating the shared RebuiltArtifact", "rebuiltartifact", ra.Name, "image", ra.Spec.Image)
+		err = r.client.Update(ctx, &ra)
+	}
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	if ra.Status.SBOM != shared.Status.SBOM || ra.Status.Provenance != shared.Status.Provenance {
+		patch := client.MergeFrom(ra.DeepCopy())
+		ra.Status.SBOM = shared.Status.SBOM
+		ra.Status.Provenance = shared.Status.Provenance
+		if err := r.client.Status().Patch(ctx, &ra, patch); err != nil {
+			return reconcile.Result{}, err
+		}
+	}
+	setCondition(abr, v1alpha1.ArtifactBuildConditionDependencyBuildLinked, metav1.ConditionTrue, v1alpha1.ArtifactBuildReasonDependencyBuildShared, "linked to DependencyBuild "+db.Name+" in trusted namespace "+db.Namespace)
+	return r.handleDependencyBuildSuccess(ctx, db, abr)
+}
@@ -0,0 +1,290 @@
+package dependencybuild
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/redhat-appstudio/jvm-build-service/pkg/apis/jvmbuildservice/v1alpha1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// DependencyBuildStateIndex indexes the DependencyBuilds by their state, so admission only lists the running and
+	// queued builds
+	DependencyBuildStateIndex = "status.state"
+
+	// admittedBuildExpiry how long a build admitted by this controller is counted as running if the cache never shows
+	// it again, for example because it was deleted
+	admittedBuildExpiry = 5 * time.Minute
+)
+
+func dependencyBuildState(o client.Object) []string {
+	return []string{o.(*v1alpha1.DependencyBuild).Status.State}
+}
+
+// admittedBuilds the builds this controller admitted, with the resource version they had while they were queued. The
+// cache can still show these as queued after the status update, and they must count as running or back-to-back
+// reconciles would admit more builds than the limits allow.
+type admittedBuilds struct {
+	lock   sync.Mutex
+	builds map[types.NamespacedName]admittedBuild
+}
+
+type admittedBuild struct {
+	queuedVersion string
+	admitted      time.Time
+}
+
+func (a *admittedBuilds) add(db *v1alpha1.DependencyBuild, queuedVersion string) {
+	if a.builds == nil {
+		a.builds = map[types.NamespacedName]admittedBuild{}
+	}
+	a.builds[types.NamespacedName{Namespace: db.Namespace, Name: db.Name}] = admittedBuild{queuedVersion: queuedVersion, admitted: time.Now()}
+}
+
+// forgetExpired drops the builds that were not seen again, such as builds that were deleted or finished before the
+// next admission
+func (a *admittedBuilds) forgetExpired() {
+	for key, build := range a.builds {
+		if time.Since(build.admitted) >= admittedBuildExpiry {
+			delete(a.builds, key)
+		}
+	}
+}
+
+// pending returns true if the cached build is the queued version of a build this controller admitted, and forgets
+// the build once the cache has caught up
+func (a *admittedBuilds) pending(db *v1alpha1.DependencyBuild) bool {
+	key := types.NamespacedName{Namespace: db.Namespace, Name: db.Name}
+	build, ok := a.builds[key]
+	if !ok {
+		return false
+	}
+	if db.ResourceVersion == build.queuedVersion {
+		return true
+	}
+	delete(a.builds, key)
+	return false
+}
+
+// buildAdmission the result of checking if a queued build can submit its pipeline
+type buildAdmission struct {
+	admitted bool
+	position int
+	reason   string
+	message  string
+}
+
+// admitBuild decides if a queued build can start. Builds in a namespace are admitted in the order they were queued,
+// subject to the namespace limit from the JBSConfig, the cluster limit from the SystemConfig and the headroom in the
+// namespace ResourceQuotas. While other namespaces are waiting a namespace can only use its fair share of the
+// cluster limit. The caller must hold the admitted lock until the status of an admitted build has been updated.
+func (r *ReconcileDependencyBuild) admitBuild(ctx context.Context, db *v1alpha1.DependencyBuild, jbsConfig *v1alpha1.JBSConfig, systemConfig *v1alpha1.SystemConfig) (buildAdmission, error) {
+	building := v1alpha1.DependencyBuildList{}
+	if err := r.client.List(ctx, &building, client.MatchingFields{DependencyBuildStateIndex: v1alpha1.DependencyBuildStateBuilding}); err != nil {
+		return buildAdmission{}, err
+	}
+	queued := v1alpha1.DependencyBuildList{}
+	if err := r.client.List(ctx, &queued, client.MatchingFields{DependencyBuildStateIndex: v1alpha1.DependencyBuildStateQueued}); err != nil {
+		return buildAdmission{}, err
+	}
+	r.admitted.forgetExpired()
+	running := map[string]int{}
+	waiting := map[string]int{}
+	totalRunning := 0
+	ahead := []*v1alpha1.DependencyBuild{}
+	for i := range building.Items {
+		other := &building.Items[i]
+		r.admitted.pending(other)
+		if other.Namespace == db.Namespace && other.Name == db.Name {
+			continue
+		}
+		running[other.Namespace]++
+		totalRunning++
+	}
+	for i := range queued.Items {
+		other := &queued.Items[i]
+		if other.Namespace == db.Namespace && other.Name == db.Name {
+			continue
+		}
+		if r.admitted.pending(other) {
+			running[other.Namespace]++
+			totalRunning++
+			continue
+		}
+		waiting[other.Namespace]++
+		if other.Namespace == db.Namespace && queuedBefore(other, db) {
+			ahead = append(ahead, other)
+		}
+	}
+	sort.Slice(ahead, func(i, j int) bool {
+		return queuedBefore(ahead[i], ahead[j])
+	})
+	admission := buildAdmission{position: len(ahead) + 1}
+
+	namespaceLimit := jbsConfig.Spec.BuildSettings.MaxConcurrentBuilds
+	systemNamespaceLimit := systemConfig.Spec.BuildQueue.MaxConcurrentBuildsPerNamespace
+	if systemNamespaceLimit > 0 && (namespaceLimit <= 0 || namespaceLimit > systemNamespaceLimit) {
+		namespaceLimit = systemNamespaceLimit
+	}
+	//the number of builds that can start in this namespace, -1 means no limit
+	slots := -1
+	if namespaceLimit > 0 {
+		slots = namespaceLimit - running[db.Namespace]
+		if slots <= 0 {
+			admission.reason = v1alpha1.DependencyBuildReasonNamespaceConcurrencyLimit
+			admission.message = fmt.Sprintf("%d of %d builds are running in the namespace", running[db.Namespace], namespaceLimit)
+			return admission, nil
+		}
+	}
+
+	clusterLimit := systemConfig.Spec.BuildQueue.MaxConcurrentBuilds
+	if clusterLimit > 0 {
+		if totalRunning >= clusterLimit {
+			admission.reason = v1alpha1.DependencyBuildReasonClusterConcurrencyLimit
+			admission.message = fmt.Sprintf("%d of %d builds are running in the cluster", totalRunning, clusterLimit)
+			return admission, nil
+		}
+		clusterSlots := clusterLimit - totalRunning
+		if otherNamespacesWaiting(waiting, db.Namespace) {
+			namespaces := map[string]bool{db.Namespace: true}
+			for ns, count := range running {
+				if count > 0 {
+					namespaces[ns] = true
+				}
+			}
+			for ns, count := range waiting {
+				if count > 0 {
+					namespaces[ns] = true
+				}
+			}
+			fairShare := clusterLimit / len(namespaces)
+			if fairShare < 1 {
+				fairShare = 1
+			}
+			if running[db.Namespace] >= fairShare {
+				admission.reason = v1alpha1.DependencyBuildReasonFairShareExceeded
+				admission.message = fmt.Sprintf("%d builds are running in the namespace, which is its share of the cluster limit while %d namespaces are waiting", running[db.Namespace], len(namespaces))
+				return admission, nil
+			}
+			if fairShare-running[db.Namespace] < clusterSlots {
+				clusterSlots = fairShare - running[db.Namespace]
+			}
+		}
+		if slots < 0 || clusterSlots < slots {
+			slots = clusterSlots
+		}
+	}
+	if slots >= 0 && admission.position > slots {
+		admission.reason = v1alpha1.DependencyBuildReasonWaitingForEarlierBuilds
+		admission.message = fmt.Sprintf("%d builds that were queued earlier are waiting for %d available slots", len(ahead), slots)
+		return admission, nil
+	}
+
+	for _, other := range ahead {
+		if other.Status.Queue != nil && other.Status.Queue.Reason == v1alpha1.DependencyBuildReasonInsufficientResourceQuota {
+			//don't let smaller builds that were queued later starve a build that is waiting for quota
+			admission.reason = v1alpha1.DependencyBuildReasonWaitingForEarlierBuilds
+			admission.message = fmt.Sprintf("the earlier build %s is waiting for ResourceQuota", other.Name)
+			return admission, nil
+		}
+	}
+	message, err := r.checkResourceQuotas(ctx, db, jbsConfig, systemConfig)
+	if err != nil {
+		return buildAdmission{}, err
+	}
+	if message != "" {
+		admission.reason = v1alpha1.DependencyBuildReasonInsufficientResourceQuota
+		admission.message = message
+		return admission, nil
+	}
+	admission.admitted = true
+	return admission, nil
+}
+
+func otherNamespacesWaiting(waiting map[string]int, namespace string) bool {
+	for ns, count := range waiting {
+		if ns != namespace && count > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// queuedBefore orders queued builds by the time they were queued, and then by name
+func queuedBefore(a *v1alpha1.DependencyBuild, b *v1alpha1.DependencyBuild) bool {
+	if a.Status.Queue == nil || b.Status.Queue == nil {
+		return a.Status.Queue != nil || (b.Status.Queue == nil && a.Name < b.Name)
+	}
+	if !a.Status.Queue.Since.Equal(&b.Status.Queue.Since) {
+		return a.Status.Queue.Since.Before(&b.Status.Queue.Since)
+	}
+	return a.Name < b.Name
+}
+
+// checkResourceQuotas returns a message describing the first quota that does not have room for the build pod, or
+// an empty string if they all do
+func (r *ReconcileDependencyBuild) checkResourceQuotas(ctx context.Context, db *v1alpha1.DependencyBuild, jbsConfig *v1alpha1.JBSConfig, systemConfig *v1alpha1.SystemConfig) (string, error) {
+	quotaList := v1.ResourceQuotaList{}
+	if err := r.client.List(ctx, &quotaList, client.InNamespace(db.Namespace)); err != nil {
+		return "", err
+	}
+	if len(quotaList.Items) == 0 {
+		return "", nil
+	}
+	required, err := buildPodRequirements(db, jbsConfig, systemConfig)
+	if err != nil {
+		return "", err
+	}
+	for _, quota := range quotaList.Items {
+		for name, hard := range quota.Status.Hard {
+			needed, ok := required[name]
+			if !ok {
+				continue
+			}
+			available := hard.DeepCopy()
+			if used, ok := quota.Status.Used[name]; ok {
+				available.Sub(used)
+			}
+			if available.Cmp(needed) < 0 {
+				return fmt.Sprintf("ResourceQuota %s has %s %s available but the build requires %s", quota.Name, available.String(), name, needed.String()), nil
+			}
+		}
+	}
+	return "", nil
+}
+
+// buildPodRequirements the resources used by the build step, which is by far the largest part of the pipeline
+func buildPodRequirements(db *v1alpha1.DependencyBuild, jbsConfig *v1alpha1.JBSConfig, systemConfig *v1alpha1.SystemConfig) (v1.ResourceList, error) {
+	memory, err := resource.ParseQuantity(settingOrDefault(jbsConfig.Spec.BuildSettings.BuildRequestMemory, "1024Mi"))
+	if err != nil {
+		return nil, err
+	}
+	cpu, err := resource.ParseQuantity(settingOrDefault(jbsConfig.Spec.BuildSettings.BuildRequestCPU, "300m"))
+	if err != nil {
+		return nil, err
+	}
+	if db.Status.CurrentBuildRecipe != nil {
+		additionalMemory := db.Status.CurrentBuildRecipe.AdditionalMemory
+		if systemConfig.Spec.MaxAdditionalMemory > 0 && additionalMemory > systemConfig.Spec.MaxAdditionalMemory {
+			additionalMemory = systemConfig.Spec.MaxAdditionalMemory
+		}
+		if additionalMemory > 0 {
+			memory.Add(resource.MustParse(fmt.Sprintf("%dMi", additionalMemory)))
+		}
+	}
+	return v1.ResourceList{
+		v1.ResourcePods:           resource.MustParse("1"),
+		v1.ResourceRequestsMemory: memory,
+		v1.ResourceMemory:         memory,
+		v1.ResourceLimitsMemory:   memory,
+		v1.ResourceRequestsCPU:    cpu,
+		v1.ResourceCPU:            cpu,
+	}, nil
+}
@@ -0,0 +1,77 @@
+package dependencybuild
+
+import (
+	"fmt"
+
+	"github.com/redhat-appstudio/jvm-build-service/pkg/apis/jvmbuildservice/v1alpha1"
+	pipelinev1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// currentDependencyBuildPipelineName the pipelines are numbered by the build attempts, so retrying the same recipe
+// gets a new name. Builds that were started before the attempts were recorded also count the failed recipes that
+// have no attempt, so the numbering carries on from the pipelines that already exist.
+func currentDependencyBuildPipelineName(db *v1alpha1.DependencyBuild) string {
+	if attempt := runningBuildAttempt(db); attempt != nil {
+		return attempt.PipelineRun
+	}
+	return fmt.Sprintf("%s-build-%d", db.Name, len(db.Status.BuildAttempts)+unrecordedBuildAttempts(db))
+}
+
+// unrecordedBuildAttempts the number of failed recipes that were built before the attempts were recorded
+func unrecordedBuildAttempts(db *v1alpha1.DependencyBuild) int {
+	failed := 0
+	for _, attempt := range db.Status.BuildAttempts {
+		if attempt.Outcome == v1alpha1.BuildAttemptOutcomeFailed {
+			failed++
+		}
+	}
+	if failed >= len(db.Status.FailedBuildRecipes) {
+		return 0
+	}
+	return len(db.Status.FailedBuildRecipes) - failed
+}
+
+// runningBuildAttempt returns the last attempt if its pipeline has not completed yet
+func runningBuildAttempt(db *v1alpha1.DependencyBuild) *v1alpha1.BuildAttempt {
+	if len(db.Status.BuildAttempts) == 0 {
+		return nil
+	}
+	attempt := &db.Status.BuildAttempts[len(db.Status.BuildAttempts)-1]
+	if attempt.Outcome != v1alpha1.BuildAttemptOutcomeRunning {
+		return nil
+	}
+	return attempt
+}
+
+func lastBuildAttemptRetried(db *v1alpha1.DependencyBuild) bool {
+	return len(db.Status.BuildAttempts) > 0 && db.Status.BuildAttempts[len(db.Status.BuildAttempts)-1].Outcome == v1alpha1.BuildAttemptOutcomeRetried
+}
+
+// startBuildAttempt records a newly created build pipeline
+func startBuildAttempt(db *v1alpha1.DependencyBuild, pr *pipelinev1beta1.PipelineRun) {
+	now := metav1.Now()
+	db.Status.BuildAttempts = append(db.Status.BuildAttempts, v1alpha1.BuildAttempt{
+		PipelineRun:    pr.Name,
+		PipelineRunUID: pr.UID,
+		Recipe:         db.Status.CurrentBuildRecipe.DeepCopy(),
+		StartTime:      &now,
+		Outcome:        v1alpha1.BuildAttemptOutcomeRunning,
+	})
+}
+
+// completeBuildAttempt returns the attempt for a completed build pipeline, so the outcome can be recorded. If the
+// status update failed after the pipeline was created there is no attempt yet, so one is added.
+func completeBuildAttempt(db *v1alpha1.DependencyBuild, pr *pipelinev1beta1.PipelineRun) *v1alpha1.BuildAttempt {
+	attempt := runningBuildAttempt(db)
+	if attempt == nil || attempt.PipelineRun != pr.Name {
+		db.Status.BuildAttempts = append(db.Status.BuildAttempts, v1alpha1.BuildAttempt{PipelineRun: pr.Name, StartTime: pr.Status.StartTime})
+		attempt = &db.Status.BuildAttempts[len(db.Status.BuildAttempts)-1]
+		if db.Status.CurrentBuildRecipe != nil {
+			attempt.Recipe = db.Status.CurrentBuildRecipe.DeepCopy()
+		}
+	}
+	attempt.PipelineRunUID = pr.UID
+	attempt.CompletionTime = pr.Status.CompletionTime
+	return attempt
+}
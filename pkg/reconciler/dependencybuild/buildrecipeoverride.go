@@ -0,0 +1,157 @@
+package dependencybuild
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/go-logr/logr"
+	"github.com/redhat-appstudio/jvm-build-service/pkg/apis/jvmbuildservice/v1alpha1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// matchingBuildRecipeOverrides returns the BuildRecipeOverrides in the namespace of the build that apply to it
+// they are sorted by name, so if more than one override sets the same field the last one wins
+func (r *ReconcileDependencyBuild) matchingBuildRecipeOverrides(ctx context.Context, log logr.Logger, db *v1alpha1.DependencyBuild) ([]v1alpha1.BuildRecipeOverride, error) {
+	overrideList := v1alpha1.BuildRecipeOverrideList{}
+	if err := r.client.List(ctx, &overrideList, client.InNamespace(db.Namespace)); err != nil {
+		return nil, err
+	}
+	if len(overrideList.Items) == 0 {
+		return nil, nil
+	}
+	var gavs map[string]bool
+	ret := []v1alpha1.BuildRecipeOverride{}
+	for _, override := range overrideList.Items {
+		if len(override.Spec.Match.GAV) > 0 && gavs == nil {
+			var err error
+			gavs, err = r.requestedGavs(ctx, db)
+			if err != nil {
+				return nil, err
+			}
+		}
+		matches, err := buildRecipeOverrideMatches(&override.Spec.Match, db, gavs)
+		if err != nil {
+			//an invalid override should not block the build
+			log.Error(err, fmt.Sprintf("Ignoring invalid BuildRecipeOverride %s", override.Name))
+			r.eventRecorder.Eventf(&override, v1.EventTypeWarning, "InvalidBuildRecipeOverride", "The BuildRecipeOverride %s/%s could not be applied: %s", override.Namespace, override.Name, err.Error())
+			continue
+		}
+		if matches {
+			ret = append(ret, override)
+		}
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].Name < ret[j].Name
+	})
+	return ret, nil
+}
+
+// requestedGavs returns the GAVs of the ArtifactBuilds that own the build
+func (r *ReconcileDependencyBuild) requestedGavs(ctx context.Context, db *v1alpha1.DependencyBuild) (map[string]bool, error) {
+	gavs := map[string]bool{}
+	for _, ownerRef := range db.OwnerReferences {
+		if strings.EqualFold(ownerRef.Kind, "artifactbuild") || strings.EqualFold(ownerRef.Kind, "artifactbuilds") {
+			abr := v1alpha1.ArtifactBuild{}
+			err := r.client.Get(ctx, types.NamespacedName{Namespace: db.Namespace, Name: ownerRef.Name}, &abr)
+			if err != nil {
+				if client.IgnoreNotFound(err) != nil {
+					return nil, err
+				}
+				continue
+			}
+			gavs[abr.Spec.GAV] = true
+		}
+	}
+	return gavs, nil
+}
+
+// buildRecipeOverrideMatches all the fields that are set in the match must match the build, a match with
+// no fields set never matches
+func buildRecipeOverrideMatches(match *v1alpha1.BuildRecipeOverrideMatch, db *v1alpha1.DependencyBuild, gavs map[string]bool) (bool, error) {
+	if len(match.SCMURL) == 0 && len(match.TagPattern) == 0 && len(match.VersionPattern) == 0 && len(match.GAV) == 0 {
+		return false, nil
+	}
+	if len(match.SCMURL) > 0 && normalizeSCMURL(match.SCMURL) != normalizeSCMURL(db.Spec.ScmInfo.SCMURL) {
+		return false, nil
+	}
+	if len(match.TagPattern) > 0 {
+		matches, err := fullMatch(match.TagPattern, db.Spec.ScmInfo.Tag)
+		if err != nil || !matches {
+			return false, err
+		}
+	}
+	if len(match.VersionPattern) > 0 {
+		matches, err := fullMatch(match.VersionPattern, db.Spec.Version)
+		if err != nil || !matches {
+			return false, err
+		}
+	}
+	if len(match.GAV) > 0 && !gavs[match.GAV] {
+		return false, nil
+	}
+	return true, nil
+}
+
+func fullMatch(pattern string, value string) (bool, error) {
+	regex, err := regexp.Compile("^(?:" + pattern + ")$")
+	if err != nil {
+		return false, err
+	}
+	return regex.MatchString(value), nil
+}
+
+func normalizeSCMURL(url string) string {
+	url = strings.TrimSuffix(url, "/")
+	url = strings.TrimSuffix(url, ".git")
+	return strings.ToLower(url)
+}
+
+// applyBuildRecipeOverride merges an override into a recipe, lists from the override are added to the recipe
+// and any other field that is set replaces the value from the recipe database
+func applyBuildRecipeOverride(recipe *v1alpha1.BuildRecipe, override *v1alpha1.BuildRecipeOverrideSpec) {
+	if len(override.PreBuildScript) > 0 {
+		recipe.PreBuildScript = override.PreBuildScript
+	}
+	if len(override.PostBuildScript) > 0 {
+		recipe.PostBuildScript = override.PostBuildScript
+	}
+	if len(override.AdditionalDownloads) > 0 {
+		recipe.AdditionalDownloads = append(append([]v1alpha1.AdditionalDownload{}, recipe.AdditionalDownloads...), override.AdditionalDownloads...)
+	}
+	if override.AdditionalMemory > 0 {
+		recipe.AdditionalMemory = override.AdditionalMemory
+	}
+	if override.PipelineTimeout != nil {
+		recipe.PipelineTimeout = override.PipelineTimeout
+	}
+	if override.StepTimeout != nil {
+		recipe.StepTimeout = override.StepTimeout
+	}
+	if len(override.Repositories) > 0 {
+		//the slice is shared between all the recipes generated from the build info
+		recipe.Repositories = append([]string{}, recipe.Repositories...)
+	}
+	for _, repo := range override.Repositories {
+		found := false
+		for _, existing := range recipe.Repositories {
+			if existing == repo {
+				found = true
+				break
+			}
+		}
+		if !found {
+			recipe.Repositories = append(recipe.Repositories, repo)
+		}
+	}
+	if override.DisableSubmodules != nil {
+		recipe.DisableSubmodules = *override.DisableSubmodules
+	}
+	if len(override.CommandLine) > 0 {
+		recipe.CommandLine = append([]string{}, override.CommandLine...)
+	}
+}
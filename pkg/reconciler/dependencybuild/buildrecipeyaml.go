@@ -92,16 +92,16 @@ func createPipelineSpec(tool string, commitTime int64, jbsConfig *v1alpha12.JBSC
 
 	install := ""
 	for count, i := range recipe.AdditionalDownloads {
-		if i.FileType == "tar" {
+		if i.FileType == v1alpha12.AdditionalDownloadTypeTar {
 			if i.BinaryPath == "" {
 				install = "echo 'Binary path not specified for package " + i.Uri + "'; exit 1"
 			}
 
-		} else if i.FileType == "executable" {
+		} else if i.FileType == v1alpha12.AdditionalDownloadTypeExecutable {
 			if i.FileName == "" {
 				install = "echo 'File name not specified for package " + i.Uri + "'; exit 1"
 			}
-		} else if i.FileType == "rpm" {
+		} else if i.FileType == v1alpha12.AdditionalDownloadTypeRpm {
 			if i.PackageName == "" {
 				install = "echo 'Package name not specified for rpm type'; exit 1"
 			}
@@ -211,10 +211,7 @@ func createPipelineSpec(tool string, commitTime int64, jbsConfig *v1alpha12.JBSC
 	build = strings.ReplaceAll(build, "{{INSTALL_PACKAGE_SCRIPT}}", install)
 	build = strings.ReplaceAll(build, "{{PRE_BUILD_SCRIPT}}", recipe.PreBuildScript)
 	build = strings.ReplaceAll(build, "{{POST_BUILD_SCRIPT}}", recipe.PostBuildScript)
-	cacheUrl := "https://jvm-build-workspace-artifact-cache-tls." + jbsConfig.Namespace + ".svc.cluster.local/v2/cache/rebuild"
-	if jbsConfig.Spec.CacheSettings.DisableTLS {
-		cacheUrl = "http://jvm-build-workspace-artifact-cache." + jbsConfig.Namespace + ".svc.cluster.local/v2/cache/rebuild"
-	}
+	cacheUrl := jbsConfig.CacheURL() + "/v2/cache/rebuild"
 	pullPolicy := v1.PullIfNotPresent
 	if strings.HasPrefix(buildRequestProcessorImage, "quay.io/minikube") {
 		pullPolicy = v1.PullNever
@@ -301,7 +298,7 @@ func createPipelineSpec(tool string, commitTime int64, jbsConfig *v1alpha12.JBSC
 				ImagePullPolicy: pullPolicy,
 				SecurityContext: &v1.SecurityContext{RunAsUser: &zero},
 				Env: []v1.EnvVar{
-					{Name: "REGISTRY_TOKEN", ValueFrom: &v1.EnvVarSource{SecretKeyRef: &v1.SecretKeySelector{LocalObjectReference: v1.LocalObjectReference{Name: v1alpha12.ImageSecretName}, Key: v1alpha12.ImageSecretTokenKey, Optional: &trueBool}}},
+					{Name: "REGISTRY_TOKEN", ValueFrom: &v1.EnvVarSource{SecretKeyRef: &v1.SecretKeySelector{LocalObjectReference: v1.LocalObjectReference{Name: jbsConfig.ImageSecret()}, Key: v1alpha12.ImageSecretTokenKey, Optional: &trueBool}}},
 				},
 				Resources: v1.ResourceRequirements{
 					//TODO: make configurable
@@ -312,6 +309,11 @@ func createPipelineSpec(tool string, commitTime int64, jbsConfig *v1alpha12.JBSC
 			},
 		},
 	}
+	if timeout := stepTimeout(recipe, jbsConfig); timeout != nil {
+		for i := range buildSetup.Steps {
+			buildSetup.Steps[i].Timeout = timeout
+		}
+	}
 
 	ps := &pipelinev1beta1.PipelineSpec{
 		Tasks: []pipelinev1beta1.PipelineTask{
@@ -1,6 +1,8 @@
 package dependencybuild
 
 import (
+	"context"
+
 	"k8s.io/apimachinery/pkg/types"
 
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -18,6 +20,9 @@ import (
 )
 
 func SetupNewReconcilerWithManager(mgr ctrl.Manager) error {
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &v1alpha1.DependencyBuild{}, DependencyBuildStateIndex, dependencyBuildState); err != nil {
+		return err
+	}
 	r := newReconciler(mgr)
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&v1alpha1.DependencyBuild{}, builder.WithPredicates(predicate.Funcs{
@@ -16,9 +16,12 @@ import (
 
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	v12 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/record"
 	"knative.dev/pkg/apis"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -28,9 +31,11 @@ import (
 
 	"github.com/go-logr/logr"
 	"github.com/redhat-appstudio/jvm-build-service/pkg/apis/jvmbuildservice/v1alpha1"
+	"github.com/redhat-appstudio/jvm-build-service/pkg/metrics"
 	"github.com/redhat-appstudio/jvm-build-service/pkg/reconciler/artifactbuild"
 	"github.com/redhat-appstudio/jvm-build-service/pkg/reconciler/systemconfig"
 	"github.com/redhat-appstudio/jvm-build-service/pkg/reconciler/util"
+	"github.com/redhat-appstudio/jvm-build-service/pkg/webhook"
 	pipelinev1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
 )
 
@@ -61,23 +66,37 @@ const (
 	PipelineTypeBuildInfo = "build-info"
 	PipelineTypeBuild     = "build"
 
-	RetryDueToMemoryAnnotation = "jvmbuildservice.io/retry-build-lookup-due-to-memory"
-	MaxRetries                 = 3
-	MemoryIncrement            = 512
+	queuedBuildRequeueInterval = time.Second * 30
 )
 
 type ReconcileDependencyBuild struct {
-	client        client.Client
-	scheme        *runtime.Scheme
-	eventRecorder record.EventRecorder
+	client            client.Client
+	scheme            *runtime.Scheme
+	eventRecorder     record.EventRecorder
+	recipeScorers     []RecipeScorer
+	stepLogReader     StepLogReader
+	logArchiver       LogArchiver
+	referrerPublisher ReferrerPublisher
+	admitted          admittedBuilds
 }
 
 func newReconciler(mgr ctrl.Manager) reconcile.Reconciler {
-	return &ReconcileDependencyBuild{
-		client:        mgr.GetClient(),
-		scheme:        mgr.GetScheme(),
-		eventRecorder: mgr.GetEventRecorderFor("DependencyBuild"),
+	r := &ReconcileDependencyBuild{
+		client:            mgr.GetClient(),
+		scheme:            mgr.GetScheme(),
+		eventRecorder:     mgr.GetEventRecorderFor("DependencyBuild"),
+		recipeScorers:     DefaultRecipeScorers(),
+		logArchiver:       &registryLogArchiver{},
+		referrerPublisher: &registryReferrerPublisher{},
 	}
+	clientset, err := kubernetes.NewForConfig(mgr.GetConfig())
+	if err != nil {
+		//failures can still be classified from the step states
+		ctrl.Log.WithName("dependencybuild").Error(err, "unable to create a client to read build logs")
+	} else {
+		r.stepLogReader = &podLogReader{clientset: clientset}
+	}
+	return r
 }
 
 func (r *ReconcileDependencyBuild) Reconcile(ctx context.Context, request reconcile.Request) (reconcile.Result, error) {
@@ -135,7 +154,9 @@ func (r *ReconcileDependencyBuild) Reconcile(ctx context.Context, request reconc
 		case "", v1alpha1.DependencyBuildStateNew:
 			return r.handleStateNew(ctx, log, &db)
 		case v1alpha1.DependencyBuildStateSubmitBuild:
-			return r.handleStateSubmitBuild(ctx, &db)
+			return r.handleStateSubmitBuild(ctx, log, &db)
+		case v1alpha1.DependencyBuildStateQueued:
+			return r.handleStateQueued(ctx, log, &db)
 		case v1alpha1.DependencyBuildStateFailed:
 			return reconcile.Result{}, nil
 		case v1alpha1.DependencyBuildStateBuilding:
@@ -147,24 +168,23 @@ func (r *ReconcileDependencyBuild) Reconcile(ctx context.Context, request reconc
 		}
 
 	case trerr == nil:
-
-		if pr.DeletionTimestamp != nil {
-			//always remove the finalizer if it is deleted
-			//but continue with the method
-			//if the PR is deleted while it is running then we want to allow that
-			result, err2 := RemovePipelineFinalizer(ctx, &pr, r.client)
-			if err2 != nil {
-				return result, err2
-			}
-		}
 		log = log.WithValues("kind", "PipelineRun")
+		var result reconcile.Result
+		var err error
 		pipelineType := pr.Labels[PipelineTypeLabel]
 		switch pipelineType {
 		case PipelineTypeBuildInfo:
-			return r.handleStateAnalyzeBuild(ctx, log, &pr)
+			result, err = r.handleStateAnalyzeBuild(ctx, log, &pr)
 		case PipelineTypeBuild:
-			return r.handleBuildPipelineRunReceived(ctx, log, &pr)
+			result, err = r.handleBuildPipelineRunReceived(ctx, log, &pr)
 		}
+		if err != nil || pr.DeletionTimestamp == nil {
+			return result, err
+		}
+		//always remove the finalizer if it is deleted, but only once the pipeline has been handled, as the logs
+		//are archived from its pods, which go once the finalizer is removed
+		//if the PR is deleted while it is running then we want to allow that
+		return RemovePipelineFinalizer(ctx, &pr, r.client)
 	}
 
 	return reconcile.Result{}, nil
@@ -177,8 +197,11 @@ func hashToString(unique string) string {
 }
 
 func (r *ReconcileDependencyBuild) handleStateNew(ctx context.Context, log logr.Logger, db *v1alpha1.DependencyBuild) (reconcile.Result, error) {
+	if delay := retryDelay(db); delay > 0 {
+		return reconcile.Result{RequeueAfter: delay}, nil
+	}
 	jbsConfig := &v1alpha1.JBSConfig{}
-	err := r.client.Get(ctx, types.NamespacedName{Namespace: db.Namespace, Name: v1alpha1.JBSConfigName}, jbsConfig)
+	err := r.client.Get(ctx, types.NamespacedName{Namespace: db.Namespace, Name: v1alpha1.JBSConfigNameOrDefault(db.Spec.JBSConfig)}, jbsConfig)
 	if err != nil && !errors.IsNotFound(err) {
 		return reconcile.Result{}, err
 	}
@@ -195,13 +218,13 @@ func (r *ReconcileDependencyBuild) handleStateNew(ctx context.Context, log logr.
 		//no need to retry it would just result in an infinite loop
 		return reconcile.Result{}, nil
 	}
-	additionalMemory := 0
-	if db.Annotations != nil && db.Annotations[RetryDueToMemoryAnnotation] == "true" {
-		//TODO: hard coded for now
-		//should be enough for the build lookup task
-		additionalMemory = 1024
+	analyzeReason := v1alpha1.DependencyBuildReasonAnalyzing
+	analyzeMessage := "looking up build information"
+	if db.Status.BuildInfoRetries > 0 {
+		analyzeReason = v1alpha1.DependencyBuildReasonOOMRetry
+		analyzeMessage = fmt.Sprintf("retrying build information lookup with %dMi additional memory", db.Status.BuildInfoAdditionalMemory)
 	}
-	pr.Spec.PipelineSpec, err = r.createLookupBuildInfoPipeline(ctx, log, &db.Spec, jbsConfig, additionalMemory)
+	pr.Spec.PipelineSpec, err = r.createLookupBuildInfoPipeline(ctx, log, &db.Spec, jbsConfig, db.Status.BuildInfoAdditionalMemory)
 	if err != nil {
 		return reconcile.Result{}, err
 	}
@@ -213,10 +236,16 @@ func (r *ReconcileDependencyBuild) handleStateNew(ctx context.Context, log logr.
 	pr.Namespace = db.Namespace
 	pr.GenerateName = db.Name + "-build-discovery-"
 	pr.Labels = map[string]string{artifactbuild.PipelineRunLabel: "", artifactbuild.DependencyBuildIdLabel: db.Name, PipelineTypeLabel: PipelineTypeBuildInfo}
+	if db.Spec.JBSConfig != "" {
+		pr.Labels[artifactbuild.JBSConfigLabel] = db.Spec.JBSConfig
+	}
 	if err := controllerutil.SetOwnerReference(db, &pr, r.scheme); err != nil {
 		return reconcile.Result{}, err
 	}
 	db.Status.State = v1alpha1.DependencyBuildStateAnalyzeBuild
+	db.Status.RetryAfter = nil
+	setCondition(db, v1alpha1.DependencyBuildConditionBuildInfoAnalyzed, v12.ConditionUnknown, analyzeReason, analyzeMessage)
+	setCondition(db, v1alpha1.DependencyBuildConditionSucceeded, v12.ConditionUnknown, v1alpha1.DependencyBuildReasonAnalyzing, analyzeMessage)
 	if err := r.client.Status().Update(ctx, db); err != nil {
 		return reconcile.Result{}, err
 	}
@@ -269,6 +298,21 @@ func (r *ReconcileDependencyBuild) handleStateAnalyzeBuild(ctx context.Context,
 	if db.Status.State != v1alpha1.DependencyBuildStateAnalyzeBuild {
 		return RemovePipelineFinalizer(ctx, pr, r.client)
 	}
+	if archive := r.archivePipelineLogs(ctx, log, &db, pr); archive != "" {
+		db.Status.DiscoveryLogArchives = append(db.Status.DiscoveryLogArchives, archive)
+	}
+	//the metrics are recorded once the status update succeeds, so a conflict does not record them twice
+	var pendingMetrics []func()
+	recordMetrics := func() {
+		for _, record := range pendingMetrics {
+			record()
+		}
+		pendingMetrics = nil
+	}
+	if pr.Status.StartTime != nil {
+		duration := pr.Status.CompletionTime.Sub(pr.Status.StartTime.Time)
+		pendingMetrics = append(pendingMetrics, func() { metrics.ObserveBuildInfoDuration(db.Namespace, duration) })
+	}
 
 	var buildInfo string
 	var message string
@@ -284,19 +328,48 @@ func (r *ReconcileDependencyBuild) handleStateAnalyzeBuild(ctx context.Context,
 	success := pr.Status.GetCondition(apis.ConditionSucceeded).IsTrue()
 	if !success || len(buildInfo) == 0 {
 
-		if (db.Annotations == nil || db.Annotations[RetryDueToMemoryAnnotation] != "true") && failedDueToMemory(pr) {
-			err := r.client.Delete(ctx, pr)
+		policy, err := r.loadRetryPolicy(ctx, &db)
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+		additionalMemory := policy.nextAdditionalMemory(db.Status.BuildInfoAdditionalMemory)
+		if failedDueToMemory(pr) && additionalMemory > 0 && db.Status.BuildInfoRetries < policy.maxRetries(v1alpha1.BuildFailureClassOOM) {
+			//remove the finalizer first, so the deleted pipeline can't be mistaken for the failure of the retry
+			_, err := RemovePipelineFinalizer(ctx, pr, r.client)
 			if err != nil {
 				return reconcile.Result{}, err
 			}
-			if db.Annotations == nil {
-				db.Annotations = map[string]string{}
+			err = r.client.Delete(ctx, pr)
+			if err != nil {
+				return reconcile.Result{}, err
 			}
-			db.Annotations[RetryDueToMemoryAnnotation] = "true"
-			return r.handleStateNew(ctx, log, &db)
+			delay := policy.backoff(db.Status.BuildInfoRetries)
+			db.Status.BuildInfoRetries++
+			db.Status.BuildInfoAdditionalMemory = additionalMemory
+			scheduleRetry(&db, delay)
+			pendingMetrics = append(pendingMetrics, func() { metrics.OOMRetried(db.Namespace, nil) })
+			if delay <= 0 {
+				result, err := r.handleStateNew(ctx, log, &db)
+				if err != nil {
+					return reconcile.Result{}, err
+				}
+				recordMetrics()
+				return result, nil
+			}
+			msg := fmt.Sprintf("build information lookup ran out of memory, retrying in %s with %dMi additional memory", delay, additionalMemory)
+			log.Info(msg)
+			db.Status.State = v1alpha1.DependencyBuildStateNew
+			setCondition(&db, v1alpha1.DependencyBuildConditionBuildInfoAnalyzed, v12.ConditionUnknown, v1alpha1.DependencyBuildReasonOOMRetry, msg)
+			if err := r.client.Status().Update(ctx, &db); err != nil {
+				return reconcile.Result{}, err
+			}
+			recordMetrics()
+			return reconcile.Result{RequeueAfter: delay}, nil
 		} else {
 			db.Status.State = v1alpha1.DependencyBuildStateFailed
 			db.Status.Message = message
+			setCondition(&db, v1alpha1.DependencyBuildConditionBuildInfoAnalyzed, v12.ConditionFalse, v1alpha1.DependencyBuildReasonBuildInfoLookupFailed, message)
+			setCondition(&db, v1alpha1.DependencyBuildConditionSucceeded, v12.ConditionFalse, v1alpha1.DependencyBuildReasonBuildInfoLookupFailed, message)
 		}
 
 	} else {
@@ -306,6 +379,19 @@ func (r *ReconcileDependencyBuild) handleStateAnalyzeBuild(ctx context.Context,
 			r.eventRecorder.Eventf(&db, v1.EventTypeWarning, "InvalidJson", "Failed to unmarshal build info for AB %s/%s JSON: %s", db.Namespace, db.Name, buildInfo)
 			return reconcile.Result{}, err
 		}
+		//the recipes are written to the status, which the webhooks do not validate
+		if errs := webhook.ValidateAdditionalDownloads(field.NewPath("additionalDownloads"), unmarshalled.AdditionalDownloads); len(errs) > 0 {
+			message := "invalid build info: " + errs.ToAggregate().Error()
+			log.Error(nil, message)
+			db.Status.State = v1alpha1.DependencyBuildStateFailed
+			setCondition(&db, v1alpha1.DependencyBuildConditionBuildInfoAnalyzed, v12.ConditionFalse, v1alpha1.DependencyBuildReasonInvalidBuildInfo, message)
+			setCondition(&db, v1alpha1.DependencyBuildConditionSucceeded, v12.ConditionFalse, v1alpha1.DependencyBuildReasonInvalidBuildInfo, message)
+			if err := r.client.Status().Update(ctx, &db); err != nil {
+				return reconcile.Result{}, err
+			}
+			recordMetrics()
+			return RemovePipelineFinalizer(ctx, pr, r.client)
+		}
 		//read our builder images from the config
 		var allBuilderImages []BuilderImage
 		var selectedImages []BuilderImage
@@ -318,6 +404,21 @@ func (r *ReconcileDependencyBuild) handleStateAnalyzeBuild(ctx context.Context,
 		buildRecipes := []*v1alpha1.BuildRecipe{}
 		java := unmarshalled.Tools["jdk"]
 		db.Status.CommitTime = unmarshalled.CommitTime
+		recipeTimeout := parseRecipeTimeout(log, "timeout", unmarshalled.Timeout)
+		recipeStepTimeout := parseRecipeTimeout(log, "step timeout", unmarshalled.StepTimeout)
+
+		overrides, err := r.matchingBuildRecipeOverrides(ctx, log, &db)
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+		javaOverride := ""
+		db.Status.AppliedBuildRecipeOverrides = nil
+		for _, override := range overrides {
+			if override.Spec.JavaVersion != "" {
+				javaOverride = override.Spec.JavaVersion
+			}
+			db.Status.AppliedBuildRecipeOverrides = append(db.Status.AppliedBuildRecipeOverrides, override.Name)
+		}
 
 		for _, image := range allBuilderImages {
 			//we only have one JDK version in the builder at the moment
@@ -325,6 +426,15 @@ func (r *ReconcileDependencyBuild) handleStateAnalyzeBuild(ctx context.Context,
 			//we only want to use builder images that have java versions that the analyser
 			//detected might be appropriate
 			imageJava := image.Tools["jdk"][0]
+			if javaOverride != "" {
+				//an override replaces the detected versions
+				if imageJava == javaOverride {
+					selectedImages = append(selectedImages, image)
+				} else {
+					log.Info(fmt.Sprintf("Not building with %s because a BuildRecipeOverride requires java version %s (image version %s)", image.Image, javaOverride, imageJava))
+				}
+				continue
+			}
 			if java.Min != "" {
 				versionResult, err := compareVersions(imageJava, java.Min)
 				if err != nil {
@@ -353,7 +463,13 @@ func (r *ReconcileDependencyBuild) handleStateAnalyzeBuild(ctx context.Context,
 		if len(unmarshalled.Invocations) == 0 {
 			log.Error(nil, "Unable to determine build tool", "info", unmarshalled)
 			db.Status.State = v1alpha1.DependencyBuildStateFailed
-			return reconcile.Result{}, r.client.Status().Update(ctx, &db)
+			setCondition(&db, v1alpha1.DependencyBuildConditionBuildInfoAnalyzed, v12.ConditionFalse, v1alpha1.DependencyBuildReasonBuildToolNotDetected, "unable to determine build tool")
+			setCondition(&db, v1alpha1.DependencyBuildConditionSucceeded, v12.ConditionFalse, v1alpha1.DependencyBuildReasonBuildToolNotDetected, "unable to determine build tool")
+			if err := r.client.Status().Update(ctx, &db); err != nil {
+				return reconcile.Result{}, err
+			}
+			recordMetrics()
+			return reconcile.Result{}, nil
 		}
 		for _, image := range selectedImages {
 			imageJava := image.Tools["jdk"][0]
@@ -364,9 +480,22 @@ func (r *ReconcileDependencyBuild) handleStateAnalyzeBuild(ctx context.Context,
 				command = command[1:]
 				var toolVersions []string
 				if tool == "maven" {
-					//TODO: maven version selection
-					//for now we just fake it
-					toolVersions = []string{"3.8.1"}
+					//maven has a version range, from the wrapper or the prerequisites/enforcer requirements
+					//we need to map it to what is in the image
+					requested := unmarshalled.Tools["maven"]
+					toolVersions, err = mavenVersionsInRange(image.Tools["maven"], requested)
+					if err != nil {
+						log.Error(err, fmt.Sprintf("Ignoring the requested maven version range, using all versions in %s", image.Image))
+						toolVersions = image.Tools["maven"]
+					} else if len(toolVersions) == 0 {
+						//we would rather attempt the build with a different maven than not attempt it at all
+						if requested.Range != "" {
+							log.Info(fmt.Sprintf("No maven version in %s satisfies the requested range %s, using all versions", image.Image, requested.Range))
+						} else {
+							log.Info(fmt.Sprintf("No maven version in %s satisfies the requested range %s-%s, using all versions", image.Image, requested.Min, requested.Max))
+						}
+						toolVersions = image.Tools["maven"]
+					}
 				} else if tool == "gradle" {
 					//gradle has an explicit tool version, but we need to map it to what is in the image
 					gradleVersionsInImage := image.Tools["gradle"]
@@ -395,24 +524,43 @@ func (r *ReconcileDependencyBuild) handleStateAnalyzeBuild(ctx context.Context,
 				} else {
 					log.Error(nil, "Unknown tool ", "tool", tool)
 					db.Status.State = v1alpha1.DependencyBuildStateFailed
-					return reconcile.Result{}, r.client.Status().Update(ctx, &db)
+					setCondition(&db, v1alpha1.DependencyBuildConditionBuildInfoAnalyzed, v12.ConditionFalse, v1alpha1.DependencyBuildReasonUnknownBuildTool, "unknown build tool "+tool)
+					setCondition(&db, v1alpha1.DependencyBuildConditionSucceeded, v12.ConditionFalse, v1alpha1.DependencyBuildReasonUnknownBuildTool, "unknown build tool "+tool)
+					if err := r.client.Status().Update(ctx, &db); err != nil {
+						return reconcile.Result{}, err
+					}
+					recordMetrics()
+					return reconcile.Result{}, nil
 				}
 				_, hasTool := image.Tools[tool]
 				if hasTool {
 					for _, tv := range toolVersions {
-						buildRecipes = append(buildRecipes, &v1alpha1.BuildRecipe{Image: image.Image, CommandLine: command, EnforceVersion: unmarshalled.EnforceVersion, ToolVersion: tv, JavaVersion: imageJava, Tool: tool, PreBuildScript: unmarshalled.PreBuildScript, PostBuildScript: unmarshalled.PostBuildScript, AdditionalDownloads: unmarshalled.AdditionalDownloads, DisableSubmodules: unmarshalled.DisableSubmodules, AdditionalMemory: unmarshalled.AdditionalMemory, Repositories: unmarshalled.Repositories})
+						buildRecipes = append(buildRecipes, &v1alpha1.BuildRecipe{Image: image.Image, CommandLine: command, EnforceVersion: unmarshalled.EnforceVersion, ToolVersion: tv, JavaVersion: imageJava, Tool: tool, PreBuildScript: unmarshalled.PreBuildScript, PostBuildScript: unmarshalled.PostBuildScript, AdditionalDownloads: unmarshalled.AdditionalDownloads, DisableSubmodules: unmarshalled.DisableSubmodules, AdditionalMemory: unmarshalled.AdditionalMemory, Repositories: unmarshalled.Repositories, PipelineTimeout: recipeTimeout, StepTimeout: recipeStepTimeout})
 					}
 				}
 			}
 		}
 
+		for _, override := range overrides {
+			for _, recipe := range buildRecipes {
+				applyBuildRecipeOverride(recipe, &override.Spec)
+			}
+			r.eventRecorder.Eventf(&db, v1.EventTypeNormal, "BuildRecipeOverrideApplied", "The BuildRecipeOverride %s was applied to the DependencyBuild %s/%s", override.Name, db.Namespace, db.Name)
+		}
+		ranking, err := r.recipeRankingContext(ctx, &db, &unmarshalled)
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+		buildRecipes = rankBuildRecipes(buildRecipes, ranking, r.recipeScorers)
 		db.Status.PotentialBuildRecipes = buildRecipes
 		db.Status.State = v1alpha1.DependencyBuildStateSubmitBuild
+		setCondition(&db, v1alpha1.DependencyBuildConditionBuildInfoAnalyzed, v12.ConditionTrue, v1alpha1.DependencyBuildReasonBuildInfoFound, fmt.Sprintf("found %d potential build recipes", len(buildRecipes)))
 	}
 	err = r.client.Status().Update(ctx, &db)
 	if err != nil {
 		return reconcile.Result{}, err
 	}
+	recordMetrics()
 
 	return RemovePipelineFinalizer(ctx, pr, r.client)
 }
@@ -429,12 +577,16 @@ type marshalledBuildInfo struct {
 	DisableSubmodules   bool
 	AdditionalMemory    int
 	Repositories        []string
+	Timeout             string
+	StepTimeout         string
 }
 
 type toolInfo struct {
 	Min       string
 	Max       string
 	Preferred string
+	// Range the version specification the min and max came from, if it was a maven version range
+	Range string
 }
 
 // compares versions, returns 0 if versions
@@ -475,6 +627,137 @@ func sameMajorVersion(v1 string, v2 string) bool {
 	return v2p[0] == v1p[0]
 }
 
+// compareMavenVersions compares versions like maven does, every segment counts and missing segments are zero, so
+// 3.6 is less than 3.6.3. Returns 0 if the versions are the same, -1 if v1 < v2 and 1 if v2 < v1
+func compareMavenVersions(v1 string, v2 string) (int, error) {
+	v1p := strings.Split(v1, ".")
+	v2p := strings.Split(v2, ".")
+	for i := 0; i < len(v1p) || i < len(v2p); i++ {
+		var v1segment, v2segment int64
+		var err error
+		if i < len(v1p) {
+			if v1segment, err = strconv.ParseInt(v1p[i], 10, 64); err != nil {
+				return 0, err
+			}
+		}
+		if i < len(v2p) {
+			if v2segment, err = strconv.ParseInt(v2p[i], 10, 64); err != nil {
+				return 0, err
+			}
+		}
+		if v1segment < v2segment {
+			return -1, nil
+		}
+		if v1segment > v2segment {
+			return 1, nil
+		}
+	}
+	return 0, nil
+}
+
+// mavenVersionRange is one of the ranges of a maven version specification such as [3.0,3.6),(3.7,)
+type mavenVersionRange struct {
+	min          string
+	max          string
+	minExclusive bool
+	maxExclusive bool
+}
+
+// contains returns true if the version is within the range, versions that can't be compared are not
+func (m mavenVersionRange) contains(version string) bool {
+	if m.min != "" {
+		result, err := compareMavenVersions(version, m.min)
+		if err != nil || result < 0 || (result == 0 && m.minExclusive) {
+			return false
+		}
+	}
+	if m.max != "" {
+		result, err := compareMavenVersions(version, m.max)
+		if err != nil || result > 0 || (result == 0 && m.maxExclusive) {
+			return false
+		}
+	}
+	return true
+}
+
+// parseMavenVersionRanges parses a maven version specification, a plain version is a minimum
+func parseMavenVersionRanges(spec string) ([]mavenVersionRange, error) {
+	spec = strings.TrimSpace(spec)
+	if !strings.HasPrefix(spec, "[") && !strings.HasPrefix(spec, "(") {
+		return []mavenVersionRange{{min: spec}}, nil
+	}
+	ret := []mavenVersionRange{}
+	for remaining := spec; remaining != ""; {
+		end := strings.IndexAny(remaining, "])")
+		if end < 0 || (remaining[0] != '[' && remaining[0] != '(') {
+			return nil, fmt.Errorf("invalid maven version range %s", spec)
+		}
+		bounds := strings.Split(remaining[1:end], ",")
+		r := mavenVersionRange{min: strings.TrimSpace(bounds[0]), minExclusive: remaining[0] == '(', maxExclusive: remaining[end] == ')'}
+		switch len(bounds) {
+		case 1:
+			//a single version must be an exact match
+			if r.minExclusive || r.maxExclusive || r.min == "" {
+				return nil, fmt.Errorf("invalid maven version range %s", spec)
+			}
+			r.max = r.min
+		case 2:
+			r.max = strings.TrimSpace(bounds[1])
+		default:
+			return nil, fmt.Errorf("invalid maven version range %s", spec)
+		}
+		ret = append(ret, r)
+		remaining = strings.TrimSpace(remaining[end+1:])
+		if strings.HasPrefix(remaining, ",") {
+			remaining = strings.TrimSpace(remaining[1:])
+			if remaining == "" {
+				return nil, fmt.Errorf("invalid maven version range %s", spec)
+			}
+		} else if remaining != "" {
+			return nil, fmt.Errorf("invalid maven version range %s", spec)
+		}
+	}
+	return ret, nil
+}
+
+// mavenVersionsInRange returns the maven versions in the image that are within the requested range. If the range
+// specification is known it is used, so the exclusive bounds and gaps between ranges are kept, otherwise the min and
+// max are inclusive. Versions that can't be compared are ignored.
+func mavenVersionsInRange(versionsInImage []string, requested toolInfo) ([]string, error) {
+	ret := []string{}
+	if requested.Range != "" {
+		ranges, err := parseMavenVersionRanges(requested.Range)
+		if err != nil {
+			return nil, err
+		}
+		for _, version := range versionsInImage {
+			for _, r := range ranges {
+				if r.contains(version) {
+					ret = append(ret, version)
+					break
+				}
+			}
+		}
+		return ret, nil
+	}
+	for _, version := range versionsInImage {
+		if requested.Min != "" {
+			result, err := compareVersions(version, requested.Min)
+			if err != nil || result < 0 {
+				continue
+			}
+		}
+		if requested.Max != "" {
+			result, err := compareVersions(version, requested.Max)
+			if err != nil || result > 0 {
+				continue
+			}
+		}
+		ret = append(ret, version)
+	}
+	return ret, nil
+}
+
 func (r *ReconcileDependencyBuild) processBuilderImages(ctx context.Context, log logr.Logger) ([]BuilderImage, error) {
 	systemConfig := v1alpha1.SystemConfig{}
 	getCtx := ctx
@@ -510,27 +793,84 @@ func (r *ReconcileDependencyBuild) processBuilderImageTags(tags string) map[stri
 	return tools
 }
 
-func (r *ReconcileDependencyBuild) handleStateSubmitBuild(ctx context.Context, db *v1alpha1.DependencyBuild) (reconcile.Result, error) {
+func (r *ReconcileDependencyBuild) handleStateSubmitBuild(ctx context.Context, log logr.Logger, db *v1alpha1.DependencyBuild) (reconcile.Result, error) {
+	if delay := retryDelay(db); delay > 0 {
+		//the failed pipeline is being retried after a backoff
+		return reconcile.Result{RequeueAfter: delay}, nil
+	}
+	db.Status.RetryAfter = nil
 	//the current recipe has been built, we need to pick a new one
 	//pick the first recipe in the potential list
 	//new build, kick off a pipeline run to run the build
 	//first we update the recipes, but add a flag that this is not submitted yet
-	if db.Status.CurrentBuildRecipe != nil {
+	//a recipe that is being retried has not failed yet
+	if db.Status.CurrentBuildRecipe != nil && !lastBuildAttemptRetried(db) {
 		db.Status.FailedBuildRecipes = append(db.Status.FailedBuildRecipes, db.Status.CurrentBuildRecipe)
 	}
 	//no more attempts
 	if len(db.Status.PotentialBuildRecipes) == 0 {
 		db.Status.State = v1alpha1.DependencyBuildStateFailed
+		msg := fmt.Sprintf("all %d build recipes failed", len(db.Status.FailedBuildRecipes))
+		setCondition(db, v1alpha1.DependencyBuildConditionRecipeSelected, v12.ConditionFalse, v1alpha1.DependencyBuildReasonRecipesExhausted, msg)
+		setCondition(db, v1alpha1.DependencyBuildConditionSucceeded, v12.ConditionFalse, v1alpha1.DependencyBuildReasonRecipesExhausted, msg)
 		r.eventRecorder.Eventf(db, v1.EventTypeWarning, "BuildFailed", "The DependencyBuild %s/%s moved to failed, all recipes exhausted", db.Namespace, db.Name)
 		return reconcile.Result{}, r.client.Status().Update(ctx, db)
 	}
 	db.Status.CurrentBuildRecipe = db.Status.PotentialBuildRecipes[0]
 	//and remove if from the potential list
 	db.Status.PotentialBuildRecipes = db.Status.PotentialBuildRecipes[1:]
-	db.Status.State = v1alpha1.DependencyBuildStateBuilding
-	//update the recipes
-	return reconcile.Result{}, r.client.Status().Update(ctx, db)
+	setCondition(db, v1alpha1.DependencyBuildConditionRecipeSelected, v12.ConditionTrue, v1alpha1.DependencyBuildReasonRecipeSelected, fmt.Sprintf("building with %s %s and JDK %s using image %s", db.Status.CurrentBuildRecipe.Tool, db.Status.CurrentBuildRecipe.ToolVersion, db.Status.CurrentBuildRecipe.JavaVersion, db.Status.CurrentBuildRecipe.Image))
+	//the build has to be admitted before the pipeline is created
+	db.Status.State = v1alpha1.DependencyBuildStateQueued
+	db.Status.Queue = &v1alpha1.BuildQueueStatus{Position: 1, Since: v12.Now()}
+	return r.handleStateQueued(ctx, log, db)
+}
 
+func (r *ReconcileDependencyBuild) handleStateQueued(ctx context.Context, log logr.Logger, db *v1alpha1.DependencyBuild) (reconcile.Result, error) {
+	jbsConfig := &v1alpha1.JBSConfig{}
+	err := r.client.Get(ctx, types.NamespacedName{Namespace: db.Namespace, Name: v1alpha1.JBSConfigNameOrDefault(db.Spec.JBSConfig)}, jbsConfig)
+	if err != nil && !errors.IsNotFound(err) {
+		return reconcile.Result{}, err
+	}
+	//without a SystemConfig there are no cluster limits, only the JBSConfig limits apply
+	systemConfig := v1alpha1.SystemConfig{}
+	err = r.client.Get(ctx, types.NamespacedName{Name: systemconfig.SystemConfigKey}, &systemConfig)
+	if err != nil && !errors.IsNotFound(err) {
+		return reconcile.Result{}, err
+	}
+	if db.Status.Queue == nil {
+		db.Status.Queue = &v1alpha1.BuildQueueStatus{Since: v12.Now()}
+	}
+	r.admitted.lock.Lock()
+	defer r.admitted.lock.Unlock()
+	admission, err := r.admitBuild(ctx, db, jbsConfig, &systemConfig)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	if admission.admitted {
+		queuedVersion := db.ResourceVersion
+		db.Status.Queue = nil
+		db.Status.State = v1alpha1.DependencyBuildStateBuilding
+		setCondition(db, v1alpha1.DependencyBuildConditionPipelineSubmitted, v12.ConditionFalse, v1alpha1.DependencyBuildReasonPipelineRunPending, "")
+		if err := r.client.Status().Update(ctx, db); err != nil {
+			return reconcile.Result{}, err
+		}
+		r.admitted.add(db, queuedVersion)
+		return reconcile.Result{}, nil
+	}
+	//there is no event when capacity frees up in another namespace, so we poll
+	result := reconcile.Result{RequeueAfter: queuedBuildRequeueInterval}
+	queue := db.Status.Queue
+	if queue.Position == admission.position && queue.Reason == admission.reason && queue.Message == admission.message {
+		//nothing has changed, the reason is always set when a build is not admitted
+		return result, nil
+	}
+	log.Info("build is queued", "position", admission.position, "reason", admission.reason, "message", admission.message)
+	queue.Position = admission.position
+	queue.Reason = admission.reason
+	queue.Message = admission.message
+	setCondition(db, v1alpha1.DependencyBuildConditionPipelineSubmitted, v12.ConditionFalse, admission.reason, admission.message)
+	return result, r.client.Status().Update(ctx, db)
 }
 
 func (r *ReconcileDependencyBuild) handleStateBuilding(ctx context.Context, log logr.Logger, db *v1alpha1.DependencyBuild) (reconcile.Result, error) {
@@ -547,9 +887,12 @@ func (r *ReconcileDependencyBuild) handleStateBuilding(ctx context.Context, log
 	// we move the db out of building
 	pr.Name = currentDependencyBuildPipelineName(db)
 	pr.Labels = map[string]string{artifactbuild.DependencyBuildIdLabel: db.Labels[artifactbuild.DependencyBuildIdLabel], artifactbuild.PipelineRunLabel: "", PipelineTypeLabel: PipelineTypeBuild}
+	if db.Spec.JBSConfig != "" {
+		pr.Labels[artifactbuild.JBSConfigLabel] = db.Spec.JBSConfig
+	}
 
 	jbsConfig := &v1alpha1.JBSConfig{}
-	err = r.client.Get(ctx, types.NamespacedName{Namespace: db.Namespace, Name: v1alpha1.JBSConfigName}, jbsConfig)
+	err = r.client.Get(ctx, types.NamespacedName{Namespace: db.Namespace, Name: v1alpha1.JBSConfigNameOrDefault(db.Spec.JBSConfig)}, jbsConfig)
 	if err != nil && !errors.IsNotFound(err) {
 		return reconcile.Result{}, err
 	}
@@ -599,24 +942,33 @@ func (r *ReconcileDependencyBuild) handleStateBuilding(ctx context.Context, log
 	} else {
 		pr.Spec.Workspaces = append(pr.Spec.Workspaces, pipelinev1beta1.WorkspaceBinding{Name: "tls", EmptyDir: &v1.EmptyDirVolumeSource{}})
 	}
-	pr.Spec.Timeout = &v12.Duration{Duration: time.Hour * 3}
+	pr.Spec.Timeout = &v12.Duration{Duration: pipelineTimeout(db.Status.CurrentBuildRecipe, jbsConfig)}
 	if err := controllerutil.SetOwnerReference(db, &pr, r.scheme); err != nil {
 		return reconcile.Result{}, err
 	}
 	//now we submit the build
 	if err := r.client.Create(ctx, &pr); err != nil {
-		if errors.IsAlreadyExists(err) {
+		if !errors.IsAlreadyExists(err) {
+			r.eventRecorder.Eventf(db, v1.EventTypeWarning, "PipelineRunCreationFailed", "The DependencyBuild %s/%s failed to create its build pipeline run", db.Namespace, db.Name)
+			return reconcile.Result{}, err
+		}
+		if runningBuildAttempt(db) != nil {
 			log.V(4).Info("handleStateBuilding: pipelinerun %s:%s already exists, not retrying", pr.Namespace, pr.Name)
 			return reconcile.Result{}, nil
 		}
-		r.eventRecorder.Eventf(db, v1.EventTypeWarning, "PipelineRunCreationFailed", "The DependencyBuild %s/%s failed to create its build pipeline run", db.Namespace, db.Name)
+		//the status update failed after the pipeline was created, so the attempt is recorded now
+		if err := r.client.Get(ctx, types.NamespacedName{Namespace: pr.Namespace, Name: pr.Name}, &pr); err != nil {
+			return reconcile.Result{}, err
+		}
+	}
+	startBuildAttempt(db, &pr)
+	setCondition(db, v1alpha1.DependencyBuildConditionPipelineSubmitted, v12.ConditionTrue, v1alpha1.DependencyBuildReasonPipelineRunCreated, "created PipelineRun "+pr.Name)
+	setCondition(db, v1alpha1.DependencyBuildConditionSucceeded, v12.ConditionUnknown, v1alpha1.DependencyBuildReasonBuilding, "PipelineRun "+pr.Name+" is running")
+	if err := r.client.Status().Update(ctx, db); err != nil {
 		return reconcile.Result{}, err
 	}
-	return reconcile.Result{}, r.client.Status().Update(ctx, db)
-}
-
-func currentDependencyBuildPipelineName(db *v1alpha1.DependencyBuild) string {
-	return fmt.Sprintf("%s-build-%d", db.Name, len(db.Status.FailedBuildRecipes))
+	metrics.RecipeAttempted(db.Namespace, db.Status.CurrentBuildRecipe)
+	return reconcile.Result{}, nil
 }
 
 func (r *ReconcileDependencyBuild) handleBuildPipelineRunReceived(ctx context.Context, log logr.Logger, pr *pipelinev1beta1.PipelineRun) (reconcile.Result, error) {
@@ -661,7 +1013,7 @@ func (r *ReconcileDependencyBuild) handleBuildPipelineRunReceived(ctx context.Co
 			msg := "get for pipelinerun %s:%s owning db %s:%s yielded error %s"
 			r.eventRecorder.Eventf(pr, v1.EventTypeWarning, msg, pr.Namespace, pr.Name, pr.Namespace, ownerRef.Name, err.Error())
 			log.Error(err, fmt.Sprintf(msg, pr.Namespace, pr.Name, pr.Namespace, ownerRef.Name, err.Error()))
-			if errors.IsNotFound(err) {
+			if !errors.IsNotFound(err) {
 				return reconcile.Result{}, err
 			}
 			//on not found we don't return the error
@@ -673,69 +1025,144 @@ func (r *ReconcileDependencyBuild) handleBuildPipelineRunReceived(ctx context.Co
 			//already handled
 			return RemovePipelineFinalizer(ctx, pr, r.client)
 		}
+		//the metrics are recorded once the status update succeeds, so a conflict does not record them twice
+		var pendingMetrics []func()
+		recordMetrics := func() {
+			for _, record := range pendingMetrics {
+				record()
+			}
+			pendingMetrics = nil
+		}
+		attempt := completeBuildAttempt(&db, pr)
+		attempt.LogArchive = r.archivePipelineLogs(ctx, log, &db, pr)
+		if pr.Status.StartTime != nil {
+			recipe := attempt.Recipe
+			duration := pr.Status.CompletionTime.Sub(pr.Status.StartTime.Time)
+			pendingMetrics = append(pendingMetrics, func() {
+				metrics.ObserveBuildPipelineRunDuration(db.Namespace, recipe, duration)
+			})
+		}
 		success := pr.Status.GetCondition(apis.ConditionSucceeded).IsTrue()
 
 		if !success {
-
-			//if there was a cache issue we want to retry the build
-			//we check and see if there is a cache pod newer than the build
-			//if so we just delete the pipelinerun
-			p := v1.PodList{}
-			listOpts := &client.ListOptions{
-				Namespace:     pr.Namespace,
-				LabelSelector: labels.SelectorFromSet(map[string]string{"app": v1alpha1.CacheDeploymentName}),
-			}
-			err := r.client.List(ctx, &p, listOpts)
+			//this is overridden below if the same recipe is going to be retried
+			setCondition(&db, v1alpha1.DependencyBuildConditionSucceeded, v12.ConditionUnknown, v1alpha1.DependencyBuildReasonRecipeFailed, "PipelineRun "+pr.Name+" failed, trying the next build recipe")
+
+			failure := r.classifyBuildFailure(ctx, log, pr)
+			db.Status.BuildFailures = append(db.Status.BuildFailures, failure)
+			attempt.Outcome = v1alpha1.BuildAttemptOutcomeFailed
+			attempt.Cause = failure.Class
+			attempt.Message = failure.Message
+			policy, err := r.loadRetryPolicy(ctx, &db)
 			if err != nil {
 				return reconcile.Result{}, err
 			}
-			if db.Status.PipelineRetries < MaxRetries {
-
-				doRetry := false
-				for _, pod := range p.Items {
-					if pod.ObjectMeta.CreationTimestamp.After(pr.ObjectMeta.CreationTimestamp.Time) {
-						doRetry = true
-						msg := fmt.Sprintf("Cache problems detected, retrying the build for DependencyBuild %s", db.Name)
-						log.Info(msg)
-					}
-
+			doRetry := false
+			retryCause := ""
+			if failure.Class == v1alpha1.BuildFailureClassTimeout {
+				//a timeout is not a cache or memory problem, the timeout policy decides if the recipe is retried
+				doRetry, err = r.handleBuildTimeout(ctx, log, &db, pr, policy)
+				if err != nil {
+					return reconcile.Result{}, err
 				}
-				if !doRetry && db.Status.CurrentBuildRecipe.AdditionalMemory < 2048 {
-					for _, trs := range pr.Status.TaskRuns {
-						for _, cont := range trs.Status.Steps {
-							//check for oomkilled pods
-							if cont.Terminated != nil && (cont.Terminated.ExitCode == 137 || cont.Terminated.ExitCode == 134 || cont.Terminated.Reason == "OOMKilled") {
-								msg := fmt.Sprintf("OOMKilled Pod detected, retrying the build for DependencyBuild with more memory %s, PR UID: %s, Current additional memory: %d", db.Name, pr.UID, db.Status.CurrentBuildRecipe.AdditionalMemory)
-								log.Info(msg)
-								doRetry = true
-								//increase the memory limit
-								if db.Status.CurrentBuildRecipe.AdditionalMemory == 0 {
-									db.Status.CurrentBuildRecipe.AdditionalMemory = MemoryIncrement
-								} else {
-									db.Status.CurrentBuildRecipe.AdditionalMemory = db.Status.CurrentBuildRecipe.AdditionalMemory * 2
-								}
-								for i := range db.Status.PotentialBuildRecipes {
-									db.Status.PotentialBuildRecipes[i].AdditionalMemory = db.Status.CurrentBuildRecipe.AdditionalMemory
-								}
-								break
-							}
+				if doRetry {
+					retryCause = v1alpha1.BuildFailureClassTimeout
+				}
+			} else {
+				//if there was a cache issue we want to retry the build
+				//we check and see if there is a cache pod newer than the build
+				//if so we just delete the pipelinerun
+				jbsConfig := &v1alpha1.JBSConfig{}
+				err := r.client.Get(ctx, types.NamespacedName{Namespace: db.Namespace, Name: v1alpha1.JBSConfigNameOrDefault(db.Spec.JBSConfig)}, jbsConfig)
+				if err != nil && !errors.IsNotFound(err) {
+					return reconcile.Result{}, err
+				}
+				p := v1.PodList{}
+				listOpts := &client.ListOptions{
+					Namespace:     pr.Namespace,
+					LabelSelector: labels.SelectorFromSet(map[string]string{v1alpha1.CacheLabel: jbsConfig.CacheName()}),
+				}
+				err = r.client.List(ctx, &p, listOpts)
+				if err != nil {
+					return reconcile.Result{}, err
+				}
+				if policy.canRetry(v1alpha1.RetryCauseCacheRestart, db.Status.RetriesByCause) {
+					for _, pod := range p.Items {
+						if pod.ObjectMeta.CreationTimestamp.After(pr.ObjectMeta.CreationTimestamp.Time) {
+							doRetry = true
+							retryCause = v1alpha1.RetryCauseCacheRestart
+							msg := fmt.Sprintf("Cache problems detected, retrying the build for DependencyBuild %s", db.Name)
+							log.Info(msg)
+							setCondition(&db, v1alpha1.DependencyBuildConditionSucceeded, v12.ConditionUnknown, v1alpha1.DependencyBuildReasonCacheRestartRetry, "the cache was restarted during PipelineRun "+pr.Name+", retrying the build")
 						}
 					}
 				}
-
-				if doRetry {
-
-					existing := db.Status.PotentialBuildRecipes
-					db.Status.PotentialBuildRecipes = []*v1alpha1.BuildRecipe{db.Status.CurrentBuildRecipe}
-					db.Status.PotentialBuildRecipes = append(db.Status.PotentialBuildRecipes, existing...)
-					db.Status.PipelineRetries++
-					err := r.client.Status().Update(ctx, &db)
-					if err != nil {
-						return reconcile.Result{}, err
+				if !doRetry && failure.Class == v1alpha1.BuildFailureClassOOM && policy.canRetry(failure.Class, db.Status.RetriesByCause) {
+					additionalMemory := policy.nextAdditionalMemory(db.Status.CurrentBuildRecipe.AdditionalMemory)
+					if additionalMemory > 0 {
+						msg := fmt.Sprintf("OOMKilled Pod detected, retrying the build for DependencyBuild with more memory %s, PR UID: %s, Current additional memory: %d", db.Name, pr.UID, db.Status.CurrentBuildRecipe.AdditionalMemory)
+						log.Info(msg)
+						doRetry = true
+						retryCause = failure.Class
+						setCondition(&db, v1alpha1.DependencyBuildConditionSucceeded, v12.ConditionUnknown, v1alpha1.DependencyBuildReasonOOMRetry, "PipelineRun "+pr.Name+" ran out of memory, retrying the build with more memory")
+						//increase the memory limit
+						db.Status.CurrentBuildRecipe.AdditionalMemory = additionalMemory
+						for i := range db.Status.PotentialBuildRecipes {
+							db.Status.PotentialBuildRecipes[i].AdditionalMemory = additionalMemory
+						}
 					}
 				}
+				if !doRetry && recipeIndependentFailureClass(failure.Class) && policy.canRetry(failure.Class, db.Status.RetriesByCause) {
+					doRetry = true
+					retryCause = failure.Class
+					msg := fmt.Sprintf("PipelineRun %s failed in the %s step, retrying the build with the same recipe", pr.Name, failure.Step)
+					log.Info(msg)
+					setCondition(&db, v1alpha1.DependencyBuildConditionSucceeded, v12.ConditionUnknown, v1alpha1.DependencyBuildReasonTransientFailureRetry, msg)
+				}
+			}
+			if doRetry {
+				if db.Status.RetriesByCause == nil {
+					db.Status.RetriesByCause = map[string]int{}
+				}
+				scheduleRetry(&db, policy.backoff(db.Status.RetriesByCause[retryCause]))
+				db.Status.RetriesByCause[retryCause]++
+				db.Status.PipelineRetries++
+				recipe := db.Status.CurrentBuildRecipe
+				switch retryCause {
+				case v1alpha1.BuildFailureClassOOM:
+					pendingMetrics = append(pendingMetrics, func() { metrics.OOMRetried(db.Namespace, recipe) })
+				case v1alpha1.RetryCauseCacheRestart:
+					pendingMetrics = append(pendingMetrics, func() { metrics.CacheRestartRetried(db.Namespace, recipe) })
+				}
+			}
+			if !doRetry && recipeIndependentFailureClass(failure.Class) {
+				//the remaining recipes would fail in the same way
+				db.Status.FailedBuildRecipes = append(db.Status.FailedBuildRecipes, db.Status.CurrentBuildRecipe)
+				db.Status.CurrentBuildRecipe = nil
+				db.Status.PotentialBuildRecipes = nil
+				db.Status.LastCompletedBuildPipelineRun = pr.Name
+				db.Status.State = v1alpha1.DependencyBuildStateFailed
+				msg := fmt.Sprintf("PipelineRun %s failed in the %s step with a %s failure, which another build recipe will not fix", pr.Name, failure.Step, failure.Class)
+				setCondition(&db, v1alpha1.DependencyBuildConditionSucceeded, v12.ConditionFalse, v1alpha1.DependencyBuildReasonUnrecoverableFailure, msg)
+				r.eventRecorder.Eventf(&db, v1.EventTypeWarning, "BuildFailed", "The DependencyBuild %s/%s moved to failed, %s", db.Namespace, db.Name, msg)
+				if err := r.client.Status().Update(ctx, &db); err != nil {
+					return reconcile.Result{}, err
+				}
+				recordMetrics()
+				return RemovePipelineFinalizer(ctx, pr, r.client)
+			}
+			if doRetry {
+				attempt.Outcome = v1alpha1.BuildAttemptOutcomeRetried
+				attempt.Cause = retryCause
+				existing := db.Status.PotentialBuildRecipes
+				db.Status.PotentialBuildRecipes = []*v1alpha1.BuildRecipe{db.Status.CurrentBuildRecipe}
+				db.Status.PotentialBuildRecipes = append(db.Status.PotentialBuildRecipes, existing...)
+				err := r.client.Status().Update(ctx, &db)
+				if err != nil {
+					return reconcile.Result{}, err
+				}
+				recordMetrics()
 			}
-
 		}
 
 		db.Status.LastCompletedBuildPipelineRun = pr.Name
@@ -743,16 +1170,21 @@ func (r *ReconcileDependencyBuild) handleBuildPipelineRunReceived(ctx context.Co
 		//we just set the state here, the ABR logic is in the ABR controller
 		//this keeps as much of the logic in one place as possible
 
+		var image string
+		var digest string
+		var deployed []string
 		if success {
-			var image string
-			var digest string
 			for _, i := range pr.Status.PipelineResults {
 				if i.Name == PipelineResultImage {
 					image = i.Value.StringVal
 				} else if i.Name == PipelineResultImageDigest {
 					digest = i.Value.StringVal
+				} else if i.Name == artifactbuild.PipelineResultDeployedResources && len(i.Value.StringVal) > 0 {
+					deployed = strings.Split(i.Value.StringVal, ",")
 				}
 			}
+			attempt.Outcome = v1alpha1.BuildAttemptOutcomeSucceeded
+			attempt.ImageDigest = digest
 			for _, i := range pr.Status.PipelineResults {
 				if i.Name == artifactbuild.PipelineResultContaminants {
 
@@ -764,7 +1196,6 @@ func (r *ReconcileDependencyBuild) handleBuildPipelineRunReceived(ctx context.Co
 					}
 				} else if i.Name == artifactbuild.PipelineResultDeployedResources && len(i.Value.StringVal) > 0 {
 					//we need to create 'DeployedArtifact' resources for the objects that were deployed
-					deployed := strings.Split(i.Value.StringVal, ",")
 					db.Status.DeployedArtifacts = deployed
 					for _, i := range deployed {
 						ra := v1alpha1.RebuiltArtifact{}
@@ -777,8 +1208,11 @@ func (r *ReconcileDependencyBuild) handleBuildPipelineRunReceived(ctx context.Co
 						ra.Spec.GAV = i
 						ra.Spec.Image = image
 						ra.Spec.Digest = digest
+						ra.Spec.JBSConfig = db.Spec.JBSConfig
 						err := r.client.Create(ctx, &ra)
-						if err != nil {
+						if err == nil {
+							metrics.RebuiltArtifactCreated(db.Namespace, db.Status.CurrentBuildRecipe)
+						} else {
 							if !errors.IsAlreadyExists(err) {
 								return reconcile.Result{}, err
 							} else {
@@ -805,13 +1239,24 @@ func (r *ReconcileDependencyBuild) handleBuildPipelineRunReceived(ctx context.Co
 				} else if i.Name == artifactbuild.PipelineResultPassedVerification {
 					parseBool, _ := strconv.ParseBool(i.Value.StringVal)
 					db.Status.FailedVerification = !parseBool
+					if parseBool {
+						setCondition(&db, v1alpha1.DependencyBuildConditionVerified, v12.ConditionTrue, v1alpha1.DependencyBuildReasonVerificationPassed, "")
+					} else {
+						setCondition(&db, v1alpha1.DependencyBuildConditionVerified, v12.ConditionFalse, v1alpha1.DependencyBuildReasonVerificationFailed, "the rebuilt artifacts did not match the upstream artifacts")
+						recipe := db.Status.CurrentBuildRecipe
+						pendingMetrics = append(pendingMetrics, func() { metrics.VerificationFailed(db.Namespace, recipe) })
+					}
 				}
 			}
+			setCondition(&db, v1alpha1.DependencyBuildConditionSucceeded, v12.ConditionTrue, v1alpha1.DependencyBuildReasonBuildSucceeded, "PipelineRun "+pr.Name+" succeeded")
 
 			if len(db.Status.Contaminants) == 0 {
 				db.Status.State = v1alpha1.DependencyBuildStateComplete
+				setCondition(&db, v1alpha1.DependencyBuildConditionContaminated, v12.ConditionFalse, v1alpha1.DependencyBuildReasonNotContaminated, "")
 			} else {
 				r.eventRecorder.Eventf(&db, v1.EventTypeWarning, "BuildContaminated", "The DependencyBuild %s/%s was contaminated with community dependencies", db.Namespace, db.Name)
+				recipe := db.Status.CurrentBuildRecipe
+				pendingMetrics = append(pendingMetrics, func() { metrics.BuildContaminated(db.Namespace, recipe) })
 				//the dependency was contaminated with community deps
 				//most likely shaded in
 				//we don't need to update the status here, it will be handled by the handleStateComplete method
@@ -820,6 +1265,8 @@ func (r *ReconcileDependencyBuild) handleBuildPipelineRunReceived(ctx context.Co
 				if err != nil {
 					return reconcile.Result{}, err
 				}
+				recordMetrics()
+				r.publishReferrers(ctx, log, &db, pr, image, digest, deployed)
 				return RemovePipelineFinalizer(ctx, pr, r.client)
 			}
 		} else {
@@ -830,6 +1277,10 @@ func (r *ReconcileDependencyBuild) handleBuildPipelineRunReceived(ctx context.Co
 		if err != nil {
 			return reconcile.Result{}, err
 		}
+		recordMetrics()
+		if success {
+			r.publishReferrers(ctx, log, &db, pr, image, digest, deployed)
+		}
 		return RemovePipelineFinalizer(ctx, pr, r.client)
 	}
 	return reconcile.Result{}, nil
@@ -845,8 +1296,11 @@ func (r *ReconcileDependencyBuild) handleStateCompleted(ctx context.Context, db
 	ownerGavs := map[string]bool{}
 	db.Status.State = v1alpha1.DependencyBuildStateComplete
 	if len(db.Status.Contaminants) == 0 {
+		setCondition(db, v1alpha1.DependencyBuildConditionContaminated, v12.ConditionFalse, v1alpha1.DependencyBuildReasonNotContaminated, "")
 		return reconcile.Result{}, r.client.Status().Update(ctx, db)
 	}
+	//contaminants that are not in artifacts we were asked for do not affect the build
+	setCondition(db, v1alpha1.DependencyBuildConditionContaminated, v12.ConditionFalse, v1alpha1.DependencyBuildReasonNotContaminated, "contaminants do not affect the requested artifacts")
 	l.Info("Resolving contaminates for build", "build", db.Name)
 	//get all the owning artifact builds
 	//if any of these are contaminated
@@ -870,6 +1324,7 @@ func (r *ReconcileDependencyBuild) handleStateCompleted(ctx context.Context, db
 		for _, artifact := range contaminant.ContaminatedArtifacts {
 			if ownerGavs[artifact] {
 				db.Status.State = v1alpha1.DependencyBuildStateContaminated
+				setCondition(db, v1alpha1.DependencyBuildConditionContaminated, v12.ConditionTrue, v1alpha1.DependencyBuildReasonContaminated, fmt.Sprintf("%s is contaminated by %s", artifact, contaminant.GAV))
 				abrName := artifactbuild.CreateABRName(contaminant.GAV)
 				abr := v1alpha1.ArtifactBuild{}
 				//look for existing ABR
@@ -879,7 +1334,7 @@ func (r *ReconcileDependencyBuild) handleStateCompleted(ctx context.Context, db
 					l.Info(fmt.Sprintf("Creating ArtifactBuild %s for GAV %s to resolve contamination of %s", abrName, contaminant.GAV, artifact), "contaminate", contaminant, "owner", artifact, "action", "ADD")
 					//we just assume this is because it does not exist
 					//TODO: how to check the type of the error?
-					abr.Spec = v1alpha1.ArtifactBuildSpec{GAV: contaminant.GAV}
+					abr.Spec = v1alpha1.ArtifactBuildSpec{GAV: contaminant.GAV, JBSConfig: db.Spec.JBSConfig}
 					abr.Name = abrName
 					abr.Namespace = db.Namespace
 					abr.Annotations = map[string]string{}
@@ -911,11 +1366,23 @@ func (r *ReconcileDependencyBuild) handleStateContaminated(ctx context.Context,
 		//this is triggered when contaminants are removed by the ABR controller
 		//setting it back to building should re-try the recipe that actually worked
 		db.Status.State = v1alpha1.DependencyBuildStateNew
+		setCondition(db, v1alpha1.DependencyBuildConditionContaminated, v12.ConditionFalse, v1alpha1.DependencyBuildReasonContaminantsResolved, "all contaminants have been rebuilt")
 		return reconcile.Result{}, r.client.Update(ctx, db)
 	}
 	return reconcile.Result{}, nil
 }
 
+// setCondition records a condition on the DependencyBuild, the transition time is only changed if the status changes
+func setCondition(db *v1alpha1.DependencyBuild, conditionType string, status v12.ConditionStatus, reason string, message string) {
+	meta.SetStatusCondition(&db.Status.Conditions, v12.Condition{
+		Type:               conditionType,
+		Status:             status,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: db.Generation,
+	})
+}
+
 func (r *ReconcileDependencyBuild) createLookupBuildInfoPipeline(ctx context.Context, log logr.Logger, build *v1alpha1.DependencyBuildSpec, jbsConfig *v1alpha1.JBSConfig, additionalMemory int) (*pipelinev1beta1.PipelineSpec, error) {
 	image, err := r.buildRequestProcessorImage(ctx, log)
 	if err != nil {
@@ -927,10 +1394,7 @@ func (r *ReconcileDependencyBuild) createLookupBuildInfoPipeline(ctx context.Con
 		path = "."
 	}
 	zero := int64(0)
-	cacheUrl := "https://jvm-build-workspace-artifact-cache-tls." + jbsConfig.Namespace + ".svc.cluster.local"
-	if jbsConfig.Spec.CacheSettings.DisableTLS {
-		cacheUrl = "http://jvm-build-workspace-artifact-cache." + jbsConfig.Namespace + ".svc.cluster.local"
-	}
+	cacheUrl := jbsConfig.CacheURL()
 	trueBool := true
 	args := []string{
 		"lookup-build-info",
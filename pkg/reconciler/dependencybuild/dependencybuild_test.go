@@ -1,12 +1,25 @@
 package dependencybuild
 
 import (
+	"bytes"
 	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
 	"encoding/json"
+	"encoding/pem"
 	"fmt"
+	"strings"
 	"testing"
 	"time"
 
+	cdx "github.com/CycloneDX/cyclonedx-go"
+	"github.com/go-logr/logr"
+	ggcrv1 "github.com/google/go-containerregistry/pkg/v1"
 	. "github.com/onsi/gomega"
 
 	"github.com/redhat-appstudio/jvm-build-service/pkg/apis/jvmbuildservice/v1alpha1"
@@ -17,6 +30,9 @@ import (
 
 	appsv1 "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
@@ -37,17 +53,20 @@ func setupClientAndReconciler(objs ...runtimeclient.Object) (runtimeclient.Clien
 	_ = pipelinev1beta1.AddToScheme(scheme)
 	_ = v1.AddToScheme(scheme)
 	_ = appsv1.AddToScheme(scheme)
-	client := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
+	client := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).WithIndex(&v1alpha1.DependencyBuild{}, DependencyBuildStateIndex, dependencyBuildState).Build()
 	reconciler := &ReconcileDependencyBuild{
 		client:        client,
 		scheme:        scheme,
 		eventRecorder: &record.FakeRecorder{},
+		recipeScorers: DefaultRecipeScorers(),
 	}
 
 	sysConfig := v1alpha1.SystemConfig{
 		ObjectMeta: metav1.ObjectMeta{Name: systemconfig.SystemConfigKey},
 		Spec: v1alpha1.SystemConfigSpec{
 			MaxAdditionalMemory: MaxAdditionalMemory,
+			//retry straight away, so the tests don't need to wait for the backoff
+			RetryPolicy: v1alpha1.RetryPolicy{InitialBackoff: &metav1.Duration{}},
 			Builders: map[string]v1alpha1.JavaVersionInfo{
 				v1alpha1.JDK8Builder: {
 					Image: "quay.io/redhat-appstudio/hacbs-jdk8-builder:latest",
@@ -55,7 +74,7 @@ func setupClientAndReconciler(objs ...runtimeclient.Object) (runtimeclient.Clien
 				},
 				v1alpha1.JDK11Builder: {
 					Image: "quay.io/redhat-appstudio/hacbs-jdk11-builder:latest",
-					Tag:   "jdk:11,maven:3.8,gradle:8.0.2;7.4.2;6.9.2;5.6.4;4.10.3",
+					Tag:   "jdk:11,maven:3.8;3.6.3,gradle:8.0.2;7.4.2;6.9.2;5.6.4;4.10.3",
 				},
 				v1alpha1.JDK17Builder: {
 					Image: "quay.io/redhat-appstudio/hacbs-jdk17-builder:latest",
@@ -186,6 +205,7 @@ func TestStateDetect(t *testing.T) {
 		runBuildDiscoveryPipeline(db, g, reconciler, client, ctx, true)
 
 		g.Expect(getBuild(client, g).Status.State).Should(Equal(v1alpha1.DependencyBuildStateSubmitBuild))
+		g.Expect(meta.IsStatusConditionTrue(getBuild(client, g).Status.Conditions, v1alpha1.DependencyBuildConditionBuildInfoAnalyzed)).Should(BeTrue())
 		g.Expect(reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: db.Namespace, Name: db.Name}}))
 
 		g.Expect(client.Get(ctx, types.NamespacedName{
@@ -193,7 +213,12 @@ func TestStateDetect(t *testing.T) {
 			Name:      "test",
 		}, &db))
 		g.Expect(db.Status.State).Should(Equal(v1alpha1.DependencyBuildStateBuilding))
+		g.Expect(meta.IsStatusConditionTrue(db.Status.Conditions, v1alpha1.DependencyBuildConditionRecipeSelected)).Should(BeTrue())
+		g.Expect(meta.IsStatusConditionFalse(db.Status.Conditions, v1alpha1.DependencyBuildConditionPipelineSubmitted)).Should(BeTrue())
 		g.Expect(reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: db.Namespace, Name: db.Name}}))
+		db = *getBuild(client, g)
+		g.Expect(meta.IsStatusConditionTrue(db.Status.Conditions, v1alpha1.DependencyBuildConditionPipelineSubmitted)).Should(BeTrue())
+		g.Expect(meta.FindStatusCondition(db.Status.Conditions, v1alpha1.DependencyBuildConditionSucceeded).Status).Should(Equal(metav1.ConditionUnknown))
 
 		trList := &pipelinev1beta1.PipelineRunList{}
 		g.Expect(client.List(ctx, trList))
@@ -231,17 +256,73 @@ func TestStateDetect(t *testing.T) {
 				case PipelineParamEnforceVersion:
 					g.Expect(param.Value.StringVal).Should(BeEmpty())
 				case PipelineParamToolVersion:
-					g.Expect(param.Value.StringVal).Should(Equal("3.8.1"))
+					g.Expect(param.Value.StringVal).Should(Equal("3.8"))
 				}
 			}
 		}
 	})
+	t.Run("Test reconcile build info discovery runs out of memory", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		db, client, reconciler, ctx := setup(g)
+		oom := func() {
+			pr := findBuildInfoPipeline(client, g)
+			pr.Status.CompletionTime = &metav1.Time{Time: time.Now()}
+			pr.Status.SetCondition(&apis.Condition{Type: apis.ConditionSucceeded, Status: "False"})
+			pr.Status.TaskRuns = map[string]*pipelinev1beta1.PipelineRunTaskRunStatus{"task": {Status: &pipelinev1beta1.TaskRunStatus{TaskRunStatusFields: pipelinev1beta1.TaskRunStatusFields{Steps: []pipelinev1beta1.StepState{{ContainerState: v1.ContainerState{Terminated: &v1.ContainerStateTerminated{Reason: "OOMKilled"}}}}}}}}
+			g.Expect(client.Update(ctx, pr)).Should(Succeed())
+			g.Expect(reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: pr.Namespace, Name: pr.Name}}))
+		}
+		for _, memory := range []int{512, 1024, 2048} {
+			oom()
+			db = *getBuild(client, g)
+			g.Expect(db.Status.State).Should(Equal(v1alpha1.DependencyBuildStateAnalyzeBuild))
+			g.Expect(db.Status.BuildInfoAdditionalMemory).Should(Equal(memory))
+			g.Expect(meta.FindStatusCondition(db.Status.Conditions, v1alpha1.DependencyBuildConditionBuildInfoAnalyzed).Reason).Should(Equal(v1alpha1.DependencyBuildReasonOOMRetry))
+		}
+		//the retries are used up
+		oom()
+		db = *getBuild(client, g)
+		g.Expect(db.Status.State).Should(Equal(v1alpha1.DependencyBuildStateFailed))
+		g.Expect(db.Status.BuildInfoRetries).Should(Equal(defaultMaxRetries))
+	})
+	t.Run("Test reconcile build info discovery retry backs off", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		db, client, reconciler, ctx := setup(g)
+		jbsConfig := v1alpha1.JBSConfig{}
+		g.Expect(client.Get(ctx, types.NamespacedName{Namespace: metav1.NamespaceDefault, Name: v1alpha1.JBSConfigName}, &jbsConfig)).Should(Succeed())
+		jbsConfig.Spec.BuildSettings.RetryPolicy = v1alpha1.RetryPolicy{InitialBackoff: &metav1.Duration{Duration: time.Minute}, MemoryIncrement: 1024}
+		g.Expect(client.Update(ctx, &jbsConfig)).Should(Succeed())
+
+		pr := findBuildInfoPipeline(client, g)
+		pr.Status.CompletionTime = &metav1.Time{Time: time.Now()}
+		pr.Status.SetCondition(&apis.Condition{Type: apis.ConditionSucceeded, Status: "False"})
+		pr.Status.TaskRuns = map[string]*pipelinev1beta1.PipelineRunTaskRunStatus{"task": {Status: &pipelinev1beta1.TaskRunStatus{TaskRunStatusFields: pipelinev1beta1.TaskRunStatusFields{Steps: []pipelinev1beta1.StepState{{ContainerState: v1.ContainerState{Terminated: &v1.ContainerStateTerminated{ExitCode: 137}}}}}}}}
+		g.Expect(client.Update(ctx, pr)).Should(Succeed())
+		result, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: pr.Namespace, Name: pr.Name}})
+		g.Expect(err).Should(BeNil())
+		g.Expect(result.RequeueAfter).Should(Equal(time.Minute))
+		db = *getBuild(client, g)
+		g.Expect(db.Status.State).Should(Equal(v1alpha1.DependencyBuildStateNew))
+		g.Expect(db.Status.BuildInfoAdditionalMemory).Should(Equal(1024))
+		g.Expect(db.Status.RetryAfter).ShouldNot(BeNil())
+
+		//the pipeline is not created until the backoff has passed
+		result, err = reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: db.Namespace, Name: db.Name}})
+		g.Expect(err).Should(BeNil())
+		g.Expect(result.RequeueAfter).Should(BeNumerically(">", 0))
+		trList := &pipelinev1beta1.PipelineRunList{}
+		g.Expect(client.List(ctx, trList)).Should(Succeed())
+		g.Expect(trList.Items).Should(BeEmpty())
+	})
 	t.Run("Test reconcile build info discovery fails", func(t *testing.T) {
 		g := NewGomegaWithT(t)
 		db, client, reconciler, ctx := setup(g)
 		runBuildDiscoveryPipeline(db, g, reconciler, client, ctx, false)
 
-		g.Expect(getBuild(client, g).Status.State).Should(Equal(v1alpha1.DependencyBuildStateFailed))
+		db = *getBuild(client, g)
+		g.Expect(db.Status.State).Should(Equal(v1alpha1.DependencyBuildStateFailed))
+		g.Expect(meta.FindStatusCondition(db.Status.Conditions, v1alpha1.DependencyBuildConditionBuildInfoAnalyzed).Reason).Should(Equal(v1alpha1.DependencyBuildReasonBuildInfoLookupFailed))
+		g.Expect(meta.IsStatusConditionFalse(db.Status.Conditions, v1alpha1.DependencyBuildConditionSucceeded)).Should(BeTrue())
 	})
 }
 
@@ -260,6 +341,20 @@ func getBuildPipelineNo(client runtimeclient.Client, g *WithT, no int) *pipeline
 	g.Expect(client.Get(ctx, types.NamespacedName{Namespace: metav1.NamespaceDefault, Name: fmt.Sprintf("test-build-%d", no)}, &build)).Should(BeNil())
 	return &build
 }
+
+// findBuildInfoPipeline finds the build info pipeline created by the reconciler, which has a generated name
+func findBuildInfoPipeline(client runtimeclient.Client, g *WithT) *pipelinev1beta1.PipelineRun {
+	trList := &pipelinev1beta1.PipelineRunList{}
+	g.Expect(client.List(context.TODO(), trList)).Should(Succeed())
+	for i := range trList.Items {
+		if trList.Items[i].Labels[PipelineTypeLabel] == PipelineTypeBuildInfo {
+			return &trList.Items[i]
+		}
+	}
+	g.Expect(trList.Items).ShouldNot(BeEmpty())
+	return nil
+}
+
 func getBuildInfoPipeline(client runtimeclient.Client, g *WithT) *pipelinev1beta1.PipelineRun {
 	ctx := context.TODO()
 	build := pipelinev1beta1.PipelineRun{}
@@ -320,6 +415,11 @@ func TestStateBuilding(t *testing.T) {
 		g.Expect(reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: buildName}))
 		db := getBuild(client, g)
 		g.Expect(db.Status.State).Should(Equal(v1alpha1.DependencyBuildStateBuilding))
+		//the pipeline already existed, so the attempt the failed status update would have recorded is added
+		g.Expect(db.Status.BuildAttempts).Should(HaveLen(1))
+		g.Expect(db.Status.BuildAttempts[0].PipelineRun).Should(Equal("test-build-0"))
+		g.Expect(reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: buildName}))
+		g.Expect(getBuild(client, g).Status.BuildAttempts).Should(HaveLen(1))
 		g.Expect(reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: taskRunName}))
 		db = getBuild(client, g)
 		g.Expect(db.Status.State).Should(Equal(v1alpha1.DependencyBuildStateBuilding))
@@ -335,11 +435,24 @@ func TestStateBuilding(t *testing.T) {
 			Status:             "True",
 			LastTransitionTime: apis.VolatileTime{Inner: metav1.Time{Time: time.Now()}},
 		})
-		pr.Status.PipelineResults = []pipelinev1beta1.PipelineRunResult{{Name: artifactbuild.PipelineResultDeployedResources, Value: pipelinev1beta1.ResultValue{Type: pipelinev1beta1.ParamTypeString, StringVal: TestArtifact}}}
+		pr.Status.PipelineResults = []pipelinev1beta1.PipelineRunResult{
+			{Name: artifactbuild.PipelineResultDeployedResources, Value: pipelinev1beta1.ResultValue{Type: pipelinev1beta1.ParamTypeString, StringVal: TestArtifact}},
+			{Name: artifactbuild.PipelineResultPassedVerification, Value: pipelinev1beta1.ResultValue{Type: pipelinev1beta1.ParamTypeString, StringVal: "true"}},
+			{Name: PipelineResultImageDigest, Value: pipelinev1beta1.ResultValue{Type: pipelinev1beta1.ParamTypeString, StringVal: "sha256:12345"}},
+		}
 		g.Expect(client.Update(ctx, pr)).Should(BeNil())
 		g.Expect(reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: taskRunName}))
 		db := getBuild(client, g)
 		g.Expect(db.Status.State).Should(Equal(v1alpha1.DependencyBuildStateComplete))
+		//the pipeline was created before the attempts were recorded, so the attempt is added when it completes
+		g.Expect(db.Status.BuildAttempts).Should(HaveLen(1))
+		g.Expect(db.Status.BuildAttempts[0].PipelineRun).Should(Equal("test-build-0"))
+		g.Expect(db.Status.BuildAttempts[0].Outcome).Should(Equal(v1alpha1.BuildAttemptOutcomeSucceeded))
+		g.Expect(db.Status.BuildAttempts[0].ImageDigest).Should(Equal("sha256:12345"))
+		g.Expect(db.Status.BuildAttempts[0].Recipe.Image).Should(Equal(db.Status.CurrentBuildRecipe.Image))
+		g.Expect(meta.IsStatusConditionTrue(db.Status.Conditions, v1alpha1.DependencyBuildConditionSucceeded)).Should(BeTrue())
+		g.Expect(meta.IsStatusConditionTrue(db.Status.Conditions, v1alpha1.DependencyBuildConditionVerified)).Should(BeTrue())
+		g.Expect(meta.IsStatusConditionFalse(db.Status.Conditions, v1alpha1.DependencyBuildConditionContaminated)).Should(BeTrue())
 		g.Expect(db.Status.DeployedArtifacts).Should(ContainElement(TestArtifact))
 		ra := v1alpha1.RebuiltArtifact{}
 		g.Expect(client.Get(ctx, types.NamespacedName{Name: artifactbuild.CreateABRName(TestArtifact), Namespace: metav1.NamespaceDefault}, &ra)).Should(Succeed())
@@ -362,9 +475,14 @@ func TestStateBuilding(t *testing.T) {
 		g.Expect(reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: taskRunName}))
 		db := getBuild(client, g)
 		g.Expect(db.Status.State).Should(Equal(v1alpha1.DependencyBuildStateSubmitBuild))
+		g.Expect(meta.FindStatusCondition(db.Status.Conditions, v1alpha1.DependencyBuildConditionSucceeded).Reason).Should(Equal(v1alpha1.DependencyBuildReasonRecipeFailed))
 		g.Expect(reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: buildName}))
 		db = getBuild(client, g)
 		g.Expect(db.Status.State).Should(Equal(v1alpha1.DependencyBuildStateFailed))
+		succeeded := meta.FindStatusCondition(db.Status.Conditions, v1alpha1.DependencyBuildConditionSucceeded)
+		g.Expect(succeeded.Status).Should(Equal(metav1.ConditionFalse))
+		g.Expect(succeeded.Reason).Should(Equal(v1alpha1.DependencyBuildReasonRecipesExhausted))
+		g.Expect(meta.FindStatusCondition(db.Status.Conditions, v1alpha1.DependencyBuildConditionRecipeSelected).Reason).Should(Equal(v1alpha1.DependencyBuildReasonRecipesExhausted))
 	})
 	t.Run("Test reconcile building DependencyBuild with OOMKilled Pipeline", func(t *testing.T) {
 		g := NewGomegaWithT(t)
@@ -383,7 +501,8 @@ func TestStateBuilding(t *testing.T) {
 		g.Expect(reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: taskRunName}))
 		db := getBuild(client, g)
 		g.Expect(db.Status.State).Should(Equal(v1alpha1.DependencyBuildStateSubmitBuild))
-		g.Expect(db.Status.CurrentBuildRecipe.AdditionalMemory).Should(Equal(MemoryIncrement))
+		g.Expect(db.Status.CurrentBuildRecipe.AdditionalMemory).Should(Equal(defaultMemoryIncrement))
+		g.Expect(meta.FindStatusCondition(db.Status.Conditions, v1alpha1.DependencyBuildConditionSucceeded).Reason).Should(Equal(v1alpha1.DependencyBuildReasonOOMRetry))
 		g.Expect(reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: db.Namespace, Name: db.Name}}))
 		g.Expect(reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: db.Namespace, Name: db.Name}}))
 
@@ -408,6 +527,15 @@ func TestStateBuilding(t *testing.T) {
 		g.Expect(reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: db.Namespace, Name: db.Name}}))
 
 		pr = getBuildPipelineNo(client, g, 2)
+		db = getBuild(client, g)
+		g.Expect(db.Status.FailedBuildRecipes).Should(BeEmpty())
+		g.Expect(db.Status.BuildAttempts).Should(HaveLen(3))
+		g.Expect(db.Status.BuildAttempts[0].Outcome).Should(Equal(v1alpha1.BuildAttemptOutcomeRetried))
+		g.Expect(db.Status.BuildAttempts[0].Cause).Should(Equal(v1alpha1.BuildFailureClassOOM))
+		g.Expect(db.Status.BuildAttempts[1].Recipe.AdditionalMemory).Should(Equal(defaultMemoryIncrement))
+		g.Expect(db.Status.BuildAttempts[2].PipelineRun).Should(Equal(pr.Name))
+		g.Expect(db.Status.BuildAttempts[2].Outcome).Should(Equal(v1alpha1.BuildAttemptOutcomeRunning))
+		g.Expect(db.Status.BuildAttempts[2].Recipe.AdditionalMemory).Should(Equal(1024))
 
 		found := false
 		for _, task := range pr.Spec.PipelineSpec.Tasks {
@@ -422,6 +550,346 @@ func TestStateBuilding(t *testing.T) {
 
 		g.Expect(found).Should(BeTrue())
 	})
+	timeOut := func(g *WithT, no int) {
+		pr := getBuildPipelineNo(client, g, no)
+		pr.Status.CompletionTime = &metav1.Time{Time: time.Now()}
+		pr.Status.SetCondition(&apis.Condition{
+			Type:               apis.ConditionSucceeded,
+			Status:             "False",
+			Reason:             pipelinev1beta1.PipelineRunReasonTimedOut.String(),
+			LastTransitionTime: apis.VolatileTime{Inner: metav1.Time{Time: time.Now()}},
+		})
+		g.Expect(client.Update(ctx, pr)).Should(BeNil())
+		g.Expect(reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: pr.Namespace, Name: pr.Name}}))
+	}
+	t.Run("Test reconcile building DependencyBuild with timed out pipeline", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		setup(g)
+		timeOut(g, 0)
+		db := getBuild(client, g)
+		g.Expect(db.Status.State).Should(Equal(v1alpha1.DependencyBuildStateSubmitBuild))
+		g.Expect(db.Status.PotentialBuildRecipes).Should(BeEmpty())
+		g.Expect(meta.FindStatusCondition(db.Status.Conditions, v1alpha1.DependencyBuildConditionSucceeded).Reason).Should(Equal(v1alpha1.DependencyBuildReasonPipelineRunTimeout))
+	})
+	t.Run("Test reconcile building DependencyBuild with timed out pipeline and longer timeout policy", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		setup(g)
+		jbsConfig := v1alpha1.JBSConfig{}
+		g.Expect(client.Get(ctx, types.NamespacedName{Namespace: metav1.NamespaceDefault, Name: v1alpha1.JBSConfigName}, &jbsConfig)).Should(Succeed())
+		jbsConfig.Spec.BuildSettings.PipelineTimeout = &metav1.Duration{Duration: time.Hour * 4}
+		jbsConfig.Spec.BuildSettings.MaxPipelineTimeout = &metav1.Duration{Duration: time.Hour * 6}
+		jbsConfig.Spec.BuildSettings.StepTimeout = &metav1.Duration{Duration: time.Hour}
+		jbsConfig.Spec.BuildSettings.TimeoutPolicy = v1alpha1.BuildTimeoutPolicyRetryWithLongerTimeout
+		jbsConfig.Spec.BuildSettings.RetryPolicy.InitialBackoff = &metav1.Duration{Duration: time.Minute}
+		g.Expect(client.Update(ctx, &jbsConfig)).Should(Succeed())
+
+		timeOut(g, 0)
+		db := getBuild(client, g)
+		g.Expect(db.Status.State).Should(Equal(v1alpha1.DependencyBuildStateSubmitBuild))
+		g.Expect(db.Status.CurrentBuildRecipe.PipelineTimeout.Duration).Should(Equal(time.Hour * 6))
+		g.Expect(meta.FindStatusCondition(db.Status.Conditions, v1alpha1.DependencyBuildConditionSucceeded).Reason).Should(Equal(v1alpha1.DependencyBuildReasonTimeoutRetry))
+		g.Expect(db.Status.RetriesByCause[v1alpha1.BuildFailureClassTimeout]).Should(Equal(1))
+		g.Expect(db.Status.PipelineRetries).Should(Equal(1))
+		g.Expect(db.Status.RetryAfter).ShouldNot(BeNil())
+
+		//the longer timeout is not tried until the backoff has passed
+		result, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: buildName})
+		g.Expect(err).Should(BeNil())
+		g.Expect(result.RequeueAfter).Should(BeNumerically(">", 0))
+		db = getBuild(client, g)
+		db.Status.RetryAfter = nil
+		g.Expect(client.Status().Update(ctx, db)).Should(Succeed())
+		g.Expect(reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: buildName}))
+		g.Expect(reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: buildName}))
+
+		pr := getBuildPipelineNo(client, g, 1)
+		g.Expect(pr.Spec.Timeout.Duration).Should(Equal(time.Hour * 6))
+		for _, task := range pr.Spec.PipelineSpec.Tasks {
+			for _, step := range task.TaskSpec.Steps {
+				g.Expect(step.Timeout.Duration).Should(Equal(time.Hour))
+			}
+		}
+
+		//the maximum timeout has been reached, so the next recipe is used
+		timeOut(g, 1)
+		db = getBuild(client, g)
+		g.Expect(meta.FindStatusCondition(db.Status.Conditions, v1alpha1.DependencyBuildConditionSucceeded).Reason).Should(Equal(v1alpha1.DependencyBuildReasonPipelineRunTimeout))
+		g.Expect(reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: buildName}))
+		db = getBuild(client, g)
+		g.Expect(db.Status.State).Should(Equal(v1alpha1.DependencyBuildStateFailed))
+	})
+	failStep := func(g *WithT, no int, step string) {
+		pr := getBuildPipelineNo(client, g, no)
+		pr.Status.CompletionTime = &metav1.Time{Time: time.Now()}
+		pr.Status.SetCondition(&apis.Condition{
+			Type:               apis.ConditionSucceeded,
+			Status:             "False",
+			LastTransitionTime: apis.VolatileTime{Inner: metav1.Time{Time: time.Now()}},
+		})
+		ts := &pipelinev1beta1.PipelineRunTaskRunStatus{Status: &pipelinev1beta1.TaskRunStatus{TaskRunStatusFields: pipelinev1beta1.TaskRunStatusFields{PodName: "test-pod", Steps: []pipelinev1beta1.StepState{{Name: step, ContainerState: v1.ContainerState{Terminated: &v1.ContainerStateTerminated{ExitCode: 1}}}}}}}
+		pr.Status.TaskRuns = map[string]*pipelinev1beta1.PipelineRunTaskRunStatus{"task": ts}
+		g.Expect(client.Update(ctx, pr)).Should(BeNil())
+		g.Expect(reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: pr.Namespace, Name: pr.Name}}))
+	}
+	t.Run("Test reconcile building DependencyBuild with git clone failure", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		setup(g)
+		db := getBuild(client, g)
+		db.Status.PotentialBuildRecipes = []*v1alpha1.BuildRecipe{{Image: "quay.io/redhat-appstudio/hacbs-jdk17-builder:latest"}}
+		g.Expect(client.Status().Update(ctx, db)).Should(Succeed())
+		for i := 0; i < defaultMaxRetries; i++ {
+			failStep(g, i, stepGitCloneAndSettings)
+			db = getBuild(client, g)
+			g.Expect(db.Status.State).Should(Equal(v1alpha1.DependencyBuildStateSubmitBuild))
+			g.Expect(meta.FindStatusCondition(db.Status.Conditions, v1alpha1.DependencyBuildConditionSucceeded).Reason).Should(Equal(v1alpha1.DependencyBuildReasonTransientFailureRetry))
+			g.Expect(reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: buildName}))
+			g.Expect(reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: buildName}))
+			db = getBuild(client, g)
+			//the same recipe is retried
+			g.Expect(db.Status.CurrentBuildRecipe.Image).Should(Equal("quay.io/redhat-appstudio/hacbs-jdk11-builder:latest"))
+		}
+		//once the retries are used up the other recipe is not tried
+		failStep(g, defaultMaxRetries, stepGitCloneAndSettings)
+		db = getBuild(client, g)
+		g.Expect(db.Status.State).Should(Equal(v1alpha1.DependencyBuildStateFailed))
+		g.Expect(meta.FindStatusCondition(db.Status.Conditions, v1alpha1.DependencyBuildConditionSucceeded).Reason).Should(Equal(v1alpha1.DependencyBuildReasonUnrecoverableFailure))
+		g.Expect(db.Status.BuildFailures).Should(HaveLen(defaultMaxRetries + 1))
+		g.Expect(db.Status.BuildFailures[0].Class).Should(Equal(v1alpha1.BuildFailureClassGitClone))
+		g.Expect(db.Status.BuildFailures[0].PipelineRun).Should(Equal("test-build-0"))
+		//every retry is a new attempt, but the recipe only failed once
+		g.Expect(db.Status.BuildAttempts).Should(HaveLen(defaultMaxRetries + 1))
+		for i, attempt := range db.Status.BuildAttempts {
+			g.Expect(attempt.PipelineRun).Should(Equal(fmt.Sprintf("test-build-%d", i)))
+			g.Expect(attempt.Cause).Should(Equal(v1alpha1.BuildFailureClassGitClone))
+			g.Expect(attempt.CompletionTime).ShouldNot(BeNil())
+			if i < defaultMaxRetries {
+				g.Expect(attempt.Outcome).Should(Equal(v1alpha1.BuildAttemptOutcomeRetried))
+			} else {
+				g.Expect(attempt.Outcome).Should(Equal(v1alpha1.BuildAttemptOutcomeFailed))
+			}
+		}
+		g.Expect(db.Status.BuildAttempts[1].StartTime).ShouldNot(BeNil())
+		g.Expect(db.Status.FailedBuildRecipes).Should(HaveLen(1))
+	})
+	t.Run("Test reconcile building DependencyBuild with compilation failure", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		setup(g)
+		reconciler.stepLogReader = fakeStepLogReader("[ERROR] COMPILATION ERROR :\n[ERROR] Foo.java:[10,5] cannot find symbol")
+		failStep(g, 0, stepBuild)
+		db := getBuild(client, g)
+		g.Expect(db.Status.State).Should(Equal(v1alpha1.DependencyBuildStateSubmitBuild))
+		g.Expect(meta.FindStatusCondition(db.Status.Conditions, v1alpha1.DependencyBuildConditionSucceeded).Reason).Should(Equal(v1alpha1.DependencyBuildReasonRecipeFailed))
+		g.Expect(db.Status.BuildFailures).Should(Equal([]v1alpha1.BuildFailure{{PipelineRun: "test-build-0", Class: v1alpha1.BuildFailureClassCompilation, Step: stepBuild, Message: "[ERROR] COMPILATION ERROR :"}}))
+	})
+	t.Run("Test reconcile building DependencyBuild with verification failure", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		setup(g)
+		db := getBuild(client, g)
+		db.Status.PotentialBuildRecipes = []*v1alpha1.BuildRecipe{{Image: "quay.io/redhat-appstudio/hacbs-jdk17-builder:latest"}}
+		g.Expect(client.Status().Update(ctx, db)).Should(Succeed())
+		reconciler.stepLogReader = fakeStepLogReader("ERROR Failed to instrument /deploy/test.jar")
+		failStep(g, 0, stepVerifyAndDeploy)
+		db = getBuild(client, g)
+		//the deploy step failed without a registry error, so the next recipe is tried
+		g.Expect(db.Status.State).Should(Equal(v1alpha1.DependencyBuildStateSubmitBuild))
+		g.Expect(meta.FindStatusCondition(db.Status.Conditions, v1alpha1.DependencyBuildConditionSucceeded).Reason).Should(Equal(v1alpha1.DependencyBuildReasonRecipeFailed))
+		g.Expect(db.Status.PotentialBuildRecipes).Should(HaveLen(1))
+		g.Expect(db.Status.BuildFailures[0].Class).Should(Equal(v1alpha1.BuildFailureClassUnknown))
+	})
+	t.Run("Test build logs are archived", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		setup(g)
+		archiver := fakeLogArchiver{}
+		reconciler.logArchiver = archiver
+		reconciler.stepLogReader = fakeStepLogReader("[INFO] BUILD FAILURE")
+		secret := v1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: metav1.NamespaceDefault, Name: v1alpha1.ImageSecretName}, Data: map[string][]byte{v1alpha1.ImageSecretTokenKey: []byte("{}")}}
+		g.Expect(client.Create(ctx, &secret)).Should(Succeed())
+		failStep(g, 0, stepBuild)
+		db := getBuild(client, g)
+		ref := "quay.io/hacbs/artifact-deployments:test-build-0-logs"
+		g.Expect(db.Status.BuildAttempts[0].LogArchive).Should(Equal(ref + "@sha256:12345"))
+		g.Expect(archiver[ref]).Should(HaveLen(1))
+		for _, logs := range archiver[ref] {
+			g.Expect(logs).Should(Equal("[INFO] BUILD FAILURE"))
+		}
+		//a retried reconcile uses the archive recorded on the pipeline rather than pushing the logs again
+		pr := getBuildPipelineNo(client, g, 0)
+		g.Expect(pr.Annotations[LogArchiveAnnotation]).Should(Equal(ref + "@sha256:12345"))
+		again := fakeLogArchiver{}
+		reconciler.logArchiver = again
+		g.Expect(reconciler.archivePipelineLogs(ctx, logr.Discard(), db, pr)).Should(Equal(ref + "@sha256:12345"))
+		g.Expect(again).Should(BeEmpty())
+	})
+	t.Run("Test the logs of a deleted pipeline are archived before the finalizer is removed", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		setup(g)
+		archiver := fakeLogArchiver{}
+		reconciler.logArchiver = archiver
+		pr := getBuildPipelineNo(client, g, 0)
+		reconciler.stepLogReader = pipelineStepLogReader{client: client, pipeline: types.NamespacedName{Namespace: pr.Namespace, Name: pr.Name}, logs: "[INFO] BUILD FAILURE"}
+		secret := v1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: metav1.NamespaceDefault, Name: v1alpha1.ImageSecretName}, Data: map[string][]byte{v1alpha1.ImageSecretTokenKey: []byte("{}")}}
+		g.Expect(client.Create(ctx, &secret)).Should(Succeed())
+		g.Expect(client.Delete(ctx, pr)).Should(Succeed())
+		failStep(g, 0, stepBuild)
+		db := getBuild(client, g)
+		ref := "quay.io/hacbs/artifact-deployments:test-build-0-logs"
+		g.Expect(db.Status.BuildAttempts[0].LogArchive).Should(Equal(ref + "@sha256:12345"))
+		g.Expect(archiver[ref]).Should(HaveLen(1))
+		//once the finalizer is removed the pipeline is gone
+		g.Expect(errors.IsNotFound(client.Get(ctx, types.NamespacedName{Namespace: pr.Namespace, Name: pr.Name}, pr))).Should(BeTrue())
+	})
+	t.Run("Test build logs are not archived if it is disabled", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		setup(g)
+		archiver := fakeLogArchiver{}
+		reconciler.logArchiver = archiver
+		reconciler.stepLogReader = fakeStepLogReader("[INFO] BUILD FAILURE")
+		secret := v1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: metav1.NamespaceDefault, Name: v1alpha1.ImageSecretName}, Data: map[string][]byte{v1alpha1.ImageSecretTokenKey: []byte("{}")}}
+		g.Expect(client.Create(ctx, &secret)).Should(Succeed())
+		jbsConfig := v1alpha1.JBSConfig{}
+		g.Expect(client.Get(ctx, types.NamespacedName{Namespace: metav1.NamespaceDefault, Name: v1alpha1.JBSConfigName}, &jbsConfig)).Should(Succeed())
+		jbsConfig.Spec.BuildSettings.DisableLogArchive = true
+		g.Expect(client.Update(ctx, &jbsConfig)).Should(Succeed())
+		failStep(g, 0, stepBuild)
+		db := getBuild(client, g)
+		g.Expect(db.Status.BuildAttempts[0].LogArchive).Should(BeEmpty())
+		g.Expect(archiver).Should(BeEmpty())
+	})
+	t.Run("Test the SBOM is published for a successful build", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		setup(g)
+		publisher := fakeReferrerPublisher{}
+		reconciler.referrerPublisher = publisher
+		secret := v1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: metav1.NamespaceDefault, Name: v1alpha1.ImageSecretName}, Data: map[string][]byte{v1alpha1.ImageSecretTokenKey: []byte("{}")}}
+		g.Expect(client.Create(ctx, &secret)).Should(Succeed())
+		pr := getBuildPipeline(client, g)
+		pr.Status.CompletionTime = &metav1.Time{Time: time.Now()}
+		pr.Status.SetCondition(&apis.Condition{
+			Type:               apis.ConditionSucceeded,
+			Status:             "True",
+			LastTransitionTime: apis.VolatileTime{Inner: metav1.Time{Time: time.Now()}},
+		})
+		pr.Status.PipelineResults = []pipelinev1beta1.PipelineRunResult{
+			{Name: artifactbuild.PipelineResultDeployedResources, Value: pipelinev1beta1.ResultValue{Type: pipelinev1beta1.ParamTypeString, StringVal: TestArtifact}},
+			{Name: PipelineResultImage, Value: pipelinev1beta1.ResultValue{Type: pipelinev1beta1.ParamTypeString, StringVal: "quay.io/hacbs/artifact-deployments:test"}},
+			{Name: PipelineResultImageDigest, Value: pipelinev1beta1.ResultValue{Type: pipelinev1beta1.ParamTypeString, StringVal: "sha256:12345"}},
+		}
+		g.Expect(client.Update(ctx, pr)).Should(BeNil())
+		g.Expect(reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: taskRunName}))
+		subject := "quay.io/hacbs/artifact-deployments@sha256:12345"
+		g.Expect(publisher[SBOMArtifactType]).Should(HaveKey(subject))
+		//there is no signing key, so no provenance
+		g.Expect(publisher).ShouldNot(HaveKey(ProvenanceArtifactType))
+		ra := v1alpha1.RebuiltArtifact{}
+		g.Expect(client.Get(ctx, types.NamespacedName{Name: artifactbuild.CreateABRName(TestArtifact), Namespace: metav1.NamespaceDefault}, &ra)).Should(Succeed())
+		g.Expect(ra.Status.SBOM).Should(Equal("quay.io/hacbs/artifact-deployments@sha256:sbom"))
+		g.Expect(ra.Status.Provenance).Should(BeEmpty())
+	})
+	t.Run("Test the SBOM is not published if the status update fails", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		setup(g)
+		publisher := fakeReferrerPublisher{}
+		reconciler.referrerPublisher = publisher
+		reconciler.client = conflictingStatusClient{Client: client}
+		secret := v1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: metav1.NamespaceDefault, Name: v1alpha1.ImageSecretName}, Data: map[string][]byte{v1alpha1.ImageSecretTokenKey: []byte("{}")}}
+		g.Expect(client.Create(ctx, &secret)).Should(Succeed())
+		pr := getBuildPipeline(client, g)
+		pr.Status.CompletionTime = &metav1.Time{Time: time.Now()}
+		pr.Status.SetCondition(&apis.Condition{
+			Type:               apis.ConditionSucceeded,
+			Status:             "True",
+			LastTransitionTime: apis.VolatileTime{Inner: metav1.Time{Time: time.Now()}},
+		})
+		pr.Status.PipelineResults = []pipelinev1beta1.PipelineRunResult{
+			{Name: artifactbuild.PipelineResultDeployedResources, Value: pipelinev1beta1.ResultValue{Type: pipelinev1beta1.ParamTypeString, StringVal: TestArtifact}},
+			{Name: PipelineResultImage, Value: pipelinev1beta1.ResultValue{Type: pipelinev1beta1.ParamTypeString, StringVal: "quay.io/hacbs/artifact-deployments:test"}},
+			{Name: PipelineResultImageDigest, Value: pipelinev1beta1.ResultValue{Type: pipelinev1beta1.ParamTypeString, StringVal: "sha256:12345"}},
+		}
+		g.Expect(client.Update(ctx, pr)).Should(BeNil())
+		_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: taskRunName})
+		g.Expect(errors.IsConflict(err)).Should(BeTrue())
+		//it is published by the reconcile that is retried
+		g.Expect(publisher).Should(BeEmpty())
+	})
+	t.Run("Test signed provenance is published for a successful build", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		setup(g)
+		publisher := fakeReferrerPublisher{}
+		reconciler.referrerPublisher = publisher
+		secret := v1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: metav1.NamespaceDefault, Name: v1alpha1.ImageSecretName}, Data: map[string][]byte{v1alpha1.ImageSecretTokenKey: []byte("{}")}}
+		g.Expect(client.Create(ctx, &secret)).Should(Succeed())
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		g.Expect(err).Should(Succeed())
+		der, err := x509.MarshalPKCS8PrivateKey(key)
+		g.Expect(err).Should(Succeed())
+		signingSecret := v1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: v1alpha1.ControllerNamespace, Name: "provenance-key"}, Data: map[string][]byte{DefaultSigningKeySecretKey: pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})}}
+		g.Expect(client.Create(ctx, &signingSecret)).Should(Succeed())
+		sysConfig := v1alpha1.SystemConfig{}
+		g.Expect(client.Get(ctx, types.NamespacedName{Name: systemconfig.SystemConfigKey}, &sysConfig)).Should(Succeed())
+		sysConfig.Spec.Provenance.SigningKeySecret = "provenance-key"
+		g.Expect(client.Update(ctx, &sysConfig)).Should(Succeed())
+		pr := getBuildPipeline(client, g)
+		pr.Status.StartTime = &metav1.Time{Time: time.Now().Add(-time.Minute)}
+		pr.Status.CompletionTime = &metav1.Time{Time: time.Now()}
+		pr.Status.SetCondition(&apis.Condition{
+			Type:               apis.ConditionSucceeded,
+			Status:             "True",
+			LastTransitionTime: apis.VolatileTime{Inner: metav1.Time{Time: time.Now()}},
+		})
+		pr.Status.PipelineResults = []pipelinev1beta1.PipelineRunResult{
+			{Name: artifactbuild.PipelineResultDeployedResources, Value: pipelinev1beta1.ResultValue{Type: pipelinev1beta1.ParamTypeString, StringVal: TestArtifact}},
+			{Name: PipelineResultImage, Value: pipelinev1beta1.ResultValue{Type: pipelinev1beta1.ParamTypeString, StringVal: "quay.io/hacbs/artifact-deployments:test"}},
+			{Name: PipelineResultImageDigest, Value: pipelinev1beta1.ResultValue{Type: pipelinev1beta1.ParamTypeString, StringVal: "sha256:12345"}},
+		}
+		g.Expect(client.Update(ctx, pr)).Should(BeNil())
+		g.Expect(reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: taskRunName}))
+		subject := "quay.io/hacbs/artifact-deployments@sha256:12345"
+		g.Expect(publisher[ProvenanceArtifactType]).Should(HaveKey(subject))
+		envelope := DSSEEnvelope{}
+		g.Expect(json.Unmarshal(publisher[ProvenanceArtifactType][subject], &envelope)).Should(Succeed())
+		g.Expect(envelope.PayloadType).Should(Equal(InTotoPayloadType))
+		g.Expect(envelope.Signatures).Should(HaveLen(1))
+		payload, err := base64.StdEncoding.DecodeString(envelope.Payload)
+		g.Expect(err).Should(Succeed())
+		sig, err := base64.StdEncoding.DecodeString(envelope.Signatures[0].Sig)
+		g.Expect(err).Should(Succeed())
+		hash := sha256.Sum256([]byte(fmt.Sprintf("DSSEv1 %d %s %d %s", len(InTotoPayloadType), InTotoPayloadType, len(payload), payload)))
+		g.Expect(ecdsa.VerifyASN1(&key.PublicKey, hash[:], sig)).Should(BeTrue())
+		statement := InTotoStatement{}
+		g.Expect(json.Unmarshal(payload, &statement)).Should(Succeed())
+		g.Expect(statement.Subject).Should(Equal([]ProvenanceSubject{{Name: "quay.io/hacbs/artifact-deployments", Digest: map[string]string{"sha256": "12345"}}}))
+		g.Expect(statement.Predicate.BuildConfig.PipelineRun).Should(Equal("test-build-0"))
+		g.Expect(statement.Predicate.Metadata.BuildStartedOn).ShouldNot(BeNil())
+		ra := v1alpha1.RebuiltArtifact{}
+		g.Expect(client.Get(ctx, types.NamespacedName{Name: artifactbuild.CreateABRName(TestArtifact), Namespace: metav1.NamespaceDefault}, &ra)).Should(Succeed())
+		g.Expect(ra.Status.Provenance).Should(Equal("quay.io/hacbs/artifact-deployments@sha256:att"))
+	})
+	t.Run("Test the SBOM is not published without registry credentials", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		setup(g)
+		publisher := fakeReferrerPublisher{}
+		reconciler.referrerPublisher = publisher
+		pr := getBuildPipeline(client, g)
+		pr.Status.CompletionTime = &metav1.Time{Time: time.Now()}
+		pr.Status.SetCondition(&apis.Condition{
+			Type:               apis.ConditionSucceeded,
+			Status:             "True",
+			LastTransitionTime: apis.VolatileTime{Inner: metav1.Time{Time: time.Now()}},
+		})
+		pr.Status.PipelineResults = []pipelinev1beta1.PipelineRunResult{
+			{Name: artifactbuild.PipelineResultDeployedResources, Value: pipelinev1beta1.ResultValue{Type: pipelinev1beta1.ParamTypeString, StringVal: TestArtifact}},
+			{Name: PipelineResultImage, Value: pipelinev1beta1.ResultValue{Type: pipelinev1beta1.ParamTypeString, StringVal: "quay.io/hacbs/artifact-deployments:test"}},
+			{Name: PipelineResultImageDigest, Value: pipelinev1beta1.ResultValue{Type: pipelinev1beta1.ParamTypeString, StringVal: "sha256:12345"}},
+		}
+		g.Expect(client.Update(ctx, pr)).Should(BeNil())
+		g.Expect(reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: taskRunName}))
+		g.Expect(publisher).Should(BeEmpty())
+		db := getBuild(client, g)
+		g.Expect(db.Status.State).Should(Equal(v1alpha1.DependencyBuildStateComplete))
+		ra := v1alpha1.RebuiltArtifact{}
+		g.Expect(client.Get(ctx, types.NamespacedName{Name: artifactbuild.CreateABRName(TestArtifact), Namespace: metav1.NamespaceDefault}, &ra)).Should(Succeed())
+		g.Expect(ra.Status.SBOM).Should(BeEmpty())
+	})
 	t.Run("Test reconcile building DependencyBuild with contaminants", func(t *testing.T) {
 		g := NewGomegaWithT(t)
 		setup(g)
@@ -448,8 +916,145 @@ func TestStateBuilding(t *testing.T) {
 		g.Expect(reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: buildName}))
 		db = getBuild(client, g)
 		g.Expect(db.Status.State).Should(Equal(v1alpha1.DependencyBuildStateContaminated))
+		g.Expect(meta.IsStatusConditionTrue(db.Status.Conditions, v1alpha1.DependencyBuildConditionContaminated)).Should(BeTrue())
+	})
+
+}
+
+func TestStateQueued(t *testing.T) {
+	ctx := context.TODO()
+
+	var client runtimeclient.Client
+	var reconciler *ReconcileDependencyBuild
+	buildName := types.NamespacedName{Namespace: metav1.NamespaceDefault, Name: "test"}
+	createBuild := func(g *WithT, namespace string, name string, state string, queuedAt time.Time) {
+		db := v1alpha1.DependencyBuild{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name}}
+		g.Expect(client.Create(ctx, &db)).Should(Succeed())
+		db.Status.State = state
+		if state == v1alpha1.DependencyBuildStateQueued {
+			db.Status.Queue = &v1alpha1.BuildQueueStatus{Since: metav1.NewTime(queuedAt)}
+		}
+		g.Expect(client.Status().Update(ctx, &db)).Should(Succeed())
+	}
+	setup := func(g *WithT, namespaceLimit int, clusterLimit int) {
+		client, reconciler = setupClientAndReconciler()
+		jbsConfig := v1alpha1.JBSConfig{}
+		g.Expect(client.Get(ctx, types.NamespacedName{Namespace: metav1.NamespaceDefault, Name: v1alpha1.JBSConfigName}, &jbsConfig)).Should(Succeed())
+		jbsConfig.Spec.BuildSettings.MaxConcurrentBuilds = namespaceLimit
+		g.Expect(client.Update(ctx, &jbsConfig)).Should(Succeed())
+		sysConfig := v1alpha1.SystemConfig{}
+		g.Expect(client.Get(ctx, types.NamespacedName{Name: systemconfig.SystemConfigKey}, &sysConfig)).Should(Succeed())
+		sysConfig.Spec.BuildQueue.MaxConcurrentBuilds = clusterLimit
+		g.Expect(client.Update(ctx, &sysConfig)).Should(Succeed())
+
+		db := v1alpha1.DependencyBuild{ObjectMeta: metav1.ObjectMeta{Namespace: metav1.NamespaceDefault, Name: "test"}}
+		db.Spec.ScmInfo.SCMURL = "some-url"
+		db.Spec.ScmInfo.Tag = "some-tag"
+		db.Labels = map[string]string{artifactbuild.DependencyBuildIdLabel: hashToString(db.Spec.ScmInfo.SCMURL + db.Spec.ScmInfo.Tag + db.Spec.ScmInfo.Path)}
+		g.Expect(client.Create(ctx, &db)).Should(Succeed())
+		db.Status.State = v1alpha1.DependencyBuildStateSubmitBuild
+		db.Status.PotentialBuildRecipes = []*v1alpha1.BuildRecipe{{Image: "quay.io/redhat-appstudio/hacbs-jdk11-builder:latest", Tool: "maven", AdditionalMemory: 512}}
+		g.Expect(client.Status().Update(ctx, &db)).Should(Succeed())
+	}
+	expectQueued := func(g *WithT, position int, reason string) {
+		db := getBuild(client, g)
+		g.Expect(db.Status.State).Should(Equal(v1alpha1.DependencyBuildStateQueued))
+		g.Expect(db.Status.Queue).ShouldNot(BeNil())
+		g.Expect(db.Status.Queue.Position).Should(Equal(position))
+		g.Expect(db.Status.Queue.Reason).Should(Equal(reason))
+		g.Expect(meta.FindStatusCondition(db.Status.Conditions, v1alpha1.DependencyBuildConditionPipelineSubmitted).Reason).Should(Equal(reason))
+	}
+
+	t.Run("Test build is admitted without limits", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		setup(g, 0, 0)
+		createBuild(g, metav1.NamespaceDefault, "running", v1alpha1.DependencyBuildStateBuilding, time.Now())
+		g.Expect(reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: buildName}))
+		db := getBuild(client, g)
+		g.Expect(db.Status.State).Should(Equal(v1alpha1.DependencyBuildStateBuilding))
+		g.Expect(db.Status.Queue).Should(BeNil())
+	})
+	t.Run("Test build is admitted without a SystemConfig", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		setup(g, 0, 0)
+		g.Expect(client.Delete(ctx, &v1alpha1.SystemConfig{ObjectMeta: metav1.ObjectMeta{Name: systemconfig.SystemConfigKey}})).Should(Succeed())
+		_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: buildName})
+		g.Expect(err).Should(BeNil())
+		db := getBuild(client, g)
+		g.Expect(db.Status.State).Should(Equal(v1alpha1.DependencyBuildStateBuilding))
+		g.Expect(db.Status.Queue).Should(BeNil())
+	})
+	t.Run("Test namespace limit", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		setup(g, 1, 0)
+		createBuild(g, metav1.NamespaceDefault, "running", v1alpha1.DependencyBuildStateBuilding, time.Now())
+		result, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: buildName})
+		g.Expect(err).Should(BeNil())
+		g.Expect(result.RequeueAfter).Should(Equal(queuedBuildRequeueInterval))
+		expectQueued(g, 1, v1alpha1.DependencyBuildReasonNamespaceConcurrencyLimit)
+
+		running := v1alpha1.DependencyBuild{}
+		g.Expect(client.Get(ctx, types.NamespacedName{Namespace: metav1.NamespaceDefault, Name: "running"}, &running)).Should(Succeed())
+		running.Status.State = v1alpha1.DependencyBuildStateComplete
+		g.Expect(client.Status().Update(ctx, &running)).Should(Succeed())
+		g.Expect(reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: buildName}))
+		db := getBuild(client, g)
+		g.Expect(db.Status.State).Should(Equal(v1alpha1.DependencyBuildStateBuilding))
+		g.Expect(db.Status.Queue).Should(BeNil())
+	})
+	t.Run("Test builds admitted before the cache is updated count as running", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		setup(g, 1, 0)
+		createBuild(g, metav1.NamespaceDefault, "admitted", v1alpha1.DependencyBuildStateQueued, time.Now().Add(time.Minute))
+		admitted := v1alpha1.DependencyBuild{}
+		g.Expect(client.Get(ctx, types.NamespacedName{Namespace: metav1.NamespaceDefault, Name: "admitted"}, &admitted)).Should(Succeed())
+		//the cache still has the queued version of a build that was just admitted
+		reconciler.admitted.add(&admitted, admitted.ResourceVersion)
+		g.Expect(reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: buildName}))
+		expectQueued(g, 1, v1alpha1.DependencyBuildReasonNamespaceConcurrencyLimit)
+
+		//once the cache shows another version it is counted from its state
+		admitted.Status.State = v1alpha1.DependencyBuildStateComplete
+		g.Expect(client.Status().Update(ctx, &admitted)).Should(Succeed())
+		g.Expect(reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: buildName}))
+		g.Expect(getBuild(client, g).Status.State).Should(Equal(v1alpha1.DependencyBuildStateBuilding))
+	})
+	t.Run("Test builds are admitted in queue order", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		setup(g, 1, 0)
+		createBuild(g, metav1.NamespaceDefault, "earlier", v1alpha1.DependencyBuildStateQueued, time.Now().Add(-time.Minute))
+		g.Expect(reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: buildName}))
+		expectQueued(g, 2, v1alpha1.DependencyBuildReasonWaitingForEarlierBuilds)
+	})
+	t.Run("Test fair share of the cluster limit", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		setup(g, 0, 2)
+		createBuild(g, metav1.NamespaceDefault, "running", v1alpha1.DependencyBuildStateBuilding, time.Now())
+		createBuild(g, "other", "waiting", v1alpha1.DependencyBuildStateQueued, time.Now())
+		g.Expect(reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: buildName}))
+		expectQueued(g, 1, v1alpha1.DependencyBuildReasonFairShareExceeded)
+
+		createBuild(g, "other", "running", v1alpha1.DependencyBuildStateBuilding, time.Now())
+		g.Expect(reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: buildName}))
+		expectQueued(g, 1, v1alpha1.DependencyBuildReasonClusterConcurrencyLimit)
 	})
+	t.Run("Test resource quota headroom", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		setup(g, 0, 0)
+		quota := v1.ResourceQuota{ObjectMeta: metav1.ObjectMeta{Namespace: metav1.NamespaceDefault, Name: "quota"}}
+		g.Expect(client.Create(ctx, &quota)).Should(Succeed())
+		quota.Status.Hard = v1.ResourceList{v1.ResourceRequestsMemory: resource.MustParse("4Gi")}
+		quota.Status.Used = v1.ResourceList{v1.ResourceRequestsMemory: resource.MustParse("3Gi")}
+		g.Expect(client.Status().Update(ctx, &quota)).Should(Succeed())
+		//the build needs 1024Mi plus the 512Mi from the recipe
+		g.Expect(reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: buildName}))
+		expectQueued(g, 1, v1alpha1.DependencyBuildReasonInsufficientResourceQuota)
 
+		quota.Status.Used = v1.ResourceList{v1.ResourceRequestsMemory: resource.MustParse("2Gi")}
+		g.Expect(client.Status().Update(ctx, &quota)).Should(Succeed())
+		g.Expect(reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: buildName}))
+		g.Expect(getBuild(client, g).Status.State).Should(Equal(v1alpha1.DependencyBuildStateBuilding))
+	})
 }
 
 func TestStateDependencyBuildStateAnalyzeBuild(t *testing.T) {
@@ -543,4 +1148,524 @@ func TestStateDependencyBuildStateAnalyzeBuild(t *testing.T) {
 		g.Expect(find11).To(BeTrue())
 		g.Expect(find8).To(BeTrue())
 	})
+
+	t.Run("Test build recipes are ranked by preferred JDK and build history", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		setup(g)
+		previous := v1alpha1.DependencyBuild{ObjectMeta: metav1.ObjectMeta{Name: "previous", Namespace: metav1.NamespaceDefault}}
+		g.Expect(client.Create(ctx, &previous)).Should(Succeed())
+		previous.Status.State = v1alpha1.DependencyBuildStateComplete
+		previous.Status.CurrentBuildRecipe = &v1alpha1.BuildRecipe{Image: "quay.io/redhat-appstudio/hacbs-jdk17-builder:latest", Tool: "gradle"}
+		previous.Status.FailedBuildRecipes = []*v1alpha1.BuildRecipe{
+			{Image: "quay.io/redhat-appstudio/hacbs-jdk8-builder:latest", Tool: "gradle"},
+			{Image: "quay.io/redhat-appstudio/hacbs-jdk8-builder:latest", Tool: "gradle"},
+		}
+		g.Expect(client.Status().Update(ctx, &previous)).Should(Succeed())
+		buildInfoJson, err := json.Marshal(marshalledBuildInfo{ToolVersion: "7.4", Tools: map[string]toolInfo{"gradle": {}, "jdk": {Min: "8", Max: "17", Preferred: "11"}}, Invocations: [][]string{{"gradle", "build"}, {"gradle", "build"}}})
+		g.Expect(err).Should(BeNil())
+		pr := getBuildInfoPipeline(client, g)
+		pr.Status.CompletionTime = &metav1.Time{Time: time.Now()}
+		pr.Status.PipelineResults = []pipelinev1beta1.PipelineRunResult{{Name: BuildInfoPipelineResultBuildInfo, Value: pipelinev1beta1.ResultValue{Type: pipelinev1beta1.ParamTypeString, StringVal: string(buildInfoJson)}}}
+		pr.Status.SetCondition(&apis.Condition{
+			Type:               apis.ConditionSucceeded,
+			Status:             "True",
+			LastTransitionTime: apis.VolatileTime{Inner: metav1.Time{Time: time.Now()}},
+		})
+		g.Expect(client.Status().Update(ctx, pr)).Should(BeNil())
+		g.Expect(reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: taskRunName}))
+
+		db := getBuild(client, g)
+		g.Expect(db.Status.State).Should(Equal(v1alpha1.DependencyBuildStateSubmitBuild))
+		//the duplicate invocation is pruned
+		g.Expect(len(db.Status.PotentialBuildRecipes)).Should(Equal(3))
+		g.Expect(db.Status.PotentialBuildRecipes[0].JavaVersion).Should(Equal("11"))
+		g.Expect(db.Status.PotentialBuildRecipes[0].ToolVersion).Should(Equal("7.4.2"))
+		//JDK 8 is closer to the preferred version, but has failed before
+		g.Expect(db.Status.PotentialBuildRecipes[1].JavaVersion).Should(Equal("17"))
+		g.Expect(db.Status.PotentialBuildRecipes[2].JavaVersion).Should(Equal("8"))
+	})
+
+	t.Run("Test maven version is selected from the builder image", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		setup(g)
+		buildInfoJson, err := json.Marshal(marshalledBuildInfo{Tools: map[string]toolInfo{"maven": {Min: "3.6.3", Max: "3.6.3", Preferred: "3.6.3"}, "jdk": {Min: "8", Max: "17", Preferred: "11"}}, Invocations: [][]string{{"maven", "install"}}})
+		g.Expect(err).Should(BeNil())
+		pr := getBuildInfoPipeline(client, g)
+		pr.Status.CompletionTime = &metav1.Time{Time: time.Now()}
+		pr.Status.PipelineResults = []pipelinev1beta1.PipelineRunResult{{Name: BuildInfoPipelineResultBuildInfo, Value: pipelinev1beta1.ResultValue{Type: pipelinev1beta1.ParamTypeString, StringVal: string(buildInfoJson)}}}
+		pr.Status.SetCondition(&apis.Condition{
+			Type:               apis.ConditionSucceeded,
+			Status:             "True",
+			LastTransitionTime: apis.VolatileTime{Inner: metav1.Time{Time: time.Now()}},
+		})
+		g.Expect(client.Status().Update(ctx, pr)).Should(BeNil())
+		g.Expect(reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: taskRunName}))
+
+		db := getBuild(client, g)
+		g.Expect(db.Status.State).Should(Equal(v1alpha1.DependencyBuildStateSubmitBuild))
+		g.Expect(len(db.Status.PotentialBuildRecipes)).Should(Equal(3))
+		g.Expect(db.Status.PotentialBuildRecipes[0].JavaVersion).Should(Equal("11"))
+		g.Expect(db.Status.PotentialBuildRecipes[0].ToolVersion).Should(Equal("3.6.3"))
+		//the other images don't have the requested version, so they fall back to the version they have
+		g.Expect(db.Status.PotentialBuildRecipes[1].ToolVersion).Should(Equal("3.8"))
+		g.Expect(db.Status.PotentialBuildRecipes[2].ToolVersion).Should(Equal("3.8"))
+	})
+
+	t.Run("Test build recipe overrides are applied", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		setup(g)
+		disableSubmodules := true
+		g.Expect(client.Create(ctx, &v1alpha1.BuildRecipeOverride{
+			ObjectMeta: metav1.ObjectMeta{Name: "jdk17", Namespace: metav1.NamespaceDefault},
+			Spec: v1alpha1.BuildRecipeOverrideSpec{
+				Match:             v1alpha1.BuildRecipeOverrideMatch{SCMURL: "some-url.git", TagPattern: "some-.*"},
+				JavaVersion:       "17",
+				PreBuildScript:    "echo pre",
+				Repositories:      []string{"jboss", "confluent"},
+				DisableSubmodules: &disableSubmodules,
+				StepTimeout:       &metav1.Duration{Duration: time.Hour},
+				AdditionalDownloads: []v1alpha1.AdditionalDownload{
+					{FileType: v1alpha1.AdditionalDownloadTypeRpm, PackageName: "glibc-devel"},
+				},
+			},
+		})).Should(Succeed())
+		g.Expect(client.Create(ctx, &v1alpha1.BuildRecipeOverride{
+			ObjectMeta: metav1.ObjectMeta{Name: "other-tag", Namespace: metav1.NamespaceDefault},
+			Spec: v1alpha1.BuildRecipeOverrideSpec{
+				Match:          v1alpha1.BuildRecipeOverrideMatch{TagPattern: "other-.*"},
+				PreBuildScript: "echo other",
+			},
+		})).Should(Succeed())
+		buildInfoJson, err := json.Marshal(marshalledBuildInfo{ToolVersion: "7.4", Tools: map[string]toolInfo{"gradle": {}, "jdk": {Min: "8", Max: "11"}}, Invocations: [][]string{{"gradle", "build"}}, Repositories: []string{"jboss"}, Timeout: "5h", StepTimeout: "2h"})
+		g.Expect(err).Should(BeNil())
+		pr := getBuildInfoPipeline(client, g)
+		pr.Status.CompletionTime = &metav1.Time{Time: time.Now()}
+		pr.Status.PipelineResults = []pipelinev1beta1.PipelineRunResult{{Name: BuildInfoPipelineResultBuildInfo, Value: pipelinev1beta1.ResultValue{Type: pipelinev1beta1.ParamTypeString, StringVal: string(buildInfoJson)}}}
+		pr.Status.SetCondition(&apis.Condition{
+			Type:               apis.ConditionSucceeded,
+			Status:             "True",
+			LastTransitionTime: apis.VolatileTime{Inner: metav1.Time{Time: time.Now()}},
+		})
+		g.Expect(client.Status().Update(ctx, pr)).Should(BeNil())
+		g.Expect(reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: taskRunName}))
+
+		db := getBuild(client, g)
+		g.Expect(db.Status.State).Should(Equal(v1alpha1.DependencyBuildStateSubmitBuild))
+		g.Expect(db.Status.AppliedBuildRecipeOverrides).Should(Equal([]string{"jdk17"}))
+		g.Expect(len(db.Status.PotentialBuildRecipes)).Should(Equal(1))
+		recipe := db.Status.PotentialBuildRecipes[0]
+		g.Expect(recipe.Image).Should(Equal("quay.io/redhat-appstudio/hacbs-jdk17-builder:latest"))
+		g.Expect(recipe.JavaVersion).Should(Equal("17"))
+		g.Expect(recipe.PreBuildScript).Should(Equal("echo pre"))
+		g.Expect(recipe.Repositories).Should(Equal([]string{"jboss", "confluent"}))
+		g.Expect(recipe.DisableSubmodules).Should(BeTrue())
+		g.Expect(recipe.AdditionalDownloads).Should(HaveLen(1))
+		g.Expect(recipe.CommandLine).Should(Equal([]string{"build"}))
+		g.Expect(recipe.PipelineTimeout.Duration).Should(Equal(time.Hour * 5))
+		g.Expect(recipe.StepTimeout.Duration).Should(Equal(time.Hour))
+	})
+	t.Run("Test build info with an invalid additional download fails the build", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		setup(g)
+		buildInfoJson, err := json.Marshal(marshalledBuildInfo{ToolVersion: "7.4", Tools: map[string]toolInfo{"gradle": {}, "jdk": {Min: "8", Max: "17"}}, Invocations: [][]string{{"gradle", "build"}}, AdditionalDownloads: []v1alpha1.AdditionalDownload{{FileType: "zip", Uri: "https://example.com/tool.zip"}}})
+		g.Expect(err).Should(BeNil())
+		pr := getBuildInfoPipeline(client, g)
+		pr.Status.CompletionTime = &metav1.Time{Time: time.Now()}
+		pr.Status.PipelineResults = []pipelinev1beta1.PipelineRunResult{{Name: BuildInfoPipelineResultBuildInfo, Value: pipelinev1beta1.ResultValue{Type: pipelinev1beta1.ParamTypeString, StringVal: string(buildInfoJson)}}}
+		pr.Status.SetCondition(&apis.Condition{
+			Type:               apis.ConditionSucceeded,
+			Status:             "True",
+			LastTransitionTime: apis.VolatileTime{Inner: metav1.Time{Time: time.Now()}},
+		})
+		g.Expect(client.Status().Update(ctx, pr)).Should(BeNil())
+		g.Expect(reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: taskRunName}))
+
+		db := getBuild(client, g)
+		g.Expect(db.Status.State).Should(Equal(v1alpha1.DependencyBuildStateFailed))
+		g.Expect(db.Status.PotentialBuildRecipes).Should(BeEmpty())
+		condition := meta.FindStatusCondition(db.Status.Conditions, v1alpha1.DependencyBuildConditionBuildInfoAnalyzed)
+		g.Expect(condition.Reason).Should(Equal(v1alpha1.DependencyBuildReasonInvalidBuildInfo))
+		g.Expect(condition.Message).Should(ContainSubstring("zip"))
+	})
+}
+
+func TestMavenVersionsInRange(t *testing.T) {
+	versions := []string{"3.8", "3.6.3", "3.3.9"}
+	inRange := func(g *WithT, requested toolInfo) []string {
+		ret, err := mavenVersionsInRange(versions, requested)
+		g.Expect(err).Should(BeNil())
+		return ret
+	}
+	t.Run("Test min and max", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		g.Expect(inRange(g, toolInfo{Min: "3.8", Max: "3.8"})).Should(Equal([]string{"3.8"}))
+		g.Expect(inRange(g, toolInfo{Min: "3.5"})).Should(Equal([]string{"3.8", "3.6.3"}))
+		g.Expect(inRange(g, toolInfo{Max: "3.6"})).Should(Equal([]string{"3.6.3", "3.3.9"}))
+		g.Expect(inRange(g, toolInfo{})).Should(Equal(versions))
+		g.Expect(inRange(g, toolInfo{Min: "4.0"})).Should(BeEmpty())
+	})
+	t.Run("Test maven version ranges", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		//the exclusive upper bound excludes every 3.6.x version
+		g.Expect(inRange(g, toolInfo{Min: "3.3.9", Max: "3.6", Range: "[3.3.9,3.6)"})).Should(Equal([]string{"3.3.9"}))
+		g.Expect(inRange(g, toolInfo{Min: "3.3.9", Max: "3.6.3", Range: "(3.3.9,3.6.3]"})).Should(Equal([]string{"3.6.3"}))
+		g.Expect(inRange(g, toolInfo{Min: "3.5", Range: "3.5"})).Should(Equal([]string{"3.8", "3.6.3"}))
+		g.Expect(inRange(g, toolInfo{Min: "3.6.3", Max: "3.6.3", Range: "[3.6.3]"})).Should(Equal([]string{"3.6.3"}))
+		g.Expect(inRange(g, toolInfo{Min: "3.0", Range: "[3.0,3.6), (3.7,)"})).Should(Equal([]string{"3.8", "3.3.9"}))
+		for _, invalid := range []string{"[3.0,3.6", "[3.0,3.6),", "[3.0,3.6)x", "(3.6)", "[3.0,3.5,3.6]"} {
+			_, err := mavenVersionsInRange(versions, toolInfo{Range: invalid})
+			g.Expect(err).ShouldNot(BeNil(), invalid)
+		}
+	})
+}
+
+func TestRankBuildRecipes(t *testing.T) {
+	t.Run("Test closer tool versions are preferred", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		recipes := []*v1alpha1.BuildRecipe{
+			{Image: "jdk11", JavaVersion: "11", Tool: "gradle", ToolVersion: "8.0.2"},
+			{Image: "jdk11", JavaVersion: "11", Tool: "gradle", ToolVersion: "6.9.2"},
+			{Image: "jdk11", JavaVersion: "11", Tool: "gradle", ToolVersion: "7.4.2"},
+		}
+		ranked := rankBuildRecipes(recipes, &RecipeRankingContext{RequestedToolVersions: map[string]string{"gradle": "7.3"}}, DefaultRecipeScorers())
+		g.Expect(ranked[0].ToolVersion).Should(Equal("7.4.2"))
+		g.Expect(ranked[1].ToolVersion).Should(Equal("8.0.2"))
+		g.Expect(ranked[2].ToolVersion).Should(Equal("6.9.2"))
+	})
+	t.Run("Test priority order is kept without ranking information", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		recipes := []*v1alpha1.BuildRecipe{
+			{Image: "jdk17", JavaVersion: "17", Tool: "maven", ToolVersion: "3.8.1"},
+			{Image: "jdk8", JavaVersion: "8", Tool: "maven", ToolVersion: "3.8.1"},
+			{Image: "other-jdk17", JavaVersion: "17", Tool: "maven", ToolVersion: "3.8.1"},
+		}
+		ranked := rankBuildRecipes(recipes, &RecipeRankingContext{}, DefaultRecipeScorers())
+		g.Expect(ranked).Should(HaveLen(2))
+		g.Expect(ranked[0].Image).Should(Equal("jdk17"))
+		g.Expect(ranked[1].Image).Should(Equal("jdk8"))
+	})
+	t.Run("Test java 1.x versions", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		g.Expect(PreferredJavaVersionScorer{Weight: 1}.Score(&v1alpha1.BuildRecipe{JavaVersion: "8"}, &RecipeRankingContext{PreferredJavaVersion: "1.8"})).Should(Equal(1.0))
+	})
+}
+
+type fakeStepLogReader string
+
+func (f fakeStepLogReader) TailStepLog(ctx context.Context, namespace string, pod string, container string, lines int64) (string, error) {
+	return string(f), nil
+}
+
+func (f fakeStepLogReader) StepLog(ctx context.Context, namespace string, pod string, container string, limitBytes int64) (string, error) {
+	return string(f), nil
+}
+
+// pipelineStepLogReader can only read the logs while the pipeline exists, as its pods are deleted with it
+type pipelineStepLogReader struct {
+	client   runtimeclient.Client
+	pipeline types.NamespacedName
+	logs     string
+}
+
+func (p pipelineStepLogReader) TailStepLog(ctx context.Context, namespace string, pod string, container string, lines int64) (string, error) {
+	return p.StepLog(ctx, namespace, pod, container, 0)
+}
+
+func (p pipelineStepLogReader) StepLog(ctx context.Context, namespace string, pod string, container string, limitBytes int64) (string, error) {
+	if err := p.client.Get(ctx, p.pipeline, &pipelinev1beta1.PipelineRun{}); err != nil {
+		return "", err
+	}
+	return p.logs, nil
+}
+
+// conflictingStatusClient fails every DependencyBuild status update with a conflict
+type conflictingStatusClient struct {
+	runtimeclient.Client
+}
+
+func (c conflictingStatusClient) Status() runtimeclient.StatusWriter {
+	return conflictingStatusWriter{StatusWriter: c.Client.Status()}
+}
+
+type conflictingStatusWriter struct {
+	runtimeclient.StatusWriter
+}
+
+func (c conflictingStatusWriter) Update(ctx context.Context, obj runtimeclient.Object, opts ...runtimeclient.SubResourceUpdateOption) error {
+	if _, ok := obj.(*v1alpha1.DependencyBuild); ok {
+		return errors.NewConflict(v1alpha1.Resource("dependencybuilds"), obj.GetName(), fmt.Errorf("the object has been modified"))
+	}
+	return c.StatusWriter.Update(ctx, obj, opts...)
+}
+
+// fakeLogArchiver records the logs it was asked to archive, keyed by image reference
+type fakeLogArchiver map[string]map[string]string
+
+func (f fakeLogArchiver) ArchiveLogs(ctx context.Context, ref string, insecure bool, dockerConfig []byte, logs map[string]string) (string, error) {
+	f[ref] = logs
+	return ref + "@sha256:12345", nil
+}
+
+// fakeReferrerPublisher records the artifacts it was asked to publish, keyed by artifact type and the image they describe
+type fakeReferrerPublisher map[string]map[string][]byte
+
+func (f fakeReferrerPublisher) PublishReferrer(ctx context.Context, subject string, artifactType string, insecure bool, dockerConfig []byte, data []byte) (string, error) {
+	if f[artifactType] == nil {
+		f[artifactType] = map[string][]byte{}
+	}
+	f[artifactType][subject] = data
+	digests := map[string]string{SBOMArtifactType: "sbom", ProvenanceArtifactType: "att"}
+	return strings.Split(subject, "@")[0] + "@sha256:" + digests[artifactType], nil
+}
+
+func TestClassifyBuildFailure(t *testing.T) {
+	ctx := context.TODO()
+	log := logr.Discard()
+	failedRun := func(steps ...pipelinev1beta1.StepState) *pipelinev1beta1.PipelineRun {
+		pr := &pipelinev1beta1.PipelineRun{ObjectMeta: metav1.ObjectMeta{Name: "test-build-0", Namespace: metav1.NamespaceDefault}}
+		pr.Status.SetCondition(&apis.Condition{Type: apis.ConditionSucceeded, Status: "False"})
+		pr.Status.TaskRuns = map[string]*pipelinev1beta1.PipelineRunTaskRunStatus{"task": {Status: &pipelinev1beta1.TaskRunStatus{TaskRunStatusFields: pipelinev1beta1.TaskRunStatusFields{PodName: "test-pod", Steps: steps}}}}
+		return pr
+	}
+	exited := func(name string, code int32) pipelinev1beta1.StepState {
+		return pipelinev1beta1.StepState{Name: name, ContainerState: v1.ContainerState{Terminated: &v1.ContainerStateTerminated{ExitCode: code}}}
+	}
+	t.Run("Test step failures", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		reconciler := &ReconcileDependencyBuild{}
+		g.Expect(reconciler.classifyBuildFailure(ctx, log, failedRun(exited(stepGitCloneAndSettings, 1), exited(stepBuild, 1))).Class).Should(Equal(v1alpha1.BuildFailureClassGitClone))
+		//only registry errors are deploy failures, a failed verification is left to the next recipe
+		g.Expect(reconciler.classifyBuildFailure(ctx, log, failedRun(exited(stepGitCloneAndSettings, 0), exited(stepBuild, 0), exited(stepVerifyAndDeploy, 1))).Class).Should(Equal(v1alpha1.BuildFailureClassUnknown))
+		g.Expect(reconciler.classifyBuildFailure(ctx, log, failedRun(exited(stepGitCloneAndSettings, 0), exited(stepBuild, 137))).Class).Should(Equal(v1alpha1.BuildFailureClassOOM))
+		//without the log the build step failure can't be classified
+		unknown := reconciler.classifyBuildFailure(ctx, log, failedRun(exited(stepBuild, 1)))
+		g.Expect(unknown.Class).Should(Equal(v1alpha1.BuildFailureClassUnknown))
+		g.Expect(unknown.Step).Should(Equal(stepBuild))
+	})
+	t.Run("Test timeouts", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		reconciler := &ReconcileDependencyBuild{}
+		pr := failedRun(exited(stepBuild, 1))
+		pr.Status.SetCondition(&apis.Condition{Type: apis.ConditionSucceeded, Status: "False", Reason: pipelinev1beta1.PipelineRunReasonTimedOut.String()})
+		g.Expect(reconciler.classifyBuildFailure(ctx, log, pr).Class).Should(Equal(v1alpha1.BuildFailureClassTimeout))
+		pr = failedRun(exited(stepBuild, 1))
+		pr.Status.TaskRuns["task"].Status.SetCondition(&apis.Condition{Type: apis.ConditionSucceeded, Status: "False", Reason: pipelinev1beta1.TaskRunReasonTimedOut.String()})
+		g.Expect(reconciler.classifyBuildFailure(ctx, log, pr).Class).Should(Equal(v1alpha1.BuildFailureClassTimeout))
+	})
+	t.Run("Test deploy log classification", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		for logs, class := range map[string]string{
+			"ERROR Deployment failed: com.google.cloud.tools.jib.api.RegistryUnauthorizedException: Unauthorized for quay.io/test/test": v1alpha1.BuildFailureClassDeploy,
+			"Caused by: java.net.UnknownHostException: quay.io":                                                                         v1alpha1.BuildFailureClassDeploy,
+			"ERROR com.test:test:1.0 was contaminated by org.test:test:1.0 from central":                                                v1alpha1.BuildFailureClassUnknown,
+			"ERROR Failed to instrument /deploy/test.jar":                                                                               v1alpha1.BuildFailureClassUnknown,
+		} {
+			reconciler := &ReconcileDependencyBuild{stepLogReader: fakeStepLogReader(logs)}
+			failure := reconciler.classifyBuildFailure(ctx, log, failedRun(exited(stepBuild, 0), exited(stepVerifyAndDeploy, 1)))
+			g.Expect(failure.Class).Should(Equal(class), logs)
+			g.Expect(failure.Step).Should(Equal(stepVerifyAndDeploy))
+		}
+	})
+	t.Run("Test build log classification", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		for logs, class := range map[string]string{
+			"[ERROR] Failed to execute goal org.codehaus.mojo:versions-maven-plugin:2.12.0:set (default-cli)":                  v1alpha1.BuildFailureClassEnforceVersion,
+			"[ERROR] Tests run: 12, Failures: 2, Errors: 0, Skipped: 0\n[ERROR] There are test failures.":                      v1alpha1.BuildFailureClassTest,
+			"> Task :core:test FAILED\n\n120 tests completed, 1 failed":                                                        v1alpha1.BuildFailureClassTest,
+			"> Task :core:compileJava FAILED":                                                                                  v1alpha1.BuildFailureClassCompilation,
+			"[ERROR] Failed to execute goal on project test: Could not resolve dependencies for project com.test:test:jar:1.0": v1alpha1.BuildFailureClassDependencyResolution,
+			"> Could not resolve all files for configuration ':compileClasspath'.":                                             v1alpha1.BuildFailureClassDependencyResolution,
+			"[INFO] BUILD FAILURE": v1alpha1.BuildFailureClassUnknown,
+		} {
+			reconciler := &ReconcileDependencyBuild{stepLogReader: fakeStepLogReader(logs)}
+			g.Expect(reconciler.classifyBuildFailure(ctx, log, failedRun(exited(stepBuild, 1))).Class).Should(Equal(class), logs)
+		}
+	})
+}
+
+func TestRetryPolicy(t *testing.T) {
+	t.Run("Test defaults", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		policy := newRetryPolicy(&v1alpha1.JBSConfig{}, &v1alpha1.SystemConfig{})
+		g.Expect(policy.maxRetries(v1alpha1.BuildFailureClassOOM)).Should(Equal(defaultMaxRetries))
+		g.Expect(policy.nextAdditionalMemory(0)).Should(Equal(512))
+		g.Expect(policy.nextAdditionalMemory(512)).Should(Equal(1024))
+		g.Expect(policy.nextAdditionalMemory(1500)).Should(Equal(2048))
+		g.Expect(policy.nextAdditionalMemory(2048)).Should(Equal(0))
+		g.Expect(policy.backoff(0)).Should(Equal(time.Second * 10))
+		g.Expect(policy.backoff(2)).Should(Equal(time.Second * 40))
+		g.Expect(policy.backoff(20)).Should(Equal(time.Minute * 10))
+	})
+	t.Run("Test the JBSConfig overrides the SystemConfig", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		jbsConfig := v1alpha1.JBSConfig{}
+		jbsConfig.Spec.BuildSettings.RetryPolicy = v1alpha1.RetryPolicy{MaxRetries: map[string]int{v1alpha1.BuildFailureClassOOM: 1}, MemoryGrowthFactor: 3, MaxBackoff: &metav1.Duration{Duration: time.Minute}}
+		systemConfig := v1alpha1.SystemConfig{}
+		systemConfig.Spec.RetryPolicy = v1alpha1.RetryPolicy{MaxRetries: map[string]int{v1alpha1.BuildFailureClassOOM: 5, v1alpha1.RetryCauseCacheRestart: 0}, MemoryIncrement: 256, MemoryCeiling: 4096, InitialBackoff: &metav1.Duration{Duration: time.Second * 20}}
+		policy := newRetryPolicy(&jbsConfig, &systemConfig)
+		g.Expect(policy.maxRetries(v1alpha1.BuildFailureClassOOM)).Should(Equal(1))
+		g.Expect(policy.canRetry(v1alpha1.RetryCauseCacheRestart, nil)).Should(BeFalse())
+		g.Expect(policy.canRetry(v1alpha1.BuildFailureClassGitClone, map[string]int{v1alpha1.BuildFailureClassGitClone: 2})).Should(BeTrue())
+		g.Expect(policy.nextAdditionalMemory(0)).Should(Equal(256))
+		g.Expect(policy.nextAdditionalMemory(256)).Should(Equal(768))
+		g.Expect(policy.nextAdditionalMemory(2304)).Should(Equal(4096))
+		g.Expect(policy.backoff(1)).Should(Equal(time.Second * 40))
+		g.Expect(policy.backoff(2)).Should(Equal(time.Minute))
+	})
+	t.Run("Test zero backoff retries straight away", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		jbsConfig := v1alpha1.JBSConfig{}
+		jbsConfig.Spec.BuildSettings.RetryPolicy.InitialBackoff = &metav1.Duration{}
+		g.Expect(newRetryPolicy(&jbsConfig, &v1alpha1.SystemConfig{}).backoff(3)).Should(Equal(time.Duration(0)))
+	})
+}
+
+func TestBuildPipelineName(t *testing.T) {
+	recipe := &v1alpha1.BuildRecipe{Image: "quay.io/redhat-appstudio/hacbs-jdk11-builder:latest"}
+	t.Run("Test pipelines are numbered by the attempts", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		db := v1alpha1.DependencyBuild{ObjectMeta: metav1.ObjectMeta{Name: "test"}}
+		g.Expect(currentDependencyBuildPipelineName(&db)).Should(Equal("test-build-0"))
+		db.Status.BuildAttempts = []v1alpha1.BuildAttempt{{PipelineRun: "test-build-0", Outcome: v1alpha1.BuildAttemptOutcomeRunning}}
+		g.Expect(currentDependencyBuildPipelineName(&db)).Should(Equal("test-build-0"))
+		db.Status.BuildAttempts[0].Outcome = v1alpha1.BuildAttemptOutcomeRetried
+		g.Expect(currentDependencyBuildPipelineName(&db)).Should(Equal("test-build-1"))
+		db.Status.BuildAttempts[0].Outcome = v1alpha1.BuildAttemptOutcomeFailed
+		db.Status.FailedBuildRecipes = []*v1alpha1.BuildRecipe{recipe}
+		g.Expect(currentDependencyBuildPipelineName(&db)).Should(Equal("test-build-1"))
+	})
+	t.Run("Test numbering carries on from builds started before the attempts were recorded", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		db := v1alpha1.DependencyBuild{ObjectMeta: metav1.ObjectMeta{Name: "test"}}
+		db.Status.FailedBuildRecipes = []*v1alpha1.BuildRecipe{recipe, recipe}
+		g.Expect(currentDependencyBuildPipelineName(&db)).Should(Equal("test-build-2"))
+		//the attempt for the running pipeline is added when it completes
+		db.Status.BuildAttempts = []v1alpha1.BuildAttempt{{PipelineRun: "test-build-2", Outcome: v1alpha1.BuildAttemptOutcomeRetried}}
+		g.Expect(currentDependencyBuildPipelineName(&db)).Should(Equal("test-build-3"))
+		db.Status.BuildAttempts = append(db.Status.BuildAttempts, v1alpha1.BuildAttempt{PipelineRun: "test-build-3", Outcome: v1alpha1.BuildAttemptOutcomeFailed})
+		db.Status.FailedBuildRecipes = append(db.Status.FailedBuildRecipes, recipe)
+		g.Expect(currentDependencyBuildPipelineName(&db)).Should(Equal("test-build-4"))
+	})
+}
+
+func TestLogArchive(t *testing.T) {
+	t.Run("Test the logs are tagged next to the rebuilt images", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		pr := &pipelinev1beta1.PipelineRun{ObjectMeta: metav1.ObjectMeta{Name: "test-build-1"}}
+		jbsConfig := &v1alpha1.JBSConfig{}
+		g.Expect(logArchiveReference(jbsConfig, pr)).Should(Equal("quay.io/hacbs/artifact-deployments:test-build-1-logs"))
+		jbsConfig.Spec.ImageRegistry = v1alpha1.ImageRegistry{Host: "registry.example.com", Port: "5000", Owner: "test", Repository: "deployments", PrependTag: "prefix"}
+		g.Expect(logArchiveReference(jbsConfig, pr)).Should(Equal("registry.example.com:5000/test/deployments:prefix_test-build-1-logs"))
+	})
+}
+
+func TestGenerateSBOM(t *testing.T) {
+	g := NewGomegaWithT(t)
+	db := &v1alpha1.DependencyBuild{Spec: v1alpha1.DependencyBuildSpec{ScmInfo: v1alpha1.SCMInfo{SCMURL: "https://github.com/test/test.git", Tag: "1.0", CommitHash: "abc123"}}}
+	recipe := &v1alpha1.BuildRecipe{
+		Image:               "quay.io/redhat-appstudio/hacbs-jdk11-builder:latest",
+		Tool:                "maven",
+		ToolVersion:         "3.8.8",
+		JavaVersion:         "11",
+		AdditionalDownloads: []v1alpha1.AdditionalDownload{{Uri: "https://example.com/tool.tar.gz", Sha256: "fedcba", FileName: "tool.tar.gz", FileType: v1alpha1.AdditionalDownloadTypeTar}},
+		Repositories:        []string{"jboss"},
+	}
+	jbsConfig := &v1alpha1.JBSConfig{ObjectMeta: metav1.ObjectMeta{Namespace: metav1.NamespaceDefault, Name: v1alpha1.JBSConfigName}}
+	jbsConfig.Spec.EnableRebuilds = true
+	jbsConfig.Spec.MavenBaseLocations = map[string]string{"maven-repository-300-jitpack": "https://jitpack.io", "not-a-repository": "https://example.com"}
+	deployed := []string{TestArtifact, "com.test:other:1.0", "com.test:other:jar:tests:1.0", "other"}
+	data, err := generateSBOM(db, recipe, jbsConfig, deployed, "quay.io/hacbs/artifact-deployments@sha256:12345")
+	g.Expect(err).Should(Succeed())
+	bom := cdx.BOM{}
+	g.Expect(cdx.NewBOMDecoder(bytes.NewReader(data), cdx.BOMFileFormatJSON).Decode(&bom)).Should(Succeed())
+	g.Expect(bom.Metadata.Component.Name).Should(Equal("https://github.com/test/test.git"))
+	g.Expect((*bom.Metadata.Component.Pedigree.Commits)[0].UID).Should(Equal("abc123"))
+	g.Expect(*bom.Metadata.Tools).Should(ContainElement(cdx.Tool{Name: "maven", Version: "3.8.8"}))
+	g.Expect(*bom.Metadata.Tools).Should(ContainElement(cdx.Tool{Name: "jdk", Version: "11"}))
+	purls := map[string]cdx.Component{}
+	for _, i := range *bom.Components {
+		purls[i.BOMRef] = i
+	}
+	g.Expect(purls[TestArtifact].PackageURL).Should(Equal("pkg:maven/com.test/test@1.0"))
+	g.Expect(purls["com.test:other:1.0"].Type).Should(Equal(cdx.ComponentTypeLibrary))
+	g.Expect(purls["com.test:other:jar:tests:1.0"].PackageURL).Should(Equal("pkg:maven/com.test/other@1.0?classifier=tests&type=jar"))
+	//artifacts that are not a GAV are still listed
+	g.Expect(purls["other"].Name).Should(Equal("other"))
+	g.Expect(purls["other"].PackageURL).Should(BeEmpty())
+	g.Expect(purls[recipe.Image].Type).Should(Equal(cdx.ComponentTypeContainer))
+	download := purls["https://example.com/tool.tar.gz"]
+	g.Expect(*download.Hashes).Should(Equal([]cdx.Hash{{Algorithm: cdx.HashAlgoSHA256, Value: "fedcba"}}))
+	//the repositories are in the order the cache checks them
+	repositories := []string{}
+	for _, ref := range *bom.ExternalReferences {
+		repositories = append(repositories, ref.URL)
+	}
+	g.Expect(repositories).Should(Equal([]string{jbsConfig.CacheURL(), "quay.io/hacbs/artifact-deployments", centralRepositoryURL, redhatRepositoryURL, "https://jitpack.io", "jboss"}))
+}
+
+func TestGenerateProvenance(t *testing.T) {
+	g := NewGomegaWithT(t)
+	db := &v1alpha1.DependencyBuild{Spec: v1alpha1.DependencyBuildSpec{ScmInfo: v1alpha1.SCMInfo{SCMURL: "https://github.com/test/test.git", Tag: "1.0", CommitHash: "abc123", Path: "sub"}}}
+	recipe := &v1alpha1.BuildRecipe{Image: "quay.io/redhat-appstudio/hacbs-jdk11-builder:latest", Tool: "maven"}
+	pr := &pipelinev1beta1.PipelineRun{ObjectMeta: metav1.ObjectMeta{Name: "test-build-0", UID: "uid"}}
+	pr.Spec.PipelineSpec = &pipelinev1beta1.PipelineSpec{Params: []pipelinev1beta1.ParamSpec{
+		{Name: "GOALS", Default: &pipelinev1beta1.ParamValue{Type: pipelinev1beta1.ParamTypeArray, ArrayVal: []string{"install"}}},
+		{Name: PipelineParamChainsGitCommit, Default: &pipelinev1beta1.ParamValue{Type: pipelinev1beta1.ParamTypeString, StringVal: "default"}},
+	}}
+	pr.Spec.Params = []pipelinev1beta1.Param{{Name: PipelineParamChainsGitCommit, Value: pipelinev1beta1.ParamValue{Type: pipelinev1beta1.ParamTypeString, StringVal: "abc123"}}}
+	pr.Status.TaskRuns = map[string]*pipelinev1beta1.PipelineRunTaskRunStatus{"test-build-0-task": {Status: &pipelinev1beta1.TaskRunStatus{TaskRunStatusFields: pipelinev1beta1.TaskRunStatusFields{Steps: []pipelinev1beta1.StepState{
+		{Name: "build", ImageID: "docker-pullable://quay.io/redhat-appstudio/hacbs-jdk11-builder@sha256:builder"},
+		{Name: "deploy", ImageID: "quay.io/redhat-appstudio/hacbs-jvm-build-request-processor@sha256:processor"},
+		{Name: "unknown"},
+	}}}}}
+
+	statement, err := generateProvenance(db, pr, recipe, "quay.io/hacbs/artifact-deployments@sha256:12345")
+	g.Expect(err).Should(Succeed())
+	g.Expect(statement.Type).Should(Equal(InTotoStatementType))
+	g.Expect(statement.PredicateType).Should(Equal(SLSAProvenancePredicate))
+	g.Expect(statement.Predicate.Invocation.ConfigSource).Should(Equal(ProvenanceConfigSource{URI: "git+https://github.com/test/test.git", Digest: map[string]string{"sha1": "abc123"}, EntryPoint: "sub"}))
+	g.Expect(statement.Predicate.Invocation.Parameters).Should(HaveLen(2))
+	g.Expect(statement.Predicate.Invocation.Parameters[PipelineParamChainsGitCommit].StringVal).Should(Equal("abc123"))
+	g.Expect(statement.Predicate.Invocation.Parameters["GOALS"].ArrayVal).Should(Equal([]string{"install"}))
+	g.Expect(statement.Predicate.BuildConfig.Recipe).Should(Equal(recipe))
+	g.Expect(statement.Predicate.Materials).Should(Equal([]ProvenanceMaterial{
+		{URI: "git+https://github.com/test/test.git", Digest: map[string]string{"sha1": "abc123"}},
+		{URI: "oci://quay.io/redhat-appstudio/hacbs-jdk11-builder", Digest: map[string]string{"sha256": "builder"}},
+		{URI: "oci://quay.io/redhat-appstudio/hacbs-jvm-build-request-processor", Digest: map[string]string{"sha256": "processor"}},
+	}))
+
+	_, err = generateProvenance(db, pr, recipe, "quay.io/hacbs/artifact-deployments:latest")
+	g.Expect(err).ShouldNot(Succeed())
+}
+
+func TestReferrerTag(t *testing.T) {
+	g := NewGomegaWithT(t)
+	digest := ggcrv1.Hash{Algorithm: "sha256", Hex: "12345"}
+	//cosign tags its attestations and SBOMs sha256-<digest>.att and .sbom, which Tekton Chains pushes for the same images
+	g.Expect(referrerTag(digest, ProvenanceArtifactType)).Should(Equal("sha256-12345.jbs.att"))
+	g.Expect(referrerTag(digest, SBOMArtifactType)).Should(Equal("sha256-12345.jbs.sbom"))
+}
+
+func TestSigningKey(t *testing.T) {
+	g := NewGomegaWithT(t)
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	g.Expect(err).Should(Succeed())
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	g.Expect(err).Should(Succeed())
+	signer, err := parseSigningKey(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+	g.Expect(err).Should(Succeed())
+	statement := &InTotoStatement{Type: InTotoStatementType}
+	data, err := signStatement(statement, signer)
+	g.Expect(err).Should(Succeed())
+	envelope := DSSEEnvelope{}
+	g.Expect(json.Unmarshal(data, &envelope)).Should(Succeed())
+	payload, _ := base64.StdEncoding.DecodeString(envelope.Payload)
+	sig, _ := base64.StdEncoding.DecodeString(envelope.Signatures[0].Sig)
+	pae := fmt.Sprintf("DSSEv1 %d %s %d %s", len(InTotoPayloadType), InTotoPayloadType, len(payload), payload)
+	g.Expect(ed25519.Verify(private.Public().(ed25519.PublicKey), []byte(pae), sig)).Should(BeTrue())
+
+	_, err = parseSigningKey([]byte("not a key"))
+	g.Expect(err).ShouldNot(Succeed())
+	_, err = parseSigningKey(pem.EncodeToMemory(&pem.Block{Type: "ENCRYPTED PRIVATE KEY", Bytes: der}))
+	g.Expect(err).ShouldNot(Succeed())
 }
@@ -0,0 +1,167 @@
+package dependencybuild
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"github.com/go-logr/logr"
+	"github.com/redhat-appstudio/jvm-build-service/pkg/apis/jvmbuildservice/v1alpha1"
+	pipelinev1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"knative.dev/pkg/apis"
+)
+
+const (
+	stepGitCloneAndSettings = "git-clone-and-settings"
+	stepBuild               = "build"
+	stepVerifyAndDeploy     = "verify-deploy-and-check-for-contaminates"
+
+	failureLogTailLines = 200
+)
+
+// StepLogReader reads the logs of pipeline steps. The end of the log of a failed step is used to tell apart the
+// different ways the build step itself can fail, and the full logs are archived once a pipeline has finished.
+type StepLogReader interface {
+	TailStepLog(ctx context.Context, namespace string, pod string, container string, lines int64) (string, error)
+	StepLog(ctx context.Context, namespace string, pod string, container string, limitBytes int64) (string, error)
+}
+
+type podLogReader struct {
+	clientset kubernetes.Interface
+}
+
+func (p *podLogReader) TailStepLog(ctx context.Context, namespace string, pod string, container string, lines int64) (string, error) {
+	data, err := p.clientset.CoreV1().Pods(namespace).GetLogs(pod, &v1.PodLogOptions{Container: container, TailLines: &lines}).DoRaw(ctx)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+func (p *podLogReader) StepLog(ctx context.Context, namespace string, pod string, container string, limitBytes int64) (string, error) {
+	data, err := p.clientset.CoreV1().Pods(namespace).GetLogs(pod, &v1.PodLogOptions{Container: container, LimitBytes: &limitBytes}).DoRaw(ctx)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// buildLogPatterns are checked in order, so the more specific failures come first. For example a test failure
+// is also reported as a failed build, and a failed version update can look like a dependency problem.
+var buildLogPatterns = []struct {
+	class   string
+	pattern *regexp.Regexp
+}{
+	{v1alpha1.BuildFailureClassEnforceVersion, regexp.MustCompile(`Failed to execute goal org\.codehaus\.mojo:versions-maven-plugin`)},
+	{v1alpha1.BuildFailureClassTest, regexp.MustCompile(`There are test failures|There were failing tests|Tests run: \d+, Failures: [1-9]|Tests run: \d+, Failures: \d+, Errors: [1-9]|\d+ tests completed, \d+ failed|Task :\S*test FAILED`)},
+	{v1alpha1.BuildFailureClassCompilation, regexp.MustCompile(`COMPILATION ERROR|Compilation failed|Compilation failure|Task :\S*compile\w* FAILED|\[error\] .*\.scala:\d+`)},
+	{v1alpha1.BuildFailureClassDependencyResolution, regexp.MustCompile(`Could not resolve dependencies|Could not transfer artifact|Could not find artifact|Failed to collect dependencies|Non-resolvable parent POM|Could not resolve all (files|dependencies|artifacts)|Could not GET|unresolved dependency|Unable to resolve artifact`)},
+}
+
+// deployLogPattern matches the registry errors of the deploy step. The step also verifies the artifacts and checks them
+// for contaminants, those failures depend on what the recipe built so they are not deploy failures.
+var deployLogPattern = regexp.MustCompile(`com\.google\.cloud\.tools\.jib\.api\.Registry\w*Exception|Unauthorized for \S+|Tried to push image|denied: |unauthorized: |UnknownHostException|ConnectException|SocketTimeoutException|SSLHandshakeException|No token configured`)
+
+// classifyBuildFailure works out why a build pipeline failed from the step states, and if there is a log reader the
+// log of the failed step
+func (r *ReconcileDependencyBuild) classifyBuildFailure(ctx context.Context, log logr.Logger, pr *pipelinev1beta1.PipelineRun) v1alpha1.BuildFailure {
+	failure := v1alpha1.BuildFailure{PipelineRun: pr.Name, Class: v1alpha1.BuildFailureClassUnknown}
+	if pipelineRunTimedOut(pr) {
+		failure.Class = v1alpha1.BuildFailureClassTimeout
+		failure.Message = "the PipelineRun timed out"
+		return failure
+	}
+	for _, trs := range pr.Status.TaskRuns {
+		if trs.Status == nil {
+			continue
+		}
+		condition := trs.Status.GetCondition(apis.ConditionSucceeded)
+		if condition != nil && condition.Reason == pipelinev1beta1.TaskRunReasonTimedOut.String() {
+			failure.Class = v1alpha1.BuildFailureClassTimeout
+			failure.Message = condition.Message
+			return failure
+		}
+		for _, step := range trs.Status.Steps {
+			if step.Terminated == nil {
+				continue
+			}
+			if step.Terminated.ExitCode == 137 || step.Terminated.ExitCode == 134 || step.Terminated.Reason == "OOMKilled" {
+				failure.Class = v1alpha1.BuildFailureClassOOM
+				failure.Step = step.Name
+				failure.Message = "the step ran out of memory"
+				return failure
+			}
+			if step.Terminated.ExitCode == 0 || failure.Step != "" {
+				continue
+			}
+			//the first step to fail is the cause, later steps are skipped
+			failure.Step = step.Name
+			switch step.Name {
+			case stepGitCloneAndSettings:
+				failure.Class = v1alpha1.BuildFailureClassGitClone
+			case stepVerifyAndDeploy:
+				//without a registry error in the log the failure is left unknown, so the next recipe is tried
+				logs := r.failedStepLog(ctx, log, pr, trs.Status, step)
+				if loc := deployLogPattern.FindStringIndex(logs); loc != nil {
+					failure.Class = v1alpha1.BuildFailureClassDeploy
+					failure.Message = matchingLine(logs, loc)
+				}
+			case stepBuild:
+				failure.Class, failure.Message = classifyBuildLog(r.failedStepLog(ctx, log, pr, trs.Status, step))
+			}
+		}
+	}
+	return failure
+}
+
+// failedStepLog returns the end of the log of a failed step, or an empty string if it can't be read
+func (r *ReconcileDependencyBuild) failedStepLog(ctx context.Context, log logr.Logger, pr *pipelinev1beta1.PipelineRun, trs *pipelinev1beta1.TaskRunStatus, step pipelinev1beta1.StepState) string {
+	if r.stepLogReader == nil || trs.PodName == "" {
+		return ""
+	}
+	logs, err := r.stepLogReader.TailStepLog(ctx, pr.Namespace, trs.PodName, stepContainerName(step), failureLogTailLines)
+	if err != nil {
+		//the log is only a hint, the pod may already have been removed
+		log.Error(err, "failed to read the step log", "pod", trs.PodName, "step", step.Name)
+		return ""
+	}
+	return logs
+}
+
+func stepContainerName(step pipelinev1beta1.StepState) string {
+	if step.ContainerName != "" {
+		return step.ContainerName
+	}
+	return "step-" + step.Name
+}
+
+// classifyBuildLog returns the failure class and the matching line
+func classifyBuildLog(logs string) (string, string) {
+	for _, p := range buildLogPatterns {
+		if loc := p.pattern.FindStringIndex(logs); loc != nil {
+			return p.class, matchingLine(logs, loc)
+		}
+	}
+	return v1alpha1.BuildFailureClassUnknown, ""
+}
+
+// matchingLine returns the line containing the match at loc
+func matchingLine(logs string, loc []int) string {
+	start := strings.LastIndex(logs[:loc[0]], "\n") + 1
+	end := strings.Index(logs[loc[0]:], "\n")
+	if end < 0 {
+		end = len(logs)
+	} else {
+		end += loc[0]
+	}
+	return strings.TrimSpace(logs[start:end])
+}
+
+// recipeIndependentFailureClass failures that happen before or after the build itself, so another recipe can't fix
+// them. They are usually caused by the environment, so the same recipe is retried, and once the retries are used up
+// the build fails rather than trying the remaining recipes.
+func recipeIndependentFailureClass(class string) bool {
+	return class == v1alpha1.BuildFailureClassGitClone || class == v1alpha1.BuildFailureClassDeploy
+}
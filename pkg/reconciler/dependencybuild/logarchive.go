@@ -0,0 +1,208 @@
+package dependencybuild
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/redhat-appstudio/jvm-build-service/pkg/apis/jvmbuildservice/v1alpha1"
+	"github.com/redhat-appstudio/jvm-build-service/pkg/reconciler/util"
+	pipelinev1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	types2 "k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	// these match the defaults of the deploy step, so the logs are next to the rebuilt images
+	defaultRegistryHost       = "quay.io"
+	defaultRegistryOwner      = "hacbs"
+	defaultRegistryRepository = "artifact-deployments"
+
+	logArchiveTagSuffix = "-logs"
+	// logArchiveStepLimitBytes stops a very verbose build from producing an archive that is too large to push
+	logArchiveStepLimitBytes = 10 * 1024 * 1024
+)
+
+// LogArchiveAnnotation records the archive on the pipeline, so a reconcile that is retried after a failed status update
+// does not push the logs again
+const LogArchiveAnnotation = "jvmbuildservice.io/log-archive"
+
+// LogArchiver stores the step logs of a finished pipeline in an image, and returns the image reference with its digest
+type LogArchiver interface {
+	ArchiveLogs(ctx context.Context, ref string, insecure bool, dockerConfig []byte, logs map[string]string) (string, error)
+}
+
+type registryLogArchiver struct {
+}
+
+func (a *registryLogArchiver) ArchiveLogs(ctx context.Context, ref string, insecure bool, dockerConfig []byte, logs map[string]string) (string, error) {
+	opts := []name.Option{}
+	if insecure {
+		opts = append(opts, name.Insecure)
+	}
+	tag, err := name.NewTag(ref, opts...)
+	if err != nil {
+		return "", err
+	}
+	auth, err := util.RegistryAuth(dockerConfig, tag.RegistryStr())
+	if err != nil {
+		return "", err
+	}
+	data, err := logArchiveLayer(logs)
+	if err != nil {
+		return "", err
+	}
+	layer, err := tarball.LayerFromOpener(func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(data)), nil
+	}, tarball.WithMediaType(types.OCILayer))
+	if err != nil {
+		return "", err
+	}
+	img, err := mutate.AppendLayers(mutate.MediaType(empty.Image, types.OCIManifestSchema1), layer)
+	if err != nil {
+		return "", err
+	}
+	if err := remote.Write(tag, img, remote.WithAuth(auth), remote.WithContext(ctx)); err != nil {
+		return "", err
+	}
+	digest, err := img.Digest()
+	if err != nil {
+		return "", err
+	}
+	return tag.Context().Digest(digest.String()).String(), nil
+}
+
+// logArchiveLayer creates a tar.gz with a file for each log, the files are sorted so the same logs give the same digest
+func logArchiveLayer(logs map[string]string) ([]byte, error) {
+	names := []string{}
+	for k := range logs {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	buf := bytes.Buffer{}
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, n := range names {
+		data := []byte(logs[n])
+		if err := tw.WriteHeader(&tar.Header{Name: "logs/" + n, Mode: 0644, Size: int64(len(data)), Typeflag: tar.TypeReg, ModTime: time.Unix(0, 0)}); err != nil {
+			return nil, err
+		}
+		if _, err := tw.Write(data); err != nil {
+			return nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// logArchiveReference the logs are tagged with the name of the pipeline, in the repository the artifacts are deployed to
+func logArchiveReference(jbsConfig *v1alpha1.JBSConfig, pr *pipelinev1beta1.PipelineRun) string {
+	tag := pr.Name + logArchiveTagSuffix
+	if prependTag := jbsConfig.ImageRegistry().PrependTag; prependTag != "" {
+		tag = prependTag + "_" + tag
+	}
+	return deploymentRepository(jbsConfig) + ":" + tag
+}
+
+// deploymentRepository the image repository the artifacts are deployed to
+func deploymentRepository(jbsConfig *v1alpha1.JBSConfig) string {
+	registry := jbsConfig.ImageRegistry()
+	host := registry.Host
+	if host == "" {
+		host = defaultRegistryHost
+	}
+	if registry.Port != "" {
+		host += ":" + registry.Port
+	}
+	owner := registry.Owner
+	if owner == "" {
+		owner = defaultRegistryOwner
+	}
+	repository := registry.Repository
+	if repository == "" {
+		repository = defaultRegistryRepository
+	}
+	return host + "/" + owner + "/" + repository
+}
+
+// archivePipelineLogs pushes the step logs of a finished pipeline to the image registry, and returns the image
+// reference or an empty string if the logs were not archived. Archiving is best effort, a failure must not stop the
+// finalizer being removed from the pipeline.
+func (r *ReconcileDependencyBuild) archivePipelineLogs(ctx context.Context, log logr.Logger, db *v1alpha1.DependencyBuild, pr *pipelinev1beta1.PipelineRun) string {
+	if archive := pr.Annotations[LogArchiveAnnotation]; archive != "" {
+		return archive
+	}
+	if r.logArchiver == nil || r.stepLogReader == nil {
+		return ""
+	}
+	jbsConfig := &v1alpha1.JBSConfig{}
+	err := r.client.Get(ctx, types2.NamespacedName{Namespace: db.Namespace, Name: v1alpha1.JBSConfigNameOrDefault(db.Spec.JBSConfig)}, jbsConfig)
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			log.Error(err, "failed to read the JBSConfig, not archiving the pipeline logs")
+		}
+		return ""
+	}
+	if jbsConfig.Spec.BuildSettings.DisableLogArchive {
+		return ""
+	}
+	secret := &v1.Secret{}
+	err = r.client.Get(ctx, types2.NamespacedName{Namespace: db.Namespace, Name: jbsConfig.ImageSecret()}, secret)
+	if err != nil {
+		//without the registry credentials there is nowhere to push the logs to
+		if !errors.IsNotFound(err) {
+			log.Error(err, "failed to read the image registry secret, not archiving the pipeline logs")
+		}
+		return ""
+	}
+	logs := map[string]string{}
+	for _, trs := range pr.Status.TaskRuns {
+		if trs.Status == nil || trs.Status.PodName == "" {
+			continue
+		}
+		for _, step := range trs.Status.Steps {
+			data, err := r.stepLogReader.StepLog(ctx, pr.Namespace, trs.Status.PodName, stepContainerName(step), logArchiveStepLimitBytes)
+			if err != nil {
+				log.Error(err, "failed to read the step log", "pod", trs.Status.PodName, "step", step.Name)
+				continue
+			}
+			logs[trs.PipelineTaskName+"/"+step.Name+".log"] = data
+		}
+	}
+	if len(logs) == 0 {
+		return ""
+	}
+	ref := logArchiveReference(jbsConfig, pr)
+	archive, err := r.logArchiver.ArchiveLogs(ctx, ref, jbsConfig.ImageRegistry().Insecure, secret.Data[v1alpha1.ImageSecretTokenKey], logs)
+	if err != nil {
+		log.Error(err, "failed to archive the pipeline logs", "image", ref)
+		r.eventRecorder.Eventf(db, v1.EventTypeWarning, "LogArchiveFailed", "The DependencyBuild %s/%s failed to archive the logs of PipelineRun %s", db.Namespace, db.Name, pr.Name)
+		return ""
+	}
+	log.Info("archived the pipeline logs", "pipelinerun", pr.Name, "image", archive)
+	if pr.Annotations == nil {
+		pr.Annotations = map[string]string{}
+	}
+	pr.Annotations[LogArchiveAnnotation] = archive
+	if err := r.client.Update(ctx, pr); err != nil {
+		log.Error(err, "failed to record the log archive on the pipeline", "pipelinerun", pr.Name)
+	}
+	return archive
+}
@@ -0,0 +1,329 @@
+package dependencybuild
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/redhat-appstudio/jvm-build-service/pkg/apis/jvmbuildservice/v1alpha1"
+	"github.com/redhat-appstudio/jvm-build-service/pkg/reconciler/systemconfig"
+	pipelinev1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	types2 "k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	ProvenanceArtifactType = "application/vnd.dsse.envelope.v1+json"
+
+	InTotoPayloadType       = "application/vnd.in-toto+json"
+	InTotoStatementType     = "https://in-toto.io/Statement/v0.1"
+	SLSAProvenancePredicate = "https://slsa.dev/provenance/v0.2"
+	ProvenanceBuilderId     = "https://github.com/redhat-appstudio/jvm-build-service"
+	ProvenanceBuildType     = "https://github.com/redhat-appstudio/jvm-build-service/DependencyBuild@v1"
+
+	DefaultSigningKeySecretKey = "private.key"
+)
+
+// InTotoStatement an in-toto statement with an SLSA provenance predicate
+type InTotoStatement struct {
+	Type          string              `json:"_type"`
+	PredicateType string              `json:"predicateType"`
+	Subject       []ProvenanceSubject `json:"subject"`
+	Predicate     SLSAProvenance      `json:"predicate"`
+}
+
+type ProvenanceSubject struct {
+	Name   string            `json:"name"`
+	Digest map[string]string `json:"digest"`
+}
+
+type SLSAProvenance struct {
+	Builder     ProvenanceBuilder    `json:"builder"`
+	BuildType   string               `json:"buildType"`
+	Invocation  ProvenanceInvocation `json:"invocation"`
+	BuildConfig ProvenanceConfig     `json:"buildConfig"`
+	Metadata    ProvenanceMetadata   `json:"metadata"`
+	Materials   []ProvenanceMaterial `json:"materials"`
+}
+
+type ProvenanceBuilder struct {
+	Id string `json:"id"`
+}
+
+type ProvenanceInvocation struct {
+	ConfigSource ProvenanceConfigSource `json:"configSource"`
+	// Parameters every parameter of the generated pipeline, with the values the pipeline ran with
+	Parameters map[string]pipelinev1beta1.ParamValue `json:"parameters"`
+}
+
+type ProvenanceConfigSource struct {
+	URI        string            `json:"uri"`
+	Digest     map[string]string `json:"digest"`
+	EntryPoint string            `json:"entryPoint,omitempty"`
+}
+
+type ProvenanceConfig struct {
+	PipelineRun string                `json:"pipelineRun"`
+	Recipe      *v1alpha1.BuildRecipe `json:"recipe,omitempty"`
+}
+
+type ProvenanceMetadata struct {
+	BuildInvocationId string     `json:"buildInvocationId"`
+	BuildStartedOn    *time.Time `json:"buildStartedOn,omitempty"`
+	BuildFinishedOn   *time.Time `json:"buildFinishedOn,omitempty"`
+	Reproducible      bool       `json:"reproducible"`
+}
+
+type ProvenanceMaterial struct {
+	URI    string            `json:"uri"`
+	Digest map[string]string `json:"digest,omitempty"`
+}
+
+// DSSEEnvelope the signed envelope the statement is published in
+type DSSEEnvelope struct {
+	PayloadType string          `json:"payloadType"`
+	Payload     string          `json:"payload"`
+	Signatures  []DSSESignature `json:"signatures"`
+}
+
+type DSSESignature struct {
+	KeyId string `json:"keyid"`
+	Sig   string `json:"sig"`
+}
+
+// generateProvenance describes how the image the artifacts were deployed to was built: the source commit, the images
+// the pipeline ran, the parameters and recipe of the pipeline, and when it ran
+func generateProvenance(db *v1alpha1.DependencyBuild, pr *pipelinev1beta1.PipelineRun, recipe *v1alpha1.BuildRecipe, subject string) (*InTotoStatement, error) {
+	parts := strings.SplitN(subject, "@", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("image %s is not referenced by digest", subject)
+	}
+	subjectDigest, err := parseDigest(parts[1])
+	if err != nil {
+		return nil, err
+	}
+	scm := db.Spec.ScmInfo
+	statement := &InTotoStatement{
+		Type:          InTotoStatementType,
+		PredicateType: SLSAProvenancePredicate,
+		Subject:       []ProvenanceSubject{{Name: parts[0], Digest: subjectDigest}},
+		Predicate: SLSAProvenance{
+			Builder:   ProvenanceBuilder{Id: ProvenanceBuilderId},
+			BuildType: ProvenanceBuildType,
+			Invocation: ProvenanceInvocation{
+				ConfigSource: ProvenanceConfigSource{URI: "git+" + scm.SCMURL, Digest: map[string]string{"sha1": scm.CommitHash}, EntryPoint: scm.Path},
+				Parameters:   map[string]pipelinev1beta1.ParamValue{},
+			},
+			BuildConfig: ProvenanceConfig{PipelineRun: pr.Name, Recipe: recipe},
+			Metadata:    ProvenanceMetadata{BuildInvocationId: string(pr.UID)},
+			Materials:   []ProvenanceMaterial{{URI: "git+" + scm.SCMURL, Digest: map[string]string{"sha1": scm.CommitHash}}},
+		},
+	}
+	//the defaults of the generated pipeline, overridden by the values it was run with
+	if pr.Spec.PipelineSpec != nil {
+		for _, p := range pr.Spec.PipelineSpec.Params {
+			if p.Default != nil {
+				statement.Predicate.Invocation.Parameters[p.Name] = *p.Default
+			}
+		}
+	}
+	for _, p := range pr.Spec.Params {
+		statement.Predicate.Invocation.Parameters[p.Name] = p.Value
+	}
+	if pr.Status.StartTime != nil {
+		t := pr.Status.StartTime.UTC()
+		statement.Predicate.Metadata.BuildStartedOn = &t
+	}
+	if pr.Status.CompletionTime != nil {
+		t := pr.Status.CompletionTime.UTC()
+		statement.Predicate.Metadata.BuildFinishedOn = &t
+	}
+	//the images the steps actually ran, which include the builder and request processor images
+	images := map[string]map[string]string{}
+	for _, trs := range pr.Status.TaskRuns {
+		if trs.Status == nil {
+			continue
+		}
+		for _, step := range trs.Status.Steps {
+			imageId := step.ImageID
+			if i := strings.Index(imageId, "://"); i >= 0 {
+				imageId = imageId[i+3:]
+			}
+			imageParts := strings.SplitN(imageId, "@", 2)
+			if len(imageParts) != 2 {
+				continue
+			}
+			digest, err := parseDigest(imageParts[1])
+			if err != nil {
+				continue
+			}
+			images["oci://"+imageParts[0]+"@"+imageParts[1]] = digest
+		}
+	}
+	if len(images) == 0 && recipe != nil && recipe.Image != "" {
+		//the images were not reported, so the builder image is recorded without its digest
+		images["oci://"+recipe.Image] = nil
+	}
+	uris := []string{}
+	for k := range images {
+		uris = append(uris, k)
+	}
+	sort.Strings(uris)
+	for _, uri := range uris {
+		statement.Predicate.Materials = append(statement.Predicate.Materials, ProvenanceMaterial{URI: strings.Split(uri, "@")[0], Digest: images[uri]})
+	}
+	return statement, nil
+}
+
+// parseDigest converts an image digest such as sha256:abc to the in-toto digest set {"sha256": "abc"}
+func parseDigest(digest string) (map[string]string, error) {
+	parts := strings.SplitN(digest, ":", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return nil, fmt.Errorf("invalid digest %s", digest)
+	}
+	return map[string]string{parts[0]: parts[1]}, nil
+}
+
+// parseSigningKey reads a PEM encoded PKCS8, PKCS1 or SEC1 private key
+func parseSigningKey(data []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("the signing key is not PEM encoded")
+	}
+	var key interface{}
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported signing key type %s, the key must not be encrypted", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		return k, nil
+	case *ecdsa.PrivateKey:
+		return k, nil
+	case ed25519.PrivateKey:
+		return k, nil
+	}
+	return nil, fmt.Errorf("unsupported signing key %T", key)
+}
+
+// signStatement wraps the statement in a DSSE envelope signed with the key. The key id is the SHA-256 of the public
+// key, so a verifier can tell which key to check the signature with.
+func signStatement(statement *InTotoStatement, signer crypto.Signer) ([]byte, error) {
+	payload, err := json.Marshal(statement)
+	if err != nil {
+		return nil, err
+	}
+	//the pre-authentication encoding from the DSSE specification
+	pae := []byte(fmt.Sprintf("DSSEv1 %d %s %d %s", len(InTotoPayloadType), InTotoPayloadType, len(payload), payload))
+	var sig []byte
+	if _, ok := signer.(ed25519.PrivateKey); ok {
+		sig, err = signer.Sign(rand.Reader, pae, crypto.Hash(0))
+	} else {
+		hash := sha256.Sum256(pae)
+		sig, err = signer.Sign(rand.Reader, hash[:], crypto.SHA256)
+	}
+	if err != nil {
+		return nil, err
+	}
+	publicKey, err := x509.MarshalPKIXPublicKey(signer.Public())
+	if err != nil {
+		return nil, err
+	}
+	keyId := sha256.Sum256(publicKey)
+	return json.Marshal(DSSEEnvelope{
+		PayloadType: InTotoPayloadType,
+		Payload:     base64.StdEncoding.EncodeToString(payload),
+		Signatures:  []DSSESignature{{KeyId: hex.EncodeToString(keyId[:]), Sig: base64.StdEncoding.EncodeToString(sig)}},
+	})
+}
+
+// loadSigningKey reads the key configured in the SystemConfig, and returns nil if provenance is not enabled
+func (r *ReconcileDependencyBuild) loadSigningKey(ctx context.Context) (crypto.Signer, error) {
+	systemConfig := v1alpha1.SystemConfig{}
+	err := r.client.Get(ctx, types2.NamespacedName{Name: systemconfig.SystemConfigKey}, &systemConfig)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	settings := systemConfig.Spec.Provenance
+	if settings.SigningKeySecret == "" {
+		return nil, nil
+	}
+	secret := v1.Secret{}
+	err = r.client.Get(ctx, types2.NamespacedName{Namespace: v1alpha1.ControllerNamespace, Name: settings.SigningKeySecret}, &secret)
+	if err != nil {
+		return nil, err
+	}
+	key := settings.SigningKeySecretKey
+	if key == "" {
+		key = DefaultSigningKeySecretKey
+	}
+	data, ok := secret.Data[key]
+	if !ok {
+		return nil, fmt.Errorf("the secret %s does not contain the key %s", settings.SigningKeySecret, key)
+	}
+	return parseSigningKey(data)
+}
+
+// publishProvenance pushes the signed provenance of a successful build as a referrer of the image the artifacts were
+// deployed to, and returns the provenance reference or an empty string if it was not published. Like the SBOM this is
+// best effort, the build has already succeeded.
+func (r *ReconcileDependencyBuild) publishProvenance(ctx context.Context, log logr.Logger, db *v1alpha1.DependencyBuild, pr *pipelinev1beta1.PipelineRun, target *referrerTarget) string {
+	if target == nil {
+		return ""
+	}
+	signer, err := r.loadSigningKey(ctx)
+	if err != nil {
+		log.Error(err, "failed to load the provenance signing key")
+		r.eventRecorder.Eventf(db, v1.EventTypeWarning, "ProvenanceFailed", "The DependencyBuild %s/%s could not sign its provenance: %s", db.Namespace, db.Name, err.Error())
+		return ""
+	}
+	if signer == nil {
+		return ""
+	}
+	statement, err := generateProvenance(db, pr, db.Status.CurrentBuildRecipe, target.subject)
+	if err != nil {
+		log.Error(err, "failed to generate the provenance")
+		return ""
+	}
+	envelope, err := signStatement(statement, signer)
+	if err != nil {
+		log.Error(err, "failed to sign the provenance")
+		return ""
+	}
+	ref, err := r.referrerPublisher.PublishReferrer(ctx, target.subject, ProvenanceArtifactType, target.insecure, target.dockerConfig, envelope)
+	if err != nil {
+		log.Error(err, "failed to publish the provenance", "image", target.subject)
+		r.eventRecorder.Eventf(db, v1.EventTypeWarning, "ProvenanceFailed", "The DependencyBuild %s/%s failed to publish the provenance of image %s", db.Namespace, db.Name, target.subject)
+		return ""
+	}
+	log.Info("published the provenance", "image", target.subject, "provenance", ref)
+	return ref
+}
@@ -0,0 +1,215 @@
+package dependencybuild
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/redhat-appstudio/jvm-build-service/pkg/apis/jvmbuildservice/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// RecipeScorer scores a potential build recipe, the scores from all the scorers are added together and the recipes
+// with the highest total are tried first. Recipes with the same total keep the builder image priority order.
+type RecipeScorer interface {
+	Score(recipe *v1alpha1.BuildRecipe, ranking *RecipeRankingContext) float64
+}
+
+// RecipeRankingContext is the information about the build that is available to the scorers
+type RecipeRankingContext struct {
+	// PreferredJavaVersion the JDK version the build info analysis prefers, may be empty
+	PreferredJavaVersion string
+	// RequestedToolVersions the build tool versions the build info analysis detected, keyed by tool
+	RequestedToolVersions map[string]string
+	// History the outcomes of the other builds in the namespace, keyed by builder image and tool
+	History map[string]*RecipeOutcomes
+}
+
+// RecipeOutcomes the number of times recipes using a builder image and tool have succeeded or failed
+type RecipeOutcomes struct {
+	Succeeded int
+	Failed    int
+}
+
+// PreferredJavaVersionScorer favours builder images with a JDK that is close to the preferred version
+type PreferredJavaVersionScorer struct {
+	Weight float64
+}
+
+func (s PreferredJavaVersionScorer) Score(recipe *v1alpha1.BuildRecipe, ranking *RecipeRankingContext) float64 {
+	if ranking.PreferredJavaVersion == "" {
+		return 0
+	}
+	preferred, err := javaMajorVersion(ranking.PreferredJavaVersion)
+	if err != nil {
+		return 0
+	}
+	actual, err := javaMajorVersion(recipe.JavaVersion)
+	if err != nil {
+		return 0
+	}
+	return s.Weight / (1 + math.Abs(float64(actual-preferred)))
+}
+
+// ToolVersionScorer favours build tool versions that are close to the version the project uses
+type ToolVersionScorer struct {
+	Weight float64
+}
+
+func (s ToolVersionScorer) Score(recipe *v1alpha1.BuildRecipe, ranking *RecipeRankingContext) float64 {
+	requested := ranking.RequestedToolVersions[recipe.Tool]
+	if requested == "" || recipe.ToolVersion == "" {
+		return 0
+	}
+	return s.Weight * versionCloseness(recipe.ToolVersion, requested)
+}
+
+// BuildHistoryScorer favours builder images and tools that have succeeded for other builds in the namespace
+type BuildHistoryScorer struct {
+	Weight float64
+}
+
+func (s BuildHistoryScorer) Score(recipe *v1alpha1.BuildRecipe, ranking *RecipeRankingContext) float64 {
+	outcomes := ranking.History[recipeHistoryKey(recipe.Image, recipe.Tool)]
+	if outcomes == nil {
+		return 0
+	}
+	//smoothed so that a single result does not dominate, and no history is neutral
+	rate := float64(outcomes.Succeeded+1) / float64(outcomes.Succeeded+outcomes.Failed+2)
+	return s.Weight * (rate - 0.5)
+}
+
+// DefaultRecipeScorers the scorers that are used to rank the recipes of every build
+func DefaultRecipeScorers() []RecipeScorer {
+	return []RecipeScorer{
+		PreferredJavaVersionScorer{Weight: 4},
+		ToolVersionScorer{Weight: 2},
+		BuildHistoryScorer{Weight: 3},
+	}
+}
+
+// rankBuildRecipes orders the recipes by score, and removes any recipe that would run the same build as a higher
+// ranked one
+func rankBuildRecipes(recipes []*v1alpha1.BuildRecipe, ranking *RecipeRankingContext, scorers []RecipeScorer) []*v1alpha1.BuildRecipe {
+	scores := map[*v1alpha1.BuildRecipe]float64{}
+	for _, recipe := range recipes {
+		for _, scorer := range scorers {
+			scores[recipe] += scorer.Score(recipe, ranking)
+		}
+	}
+	ranked := append([]*v1alpha1.BuildRecipe{}, recipes...)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return scores[ranked[i]] > scores[ranked[j]]
+	})
+	seen := map[string]bool{}
+	ret := []*v1alpha1.BuildRecipe{}
+	for _, recipe := range ranked {
+		key := recipeDeduplicationKey(recipe)
+		if !seen[key] {
+			seen[key] = true
+			ret = append(ret, recipe)
+		}
+	}
+	return ret
+}
+
+// recipeDeduplicationKey two recipes that only differ by builder image but have the same JDK and tool versions
+// are effectively the same build, so there is no point trying both
+func recipeDeduplicationKey(recipe *v1alpha1.BuildRecipe) string {
+	copied := *recipe
+	copied.Image = ""
+	key, err := json.Marshal(&copied)
+	if err != nil {
+		//should never happen, but don't prune anything if it does
+		return recipe.Image + string(key)
+	}
+	return string(key)
+}
+
+// recipeRankingContext builds the ranking information from the build info analysis and the other builds in the namespace
+func (r *ReconcileDependencyBuild) recipeRankingContext(ctx context.Context, db *v1alpha1.DependencyBuild, buildInfo *marshalledBuildInfo) (*RecipeRankingContext, error) {
+	ranking := RecipeRankingContext{PreferredJavaVersion: buildInfo.Tools["jdk"].Preferred, RequestedToolVersions: map[string]string{}, History: map[string]*RecipeOutcomes{}}
+	for tool, info := range buildInfo.Tools {
+		if tool != "jdk" && info.Preferred != "" {
+			ranking.RequestedToolVersions[tool] = info.Preferred
+		}
+	}
+	if buildInfo.ToolVersion != "" {
+		//the explicit tool version is the version the project build uses, so it takes precedence
+		for _, invocation := range buildInfo.Invocations {
+			if len(invocation) > 0 && invocation[0] != "maven" {
+				ranking.RequestedToolVersions[invocation[0]] = buildInfo.ToolVersion
+			}
+		}
+	}
+
+	dbList := v1alpha1.DependencyBuildList{}
+	if err := r.client.List(ctx, &dbList, client.InNamespace(db.Namespace)); err != nil {
+		return nil, err
+	}
+	for _, other := range dbList.Items {
+		if other.Name == db.Name {
+			continue
+		}
+		for _, failed := range other.Status.FailedBuildRecipes {
+			if failed != nil {
+				recipeOutcomes(&ranking, failed).Failed++
+			}
+		}
+		if other.Status.CurrentBuildRecipe != nil && (other.Status.State == v1alpha1.DependencyBuildStateComplete || other.Status.State == v1alpha1.DependencyBuildStateContaminated) {
+			recipeOutcomes(&ranking, other.Status.CurrentBuildRecipe).Succeeded++
+		}
+	}
+	return &ranking, nil
+}
+
+func recipeOutcomes(ranking *RecipeRankingContext, recipe *v1alpha1.BuildRecipe) *RecipeOutcomes {
+	key := recipeHistoryKey(recipe.Image, recipe.Tool)
+	outcomes := ranking.History[key]
+	if outcomes == nil {
+		outcomes = &RecipeOutcomes{}
+		ranking.History[key] = outcomes
+	}
+	return outcomes
+}
+
+func recipeHistoryKey(image string, tool string) string {
+	return image + "|" + tool
+}
+
+// javaMajorVersion handles both the 1.8 and 8 forms of the version
+func javaMajorVersion(version string) (int, error) {
+	version = strings.TrimPrefix(version, "1.")
+	return strconv.Atoi(strings.Split(version, ".")[0])
+}
+
+// versionCloseness returns a value between 0 and 1, where 1 means the version matches every segment of the
+// requested version. Earlier segments count for more, so 7.4.2 is closer to 7.4 than 7.3.0 or 8.0.
+func versionCloseness(version string, requested string) float64 {
+	vp := strings.Split(version, ".")
+	rp := strings.Split(requested, ".")
+	total := 0.0
+	score := 0.0
+	matching := true
+	for i := range rp {
+		weight := 1 / math.Pow(2, float64(i+1))
+		total += weight
+		if !matching || i >= len(vp) {
+			continue
+		}
+		if vp[i] == rp[i] {
+			score += weight
+			continue
+		}
+		matching = false
+		v, verr := strconv.Atoi(vp[i])
+		r, rerr := strconv.Atoi(rp[i])
+		if verr == nil && rerr == nil {
+			score += weight / (1 + math.Abs(float64(v-r)))
+		}
+	}
+	return score / total
+}
@@ -0,0 +1,232 @@
+package dependencybuild
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/google/go-containerregistry/pkg/name"
+	ggcrv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/redhat-appstudio/jvm-build-service/pkg/apis/jvmbuildservice/v1alpha1"
+	"github.com/redhat-appstudio/jvm-build-service/pkg/reconciler/artifactbuild"
+	"github.com/redhat-appstudio/jvm-build-service/pkg/reconciler/util"
+	pipelinev1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	types2 "k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// referrerTagSuffixes the referrers are also tagged with the digest of the image they describe, so they can be found in
+// registries that do not support the OCI referrers API. They do not use the cosign .sbom and .att tags, Tekton Chains
+// signs and attests the same images with cosign, and one would overwrite the other.
+var referrerTagSuffixes = map[string]string{
+	SBOMArtifactType:       ".jbs.sbom",
+	ProvenanceArtifactType: ".jbs.att",
+}
+
+// ReferrerPublisher pushes an artifact as an OCI referrer of the image it describes, and returns the artifact
+// reference with its digest
+type ReferrerPublisher interface {
+	PublishReferrer(ctx context.Context, subject string, artifactType string, insecure bool, dockerConfig []byte, data []byte) (string, error)
+}
+
+type registryReferrerPublisher struct {
+}
+
+// ociManifest an OCI image manifest with the artifactType and subject fields, which this version of
+// go-containerregistry does not support
+type ociManifest struct {
+	SchemaVersion int64               `json:"schemaVersion"`
+	MediaType     types.MediaType     `json:"mediaType"`
+	ArtifactType  string              `json:"artifactType"`
+	Config        ggcrv1.Descriptor   `json:"config"`
+	Layers        []ggcrv1.Descriptor `json:"layers"`
+	Subject       *ggcrv1.Descriptor  `json:"subject,omitempty"`
+	Annotations   map[string]string   `json:"annotations,omitempty"`
+}
+
+type rawManifest []byte
+
+func (m rawManifest) RawManifest() ([]byte, error) {
+	return m, nil
+}
+
+// blobLayer an uncompressed blob, the artifact is stored as it is rather than in a tar
+type blobLayer struct {
+	data      []byte
+	mediaType types.MediaType
+}
+
+func (b *blobLayer) Digest() (ggcrv1.Hash, error) {
+	h, _, err := ggcrv1.SHA256(bytes.NewReader(b.data))
+	return h, err
+}
+
+func (b *blobLayer) DiffID() (ggcrv1.Hash, error) {
+	return b.Digest()
+}
+
+func (b *blobLayer) Compressed() (io.ReadCloser, error) {
+	return io.NopCloser(bytes.NewReader(b.data)), nil
+}
+
+func (b *blobLayer) Uncompressed() (io.ReadCloser, error) {
+	return b.Compressed()
+}
+
+func (b *blobLayer) Size() (int64, error) {
+	return int64(len(b.data)), nil
+}
+
+func (b *blobLayer) MediaType() (types.MediaType, error) {
+	return b.mediaType, nil
+}
+
+func (b *blobLayer) descriptor() (ggcrv1.Descriptor, error) {
+	digest, err := b.Digest()
+	if err != nil {
+		return ggcrv1.Descriptor{}, err
+	}
+	return ggcrv1.Descriptor{MediaType: b.mediaType, Size: int64(len(b.data)), Digest: digest}, nil
+}
+
+func (p *registryReferrerPublisher) PublishReferrer(ctx context.Context, subject string, artifactType string, insecure bool, dockerConfig []byte, data []byte) (string, error) {
+	opts := []name.Option{}
+	if insecure {
+		opts = append(opts, name.Insecure)
+	}
+	subjectRef, err := name.NewDigest(subject, opts...)
+	if err != nil {
+		return "", err
+	}
+	auth, err := util.RegistryAuth(dockerConfig, subjectRef.RegistryStr())
+	if err != nil {
+		return "", err
+	}
+	remoteOpts := []remote.Option{remote.WithAuth(auth), remote.WithContext(ctx)}
+	subjectDescriptor, err := remote.Head(subjectRef, remoteOpts...)
+	if err != nil {
+		return "", err
+	}
+	repo := subjectRef.Context()
+	//the config is empty, the artifact type says what the layer holds
+	config := &blobLayer{data: []byte("{}"), mediaType: types.MediaType(artifactType)}
+	layer := &blobLayer{data: data, mediaType: types.MediaType(artifactType)}
+	manifest := ociManifest{
+		SchemaVersion: 2,
+		MediaType:     types.OCIManifestSchema1,
+		ArtifactType:  artifactType,
+		Subject:       &ggcrv1.Descriptor{MediaType: subjectDescriptor.MediaType, Size: subjectDescriptor.Size, Digest: subjectDescriptor.Digest},
+		Annotations:   map[string]string{"org.opencontainers.image.created": time.Now().UTC().Format(time.RFC3339)},
+	}
+	for _, blob := range []*blobLayer{config, layer} {
+		if err := remote.WriteLayer(repo, blob, remoteOpts...); err != nil {
+			return "", err
+		}
+	}
+	if manifest.Config, err = config.descriptor(); err != nil {
+		return "", err
+	}
+	layerDescriptor, err := layer.descriptor()
+	if err != nil {
+		return "", err
+	}
+	manifest.Layers = []ggcrv1.Descriptor{layerDescriptor}
+	raw, err := json.Marshal(manifest)
+	if err != nil {
+		return "", err
+	}
+	digest, _, err := ggcrv1.SHA256(bytes.NewReader(raw))
+	if err != nil {
+		return "", err
+	}
+	tag := repo.Tag(referrerTag(subjectDescriptor.Digest, artifactType))
+	if err := remote.Put(tag, rawManifest(raw), remoteOpts...); err != nil {
+		return "", err
+	}
+	return repo.Digest(digest.String()).String(), nil
+}
+
+// referrerTag the tag of a referrer, from the digest of the image it describes
+func referrerTag(subject ggcrv1.Hash, artifactType string) string {
+	return strings.Replace(subject.String(), ":", "-", 1) + referrerTagSuffixes[artifactType]
+}
+
+// referrerTarget the image a build deployed its artifacts to, and the credentials to attach referrers to it
+type referrerTarget struct {
+	// subject the image referenced by digest
+	subject      string
+	insecure     bool
+	dockerConfig []byte
+	// jbsConfig the configuration the build ran with
+	jbsConfig *v1alpha1.JBSConfig
+}
+
+// findReferrerTarget returns where the referrers of a successful build are pushed, or nil if they can't be
+func (r *ReconcileDependencyBuild) findReferrerTarget(ctx context.Context, log logr.Logger, db *v1alpha1.DependencyBuild, image string, digest string) *referrerTarget {
+	if r.referrerPublisher == nil || image == "" || digest == "" {
+		return nil
+	}
+	imageRef, err := name.ParseReference(image, name.WeakValidation)
+	if err != nil {
+		log.Error(err, "failed to parse the image reference, not publishing referrers", "image", image)
+		return nil
+	}
+	jbsConfig := &v1alpha1.JBSConfig{}
+	err = r.client.Get(ctx, types2.NamespacedName{Namespace: db.Namespace, Name: v1alpha1.JBSConfigNameOrDefault(db.Spec.JBSConfig)}, jbsConfig)
+	if err != nil && !errors.IsNotFound(err) {
+		log.Error(err, "failed to read the JBSConfig, not publishing referrers")
+		return nil
+	}
+	secret := &v1.Secret{}
+	err = r.client.Get(ctx, types2.NamespacedName{Namespace: db.Namespace, Name: jbsConfig.ImageSecret()}, secret)
+	if err != nil {
+		//without the registry credentials the referrers cannot be pushed
+		if !errors.IsNotFound(err) {
+			log.Error(err, "failed to read the image registry secret, not publishing referrers")
+		}
+		return nil
+	}
+	return &referrerTarget{
+		subject:      imageRef.Context().Name() + "@" + digest,
+		insecure:     jbsConfig.ImageRegistry().Insecure,
+		dockerConfig: secret.Data[v1alpha1.ImageSecretTokenKey],
+		jbsConfig:    jbsConfig,
+	}
+}
+
+// publishReferrers pushes the SBOM and provenance of a successful build, and links them from its rebuilt artifacts. This
+// is done once the status update has succeeded, so a conflict does not push them again. Like the log archive this is
+// best effort, the build has already succeeded.
+func (r *ReconcileDependencyBuild) publishReferrers(ctx context.Context, log logr.Logger, db *v1alpha1.DependencyBuild, pr *pipelinev1beta1.PipelineRun, image string, digest string, deployed []string) {
+	target := r.findReferrerTarget(ctx, log, db, image, digest)
+	if target == nil {
+		return
+	}
+	sbom := r.publishSBOM(ctx, log, db, target, deployed)
+	provenance := r.publishProvenance(ctx, log, db, pr, target)
+	if sbom == "" && provenance == "" {
+		return
+	}
+	for _, gav := range deployed {
+		ra := v1alpha1.RebuiltArtifact{}
+		if err := r.client.Get(ctx, types2.NamespacedName{Namespace: db.Namespace, Name: artifactbuild.CreateABRName(gav)}, &ra); err != nil {
+			log.Error(err, "failed to read the rebuilt artifact, not linking the SBOM and provenance", "gav", gav)
+			continue
+		}
+		//patched so this does not conflict with the status the rebuilt artifact controller maintains
+		patch := client.MergeFrom(ra.DeepCopy())
+		ra.Status.SBOM = sbom
+		ra.Status.Provenance = provenance
+		if err := r.client.Status().Patch(ctx, &ra, patch); err != nil {
+			log.Error(err, "failed to link the SBOM and provenance from the rebuilt artifact", "gav", gav)
+		}
+	}
+}
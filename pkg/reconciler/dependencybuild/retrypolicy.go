@@ -0,0 +1,135 @@
+package dependencybuild
+
+import (
+	"context"
+	"time"
+
+	"github.com/redhat-appstudio/jvm-build-service/pkg/apis/jvmbuildservice/v1alpha1"
+	"github.com/redhat-appstudio/jvm-build-service/pkg/reconciler/systemconfig"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	defaultMaxRetries         = 3
+	defaultMemoryIncrement    = 512
+	defaultMemoryGrowthFactor = 2
+	defaultMemoryCeiling      = 2048
+	defaultInitialBackoff     = time.Second * 10
+	defaultMaxBackoff         = time.Minute * 10
+)
+
+// retryPolicy the effective retry policy, the JBSConfig takes precedence over the SystemConfig
+type retryPolicy struct {
+	jbsConfig    *v1alpha1.RetryPolicy
+	systemConfig *v1alpha1.RetryPolicy
+}
+
+func newRetryPolicy(jbsConfig *v1alpha1.JBSConfig, systemConfig *v1alpha1.SystemConfig) retryPolicy {
+	return retryPolicy{jbsConfig: &jbsConfig.Spec.BuildSettings.RetryPolicy, systemConfig: &systemConfig.Spec.RetryPolicy}
+}
+
+// loadRetryPolicy the built in defaults are used if there is no JBSConfig or SystemConfig
+func (r *ReconcileDependencyBuild) loadRetryPolicy(ctx context.Context, db *v1alpha1.DependencyBuild) (retryPolicy, error) {
+	jbsConfig := &v1alpha1.JBSConfig{}
+	err := r.client.Get(ctx, types.NamespacedName{Namespace: db.Namespace, Name: v1alpha1.JBSConfigNameOrDefault(db.Spec.JBSConfig)}, jbsConfig)
+	if err != nil && !errors.IsNotFound(err) {
+		return retryPolicy{}, err
+	}
+	systemConfig := &v1alpha1.SystemConfig{}
+	err = r.client.Get(ctx, types.NamespacedName{Name: systemconfig.SystemConfigKey}, systemConfig)
+	if err != nil && !errors.IsNotFound(err) {
+		return retryPolicy{}, err
+	}
+	return newRetryPolicy(jbsConfig, systemConfig), nil
+}
+
+func (p retryPolicy) maxRetries(cause string) int {
+	if max, ok := p.jbsConfig.MaxRetries[cause]; ok {
+		return max
+	}
+	if max, ok := p.systemConfig.MaxRetries[cause]; ok {
+		return max
+	}
+	return defaultMaxRetries
+}
+
+func (p retryPolicy) canRetry(cause string, retries map[string]int) bool {
+	return retries[cause] < p.maxRetries(cause)
+}
+
+// nextAdditionalMemory returns the additional memory to retry an out of memory pipeline with, or zero if the memory
+// ceiling has been reached
+func (p retryPolicy) nextAdditionalMemory(current int) int {
+	ceiling := intOrDefault(p.jbsConfig.MemoryCeiling, p.systemConfig.MemoryCeiling, defaultMemoryCeiling)
+	if current >= ceiling {
+		return 0
+	}
+	next := current * intOrDefault(p.jbsConfig.MemoryGrowthFactor, p.systemConfig.MemoryGrowthFactor, defaultMemoryGrowthFactor)
+	if current == 0 {
+		next = intOrDefault(p.jbsConfig.MemoryIncrement, p.systemConfig.MemoryIncrement, defaultMemoryIncrement)
+	}
+	if next > ceiling {
+		next = ceiling
+	}
+	return next
+}
+
+// backoff the delay before a retry, where retries is the number of times the pipeline has already been retried
+func (p retryPolicy) backoff(retries int) time.Duration {
+	initial := defaultInitialBackoff
+	if p.jbsConfig.InitialBackoff != nil {
+		initial = p.jbsConfig.InitialBackoff.Duration
+	} else if p.systemConfig.InitialBackoff != nil {
+		initial = p.systemConfig.InitialBackoff.Duration
+	}
+	max := defaultMaxBackoff
+	if p.jbsConfig.MaxBackoff != nil {
+		max = p.jbsConfig.MaxBackoff.Duration
+	} else if p.systemConfig.MaxBackoff != nil {
+		max = p.systemConfig.MaxBackoff.Duration
+	}
+	if initial <= 0 {
+		return 0
+	}
+	delay := initial
+	for i := 0; i < retries && delay < max; i++ {
+		delay *= 2
+	}
+	if delay > max {
+		delay = max
+	}
+	return delay
+}
+
+func intOrDefault(value int, systemValue int, def int) int {
+	if value > 0 {
+		return value
+	}
+	if systemValue > 0 {
+		return systemValue
+	}
+	return def
+}
+
+// scheduleRetry delays the next pipeline by the backoff for the number of retries so far
+func scheduleRetry(db *v1alpha1.DependencyBuild, delay time.Duration) {
+	if delay <= 0 {
+		db.Status.RetryAfter = nil
+		return
+	}
+	db.Status.RetryAfter = &metav1.Time{Time: time.Now().Add(delay)}
+}
+
+// retryDelay the time left before the pipeline can be retried, zero if it can run now
+func retryDelay(db *v1alpha1.DependencyBuild) time.Duration {
+	if db.Status.RetryAfter == nil {
+		return 0
+	}
+	delay := time.Until(db.Status.RetryAfter.Time)
+	if delay < 0 {
+		return 0
+	}
+	return delay
+}
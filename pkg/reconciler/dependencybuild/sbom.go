@@ -0,0 +1,206 @@
+package dependencybuild
+
+import (
+	"bytes"
+	"context"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	cdx "github.com/CycloneDX/cyclonedx-go"
+	"github.com/go-logr/logr"
+	"github.com/redhat-appstudio/jvm-build-service/pkg/apis/jvmbuildservice/v1alpha1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/uuid"
+)
+
+const (
+	SBOMArtifactType = "application/vnd.cyclonedx+json"
+
+	sbomPropertyPrefix = "jvmbuildservice:"
+
+	// these match the stores of the cache that every JBSConfig gets, the rebuilt artifacts come first
+	centralRepositoryURL = "https://repo.maven.apache.org/maven2"
+	redhatRepositoryURL  = "https://maven.repository.redhat.com/ga"
+)
+
+var mavenRepositoryKey = regexp.MustCompile(v1alpha1.MavenRepositoryKeyPattern)
+
+// generateSBOM describes what went into a successful build: the source, the builder image and tools, the additional
+// downloads of the recipe, the repositories the dependencies were resolved from, and the artifacts that were deployed
+func generateSBOM(db *v1alpha1.DependencyBuild, recipe *v1alpha1.BuildRecipe, jbsConfig *v1alpha1.JBSConfig, deployed []string, image string) ([]byte, error) {
+	bom := cdx.NewBOM()
+	bom.SerialNumber = "urn:uuid:" + string(uuid.NewUUID())
+	scm := db.Spec.ScmInfo
+	source := cdx.Component{
+		BOMRef:  scm.SCMURL + "@" + scm.CommitHash,
+		Type:    cdx.ComponentTypeApplication,
+		Name:    scm.SCMURL,
+		Version: scm.Tag,
+		ExternalReferences: &[]cdx.ExternalReference{
+			{Type: cdx.ERTypeVCS, URL: scm.SCMURL},
+		},
+		Pedigree: &cdx.Pedigree{Commits: &[]cdx.Commit{{UID: scm.CommitHash, URL: scm.SCMURL}}},
+	}
+	if scm.Path != "" {
+		source.Properties = &[]cdx.Property{{Name: sbomPropertyPrefix + "path", Value: scm.Path}}
+	}
+	bom.Metadata = &cdx.Metadata{
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+		Tools:     &[]cdx.Tool{{Vendor: "Red Hat", Name: "jvm-build-service"}},
+		Component: &source,
+	}
+	components := []cdx.Component{}
+	for _, gav := range deployed {
+		components = append(components, deployedComponent(gav, image))
+	}
+	if recipe != nil {
+		if recipe.Image != "" {
+			builder := cdx.Component{
+				BOMRef: recipe.Image,
+				Type:   cdx.ComponentTypeContainer,
+				Name:   recipe.Image,
+				Scope:  cdx.ScopeExcluded,
+				Properties: &[]cdx.Property{
+					{Name: sbomPropertyPrefix + "tool", Value: recipe.Tool},
+					{Name: sbomPropertyPrefix + "tool-version", Value: recipe.ToolVersion},
+					{Name: sbomPropertyPrefix + "java-version", Value: recipe.JavaVersion},
+				},
+			}
+			components = append(components, builder)
+		}
+		tools := append(*bom.Metadata.Tools, cdx.Tool{Name: recipe.Tool, Version: recipe.ToolVersion}, cdx.Tool{Name: "jdk", Version: recipe.JavaVersion})
+		bom.Metadata.Tools = &tools
+		for _, download := range recipe.AdditionalDownloads {
+			component := cdx.Component{
+				BOMRef: download.Uri,
+				Type:   cdx.ComponentTypeFile,
+				Name:   download.FileName,
+				Scope:  cdx.ScopeExcluded,
+				ExternalReferences: &[]cdx.ExternalReference{
+					{Type: cdx.ERTypeDistribution, URL: download.Uri},
+				},
+			}
+			if component.Name == "" {
+				component.Name = download.PackageName
+			}
+			if component.Name == "" {
+				component.Name = download.Uri
+			}
+			if download.Sha256 != "" {
+				component.Hashes = &[]cdx.Hash{{Algorithm: cdx.HashAlgoSHA256, Value: download.Sha256}}
+			}
+			components = append(components, component)
+		}
+	}
+	if refs := buildRepositories(jbsConfig, recipe); len(refs) > 0 {
+		bom.ExternalReferences = &refs
+	}
+	bom.Components = &components
+	buf := bytes.Buffer{}
+	if err := cdx.NewBOMEncoder(&buf, cdx.BOMFileFormatJSON).Encode(bom); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// deployedComponent describes a deployed artifact, which is group:artifact:version, optionally with the type and
+// classifier before the version. Anything else is still listed, but without a package URL.
+func deployedComponent(gav string, image string) cdx.Component {
+	component := cdx.Component{
+		BOMRef: gav,
+		Type:   cdx.ComponentTypeLibrary,
+		Name:   gav,
+		ExternalReferences: &[]cdx.ExternalReference{
+			{Type: cdx.ERTypeDistribution, URL: image},
+		},
+	}
+	parts := strings.Split(gav, ":")
+	if len(parts) < 3 || len(parts) > 5 {
+		return component
+	}
+	component.Group = parts[0]
+	component.Name = parts[1]
+	component.Version = parts[len(parts)-1]
+	qualifiers := url.Values{}
+	if len(parts) > 3 {
+		qualifiers.Set("type", parts[2])
+	}
+	if len(parts) > 4 {
+		qualifiers.Set("classifier", parts[3])
+	}
+	component.PackageURL = "pkg:maven/" + component.Group + "/" + component.Name + "@" + component.Version
+	if len(qualifiers) > 0 {
+		component.PackageURL += "?" + qualifiers.Encode()
+	}
+	return component
+}
+
+// buildRepositories the repositories the build resolved its dependencies from. The build only talks to the cache, which
+// has the rebuilt artifacts, the default repositories and the repositories of the JBSConfig, in the order it checks
+// them. The recipe can add further repositories of the cache by name.
+func buildRepositories(jbsConfig *v1alpha1.JBSConfig, recipe *v1alpha1.BuildRecipe) []cdx.ExternalReference {
+	if jbsConfig == nil {
+		return nil
+	}
+	type repository struct {
+		url      string
+		position int
+	}
+	repositories := []repository{{url: centralRepositoryURL, position: 200}, {url: redhatRepositoryURL, position: 250}}
+	if jbsConfig.Spec.EnableRebuilds {
+		repositories = append(repositories, repository{url: deploymentRepository(jbsConfig), position: 100})
+	}
+	for k, v := range jbsConfig.Spec.MavenBaseLocations {
+		results := mavenRepositoryKey.FindStringSubmatch(k)
+		if results == nil {
+			continue
+		}
+		position, err := strconv.Atoi(results[1])
+		if err != nil {
+			continue
+		}
+		repositories = append(repositories, repository{url: v, position: position})
+	}
+	sort.SliceStable(repositories, func(i, j int) bool {
+		if repositories[i].position == repositories[j].position {
+			return repositories[i].url < repositories[j].url
+		}
+		return repositories[i].position < repositories[j].position
+	})
+	refs := []cdx.ExternalReference{{Type: cdx.ERTypeDistribution, URL: jbsConfig.CacheURL(), Comment: "cache the build resolved its dependencies from"}}
+	for _, repo := range repositories {
+		refs = append(refs, cdx.ExternalReference{Type: cdx.ERTypeDistribution, URL: repo.url, Comment: "repository of the cache"})
+	}
+	if recipe != nil {
+		for _, repo := range recipe.Repositories {
+			refs = append(refs, cdx.ExternalReference{Type: cdx.ERTypeDistribution, URL: repo, Comment: "repository of the cache added by the build recipe"})
+		}
+	}
+	return refs
+}
+
+// publishSBOM pushes an SBOM of a successful build as a referrer of the image the artifacts were deployed to, and
+// returns the SBOM reference or an empty string if it was not published. Like the log archive this is best effort,
+// the build has already succeeded.
+func (r *ReconcileDependencyBuild) publishSBOM(ctx context.Context, log logr.Logger, db *v1alpha1.DependencyBuild, target *referrerTarget, deployed []string) string {
+	if target == nil {
+		return ""
+	}
+	sbom, err := generateSBOM(db, db.Status.CurrentBuildRecipe, target.jbsConfig, deployed, target.subject)
+	if err != nil {
+		log.Error(err, "failed to generate the SBOM")
+		return ""
+	}
+	ref, err := r.referrerPublisher.PublishReferrer(ctx, target.subject, SBOMArtifactType, target.insecure, target.dockerConfig, sbom)
+	if err != nil {
+		log.Error(err, "failed to publish the SBOM", "image", target.subject)
+		r.eventRecorder.Eventf(db, v1.EventTypeWarning, "SBOMPublishFailed", "The DependencyBuild %s/%s failed to publish the SBOM of image %s", db.Namespace, db.Name, target.subject)
+		return ""
+	}
+	log.Info("published the SBOM", "image", target.subject, "sbom", ref)
+	return ref
+}
@@ -16,6 +16,15 @@ Pre build script: {{PRE_BUILD_SCRIPT}}
 EOF
 {{PRE_BUILD_SCRIPT}}
 
+TOOL_VERSION="$(params.TOOL_VERSION)"
+if [ -n "${TOOL_VERSION}" ] && [ -d "/opt/maven/${TOOL_VERSION}" ]; then
+    export MAVEN_HOME="/opt/maven/${TOOL_VERSION}"
+    export PATH="${MAVEN_HOME}/bin:${PATH}"
+    echo "MAVEN_HOME=${MAVEN_HOME}"
+else
+    echo "Maven ${TOOL_VERSION} not found in /opt/maven, using the default Maven"
+fi
+
 if [ -z "$(params.ENFORCE_VERSION)" ]
 then
   echo "Enforce version not set, skipping"
@@ -0,0 +1,115 @@
+package dependencybuild
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/redhat-appstudio/jvm-build-service/pkg/apis/jvmbuildservice/v1alpha1"
+	pipelinev1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"knative.dev/pkg/apis"
+)
+
+const (
+	defaultPipelineTimeout    = time.Hour * 3
+	defaultMaxPipelineTimeout = time.Hour * 12
+)
+
+// pipelineTimeout the timeout from the recipe takes precedence over the JBSConfig
+func pipelineTimeout(recipe *v1alpha1.BuildRecipe, jbsConfig *v1alpha1.JBSConfig) time.Duration {
+	if recipe.PipelineTimeout != nil && recipe.PipelineTimeout.Duration > 0 {
+		return recipe.PipelineTimeout.Duration
+	}
+	if jbsConfig.Spec.BuildSettings.PipelineTimeout != nil && jbsConfig.Spec.BuildSettings.PipelineTimeout.Duration > 0 {
+		return jbsConfig.Spec.BuildSettings.PipelineTimeout.Duration
+	}
+	return defaultPipelineTimeout
+}
+
+// stepTimeout returns nil if the steps should only be limited by the pipeline timeout
+func stepTimeout(recipe *v1alpha1.BuildRecipe, jbsConfig *v1alpha1.JBSConfig) *metav1.Duration {
+	if recipe.StepTimeout != nil && recipe.StepTimeout.Duration > 0 {
+		return recipe.StepTimeout
+	}
+	if jbsConfig.Spec.BuildSettings.StepTimeout != nil && jbsConfig.Spec.BuildSettings.StepTimeout.Duration > 0 {
+		return jbsConfig.Spec.BuildSettings.StepTimeout
+	}
+	return nil
+}
+
+func maxPipelineTimeout(jbsConfig *v1alpha1.JBSConfig) time.Duration {
+	if jbsConfig.Spec.BuildSettings.MaxPipelineTimeout != nil && jbsConfig.Spec.BuildSettings.MaxPipelineTimeout.Duration > 0 {
+		return jbsConfig.Spec.BuildSettings.MaxPipelineTimeout.Duration
+	}
+	return defaultMaxPipelineTimeout
+}
+
+func pipelineRunTimedOut(pr *pipelinev1beta1.PipelineRun) bool {
+	condition := pr.Status.GetCondition(apis.ConditionSucceeded)
+	return condition != nil && condition.IsFalse() && condition.Reason == pipelinev1beta1.PipelineRunReasonTimedOut.String()
+}
+
+// longerPipelineTimeout returns the timeout to retry a timed out recipe with, or zero if the recipe should not be
+// retried because of the timeout policy, or because the maximum timeout has already been used
+func longerPipelineTimeout(recipe *v1alpha1.BuildRecipe, jbsConfig *v1alpha1.JBSConfig) time.Duration {
+	if jbsConfig.Spec.BuildSettings.TimeoutPolicy != v1alpha1.BuildTimeoutPolicyRetryWithLongerTimeout {
+		return 0
+	}
+	current := pipelineTimeout(recipe, jbsConfig)
+	max := maxPipelineTimeout(jbsConfig)
+	if current >= max {
+		return 0
+	}
+	longer := current * 2
+	if longer > max {
+		longer = max
+	}
+	return longer
+}
+
+// parseRecipeTimeout an invalid timeout in the recipe database is ignored, so the configured timeout is used instead
+func parseRecipeTimeout(log logr.Logger, name string, value string) *metav1.Duration {
+	if value == "" {
+		return nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		log.Info(fmt.Sprintf("Ignoring invalid build recipe %s %s", name, value))
+		return nil
+	}
+	return &metav1.Duration{Duration: d}
+}
+
+// handleBuildTimeout records that the build pipeline timed out, and returns true if the current recipe should be
+// retried with a longer timeout rather than moving on to the next recipe. Like the other causes, the number of
+// timeout retries is limited by the retry policy.
+func (r *ReconcileDependencyBuild) handleBuildTimeout(ctx context.Context, log logr.Logger, db *v1alpha1.DependencyBuild, pr *pipelinev1beta1.PipelineRun, policy retryPolicy) (bool, error) {
+	jbsConfig := &v1alpha1.JBSConfig{}
+	err := r.client.Get(ctx, types.NamespacedName{Namespace: db.Namespace, Name: v1alpha1.JBSConfigNameOrDefault(db.Spec.JBSConfig)}, jbsConfig)
+	if err != nil && !errors.IsNotFound(err) {
+		return false, err
+	}
+	current := pipelineTimeout(db.Status.CurrentBuildRecipe, jbsConfig)
+	var longer time.Duration
+	if policy.canRetry(v1alpha1.BuildFailureClassTimeout, db.Status.RetriesByCause) {
+		longer = longerPipelineTimeout(db.Status.CurrentBuildRecipe, jbsConfig)
+	}
+	if longer == 0 {
+		msg := fmt.Sprintf("PipelineRun %s timed out after %s, trying the next build recipe", pr.Name, current)
+		log.Info(msg)
+		r.eventRecorder.Eventf(db, v1.EventTypeWarning, "BuildTimedOut", "The DependencyBuild %s/%s build pipeline timed out after %s", db.Namespace, db.Name, current)
+		setCondition(db, v1alpha1.DependencyBuildConditionSucceeded, metav1.ConditionUnknown, v1alpha1.DependencyBuildReasonPipelineRunTimeout, msg)
+		return false, nil
+	}
+	msg := fmt.Sprintf("PipelineRun %s timed out after %s, retrying the build with a timeout of %s", pr.Name, current, longer)
+	log.Info(msg)
+	r.eventRecorder.Eventf(db, v1.EventTypeWarning, "BuildTimedOut", "The DependencyBuild %s/%s build pipeline timed out after %s, retrying with a timeout of %s", db.Namespace, db.Name, current, longer)
+	setCondition(db, v1alpha1.DependencyBuildConditionSucceeded, metav1.ConditionUnknown, v1alpha1.DependencyBuildReasonTimeoutRetry, msg)
+	db.Status.CurrentBuildRecipe.PipelineTimeout = &metav1.Duration{Duration: longer}
+	return true, nil
+}
@@ -0,0 +1,221 @@
+package jbsconfig
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/redhat-appstudio/jvm-build-service/pkg/apis/jvmbuildservice/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// failingContainerReasons are the waiting reasons of a cache container that will not recover without a change
+var failingContainerReasons = map[string]bool{
+	"CrashLoopBackOff":           true,
+	"ImagePullBackOff":           true,
+	"ErrImagePull":               true,
+	"CreateContainerConfigError": true,
+}
+
+var mavenRepositoryKeyRegex = regexp.MustCompile("^" + v1alpha1.MavenRepositoryKeyPattern + "$")
+
+// updateConditions sets the conditions from the current state of the resources the JBSConfig depends on, rather than
+// from what the reconciler last did, so they also report a cache that was created but is not running. validationErr
+// is the error provisioning or finding the registry credentials, and settingsErr the error parsing the cache settings,
+// if any.
+func (r *ReconcilerJBSConfig) updateConditions(ctx context.Context, jbsConfig *v1alpha1.JBSConfig, validationErr error, settingsErr error) error {
+	original := jbsConfig.Status.DeepCopy()
+	if settingsErr != nil {
+		setCondition(jbsConfig, v1alpha1.JBSConfigConditionCacheReady, metav1.ConditionFalse, v1alpha1.JBSConfigReasonInvalidCacheSettings, settingsErr.Error())
+	} else if err := r.cacheCondition(ctx, jbsConfig); err != nil {
+		return err
+	}
+	credentials, err := r.registryCredentialsCondition(ctx, jbsConfig, validationErr)
+	if err != nil {
+		return err
+	}
+	err = r.tlsCondition(ctx, jbsConfig)
+	if err != nil {
+		return err
+	}
+	repositoriesCondition(jbsConfig)
+
+	if !jbsConfig.Spec.EnableRebuilds {
+		setCondition(jbsConfig, v1alpha1.JBSConfigConditionRebuildsEnabled, metav1.ConditionFalse, v1alpha1.JBSConfigReasonRebuildsDisabled, "rebuilds are not enabled, dependencies are only cached")
+	} else if credentials.Status != metav1.ConditionTrue {
+		setCondition(jbsConfig, v1alpha1.JBSConfigConditionRebuildsEnabled, metav1.ConditionFalse, v1alpha1.JBSConfigReasonRegistryCredentialsNotReady, credentials.Message)
+	} else {
+		setCondition(jbsConfig, v1alpha1.JBSConfigConditionRebuildsEnabled, metav1.ConditionTrue, v1alpha1.JBSConfigReasonRebuildsEnabled, "")
+	}
+	updateReady(jbsConfig)
+
+	if equality.Semantic.DeepEqual(original, &jbsConfig.Status) {
+		return nil
+	}
+	return r.client.Status().Update(ctx, jbsConfig)
+}
+
+func (r *ReconcilerJBSConfig) cacheCondition(ctx context.Context, jbsConfig *v1alpha1.JBSConfig) error {
+	//a JBSConfig that shares the cache reports on the default cache, as that is what its builds use
+	deployment := appsv1.Deployment{}
+	err := r.client.Get(ctx, types.NamespacedName{Namespace: jbsConfig.Namespace, Name: jbsConfig.CacheName()}, &deployment)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			setCondition(jbsConfig, v1alpha1.JBSConfigConditionCacheReady, metav1.ConditionFalse, v1alpha1.JBSConfigReasonCacheNotFound, "cache deployment "+jbsConfig.CacheName()+" does not exist")
+			return nil
+		}
+		return err
+	}
+	pods := corev1.PodList{}
+	err = r.client.List(ctx, &pods, client.InNamespace(jbsConfig.Namespace), client.MatchingLabels{v1alpha1.CacheLabel: jbsConfig.CacheName()})
+	if err != nil {
+		return err
+	}
+	for _, pod := range pods.Items {
+		if !pod.DeletionTimestamp.IsZero() {
+			continue
+		}
+		for _, status := range pod.Status.ContainerStatuses {
+			if status.State.Waiting != nil && failingContainerReasons[status.State.Waiting.Reason] {
+				message := fmt.Sprintf("container %s of cache pod %s is in %s", status.Name, pod.Name, status.State.Waiting.Reason)
+				if status.State.Waiting.Message != "" {
+					message = message + ": " + status.State.Waiting.Message
+				}
+				setCondition(jbsConfig, v1alpha1.JBSConfigConditionCacheReady, metav1.ConditionFalse, v1alpha1.JBSConfigReasonCacheFailing, message)
+				return nil
+			}
+		}
+	}
+	if deployment.Status.ObservedGeneration < deployment.Generation || deployment.Status.AvailableReplicas < 1 {
+		setCondition(jbsConfig, v1alpha1.JBSConfigConditionCacheReady, metav1.ConditionFalse, v1alpha1.JBSConfigReasonCacheProgressing, "waiting for cache deployment "+deployment.Name+" to become available")
+		return nil
+	}
+	setCondition(jbsConfig, v1alpha1.JBSConfigConditionCacheReady, metav1.ConditionTrue, v1alpha1.JBSConfigReasonCacheAvailable, "")
+	return nil
+}
+
+// registryCredentialsCondition returns the condition, so the RebuildsEnabled condition can be derived from it
+func (r *ReconcilerJBSConfig) registryCredentialsCondition(ctx context.Context, jbsConfig *v1alpha1.JBSConfig, validationErr error) (metav1.Condition, error) {
+	if !jbsConfig.Spec.EnableRebuilds || jbsConfig.Annotations[TestRegistry] == "true" {
+		setCondition(jbsConfig, v1alpha1.JBSConfigConditionRegistryCredentialsReady, metav1.ConditionTrue, v1alpha1.JBSConfigReasonCredentialsNotRequired, "")
+		return *meta.FindStatusCondition(jbsConfig.Status.Conditions, v1alpha1.JBSConfigConditionRegistryCredentialsReady), nil
+	}
+	secret := corev1.Secret{}
+	err := r.client.Get(ctx, types.NamespacedName{Namespace: jbsConfig.Namespace, Name: jbsConfig.ImageSecret()}, &secret)
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return metav1.Condition{}, err
+		}
+		message := "secret " + jbsConfig.ImageSecret() + " does not exist"
+		if validationErr != nil {
+			message = message + ": " + validationErr.Error()
+		}
+		setCondition(jbsConfig, v1alpha1.JBSConfigConditionRegistryCredentialsReady, metav1.ConditionFalse, v1alpha1.JBSConfigReasonSecretNotFound, message)
+	} else if len(secret.Data[v1alpha1.ImageSecretTokenKey]) == 0 && len(secret.StringData[v1alpha1.ImageSecretTokenKey]) == 0 {
+		setCondition(jbsConfig, v1alpha1.JBSConfigConditionRegistryCredentialsReady, metav1.ConditionFalse, v1alpha1.JBSConfigReasonTokenNotFound, fmt.Sprintf("secret %s has no %s key", jbsConfig.ImageSecret(), v1alpha1.ImageSecretTokenKey))
+	} else {
+		setCondition(jbsConfig, v1alpha1.JBSConfigConditionRegistryCredentialsReady, metav1.ConditionTrue, v1alpha1.JBSConfigReasonCredentialsFound, "")
+	}
+	return *meta.FindStatusCondition(jbsConfig.Status.Conditions, v1alpha1.JBSConfigConditionRegistryCredentialsReady), nil
+}
+
+func (r *ReconcilerJBSConfig) tlsCondition(ctx context.Context, jbsConfig *v1alpha1.JBSConfig) error {
+	if jbsConfig.Spec.CacheSettings.DisableTLS {
+		setCondition(jbsConfig, v1alpha1.JBSConfigConditionTLSReady, metav1.ConditionTrue, v1alpha1.JBSConfigReasonTLSDisabled, "")
+		return nil
+	}
+	//both are filled in by the OpenShift service CA operator
+	err := r.client.Get(ctx, types.NamespacedName{Namespace: jbsConfig.Namespace, Name: jbsConfig.TlsSecret()}, &corev1.Secret{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			setCondition(jbsConfig, v1alpha1.JBSConfigConditionTLSReady, metav1.ConditionFalse, v1alpha1.JBSConfigReasonCertificateNotIssued, "serving certificate secret "+jbsConfig.TlsSecret()+" does not exist")
+			return nil
+		}
+		return err
+	}
+	configMap := corev1.ConfigMap{}
+	err = r.client.Get(ctx, types.NamespacedName{Namespace: jbsConfig.Namespace, Name: v1alpha1.TlsConfigMapName}, &configMap)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	if err != nil || configMap.Data["service-ca.crt"] == "" {
+		setCondition(jbsConfig, v1alpha1.JBSConfigConditionTLSReady, metav1.ConditionFalse, v1alpha1.JBSConfigReasonCABundleNotInjected, "CA bundle has not been injected into config map "+v1alpha1.TlsConfigMapName)
+		return nil
+	}
+	setCondition(jbsConfig, v1alpha1.JBSConfigConditionTLSReady, metav1.ConditionTrue, v1alpha1.JBSConfigReasonCertificateIssued, "")
+	return nil
+}
+
+func repositoriesCondition(jbsConfig *v1alpha1.JBSConfig) {
+	var problems []string
+	for _, recipes := range jbsConfig.Spec.AdditionalRecipes {
+		if !absoluteURL(recipes) {
+			problems = append(problems, "additional recipe repository "+recipes+" is not an absolute URL")
+		}
+	}
+	//the cache always has these repositories
+	names := map[string]string{"central": "central", "redhat": "redhat", "rebuilt": "rebuilt"}
+	keys := make([]string, 0, len(jbsConfig.Spec.MavenBaseLocations))
+	for k := range jbsConfig.Spec.MavenBaseLocations {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		results := mavenRepositoryKeyRegex.FindStringSubmatch(k)
+		if results == nil {
+			problems = append(problems, "key "+k+" is not in the form maven-repository-<position>-<name>")
+			continue
+		}
+		if existing, ok := names[results[2]]; ok {
+			problems = append(problems, "repository "+results[2]+" of "+k+" is already defined by "+existing)
+		} else {
+			names[results[2]] = k
+		}
+		if !absoluteURL(jbsConfig.Spec.MavenBaseLocations[k]) {
+			problems = append(problems, "location "+jbsConfig.Spec.MavenBaseLocations[k]+" of "+k+" is not an absolute URL")
+		}
+	}
+	if len(problems) > 0 {
+		setCondition(jbsConfig, v1alpha1.JBSConfigConditionRecipeRepositoriesConfigured, metav1.ConditionFalse, v1alpha1.JBSConfigReasonInvalidRepository, strings.Join(problems, ", "))
+		return
+	}
+	setCondition(jbsConfig, v1alpha1.JBSConfigConditionRecipeRepositoriesConfigured, metav1.ConditionTrue, v1alpha1.JBSConfigReasonRepositoriesConfigured, "")
+}
+
+func absoluteURL(value string) bool {
+	parsed, err := url.ParseRequestURI(value)
+	return err == nil && parsed.Scheme != "" && parsed.Host != ""
+}
+
+// updateReady sets Ready from the first condition that is not true, RebuildsEnabled is not included as caching works
+// without rebuilds
+func updateReady(jbsConfig *v1alpha1.JBSConfig) {
+	for _, t := range []string{v1alpha1.JBSConfigConditionCacheReady, v1alpha1.JBSConfigConditionRegistryCredentialsReady, v1alpha1.JBSConfigConditionTLSReady, v1alpha1.JBSConfigConditionRecipeRepositoriesConfigured} {
+		condition := meta.FindStatusCondition(jbsConfig.Status.Conditions, t)
+		if condition != nil && condition.Status != metav1.ConditionTrue {
+			setCondition(jbsConfig, v1alpha1.JBSConfigConditionReady, condition.Status, condition.Reason, condition.Message)
+			return
+		}
+	}
+	setCondition(jbsConfig, v1alpha1.JBSConfigConditionReady, metav1.ConditionTrue, v1alpha1.JBSConfigReasonReady, "")
+}
+
+func setCondition(jbsConfig *v1alpha1.JBSConfig, conditionType string, status metav1.ConditionStatus, reason string, message string) {
+	meta.SetStatusCondition(&jbsConfig.Status.Conditions, metav1.Condition{
+		Type:               conditionType,
+		Status:             status,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: jbsConfig.Generation,
+	})
+}
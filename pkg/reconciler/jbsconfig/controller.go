@@ -1,18 +1,50 @@
 package jbsconfig
 
 import (
-	"github.com/redhat-appstudio/image-controller/pkg/quay"
+	"context"
+
 	"github.com/redhat-appstudio/jvm-build-service/pkg/apis/jvmbuildservice/v1alpha1"
+	"github.com/redhat-appstudio/jvm-build-service/pkg/registry"
 	"github.com/redhat-appstudio/service-provider-integration-operator/api/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/controller-runtime/pkg/source"
 )
 
-func SetupNewReconcilerWithManager(mgr ctrl.Manager, spiPresent bool, quayClient *quay.QuayClient, quayOrgName string) error {
-	r := newReconciler(mgr, spiPresent, quayClient, quayOrgName)
+func SetupNewReconcilerWithManager(mgr ctrl.Manager, spiPresent bool, provisioner *registry.ProvisionerRef) error {
+	r := newReconciler(mgr, spiPresent, provisioner)
 	builder := ctrl.NewControllerManagedBy(mgr).
-		For(&v1alpha1.JBSConfig{})
+		For(&v1alpha1.JBSConfig{}).
+		//the default cache has a build policy for every JBSConfig that shares it
+		Watches(&source.Kind{Type: &v1alpha1.JBSConfig{}}, handler.EnqueueRequestsFromMapFunc(func(o client.Object) []reconcile.Request {
+			if o.GetName() == v1alpha1.JBSConfigName {
+				return []reconcile.Request{}
+			}
+			return []reconcile.Request{{NamespacedName: types.NamespacedName{Namespace: o.GetNamespace(), Name: v1alpha1.JBSConfigName}}}
+		})).
+		//the CacheReady condition reflects the state of the cache pods
+		Watches(&source.Kind{Type: &corev1.Pod{}}, handler.EnqueueRequestsFromMapFunc(func(o client.Object) []reconcile.Request {
+			cacheName, ok := o.GetLabels()[v1alpha1.CacheLabel]
+			if !ok {
+				return []reconcile.Request{}
+			}
+			list := v1alpha1.JBSConfigList{}
+			err := mgr.GetClient().List(context.Background(), &list, client.InNamespace(o.GetNamespace()))
+			if err != nil {
+				return []reconcile.Request{}
+			}
+			var requests []reconcile.Request
+			for _, jbsConfig := range list.Items {
+				if jbsConfig.CacheName() == cacheName {
+					requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: jbsConfig.Namespace, Name: jbsConfig.Name}})
+				}
+			}
+			return requests
+		}))
 	if spiPresent {
 		builder.Watches(&source.Kind{Type: &v1beta1.SPIAccessTokenBinding{}}, &handler.EnqueueRequestForOwner{OwnerType: &v1alpha1.JBSConfig{}, IsController: false})
 	}
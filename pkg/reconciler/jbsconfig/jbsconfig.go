@@ -5,8 +5,8 @@ import (
 	"encoding/base64"
 	errors2 "errors"
 	"fmt"
-	"github.com/redhat-appstudio/image-controller/pkg/quay"
 	"github.com/redhat-appstudio/jvm-build-service/pkg/reconciler/systemconfig"
+	"github.com/redhat-appstudio/jvm-build-service/pkg/registry"
 	"github.com/redhat-appstudio/service-provider-integration-operator/api/v1beta1"
 	"regexp"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
@@ -34,12 +34,21 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
-const TlsServiceName = v1alpha1.CacheDeploymentName + "-tls"
 const TestRegistry = "jvmbuildservice.io/test-registry"
+
+// ImageRepositoryFinalizer is added to a JBSConfig that has a provisioned repository, the name predates the other
+// registry providers
 const ImageRepositoryFinalizer = "jvmbuildservice.io/quay-repository-finalizer"
 const DeleteImageRepositoryAnnotationName = "image.redhat.com/delete-image-repo"
 const UploadSecretName = "jvm-build-service-temp-upload-secret" //#nosec
 
+// RotateRegistryCredentialAnnotation replaces the password of the robot account of a provisioned repository
+const RotateRegistryCredentialAnnotation = "jvmbuildservice.io/rotate-registry-credential"
+
+// OperatorRoleBindingName the RoleBinding, and the ClusterRole it binds, that let the operator write the resources of
+// the named JBSConfigs in a namespace
+const OperatorRoleBindingName = "hacbs-jvm-operator-jbsconfig"
+
 const (
 	Action              = "action"
 	Audit               = "audit"
@@ -55,18 +64,17 @@ type ReconcilerJBSConfig struct {
 	eventRecorder        record.EventRecorder
 	configuredCacheImage string
 	spiPresent           bool
-	quayClient           *quay.QuayClient
-	quayOrgName          string
+	// provisioner the registry provisioner the SystemConfig reconciler configures
+	provisioner *registry.ProvisionerRef
 }
 
-func newReconciler(mgr ctrl.Manager, spiPresent bool, quayClient *quay.QuayClient, quayOrgName string) reconcile.Reconciler {
+func newReconciler(mgr ctrl.Manager, spiPresent bool, provisioner *registry.ProvisionerRef) reconcile.Reconciler {
 	ret := &ReconcilerJBSConfig{
 		client:        mgr.GetClient(),
 		scheme:        mgr.GetScheme(),
 		eventRecorder: mgr.GetEventRecorderFor("JBSConfig"),
 		spiPresent:    spiPresent,
-		quayClient:    quayClient,
-		quayOrgName:   quayOrgName,
+		provisioner:   provisioner,
 	}
 	return ret
 }
@@ -83,6 +91,9 @@ func (r *ReconcilerJBSConfig) Reconcile(ctx context.Context, request reconcile.R
 		// Deleted JBSConfig - delete cache resources
 		if errors.IsNotFound(err) && request.Name == v1alpha1.JBSConfigName {
 			return r.handleConfigDeleted(ctx, request, log)
+		} else if errors.IsNotFound(err) {
+			//the cache resources of any other JBSConfig are owned by it
+			return reconcile.Result{}, nil
 		}
 		return reconcile.Result{}, err
 	}
@@ -91,19 +102,28 @@ func (r *ReconcilerJBSConfig) Reconcile(ctx context.Context, request reconcile.R
 		return reconcile.Result{}, err
 	}
 
-	//TODO do we eventually want to allow more than one JBSConfig per namespace?
-	if jbsConfig.Name == v1alpha1.JBSConfigName {
-
-		systemConfig := v1alpha1.SystemConfig{}
-		err := r.client.Get(ctx, types.NamespacedName{Name: systemconfig.SystemConfigKey}, &systemConfig)
-		if err != nil {
-			return reconcile.Result{}, err
-		}
-		err = r.validations(ctx, log, request, &jbsConfig)
-		if err != nil {
+	systemConfig := v1alpha1.SystemConfig{}
+	err = r.client.Get(ctx, types.NamespacedName{Name: systemconfig.SystemConfigKey}, &systemConfig)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	if !jbsConfig.IsDefault() {
+		if err := r.operatorRoleBinding(ctx, &jbsConfig); err != nil {
 			return reconcile.Result{}, err
 		}
-
+	}
+	validationErr := r.validations(ctx, log, request, &jbsConfig)
+	//the build policy of a JBSConfig that shares the cache is part of the cache of the default JBSConfig, the
+	//controller reconciles the default JBSConfig whenever this one changes
+	var settingsErr error
+	if !jbsConfig.SharesCache() {
+		settingsErr = validateCacheSettings(&jbsConfig)
+	}
+	if settingsErr != nil {
+		//retrying will not help, the JBSConfig is reconciled again when it is fixed
+		log.Error(settingsErr, "invalid cache settings")
+		r.eventRecorder.Event(&jbsConfig, corev1.EventTypeWarning, "InvalidCacheSettings", settingsErr.Error())
+	} else if validationErr == nil && !jbsConfig.SharesCache() {
 		err = r.deploymentSupportObjects(ctx, log, request, &jbsConfig)
 		if err != nil {
 			return reconcile.Result{}, err
@@ -114,30 +134,51 @@ func (r *ReconcilerJBSConfig) Reconcile(ctx context.Context, request reconcile.R
 			return reconcile.Result{}, err
 		}
 	}
-	return reconcile.Result{}, nil
+	//the conditions are updated even if the credentials are not ready, so they report why
+	err = r.updateConditions(ctx, &jbsConfig, validationErr, settingsErr)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	return reconcile.Result{}, validationErr
 }
 
 func (r *ReconcilerJBSConfig) handlePossibleRepositoryCleanup(ctx context.Context, jbsConfig v1alpha1.JBSConfig, log logr.Logger) error {
 	if controllerutil.ContainsFinalizer(&jbsConfig, ImageRepositoryFinalizer) {
-		robotAccountName := generateRobotAccountName(&jbsConfig)
-		isDeleted, err := r.quayClient.DeleteRobotAccount(r.quayOrgName, robotAccountName)
-		if err != nil {
-			log.Error(err, "failed to delete robot account")
-			// Do not block Component deletion if failed to delete robot account
-		}
-		if isDeleted {
-			log.Info(fmt.Sprintf("Deleted robot account %s", robotAccountName))
+		provisioner, err := r.provisioner.Get()
+		if err == registry.ErrProvisionerNotConfigured {
+			//there is nothing to wait for if the SystemConfig does not exist, so the deletion is not blocked forever
+			getErr := r.client.Get(ctx, types.NamespacedName{Name: systemconfig.SystemConfigKey}, &v1alpha1.SystemConfig{})
+			if getErr == nil || !errors.IsNotFound(getErr) {
+				//retry once the SystemConfig has been reconciled, so the robot account is not leaked
+				return err
+			}
+			log.Info("there is no SystemConfig, the robot account and repository are not deleted")
+			err = nil
+		} else if err != nil {
+			// Do not block Component deletion if the provisioner is not configured correctly
+			log.Error(err, "failed to create the registry provisioner, the robot account and repository are not deleted")
 		}
-
-		if val, exists := jbsConfig.Annotations[DeleteImageRepositoryAnnotationName]; exists && val == "true" {
-			imageRepo := generateRepositoryName(&jbsConfig)
-			isRepoDeleted, err := r.quayClient.DeleteRepository(r.quayOrgName, imageRepo)
+		if provisioner != nil {
+			robotAccountName := generateRobotAccountName(&jbsConfig)
+			isDeleted, err := provisioner.DeleteRobot(ctx, robotAccountName)
 			if err != nil {
-				log.Error(err, "failed to delete image repository")
-				// Do not block Component deletion if failed to delete image repository
+				log.Error(err, "failed to delete robot account")
+				// Do not block Component deletion if failed to delete robot account
 			}
-			if isRepoDeleted {
-				log.Info(fmt.Sprintf("Deleted image repository %s", imageRepo))
+			if isDeleted {
+				log.Info(fmt.Sprintf("Deleted robot account %s", robotAccountName))
+			}
+
+			if val, exists := jbsConfig.Annotations[DeleteImageRepositoryAnnotationName]; exists && val == "true" {
+				imageRepo := generateRepositoryName(&jbsConfig)
+				isRepoDeleted, err := provisioner.DeleteRepository(ctx, imageRepo)
+				if err != nil {
+					log.Error(err, "failed to delete image repository")
+					// Do not block Component deletion if failed to delete image repository
+				}
+				if isRepoDeleted {
+					log.Info(fmt.Sprintf("Deleted image repository %s", imageRepo))
+				}
 			}
 		}
 
@@ -163,7 +204,7 @@ func (r *ReconcilerJBSConfig) handleConfigDeleted(ctx context.Context, request r
 		r.eventRecorder.Event(service, corev1.EventTypeWarning, msg, "")
 	}
 	service = &corev1.Service{}
-	service.Name = TlsServiceName
+	service.Name = v1alpha1.CacheDeploymentName + "-tls"
 	service.Namespace = request.Namespace
 	err = r.client.Delete(ctx, service)
 	if err != nil && !errors.IsNotFound(err) {
@@ -241,8 +282,97 @@ func generateRobotAccountName(component *v1alpha1.JBSConfig) string {
 }
 
 func generateRepositoryName(component *v1alpha1.JBSConfig) string {
+	if !component.IsDefault() {
+		return component.Namespace + "/jvm-build-service-artifacts-" + component.Name
+	}
 	return component.Namespace + "/jvm-build-service-artifacts"
 }
+
+// setCacheOwner the cache of a JBSConfig other than the default one is deleted with the JBSConfig, the default
+// cache is cleaned up by handleConfigDeleted
+// operatorRoleBinding binds the operator to the role that can write the resources of a named JBSConfig, the operator
+// ClusterRole can only write the resources of the default JBSConfig. The binding is owned by every named JBSConfig in
+// the namespace, so it is removed with the last one.
+func (r *ReconcilerJBSConfig) operatorRoleBinding(ctx context.Context, jbsConfig *v1alpha1.JBSConfig) error {
+	rb := rbacv1.RoleBinding{}
+	err := r.client.Get(ctx, types.NamespacedName{Namespace: jbsConfig.Namespace, Name: OperatorRoleBindingName}, &rb)
+	if errors.IsNotFound(err) {
+		rb.Name = OperatorRoleBindingName
+		rb.Namespace = jbsConfig.Namespace
+		rb.RoleRef = rbacv1.RoleRef{Kind: "ClusterRole", Name: OperatorRoleBindingName, APIGroup: "rbac.authorization.k8s.io"}
+		rb.Subjects = []rbacv1.Subject{{Kind: "ServiceAccount", Name: util.ControllerDeploymentName, Namespace: util.ControllerNamespace}}
+		if err := controllerutil.SetOwnerReference(jbsConfig, &rb, r.scheme); err != nil {
+			return err
+		}
+		return r.client.Create(ctx, &rb)
+	} else if err != nil {
+		return err
+	}
+	for _, owner := range rb.OwnerReferences {
+		if owner.UID == jbsConfig.UID {
+			return nil
+		}
+	}
+	if err := controllerutil.SetOwnerReference(jbsConfig, &rb, r.scheme); err != nil {
+		return err
+	}
+	return r.client.Update(ctx, &rb)
+}
+
+func (r *ReconcilerJBSConfig) setCacheOwner(jbsConfig *v1alpha1.JBSConfig, object client.Object) error {
+	if jbsConfig.IsDefault() {
+		return nil
+	}
+	return controllerutil.SetOwnerReference(jbsConfig, object, r.scheme)
+}
+
+// parseCacheSetting parses a quantity in the cache settings. The settings are only validated on admission if the
+// webhooks are enabled, so they are checked again before the cache is created.
+func parseCacheSetting(name, setting, def string) (resource.Quantity, error) {
+	qty, err := resource.ParseQuantity(settingOrDefault(setting, def))
+	if err != nil {
+		return qty, fmt.Errorf("invalid cacheSettings.%s %q: %w", name, setting, err)
+	}
+	return qty, nil
+}
+
+func cacheResources(jbsConfig *v1alpha1.JBSConfig) (corev1.ResourceRequirements, error) {
+	settings := jbsConfig.Spec.CacheSettings
+	requestMemory, err := parseCacheSetting("requestMemory", settings.RequestMemory, v1alpha1.ConfigArtifactCacheRequestMemoryDefault)
+	if err != nil {
+		return corev1.ResourceRequirements{}, err
+	}
+	requestCPU, err := parseCacheSetting("requestCPU", settings.RequestCPU, v1alpha1.ConfigArtifactCacheRequestCPUDefault)
+	if err != nil {
+		return corev1.ResourceRequirements{}, err
+	}
+	limitMemory, err := parseCacheSetting("limitMemory", settings.LimitMemory, v1alpha1.ConfigArtifactCacheLimitMemoryDefault)
+	if err != nil {
+		return corev1.ResourceRequirements{}, err
+	}
+	limitCPU, err := parseCacheSetting("limitCPU", settings.LimitCPU, v1alpha1.ConfigArtifactCacheLimitCPUDefault)
+	if err != nil {
+		return corev1.ResourceRequirements{}, err
+	}
+	return corev1.ResourceRequirements{
+		Requests: map[corev1.ResourceName]resource.Quantity{"memory": requestMemory, "cpu": requestCPU},
+		Limits:   map[corev1.ResourceName]resource.Quantity{"memory": limitMemory, "cpu": limitCPU},
+	}, nil
+}
+
+// validateCacheSettings returns an error if the cache cannot be created from the settings
+func validateCacheSettings(jbsConfig *v1alpha1.JBSConfig) error {
+	if _, err := parseCacheSetting("storage", jbsConfig.Spec.CacheSettings.Storage, v1alpha1.ConfigArtifactCacheStorageDefault); err != nil {
+		return err
+	}
+	_, err := cacheResources(jbsConfig)
+	return err
+}
+
+func toEnvName(name string) string {
+	return strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(name))
+}
+
 func setEnvVarValue(field, envName string, cache *appsv1.Deployment) *appsv1.Deployment {
 	envVar := corev1.EnvVar{
 		Name:  envName,
@@ -252,7 +382,7 @@ func setEnvVarValue(field, envName string, cache *appsv1.Deployment) *appsv1.Dep
 }
 
 func setEnvVar(envVar corev1.EnvVar, cache *appsv1.Deployment) *appsv1.Deployment {
-	if len(strings.TrimSpace(envVar.Value)) > 0 {
+	if len(strings.TrimSpace(envVar.Value)) > 0 || envVar.ValueFrom != nil {
 		//insert them in alphabetical order
 		for i, e := range cache.Spec.Template.Spec.Containers[0].Env {
 
@@ -304,7 +434,7 @@ func (r *ReconcilerJBSConfig) validations(ctx context.Context, log logr.Logger,
 
 	registrySecret := &corev1.Secret{}
 	// our client is wired to not cache secrets / establish informers for secrets
-	err := r.client.Get(ctx, types.NamespacedName{Namespace: request.Namespace, Name: v1alpha1.ImageSecretName}, registrySecret)
+	err := r.client.Get(ctx, types.NamespacedName{Namespace: request.Namespace, Name: jbsConfig.ImageSecret()}, registrySecret)
 	if err != nil {
 		if errors.IsNotFound(err) {
 			return r.handleNoImageSecretFound(ctx, jbsConfig)
@@ -314,7 +444,7 @@ func (r *ReconcilerJBSConfig) validations(ctx context.Context, log logr.Logger,
 	_, keyPresent1 := registrySecret.Data[v1alpha1.ImageSecretTokenKey]
 	_, keyPresent2 := registrySecret.StringData[v1alpha1.ImageSecretTokenKey]
 	if !keyPresent1 && !keyPresent2 {
-		err := fmt.Errorf("need image registry token set at key %s in secret %s to enable rebuilds", v1alpha1.ImageSecretTokenKey, v1alpha1.ImageSecretName)
+		err := fmt.Errorf("need image registry token set at key %s in secret %s to enable rebuilds", v1alpha1.ImageSecretTokenKey, jbsConfig.ImageSecret())
 		errorMessage := err.Error()
 		if jbsConfig.Status.Message != errorMessage {
 			jbsConfig.Status.Message = errorMessage
@@ -343,15 +473,18 @@ func (r *ReconcilerJBSConfig) validations(ctx context.Context, log logr.Logger,
 func (r *ReconcilerJBSConfig) deploymentSupportObjects(ctx context.Context, log logr.Logger, request reconcile.Request, jbsConfig *v1alpha1.JBSConfig) error {
 	//TODO may have to switch to ephemeral storage for KCP until storage story there is sorted out
 	pvc := corev1.PersistentVolumeClaim{}
-	deploymentName := types.NamespacedName{Namespace: request.Namespace, Name: v1alpha1.CacheDeploymentName}
+	deploymentName := types.NamespacedName{Namespace: request.Namespace, Name: jbsConfig.CacheName()}
 	err := r.client.Get(ctx, deploymentName, &pvc)
 	if err != nil {
 		if errors.IsNotFound(err) {
 			pvc = corev1.PersistentVolumeClaim{}
-			pvc.Name = v1alpha1.CacheDeploymentName
+			pvc.Name = jbsConfig.CacheName()
 			pvc.Namespace = request.Namespace
+			if err := r.setCacheOwner(jbsConfig, &pvc); err != nil {
+				return err
+			}
 			pvc.Spec.AccessModes = []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce}
-			qty, err := resource.ParseQuantity(settingOrDefault(jbsConfig.Spec.CacheSettings.Storage, v1alpha1.ConfigArtifactCacheStorageDefault))
+			qty, err := parseCacheSetting("storage", jbsConfig.Spec.CacheSettings.Storage, v1alpha1.ConfigArtifactCacheStorageDefault)
 			if err != nil {
 				return err
 			}
@@ -363,12 +496,12 @@ func (r *ReconcilerJBSConfig) deploymentSupportObjects(ctx context.Context, log
 		}
 	}
 	//and setup the service
-	err = r.client.Get(ctx, types.NamespacedName{Name: v1alpha1.CacheDeploymentName, Namespace: request.Namespace}, &corev1.Service{})
+	err = r.client.Get(ctx, types.NamespacedName{Name: jbsConfig.CacheName(), Namespace: request.Namespace}, &corev1.Service{})
 	if err != nil {
 		if errors.IsNotFound(err) {
 			service := corev1.Service{
 				ObjectMeta: ctrl.ObjectMeta{
-					Name:      v1alpha1.CacheDeploymentName,
+					Name:      jbsConfig.CacheName(),
 					Namespace: request.Namespace,
 				},
 				Spec: corev1.ServiceSpec{
@@ -380,9 +513,12 @@ func (r *ReconcilerJBSConfig) deploymentSupportObjects(ctx context.Context, log
 						},
 					},
 					Type:     corev1.ServiceTypeClusterIP,
-					Selector: map[string]string{"app": v1alpha1.CacheDeploymentName},
+					Selector: map[string]string{"app": jbsConfig.CacheName()},
 				},
 			}
+			if err := r.setCacheOwner(jbsConfig, &service); err != nil {
+				return err
+			}
 			err := r.client.Create(ctx, &service)
 			if err != nil {
 				return err
@@ -390,14 +526,14 @@ func (r *ReconcilerJBSConfig) deploymentSupportObjects(ctx context.Context, log
 		}
 	}
 	//and setup the TLS service
-	err = r.client.Get(ctx, types.NamespacedName{Name: TlsServiceName, Namespace: request.Namespace}, &corev1.Service{})
+	err = r.client.Get(ctx, types.NamespacedName{Name: jbsConfig.CacheName() + "-tls", Namespace: request.Namespace}, &corev1.Service{})
 	if err != nil {
 		if errors.IsNotFound(err) {
 			service := corev1.Service{
 				ObjectMeta: ctrl.ObjectMeta{
-					Name:        TlsServiceName,
+					Name:        jbsConfig.CacheName() + "-tls",
 					Namespace:   request.Namespace,
-					Annotations: map[string]string{"service.beta.openshift.io/serving-cert-secret-name": v1alpha1.TlsSecretName},
+					Annotations: map[string]string{"service.beta.openshift.io/serving-cert-secret-name": jbsConfig.TlsSecret()},
 				},
 				Spec: corev1.ServiceSpec{
 					Ports: []corev1.ServicePort{
@@ -408,9 +544,12 @@ func (r *ReconcilerJBSConfig) deploymentSupportObjects(ctx context.Context, log
 						},
 					},
 					Type:     corev1.ServiceTypeClusterIP,
-					Selector: map[string]string{"app": v1alpha1.CacheDeploymentName},
+					Selector: map[string]string{"app": jbsConfig.CacheName()},
 				},
 			}
+			if err := r.setCacheOwner(jbsConfig, &service); err != nil {
+				return err
+			}
 			err := r.client.Create(ctx, &service)
 			if err != nil {
 				return err
@@ -438,13 +577,16 @@ func (r *ReconcilerJBSConfig) deploymentSupportObjects(ctx context.Context, log
 	}
 	//setup the service account
 	sa := corev1.ServiceAccount{}
-	saName := types.NamespacedName{Namespace: request.Namespace, Name: v1alpha1.CacheDeploymentName}
+	saName := types.NamespacedName{Namespace: request.Namespace, Name: jbsConfig.CacheName()}
 	err = r.client.Get(ctx, saName, &sa)
 	if err != nil {
 		if errors.IsNotFound(err) {
 			sa := corev1.ServiceAccount{}
-			sa.Name = v1alpha1.CacheDeploymentName
+			sa.Name = jbsConfig.CacheName()
 			sa.Namespace = request.Namespace
+			if err := r.setCacheOwner(jbsConfig, &sa); err != nil {
+				return err
+			}
 			err := r.client.Create(ctx, &sa)
 			if err != nil {
 				return err
@@ -452,15 +594,18 @@ func (r *ReconcilerJBSConfig) deploymentSupportObjects(ctx context.Context, log
 		}
 	}
 	cb := rbacv1.RoleBinding{}
-	cbName := types.NamespacedName{Namespace: request.Namespace, Name: v1alpha1.CacheDeploymentName}
+	cbName := types.NamespacedName{Namespace: request.Namespace, Name: jbsConfig.CacheName()}
 	err = r.client.Get(ctx, cbName, &cb)
 	if err != nil {
 		if errors.IsNotFound(err) {
 			cb := rbacv1.RoleBinding{}
-			cb.Name = v1alpha1.CacheDeploymentName
+			cb.Name = jbsConfig.CacheName()
 			cb.Namespace = request.Namespace
 			cb.RoleRef = rbacv1.RoleRef{Kind: "ClusterRole", Name: "hacbs-jvm-cache", APIGroup: "rbac.authorization.k8s.io"}
-			cb.Subjects = []rbacv1.Subject{{Kind: "ServiceAccount", Name: v1alpha1.CacheDeploymentName, Namespace: request.Namespace}}
+			cb.Subjects = []rbacv1.Subject{{Kind: "ServiceAccount", Name: jbsConfig.CacheName(), Namespace: request.Namespace}}
+			if err := r.setCacheOwner(jbsConfig, &cb); err != nil {
+				return err
+			}
 			err := r.client.Create(ctx, &cb)
 			if err != nil {
 				return err
@@ -473,7 +618,7 @@ func (r *ReconcilerJBSConfig) deploymentSupportObjects(ctx context.Context, log
 func (r *ReconcilerJBSConfig) cacheDeployment(ctx context.Context, log logr.Logger, request reconcile.Request, jbsConfig *v1alpha1.JBSConfig, sysConfig *v1alpha1.SystemConfig) error {
 	cache := &appsv1.Deployment{}
 	trueBool := true
-	deploymentName := types.NamespacedName{Namespace: request.Namespace, Name: v1alpha1.CacheDeploymentName}
+	deploymentName := types.NamespacedName{Namespace: request.Namespace, Name: jbsConfig.CacheName()}
 	err := r.client.Get(ctx, deploymentName, cache)
 	create := false
 	if err != nil {
@@ -481,13 +626,20 @@ func (r *ReconcilerJBSConfig) cacheDeployment(ctx context.Context, log logr.Logg
 			create = true
 			cache.Name = deploymentName.Name
 			cache.Namespace = deploymentName.Namespace
+			if err := r.setCacheOwner(jbsConfig, cache); err != nil {
+				return err
+			}
+			resources, err := cacheResources(jbsConfig)
+			if err != nil {
+				return err
+			}
 			var replicas int32 = 1
 			var zero int32 = 0
 			cache.Spec.RevisionHistoryLimit = &zero
 			cache.Spec.Replicas = &replicas
 			cache.Spec.Strategy = appsv1.DeploymentStrategy{Type: appsv1.RecreateDeploymentStrategyType}
-			cache.Spec.Selector = &metav1.LabelSelector{MatchLabels: map[string]string{"app": v1alpha1.CacheDeploymentName}}
-			cache.Spec.Template.ObjectMeta.Labels = map[string]string{"app": v1alpha1.CacheDeploymentName}
+			cache.Spec.Selector = &metav1.LabelSelector{MatchLabels: map[string]string{"app": jbsConfig.CacheName()}}
+			cache.Spec.Template.ObjectMeta.Labels = map[string]string{"app": jbsConfig.CacheName()}
 			cache.Spec.Template.Spec.Containers = []corev1.Container{{
 				Name:            v1alpha1.CacheDeploymentName,
 				ImagePullPolicy: corev1.PullIfNotPresent,
@@ -504,22 +656,15 @@ func (r *ReconcilerJBSConfig) cacheDeployment(ctx context.Context, log logr.Logg
 					}},
 				VolumeMounts: []corev1.VolumeMount{{Name: v1alpha1.CacheDeploymentName, MountPath: "/cache"}, {Name: "tls", MountPath: "/tls"}},
 
-				Resources: corev1.ResourceRequirements{
-					Requests: map[corev1.ResourceName]resource.Quantity{
-						"memory": resource.MustParse(settingOrDefault(jbsConfig.Spec.CacheSettings.RequestMemory, v1alpha1.ConfigArtifactCacheRequestMemoryDefault)),
-						"cpu":    resource.MustParse(settingOrDefault(jbsConfig.Spec.CacheSettings.RequestCPU, v1alpha1.ConfigArtifactCacheRequestCPUDefault))},
-					Limits: map[corev1.ResourceName]resource.Quantity{
-						"memory": resource.MustParse(settingOrDefault(jbsConfig.Spec.CacheSettings.LimitMemory, v1alpha1.ConfigArtifactCacheLimitMemoryDefault)),
-						"cpu":    resource.MustParse(settingOrDefault(jbsConfig.Spec.CacheSettings.LimitCPU, v1alpha1.ConfigArtifactCacheLimitCPUDefault))},
-				},
+				Resources:      resources,
 				LivenessProbe:  &corev1.Probe{TimeoutSeconds: 15, ProbeHandler: corev1.ProbeHandler{HTTPGet: &corev1.HTTPGetAction{Path: "/q/health/live", Port: intstr.FromInt(8080)}}},
 				ReadinessProbe: &corev1.Probe{ProbeHandler: corev1.ProbeHandler{HTTPGet: &corev1.HTTPGetAction{Path: "/q/health/ready", Port: intstr.FromInt(8080)}}},
 			}}
 			cache.Spec.Template.Spec.Volumes = []corev1.Volume{
-				{Name: v1alpha1.CacheDeploymentName, VolumeSource: corev1.VolumeSource{PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: v1alpha1.CacheDeploymentName}}},
+				{Name: v1alpha1.CacheDeploymentName, VolumeSource: corev1.VolumeSource{PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: jbsConfig.CacheName()}}},
 			}
 			if !jbsConfig.Spec.CacheSettings.DisableTLS {
-				cache.Spec.Template.Spec.Volumes = append(cache.Spec.Template.Spec.Volumes, corev1.Volume{Name: "tls", VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: v1alpha1.TlsSecretName, Optional: &trueBool}}})
+				cache.Spec.Template.Spec.Volumes = append(cache.Spec.Template.Spec.Volumes, corev1.Volume{Name: "tls", VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: jbsConfig.TlsSecret(), Optional: &trueBool}}})
 			} else {
 				cache.Spec.Template.Spec.Volumes = append(cache.Spec.Template.Spec.Volumes, corev1.Volume{Name: "tls", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}})
 			}
@@ -528,7 +673,12 @@ func (r *ReconcilerJBSConfig) cacheDeployment(ctx context.Context, log logr.Logg
 			return err
 		}
 	}
-	cache.Spec.Template.Spec.ServiceAccountName = v1alpha1.CacheDeploymentName
+	//deployments created before there could be more than one cache do not have the label yet
+	if cache.Spec.Template.ObjectMeta.Labels == nil {
+		cache.Spec.Template.ObjectMeta.Labels = map[string]string{}
+	}
+	cache.Spec.Template.ObjectMeta.Labels[v1alpha1.CacheLabel] = jbsConfig.CacheName()
+	cache.Spec.Template.Spec.ServiceAccountName = jbsConfig.CacheName()
 	cache.Spec.Template.Spec.Containers[0].Env = []corev1.EnvVar{}
 	setEnvVarValue("/cache", "CACHE_PATH", cache)
 	setEnvVarValue(settingOrDefault(jbsConfig.Spec.CacheSettings.IOThreads, v1alpha1.ConfigArtifactCacheIOThreadsDefault), "QUARKUS_VERTX_EVENT_LOOPS_POOL_SIZE", cache)
@@ -544,11 +694,6 @@ func (r *ReconcilerJBSConfig) cacheDeployment(ctx context.Context, log logr.Logg
 			setEnvVarValue("true", "INSECURE_TEST_REGISTRY", cache)
 		}
 	}
-	type Repo struct {
-		name     string
-		position int
-	}
-
 	recipeData := ""
 	if sysConfig.Spec.RecipeDatabase == "" {
 		recipeData = v1alpha1.DefaultRecipeDatabase
@@ -559,34 +704,116 @@ func (r *ReconcilerJBSConfig) cacheDeployment(ctx context.Context, log logr.Logg
 		recipeData = recipeData + "," + i
 	}
 	cache = setEnvVarValue(recipeData, "BUILD_INFO_REPOSITORIES", cache)
+	if jbsConfig.Spec.EnableRebuilds {
+		cache = setEnvVar(corev1.EnvVar{
+			Name:      "GIT_TOKEN",
+			ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: v1alpha1.GitSecretName}, Key: v1alpha1.GitSecretTokenKey, Optional: &trueBool}},
+		}, cache)
+	}
+	err = buildPolicyEnv(jbsConfig, cache)
+	if err != nil {
+		return err
+	}
+
+	policies := []string{v1alpha1.DefaultBuildPolicy}
+	configs := []string{jbsConfig.Name}
+	if jbsConfig.IsDefault() {
+		//the JBSConfigs that share the cache each get a build policy
+		list := v1alpha1.JBSConfigList{}
+		err = r.client.List(ctx, &list, client.InNamespace(request.Namespace))
+		if err != nil {
+			return err
+		}
+		sort.Slice(list.Items, func(i, j int) bool {
+			return list.Items[i].Name < list.Items[j].Name
+		})
+		for i := range list.Items {
+			shared := &list.Items[i]
+			if !shared.SharesCache() || !shared.DeletionTimestamp.IsZero() {
+				continue
+			}
+			err = buildPolicyEnv(shared, cache)
+			if err != nil {
+				return err
+			}
+			policies = append(policies, shared.BuildPolicy())
+			configs = append(configs, shared.Name)
+		}
+	}
+	cache = setEnvVarValue(strings.Join(policies, ","), "BUILD_POLICIES", cache)
+	cache = setEnvVarValue(strings.Join(configs, ","), "JBS_CONFIGS", cache)
+
+	if len(r.configuredCacheImage) == 0 {
+		r.configuredCacheImage, err = util.GetImageName(ctx, r.client, log, "cache", "JVM_BUILD_SERVICE_CACHE_IMAGE")
+		if err != nil {
+			return err
+		}
+	}
+	cache.Spec.Template.Spec.Containers[0].Image = r.configuredCacheImage
+	if strings.HasPrefix(r.configuredCacheImage, "quay.io/minikube") {
+		cache.Spec.Template.Spec.Containers[0].ImagePullPolicy = corev1.PullNever
+	} else if !strings.HasPrefix(r.configuredCacheImage, "quay.io/redhat-appstudio") {
+		// work around for developer mode while we are hard coding the spec in the controller
+		cache.Spec.Template.Spec.Containers[0].ImagePullPolicy = corev1.PullAlways
+	}
+
+	if create {
+		return r.client.Create(ctx, cache)
+	} else {
+		return r.client.Update(ctx, cache)
+	}
+}
+
+// buildPolicyEnv adds the stores and relocation patterns of a JBSConfig to the cache as the build policy returned by
+// BuildPolicy. The stores of a JBSConfig that shares the cache are prefixed with its name, so they do not clash with
+// the stores of the other JBSConfigs.
+func buildPolicyEnv(jbsConfig *v1alpha1.JBSConfig, cache *appsv1.Deployment) error {
+	type Repo struct {
+		name     string
+		position int
+	}
+	trueBool := true
+	storePrefix := ""
+	if jbsConfig.SharesCache() {
+		storePrefix = jbsConfig.Name + "-"
+	}
 
 	//central is at the hard coded 200 position
 	//redhat is configured at 250
 	repos := []Repo{{name: "central", position: 200}, {name: "redhat", position: 250}}
 	if jbsConfig.Spec.EnableRebuilds {
-		repos = append(repos, Repo{name: "rebuilt", position: 100})
-
 		imageRegistry := jbsConfig.ImageRegistry()
-		cache = setEnvVarValue(imageRegistry.Owner, "REGISTRY_OWNER", cache)
-		cache = setEnvVarValue(imageRegistry.Host, "REGISTRY_HOST", cache)
-		cache = setEnvVarValue(imageRegistry.Port, "REGISTRY_PORT", cache)
-		cache = setEnvVarValue(imageRegistry.Repository, "REGISTRY_REPOSITORY", cache)
-		cache = setEnvVarValue(strconv.FormatBool(imageRegistry.Insecure), "REGISTRY_INSECURE", cache)
-		cache = setEnvVarValue(imageRegistry.PrependTag, "REGISTRY_PREPEND_TAG", cache)
-		cache = setEnvVar(corev1.EnvVar{
-			Name:      "REGISTRY_TOKEN",
-			ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: v1alpha1.ImageSecretName}, Key: v1alpha1.ImageSecretTokenKey, Optional: &trueBool}},
-		}, cache)
-		cache = setEnvVar(corev1.EnvVar{
-			Name:      "GIT_TOKEN",
-			ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: v1alpha1.GitSecretName}, Key: v1alpha1.GitSecretTokenKey, Optional: &trueBool}},
-		}, cache)
+		token := corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: jbsConfig.ImageSecret()}, Key: v1alpha1.ImageSecretTokenKey, Optional: &trueBool}}
+		if jbsConfig.SharesCache() {
+			//the rebuilt artifacts of this JBSConfig are in its own registry
+			store := "STORE_" + toEnvName(storePrefix+"rebuilt")
+			host := settingOrDefault(imageRegistry.Host, "quay.io")
+			if imageRegistry.Port != "" && imageRegistry.Port != "443" {
+				host = host + ":" + imageRegistry.Port
+			}
+			cache = setEnvVarValue("OCI_REGISTRY", store+"_TYPE", cache)
+			cache = setEnvVarValue(host, store+"_REGISTRY", cache)
+			cache = setEnvVarValue(imageRegistry.Owner, store+"_OWNER", cache)
+			cache = setEnvVarValue(imageRegistry.Repository, store+"_REPOSITORY", cache)
+			cache = setEnvVarValue(strconv.FormatBool(imageRegistry.Insecure), store+"_INSECURE", cache)
+			cache = setEnvVarValue(imageRegistry.PrependTag, store+"_PREPEND_TAG", cache)
+			cache = setEnvVar(corev1.EnvVar{Name: store + "_TOKEN", ValueFrom: &token}, cache)
+		} else {
+			cache = setEnvVarValue(imageRegistry.Owner, "REGISTRY_OWNER", cache)
+			cache = setEnvVarValue(imageRegistry.Host, "REGISTRY_HOST", cache)
+			cache = setEnvVarValue(imageRegistry.Port, "REGISTRY_PORT", cache)
+			cache = setEnvVarValue(imageRegistry.Repository, "REGISTRY_REPOSITORY", cache)
+			cache = setEnvVarValue(strconv.FormatBool(imageRegistry.Insecure), "REGISTRY_INSECURE", cache)
+			cache = setEnvVarValue(imageRegistry.PrependTag, "REGISTRY_PREPEND_TAG", cache)
+			cache = setEnvVar(corev1.EnvVar{Name: "REGISTRY_TOKEN", ValueFrom: &token}, cache)
+		}
+		repos = append(repos, Repo{name: storePrefix + "rebuilt", position: 100})
 		for _, relocationPatternElement := range jbsConfig.Spec.RelocationPatterns {
 			buildPolicy := relocationPatternElement.RelocationPattern.BuildPolicy
 			if buildPolicy == "" {
-				buildPolicy = "default"
+				buildPolicy = jbsConfig.BuildPolicy()
 			}
-			envName := "BUILD_POLICY_" + strings.ToUpper(buildPolicy) + "_RELOCATION_PATTERN"
+			envName := "BUILD_POLICY_" + toEnvName(buildPolicy) + "_RELOCATION_PATTERN"
 
 			var envValues []string
 			for _, patternElement := range relocationPatternElement.RelocationPattern.Patterns {
@@ -597,7 +824,7 @@ func (r *ReconcilerJBSConfig) cacheDeployment(ctx context.Context, log logr.Logg
 		}
 	}
 
-	regex, err := regexp.Compile(`maven-repository-(\d+)-([\w-]+)`)
+	regex, err := regexp.Compile(v1alpha1.MavenRepositoryKeyPattern)
 	if err != nil {
 		return err
 	}
@@ -620,9 +847,9 @@ func (r *ReconcilerJBSConfig) cacheDeployment(ctx context.Context, log logr.Logg
 				jbsConfig.Status.Message = jbsConfig.Status.Message + " Repository " + name + " defined twice, ignoring " + v
 				continue
 			}
-			cache = setEnvVarValue(v, "STORE_"+strings.ToUpper(strings.Replace(name, "-", "_", -1))+"_URL", cache)
-			cache = setEnvVarValue("maven2", "STORE_"+strings.ToUpper(strings.Replace(name, "-", "_", -1))+"_TYPE", cache)
-			repos = append(repos, Repo{position: atoi, name: name})
+			cache = setEnvVarValue(v, "STORE_"+toEnvName(storePrefix+name)+"_URL", cache)
+			cache = setEnvVarValue("maven2", "STORE_"+toEnvName(storePrefix+name)+"_TYPE", cache)
+			repos = append(repos, Repo{position: atoi, name: storePrefix + name})
 		}
 	}
 	var sb strings.Builder
@@ -635,35 +862,16 @@ func (r *ReconcilerJBSConfig) cacheDeployment(ctx context.Context, log logr.Logg
 		}
 		sb.WriteString(i.name)
 	}
-	cache = setEnvVarValue(sb.String(), "BUILD_POLICY_DEFAULT_STORE_LIST", cache)
-
-	if len(r.configuredCacheImage) == 0 {
-		r.configuredCacheImage, err = util.GetImageName(ctx, r.client, log, "cache", "JVM_BUILD_SERVICE_CACHE_IMAGE")
-		if err != nil {
-			return err
-		}
-	}
-	cache.Spec.Template.Spec.Containers[0].Image = r.configuredCacheImage
-	if strings.HasPrefix(r.configuredCacheImage, "quay.io/minikube") {
-		cache.Spec.Template.Spec.Containers[0].ImagePullPolicy = corev1.PullNever
-	} else if !strings.HasPrefix(r.configuredCacheImage, "quay.io/redhat-appstudio") {
-		// work around for developer mode while we are hard coding the spec in the controller
-		cache.Spec.Template.Spec.Containers[0].ImagePullPolicy = corev1.PullAlways
-	}
-
-	if create {
-		return r.client.Create(ctx, cache)
-	} else {
-		return r.client.Update(ctx, cache)
-	}
+	setEnvVarValue(sb.String(), "BUILD_POLICY_"+toEnvName(jbsConfig.BuildPolicy())+"_STORE_LIST", cache)
+	return nil
 }
 
 func (r *ReconcilerJBSConfig) handleNoImageSecretFound(ctx context.Context, config *v1alpha1.JBSConfig) error {
 	binding := v1beta1.SPIAccessTokenBinding{}
-	err := r.client.Get(ctx, types.NamespacedName{Name: v1alpha1.ImageSecretName, Namespace: config.Namespace}, &binding)
+	err := r.client.Get(ctx, types.NamespacedName{Name: config.ImageSecret(), Namespace: config.Namespace}, &binding)
 	if err != nil {
 		if errors.IsNotFound(err) {
-			binding.Name = v1alpha1.ImageSecretName
+			binding.Name = config.ImageSecret()
 			binding.Namespace = config.Namespace
 			imageRegistry := config.ImageRegistry()
 			url := "https://"
@@ -683,7 +891,7 @@ func (r *ReconcilerJBSConfig) handleNoImageSecretFound(ctx context.Context, conf
 			binding.Spec.Lifetime = "-1"
 			binding.Spec.Permissions = v1beta1.Permissions{Required: []v1beta1.Permission{{Type: v1beta1.PermissionTypeReadWrite, Area: v1beta1.PermissionAreaRegistry}}}
 			binding.Spec.Secret = v1beta1.SecretSpec{
-				Name: v1alpha1.ImageSecretName,
+				Name: config.ImageSecret(),
 				Type: corev1.SecretTypeDockerConfigJson,
 			}
 			err = controllerutil.SetOwnerReference(config, &binding, r.scheme)
@@ -710,88 +918,154 @@ func (r *ReconcilerJBSConfig) handleNoImageSecretFound(ctx context.Context, conf
 }
 
 func (r *ReconcilerJBSConfig) handleNoOwnerSpecified(ctx context.Context, log logr.Logger, config *v1alpha1.JBSConfig) error {
-	if r.quayClient == nil || r.quayOrgName == "" {
+	rotated := config.Annotations[RotateRegistryCredentialAnnotation] == "true"
+	if !rotated && config.Status.ImageRegistry != nil && controllerutil.ContainsFinalizer(config, ImageRepositoryFinalizer) {
+		//already provisioned, some registries can only return the credential by rotating it
+		err := r.client.Get(ctx, types.NamespacedName{Namespace: config.Namespace, Name: config.ImageSecret()}, &corev1.Secret{})
+		if err == nil {
+			return nil
+		} else if !errors.IsNotFound(err) {
+			return err
+		}
+	}
+	provisioner, err := r.provisioner.Get()
+	if err != nil {
+		return err
+	}
+	if provisioner == nil {
 		return errors2.New("no owner specified and automatic repo creation is disabled")
 	}
 
-	repo, robotAccount, err := r.generateImageRepository(log, config)
+	//the finalizer is added first, so a repository is never created that would not be deleted with the JBSConfig
+	if controllerutil.AddFinalizer(config, ImageRepositoryFinalizer) {
+		if err := r.client.Update(ctx, config); err != nil {
+			return err
+		}
+	}
+
+	repo, credential, err := r.generateImageRepository(ctx, log, provisioner, config)
 	if err != nil {
 		return err
 	}
-	if repo == nil || robotAccount == nil {
+	if repo == nil || credential == nil {
 		return errors2.New("unknown error in the repository generation process")
 	}
-	controllerutil.AddFinalizer(config, ImageRepositoryFinalizer)
+
+	// Write the secret with the repository credentials first, rotating the credential invalidates the old one so
+	// the new one must be stored before anything else can fail
+	imageURL := repo.URL()
+	robotAccountSecret := generateSecret(config, *credential, imageURL, r.spiPresent)
+	if err := r.writeRobotAccountSecret(ctx, log, config, &robotAccountSecret); err != nil {
+		return err
+	}
+
 	config.Status.ImageRegistry = &v1alpha1.ImageRegistry{
-		Owner:      r.quayOrgName,
-		Host:       "quay.io",
+		Owner:      repo.Owner,
+		Host:       repo.Host,
 		Repository: repo.Name,
+		Insecure:   repo.Insecure,
 	}
 	err = r.client.Status().Update(ctx, config)
 	if err != nil {
 		return err
 	}
 
-	// Create secret with the repository credentials
-	imageURL := fmt.Sprintf("quay.io/%s/%s", r.quayOrgName, repo.Name)
-	robotAccountSecret := generateSecret(config, *robotAccount, imageURL, r.spiPresent)
-
-	robotAccountSecretKey := types.NamespacedName{Namespace: config.Namespace, Name: v1alpha1.ImageSecretName}
-	existingRobotAccountSecret := &corev1.Secret{}
-	if err := r.client.Get(ctx, robotAccountSecretKey, existingRobotAccountSecret); err == nil {
-		if err := r.client.Delete(ctx, existingRobotAccountSecret); err != nil {
-			log.Error(err, fmt.Sprintf("failed to delete robot account secret %v", robotAccountSecretKey), Action, ActionDelete)
+	//the rotation is only complete once everything else has been stored
+	if rotated {
+		delete(config.Annotations, RotateRegistryCredentialAnnotation)
+		if err := r.client.Update(ctx, config); err != nil {
 			return err
-		} else {
-			log.Info(fmt.Sprintf("Deleted old robot account secret %v", robotAccountSecretKey), Action, ActionDelete)
 		}
-	} else if !errors.IsNotFound(err) {
-		log.Error(err, fmt.Sprintf("failed to read robot account secret %v", robotAccountSecretKey), Action, ActionView)
-		return err
 	}
+	return nil
+}
 
-	if err := r.client.Create(ctx, &robotAccountSecret); err != nil {
-		log.Error(err, fmt.Sprintf("error writing robot account token into Secret: %v", robotAccountSecretKey), Action, ActionAdd)
+// writeRobotAccountSecret replaces the data of the existing secret, so the credential is never missing. If SPI is
+// present the new credential is uploaded, and the old image secret is deleted so SPI injects the new one.
+func (r *ReconcilerJBSConfig) writeRobotAccountSecret(ctx context.Context, log logr.Logger, config *v1alpha1.JBSConfig, secret *corev1.Secret) error {
+	robotAccountSecretKey := types.NamespacedName{Namespace: config.Namespace, Name: secret.Name}
+	existing := &corev1.Secret{}
+	err := r.client.Get(ctx, robotAccountSecretKey, existing)
+	if err == nil && existing.Type == secret.Type {
+		existing.Data = nil
+		existing.StringData = secret.StringData
+		existing.Labels = secret.Labels
+		if err := r.client.Update(ctx, existing); err != nil {
+			log.Error(err, fmt.Sprintf("error writing robot account token into Secret: %v", robotAccountSecretKey), Action, ActionUpdate)
+			return err
+		}
+		log.Info(fmt.Sprintf("Updated image registry secret %s for Component", robotAccountSecretKey.Name), Action, ActionUpdate)
+	} else {
+		if err == nil {
+			//the type of a secret can't be changed
+			if err := r.client.Delete(ctx, existing); err != nil {
+				log.Error(err, fmt.Sprintf("failed to delete robot account secret %v", robotAccountSecretKey), Action, ActionDelete)
+				return err
+			}
+		} else if !errors.IsNotFound(err) {
+			log.Error(err, fmt.Sprintf("failed to read robot account secret %v", robotAccountSecretKey), Action, ActionView)
+			return err
+		}
+		if err := r.client.Create(ctx, secret); err != nil {
+			log.Error(err, fmt.Sprintf("error writing robot account token into Secret: %v", robotAccountSecretKey), Action, ActionAdd)
+			return err
+		}
+		log.Info(fmt.Sprintf("Created image registry secret %s for Component", robotAccountSecretKey.Name), Action, ActionAdd)
+	}
+	if secret.Name == config.ImageSecret() {
+		return nil
+	}
+	imageSecret := &corev1.Secret{}
+	imageSecretKey := types.NamespacedName{Namespace: config.Namespace, Name: config.ImageSecret()}
+	if err := r.client.Get(ctx, imageSecretKey, imageSecret); err == nil {
+		if err := r.client.Delete(ctx, imageSecret); err != nil && !errors.IsNotFound(err) {
+			log.Error(err, fmt.Sprintf("failed to delete robot account secret %v", imageSecretKey), Action, ActionDelete)
+			return err
+		}
+		log.Info(fmt.Sprintf("Deleted old robot account secret %v", imageSecretKey), Action, ActionDelete)
+	} else if !errors.IsNotFound(err) {
+		log.Error(err, fmt.Sprintf("failed to read robot account secret %v", imageSecretKey), Action, ActionView)
 		return err
 	}
-	log.Info(fmt.Sprintf("Created image registry secret %s for Component", robotAccountSecretKey.Name), Action, ActionAdd)
-
 	return nil
 }
 
-func (r *ReconcilerJBSConfig) generateImageRepository(log logr.Logger, component *v1alpha1.JBSConfig) (*quay.Repository, *quay.RobotAccount, error) {
+func (r *ReconcilerJBSConfig) generateImageRepository(ctx context.Context, log logr.Logger, provisioner registry.RegistryProvisioner, component *v1alpha1.JBSConfig) (*registry.Repository, *registry.Credential, error) {
 
 	imageRepositoryName := generateRepositoryName(component)
-	repo, err := r.quayClient.CreateRepository(quay.RepositoryRequest{
-		Namespace:   r.quayOrgName,
-		Visibility:  "public",
-		Description: "JVM Build Service repository for the user",
-		Repository:  imageRepositoryName,
-	})
+	repo, err := provisioner.CreateRepository(ctx, imageRepositoryName)
 	if err != nil {
 		log.Error(err, fmt.Sprintf("failed to create image repository %s", imageRepositoryName))
 		return nil, nil, err
 	}
 
 	robotAccountName := generateRobotAccountName(component)
-	_, _ = r.quayClient.DeleteRobotAccount(r.quayOrgName, robotAccountName)
-	robotAccount, err := r.quayClient.CreateRobotAccount(r.quayOrgName, robotAccountName)
+	if component.Annotations[RotateRegistryCredentialAnnotation] == "true" {
+		credential, err := provisioner.RotateCredential(ctx, repo, robotAccountName)
+		if err != nil {
+			log.Error(err, fmt.Sprintf("failed to rotate the credential of robot account %s", robotAccountName))
+			return nil, nil, err
+		}
+		log.Info(fmt.Sprintf("Rotated the credential of robot account %s", robotAccountName))
+		return repo, credential, nil
+	}
+	credential, err := provisioner.CreateRobot(ctx, repo, robotAccountName)
 	if err != nil {
 		log.Error(err, fmt.Sprintf("failed to create robot account %s", robotAccountName))
 		return nil, nil, err
 	}
 
-	err = r.quayClient.AddWritePermissionsToRobotAccount(r.quayOrgName, repo.Name, robotAccountName)
+	err = provisioner.GrantPush(ctx, repo, robotAccountName)
 	if err != nil {
 		log.Error(err, fmt.Sprintf("failed to add permissions to robot account %s", robotAccountName))
 		return nil, nil, err
 	}
 
-	return repo, robotAccount, nil
+	return repo, credential, nil
 }
 
 // generateSecret dumps the robot account token into a Secret for future consumption.
-func generateSecret(c *v1alpha1.JBSConfig, r quay.RobotAccount, imageURL string, spiPresent bool) corev1.Secret {
+func generateSecret(c *v1alpha1.JBSConfig, r registry.Credential, imageURL string, spiPresent bool) corev1.Secret {
 	secret := corev1.Secret{
 		ObjectMeta: metav1.ObjectMeta{
 			Namespace: c.Namespace,
@@ -811,17 +1085,17 @@ func generateSecret(c *v1alpha1.JBSConfig, r quay.RobotAccount, imageURL string,
 		secret.Name = UploadSecretName
 		secret.Type = corev1.SecretTypeOpaque
 		secretData := map[string]string{}
-		secretData["spiTokenName"] = v1alpha1.ImageSecretName
+		secretData["spiTokenName"] = c.ImageSecret()
 		secretData["providerUrl"] = "https://" + imageURL
-		secretData["userName"] = r.Name
-		secretData["tokenData"] = r.Token
+		secretData["userName"] = r.Username
+		secretData["tokenData"] = r.Password
 		secret.StringData = secretData
 		return secret
 	} else {
-		secret.Name = v1alpha1.ImageSecretName
+		secret.Name = c.ImageSecret()
 		secret.Type = corev1.SecretTypeDockerConfigJson
 		secretData := map[string]string{}
-		authString := fmt.Sprintf("%s:%s", r.Name, r.Token)
+		authString := fmt.Sprintf("%s:%s", r.Username, r.Password)
 		secretData[corev1.DockerConfigJsonKey] = fmt.Sprintf(`{"auths":{"%s":{"auth":"%s"}}}`,
 			imageURL,
 			base64.StdEncoding.EncodeToString([]byte(authString)),
@@ -2,9 +2,12 @@ package jbsconfig
 
 import (
 	"context"
+	"encoding/base64"
 	"github.com/redhat-appstudio/jvm-build-service/pkg/reconciler/systemconfig"
+	"github.com/redhat-appstudio/jvm-build-service/pkg/registry"
 	spi "github.com/redhat-appstudio/service-provider-integration-operator/api/v1beta1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	"testing"
 
 	. "github.com/onsi/gomega"
@@ -14,9 +17,11 @@ import (
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/client-go/tools/record"
 
 	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
@@ -29,6 +34,7 @@ func setupClientAndReconciler(includeSpi bool, objs ...runtimeclient.Object) (ru
 	_ = v1alpha1.AddToScheme(scheme)
 	_ = appsv1.AddToScheme(scheme)
 	_ = corev1.AddToScheme(scheme)
+	_ = rbacv1.AddToScheme(scheme)
 	if includeSpi {
 		_ = spi.AddToScheme(scheme)
 	}
@@ -37,7 +43,11 @@ func setupClientAndReconciler(includeSpi bool, objs ...runtimeclient.Object) (ru
 		client:        client,
 		scheme:        scheme,
 		eventRecorder: &record.FakeRecorder{},
+		provisioner:   &registry.ProvisionerRef{},
 	}
+	reconciler.provisioner.Update("disabled", func() (registry.RegistryProvisioner, error) {
+		return nil, nil
+	})
 	util.ImageTag = "foo"
 	return client, reconciler
 }
@@ -191,3 +201,407 @@ func TestMissingRegistrySecretWithSpi(t *testing.T) {
 	g.Expect(binding.Spec.RepoUrl).To(Equal("https://quay.io/tests/artifact-deployments"))
 
 }
+
+type fakeProvisioner struct {
+	calls    []string
+	password string
+}
+
+func (f *fakeProvisioner) CreateRepository(ctx context.Context, name string) (*registry.Repository, error) {
+	f.calls = append(f.calls, "CreateRepository "+name)
+	return &registry.Repository{Host: "harbor.local", Owner: "jbs", Name: name}, nil
+}
+
+func (f *fakeProvisioner) CreateRobot(ctx context.Context, repository *registry.Repository, robot string) (*registry.Credential, error) {
+	f.calls = append(f.calls, "CreateRobot "+robot)
+	return &registry.Credential{Username: robot, Password: f.password}, nil
+}
+
+func (f *fakeProvisioner) GrantPush(ctx context.Context, repository *registry.Repository, robot string) error {
+	f.calls = append(f.calls, "GrantPush "+robot)
+	return nil
+}
+
+func (f *fakeProvisioner) RotateCredential(ctx context.Context, repository *registry.Repository, robot string) (*registry.Credential, error) {
+	f.calls = append(f.calls, "RotateCredential "+robot)
+	f.password = f.password + "-rotated"
+	return &registry.Credential{Username: robot, Password: f.password}, nil
+}
+
+func (f *fakeProvisioner) DeleteRobot(ctx context.Context, robot string) (bool, error) {
+	f.calls = append(f.calls, "DeleteRobot "+robot)
+	return true, nil
+}
+
+func (f *fakeProvisioner) DeleteRepository(ctx context.Context, name string) (bool, error) {
+	f.calls = append(f.calls, "DeleteRepository "+name)
+	return true, nil
+}
+
+// failingSecretClient fails every write of a Secret
+type failingSecretClient struct {
+	runtimeclient.Client
+}
+
+func (c *failingSecretClient) Create(ctx context.Context, obj runtimeclient.Object, opts ...runtimeclient.CreateOption) error {
+	if _, ok := obj.(*corev1.Secret); ok {
+		return errors.NewServiceUnavailable("secret write failed")
+	}
+	return c.Client.Create(ctx, obj, opts...)
+}
+
+func (c *failingSecretClient) Update(ctx context.Context, obj runtimeclient.Object, opts ...runtimeclient.UpdateOption) error {
+	if _, ok := obj.(*corev1.Secret); ok {
+		return errors.NewServiceUnavailable("secret write failed")
+	}
+	return c.Client.Update(ctx, obj, opts...)
+}
+
+func TestRegistryProvisioning(t *testing.T) {
+	request := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: metav1.NamespaceDefault, Name: v1alpha1.JBSConfigName}}
+	setup := func(annotations map[string]string) (runtimeclient.Client, *ReconcilerJBSConfig, *fakeProvisioner) {
+		jbsConfig := setupJBSConfig()
+		jbsConfig.Spec.Owner = ""
+		jbsConfig.Spec.EnableRebuilds = true
+		jbsConfig.Annotations = annotations
+		client, reconciler := setupClientAndReconciler(false, jbsConfig, setupSystemConfig())
+		provisioner := &fakeProvisioner{password: "secret"}
+		reconciler.provisioner.Update("harbor", func() (registry.RegistryProvisioner, error) {
+			return provisioner, nil
+		})
+		return client, reconciler, provisioner
+	}
+	t.Run("Test a repository is provisioned without an owner", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		ctx := context.TODO()
+		client, reconciler, provisioner := setup(nil)
+		_, err := reconciler.Reconcile(ctx, request)
+		g.Expect(err).Should(BeNil())
+		g.Expect(provisioner.calls).Should(Equal([]string{"CreateRepository default/jvm-build-service-artifacts", "CreateRobot defaultjvm_build_config", "GrantPush defaultjvm_build_config"}))
+
+		config := v1alpha1.JBSConfig{}
+		g.Expect(client.Get(ctx, request.NamespacedName, &config)).Should(Succeed())
+		g.Expect(config.Finalizers).Should(ContainElement(ImageRepositoryFinalizer))
+		g.Expect(*config.Status.ImageRegistry).Should(Equal(v1alpha1.ImageRegistry{Host: "harbor.local", Owner: "jbs", Repository: "default/jvm-build-service-artifacts"}))
+		secret := corev1.Secret{}
+		g.Expect(client.Get(ctx, types.NamespacedName{Namespace: metav1.NamespaceDefault, Name: v1alpha1.ImageSecretName}, &secret)).Should(Succeed())
+		g.Expect(secret.StringData[corev1.DockerConfigJsonKey]).Should(ContainSubstring("harbor.local/jbs/default/jvm-build-service-artifacts"))
+
+		//it is only provisioned once
+		_, err = reconciler.Reconcile(ctx, request)
+		g.Expect(err).Should(BeNil())
+		g.Expect(provisioner.calls).Should(HaveLen(3))
+	})
+	t.Run("Test the credential is rotated", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		ctx := context.TODO()
+		client, reconciler, provisioner := setup(nil)
+		_, err := reconciler.Reconcile(ctx, request)
+		g.Expect(err).Should(BeNil())
+		config := v1alpha1.JBSConfig{}
+		g.Expect(client.Get(ctx, request.NamespacedName, &config)).Should(Succeed())
+		config.Annotations = map[string]string{RotateRegistryCredentialAnnotation: "true"}
+		g.Expect(client.Update(ctx, &config)).Should(Succeed())
+		original := corev1.Secret{}
+		g.Expect(client.Get(ctx, types.NamespacedName{Namespace: metav1.NamespaceDefault, Name: v1alpha1.ImageSecretName}, &original)).Should(Succeed())
+		_, err = reconciler.Reconcile(ctx, request)
+		g.Expect(err).Should(BeNil())
+		g.Expect(provisioner.calls[len(provisioner.calls)-1]).Should(Equal("RotateCredential defaultjvm_build_config"))
+
+		g.Expect(client.Get(ctx, request.NamespacedName, &config)).Should(Succeed())
+		g.Expect(config.Annotations).ShouldNot(HaveKey(RotateRegistryCredentialAnnotation))
+		secret := corev1.Secret{}
+		g.Expect(client.Get(ctx, types.NamespacedName{Namespace: metav1.NamespaceDefault, Name: v1alpha1.ImageSecretName}, &secret)).Should(Succeed())
+		g.Expect(secret.StringData[corev1.DockerConfigJsonKey]).Should(ContainSubstring(base64.StdEncoding.EncodeToString([]byte("defaultjvm_build_config:secret-rotated"))))
+		//the secret is updated in place, so the credential is never missing
+		g.Expect(secret.UID).Should(Equal(original.UID))
+	})
+	t.Run("Test the rotation is retried if the secret can't be written", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		ctx := context.TODO()
+		client, reconciler, _ := setup(nil)
+		_, err := reconciler.Reconcile(ctx, request)
+		g.Expect(err).Should(BeNil())
+		config := v1alpha1.JBSConfig{}
+		g.Expect(client.Get(ctx, request.NamespacedName, &config)).Should(Succeed())
+		config.Annotations = map[string]string{RotateRegistryCredentialAnnotation: "true"}
+		g.Expect(client.Update(ctx, &config)).Should(Succeed())
+		reconciler.client = &failingSecretClient{Client: client}
+		_, err = reconciler.Reconcile(ctx, request)
+		g.Expect(err).Should(HaveOccurred())
+
+		g.Expect(client.Get(ctx, request.NamespacedName, &config)).Should(Succeed())
+		g.Expect(config.Annotations).Should(HaveKeyWithValue(RotateRegistryCredentialAnnotation, "true"))
+	})
+	t.Run("Test the robot account and repository are deleted with the JBSConfig", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		ctx := context.TODO()
+		client, reconciler, provisioner := setup(map[string]string{DeleteImageRepositoryAnnotationName: "true"})
+		_, err := reconciler.Reconcile(ctx, request)
+		g.Expect(err).Should(BeNil())
+		config := v1alpha1.JBSConfig{}
+		g.Expect(client.Get(ctx, request.NamespacedName, &config)).Should(Succeed())
+		g.Expect(client.Delete(ctx, &config)).Should(Succeed())
+		_, err = reconciler.Reconcile(ctx, request)
+		g.Expect(err).Should(BeNil())
+		g.Expect(provisioner.calls[3:]).Should(Equal([]string{"DeleteRobot defaultjvm_build_config", "DeleteRepository default/jvm-build-service-artifacts"}))
+	})
+	t.Run("Test provisioning can be disabled", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		ctx := context.TODO()
+		_, reconciler, _ := setup(nil)
+		reconciler.provisioner.Update("disabled", func() (registry.RegistryProvisioner, error) {
+			return nil, nil
+		})
+		_, err := reconciler.Reconcile(ctx, request)
+		g.Expect(err).Should(MatchError("no owner specified and automatic repo creation is disabled"))
+	})
+	t.Run("Test provisioning waits for the SystemConfig", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		ctx := context.TODO()
+		_, reconciler, _ := setup(nil)
+		reconciler.provisioner = &registry.ProvisionerRef{}
+		_, err := reconciler.Reconcile(ctx, request)
+		g.Expect(err).Should(MatchError(registry.ErrProvisionerNotConfigured))
+	})
+	t.Run("Test deletion does not wait for a SystemConfig that does not exist", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		ctx := context.TODO()
+		client, reconciler, _ := setup(nil)
+		_, err := reconciler.Reconcile(ctx, request)
+		g.Expect(err).Should(BeNil())
+		reconciler.provisioner = &registry.ProvisionerRef{}
+		g.Expect(client.Delete(ctx, &v1alpha1.SystemConfig{ObjectMeta: metav1.ObjectMeta{Name: systemconfig.SystemConfigKey}})).Should(Succeed())
+		config := v1alpha1.JBSConfig{}
+		g.Expect(client.Get(ctx, request.NamespacedName, &config)).Should(Succeed())
+		g.Expect(client.Delete(ctx, &config)).Should(Succeed())
+		_, err = reconciler.Reconcile(ctx, request)
+		g.Expect(err).Should(BeNil())
+		g.Expect(errors.IsNotFound(client.Get(ctx, request.NamespacedName, &config))).Should(BeTrue())
+	})
+}
+
+func readEnv(client runtimeclient.Client, g *WithT, deploymentName string) map[string]corev1.EnvVar {
+	deployment := appsv1.Deployment{}
+	g.Expect(client.Get(context.TODO(), types.NamespacedName{Namespace: metav1.NamespaceDefault, Name: deploymentName}, &deployment)).Should(Succeed())
+	ret := map[string]corev1.EnvVar{}
+	for _, val := range deployment.Spec.Template.Spec.Containers[0].Env {
+		ret[val.Name] = val
+	}
+	return ret
+}
+
+func TestNamedJBSConfig(t *testing.T) {
+	ctx := context.TODO()
+	namedConfig := func(name string) (*v1alpha1.JBSConfig, *corev1.Secret) {
+		jbsConfig := setupJBSConfig()
+		jbsConfig.Name = name
+		jbsConfig.Spec.EnableRebuilds = true
+		jbsConfig.Spec.MavenBaseLocations = map[string]string{"maven-repository-302-gradle": "https://repo.gradle.org/artifactory/libs-releases"}
+		secret := setupSecret()
+		secret.Name = jbsConfig.ImageSecret()
+		return jbsConfig, secret
+	}
+
+	t.Run("Test a named JBSConfig deploys its own cache", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		jbsConfig, secret := namedConfig("team-a")
+		client, reconciler := setupClientAndReconciler(false, jbsConfig, secret, setupSystemConfig())
+		_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: metav1.NamespaceDefault, Name: "team-a"}})
+		g.Expect(err).Should(Succeed())
+
+		deployment := appsv1.Deployment{}
+		g.Expect(client.Get(ctx, types.NamespacedName{Namespace: metav1.NamespaceDefault, Name: v1alpha1.CacheDeploymentName + "-team-a"}, &deployment)).Should(Succeed())
+		g.Expect(deployment.OwnerReferences).Should(HaveLen(1))
+		g.Expect(deployment.OwnerReferences[0].Name).Should(Equal("team-a"))
+		g.Expect(deployment.Spec.Template.Labels).Should(HaveKeyWithValue(v1alpha1.CacheLabel, v1alpha1.CacheDeploymentName+"-team-a"))
+		env := readEnv(client, g, v1alpha1.CacheDeploymentName+"-team-a")
+		g.Expect(env["BUILD_POLICY_DEFAULT_STORE_LIST"].Value).Should(Equal("rebuilt,central,redhat,gradle"))
+		g.Expect(env["REGISTRY_TOKEN"].ValueFrom.SecretKeyRef.Name).Should(Equal(v1alpha1.ImageSecretName + "-team-a"))
+		g.Expect(env["JBS_CONFIGS"].Value).Should(Equal("team-a"))
+		g.Expect(client.Get(ctx, types.NamespacedName{Namespace: metav1.NamespaceDefault, Name: v1alpha1.CacheDeploymentName}, &appsv1.Deployment{})).ShouldNot(Succeed())
+	})
+	t.Run("Test the operator is bound to the role for named JBSConfigs", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		teamA, secretA := namedConfig("team-a")
+		teamA.UID = "team-a-uid"
+		teamB, secretB := namedConfig("team-b")
+		teamB.UID = "team-b-uid"
+		client, reconciler := setupClientAndReconciler(false, teamA, secretA, teamB, secretB, setupJBSConfig(), setupSecret(), setupSystemConfig())
+		_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: metav1.NamespaceDefault, Name: v1alpha1.JBSConfigName}})
+		g.Expect(err).Should(Succeed())
+		g.Expect(client.Get(ctx, types.NamespacedName{Namespace: metav1.NamespaceDefault, Name: OperatorRoleBindingName}, &rbacv1.RoleBinding{})).ShouldNot(Succeed())
+
+		for _, name := range []string{"team-a", "team-b", "team-a"} {
+			_, err = reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: metav1.NamespaceDefault, Name: name}})
+			g.Expect(err).Should(Succeed())
+		}
+		rb := rbacv1.RoleBinding{}
+		g.Expect(client.Get(ctx, types.NamespacedName{Namespace: metav1.NamespaceDefault, Name: OperatorRoleBindingName}, &rb)).Should(Succeed())
+		g.Expect(rb.RoleRef.Name).Should(Equal(OperatorRoleBindingName))
+		g.Expect(rb.Subjects).Should(Equal([]rbacv1.Subject{{Kind: "ServiceAccount", Name: util.ControllerDeploymentName, Namespace: util.ControllerNamespace}}))
+		g.Expect(rb.OwnerReferences).Should(HaveLen(2))
+	})
+	t.Run("Test a JBSConfig with a long name gets a cache name that fits in a DNS label", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		name := "a-team-with-a-very-long-name-for-its-config"
+		jbsConfig, secret := namedConfig(name)
+		client, reconciler := setupClientAndReconciler(false, jbsConfig, secret, setupSystemConfig())
+		_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: metav1.NamespaceDefault, Name: name}})
+		g.Expect(err).Should(Succeed())
+
+		cacheName := jbsConfig.CacheName()
+		g.Expect(validation.IsDNS1035Label(cacheName + "-tls")).Should(BeEmpty())
+		g.Expect(validation.IsValidLabelValue(cacheName)).Should(BeEmpty())
+		g.Expect(cacheName).Should(HavePrefix(v1alpha1.CacheDeploymentName + "-a-team"))
+		deployment := appsv1.Deployment{}
+		g.Expect(client.Get(ctx, types.NamespacedName{Namespace: metav1.NamespaceDefault, Name: cacheName}, &deployment)).Should(Succeed())
+		g.Expect(deployment.Spec.Template.Labels).Should(HaveKeyWithValue(v1alpha1.CacheLabel, cacheName))
+		g.Expect(client.Get(ctx, types.NamespacedName{Namespace: metav1.NamespaceDefault, Name: cacheName + "-tls"}, &corev1.Service{})).Should(Succeed())
+
+		//names that only differ after the cut off still get their own cache
+		other, _ := namedConfig(name + "-2")
+		g.Expect(other.CacheName()).ShouldNot(Equal(cacheName))
+		g.Expect(len(other.CacheName())).Should(Equal(len(cacheName)))
+	})
+	t.Run("Test a JBSConfig that shares the cache adds a build policy", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		jbsConfig, secret := namedConfig("team-b")
+		jbsConfig.Spec.CacheSettings.Shared = true
+		client, reconciler := setupClientAndReconciler(false, jbsConfig, secret, setupJBSConfig(), setupSecret(), setupSystemConfig())
+		_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: metav1.NamespaceDefault, Name: "team-b"}})
+		g.Expect(err).Should(Succeed())
+		g.Expect(client.Get(ctx, types.NamespacedName{Namespace: metav1.NamespaceDefault, Name: v1alpha1.CacheDeploymentName + "-team-b"}, &appsv1.Deployment{})).ShouldNot(Succeed())
+
+		_, err = reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: metav1.NamespaceDefault, Name: v1alpha1.JBSConfigName}})
+		g.Expect(err).Should(Succeed())
+		env := readEnv(client, g, v1alpha1.CacheDeploymentName)
+		g.Expect(env["BUILD_POLICIES"].Value).Should(Equal("default,team-b"))
+		g.Expect(env["JBS_CONFIGS"].Value).Should(Equal(v1alpha1.JBSConfigName + ",team-b"))
+		g.Expect(env["BUILD_POLICY_DEFAULT_STORE_LIST"].Value).Should(Equal("central,redhat"))
+		g.Expect(env["BUILD_POLICY_TEAM_B_STORE_LIST"].Value).Should(Equal("team-b-rebuilt,central,redhat,team-b-gradle"))
+		g.Expect(env["STORE_TEAM_B_GRADLE_URL"].Value).Should(Equal("https://repo.gradle.org/artifactory/libs-releases"))
+		g.Expect(env["STORE_TEAM_B_REBUILT_TYPE"].Value).Should(Equal("OCI_REGISTRY"))
+		g.Expect(env["STORE_TEAM_B_REBUILT_OWNER"].Value).Should(Equal("tests"))
+		g.Expect(env["STORE_TEAM_B_REBUILT_TOKEN"].ValueFrom.SecretKeyRef.Name).Should(Equal(v1alpha1.ImageSecretName + "-team-b"))
+	})
+}
+
+func TestConditions(t *testing.T) {
+	ctx := context.TODO()
+	request := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: metav1.NamespaceDefault, Name: v1alpha1.JBSConfigName}}
+	readConditions := func(client runtimeclient.Client, g *WithT) *v1alpha1.JBSConfig {
+		jbsConfig := v1alpha1.JBSConfig{}
+		g.Expect(client.Get(ctx, request.NamespacedName, &jbsConfig)).Should(Succeed())
+		return &jbsConfig
+	}
+	expectCondition := func(g *WithT, jbsConfig *v1alpha1.JBSConfig, conditionType string, status metav1.ConditionStatus, reason string) {
+		condition := meta.FindStatusCondition(jbsConfig.Status.Conditions, conditionType)
+		g.Expect(condition).ShouldNot(BeNil())
+		g.Expect(condition.Status).Should(Equal(status))
+		g.Expect(condition.Reason).Should(Equal(reason))
+	}
+	tlsObjects := func() []runtimeclient.Object {
+		return []runtimeclient.Object{
+			&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: metav1.NamespaceDefault, Name: setupJBSConfig().TlsSecret()}},
+			&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: metav1.NamespaceDefault, Name: v1alpha1.TlsConfigMapName}, Data: map[string]string{"service-ca.crt": "cert"}},
+		}
+	}
+
+	t.Run("Test the cache is progressing until the deployment is available", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		jbsConfig := setupJBSConfig()
+		jbsConfig.Spec.EnableRebuilds = true
+		client, reconciler := setupClientAndReconciler(false, append(tlsObjects(), jbsConfig, setupSecret(), setupSystemConfig())...)
+		_, err := reconciler.Reconcile(ctx, request)
+		g.Expect(err).Should(Succeed())
+		jbsConfig = readConditions(client, g)
+		expectCondition(g, jbsConfig, v1alpha1.JBSConfigConditionCacheReady, metav1.ConditionFalse, v1alpha1.JBSConfigReasonCacheProgressing)
+		expectCondition(g, jbsConfig, v1alpha1.JBSConfigConditionReady, metav1.ConditionFalse, v1alpha1.JBSConfigReasonCacheProgressing)
+		expectCondition(g, jbsConfig, v1alpha1.JBSConfigConditionRebuildsEnabled, metav1.ConditionTrue, v1alpha1.JBSConfigReasonRebuildsEnabled)
+
+		deployment := appsv1.Deployment{}
+		g.Expect(client.Get(ctx, types.NamespacedName{Namespace: metav1.NamespaceDefault, Name: v1alpha1.CacheDeploymentName}, &deployment)).Should(Succeed())
+		deployment.Status.AvailableReplicas = 1
+		deployment.Status.ObservedGeneration = deployment.Generation
+		g.Expect(client.Status().Update(ctx, &deployment)).Should(Succeed())
+		_, err = reconciler.Reconcile(ctx, request)
+		g.Expect(err).Should(Succeed())
+		jbsConfig = readConditions(client, g)
+		expectCondition(g, jbsConfig, v1alpha1.JBSConfigConditionCacheReady, metav1.ConditionTrue, v1alpha1.JBSConfigReasonCacheAvailable)
+		expectCondition(g, jbsConfig, v1alpha1.JBSConfigConditionRegistryCredentialsReady, metav1.ConditionTrue, v1alpha1.JBSConfigReasonCredentialsFound)
+		expectCondition(g, jbsConfig, v1alpha1.JBSConfigConditionTLSReady, metav1.ConditionTrue, v1alpha1.JBSConfigReasonCertificateIssued)
+		expectCondition(g, jbsConfig, v1alpha1.JBSConfigConditionRecipeRepositoriesConfigured, metav1.ConditionTrue, v1alpha1.JBSConfigReasonRepositoriesConfigured)
+		expectCondition(g, jbsConfig, v1alpha1.JBSConfigConditionReady, metav1.ConditionTrue, v1alpha1.JBSConfigReasonReady)
+	})
+	t.Run("Test a crash looping cache pod is reported", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		pod := corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: metav1.NamespaceDefault, Name: "cache-1", Labels: map[string]string{v1alpha1.CacheLabel: v1alpha1.CacheDeploymentName}}}
+		pod.Status.ContainerStatuses = []corev1.ContainerStatus{{Name: "jvm-build-workspace-artifact-cache", State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}}}}
+		client, reconciler := setupClientAndReconciler(false, append(tlsObjects(), setupJBSConfig(), &pod, setupSystemConfig())...)
+		_, err := reconciler.Reconcile(ctx, request)
+		g.Expect(err).Should(Succeed())
+		jbsConfig := readConditions(client, g)
+		expectCondition(g, jbsConfig, v1alpha1.JBSConfigConditionCacheReady, metav1.ConditionFalse, v1alpha1.JBSConfigReasonCacheFailing)
+		expectCondition(g, jbsConfig, v1alpha1.JBSConfigConditionReady, metav1.ConditionFalse, v1alpha1.JBSConfigReasonCacheFailing)
+		g.Expect(meta.FindStatusCondition(jbsConfig.Status.Conditions, v1alpha1.JBSConfigConditionReady).Message).Should(ContainSubstring("cache-1"))
+	})
+	t.Run("Test missing registry credentials are reported", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		jbsConfig := setupJBSConfig()
+		jbsConfig.Spec.EnableRebuilds = true
+		client, reconciler := setupClientAndReconciler(false, append(tlsObjects(), jbsConfig, setupSystemConfig())...)
+		_, err := reconciler.Reconcile(ctx, request)
+		g.Expect(err).ShouldNot(Succeed())
+		jbsConfig = readConditions(client, g)
+		expectCondition(g, jbsConfig, v1alpha1.JBSConfigConditionRegistryCredentialsReady, metav1.ConditionFalse, v1alpha1.JBSConfigReasonSecretNotFound)
+		expectCondition(g, jbsConfig, v1alpha1.JBSConfigConditionRebuildsEnabled, metav1.ConditionFalse, v1alpha1.JBSConfigReasonRegistryCredentialsNotReady)
+		expectCondition(g, jbsConfig, v1alpha1.JBSConfigConditionCacheReady, metav1.ConditionFalse, v1alpha1.JBSConfigReasonCacheNotFound)
+		expectCondition(g, jbsConfig, v1alpha1.JBSConfigConditionReady, metav1.ConditionFalse, v1alpha1.JBSConfigReasonCacheNotFound)
+	})
+	t.Run("Test TLS is reported until the certificate is issued", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		client, reconciler := setupClientAndReconciler(false, setupJBSConfig(), setupSystemConfig())
+		_, err := reconciler.Reconcile(ctx, request)
+		g.Expect(err).Should(Succeed())
+		jbsConfig := readConditions(client, g)
+		expectCondition(g, jbsConfig, v1alpha1.JBSConfigConditionTLSReady, metav1.ConditionFalse, v1alpha1.JBSConfigReasonCertificateNotIssued)
+		expectCondition(g, jbsConfig, v1alpha1.JBSConfigConditionRegistryCredentialsReady, metav1.ConditionTrue, v1alpha1.JBSConfigReasonCredentialsNotRequired)
+		expectCondition(g, jbsConfig, v1alpha1.JBSConfigConditionRebuildsEnabled, metav1.ConditionFalse, v1alpha1.JBSConfigReasonRebuildsDisabled)
+
+		jbsConfig.Spec.CacheSettings.DisableTLS = true
+		g.Expect(client.Update(ctx, jbsConfig)).Should(Succeed())
+		_, err = reconciler.Reconcile(ctx, request)
+		g.Expect(err).Should(Succeed())
+		expectCondition(g, readConditions(client, g), v1alpha1.JBSConfigConditionTLSReady, metav1.ConditionTrue, v1alpha1.JBSConfigReasonTLSDisabled)
+	})
+	t.Run("Test invalid repositories are reported", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		jbsConfig := setupJBSConfig()
+		jbsConfig.Spec.MavenBaseLocations = map[string]string{"maven-repository-300-central": "https://repo.example.com", "gradle": "https://repo.gradle.org/artifactory/libs-releases", "maven-repository-301-jboss": "not a url"}
+		jbsConfig.Spec.AdditionalRecipes = []string{"https://github.com/example/recipes.git"}
+		client, reconciler := setupClientAndReconciler(false, append(tlsObjects(), jbsConfig, setupSystemConfig())...)
+		_, err := reconciler.Reconcile(ctx, request)
+		g.Expect(err).Should(Succeed())
+		jbsConfig = readConditions(client, g)
+		expectCondition(g, jbsConfig, v1alpha1.JBSConfigConditionRecipeRepositoriesConfigured, metav1.ConditionFalse, v1alpha1.JBSConfigReasonInvalidRepository)
+		message := meta.FindStatusCondition(jbsConfig.Status.Conditions, v1alpha1.JBSConfigConditionRecipeRepositoriesConfigured).Message
+		g.Expect(message).Should(ContainSubstring("gradle"))
+		g.Expect(message).Should(ContainSubstring("central"))
+		g.Expect(message).Should(ContainSubstring("not a url"))
+		g.Expect(message).ShouldNot(ContainSubstring("recipes.git"))
+	})
+	t.Run("Test invalid cache settings are reported instead of creating the cache", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		jbsConfig := setupJBSConfig()
+		jbsConfig.Spec.CacheSettings.LimitMemory = "1 gig"
+		client, reconciler := setupClientAndReconciler(false, append(tlsObjects(), jbsConfig, setupSystemConfig())...)
+		_, err := reconciler.Reconcile(ctx, request)
+		g.Expect(err).Should(Succeed())
+		jbsConfig = readConditions(client, g)
+		expectCondition(g, jbsConfig, v1alpha1.JBSConfigConditionCacheReady, metav1.ConditionFalse, v1alpha1.JBSConfigReasonInvalidCacheSettings)
+		expectCondition(g, jbsConfig, v1alpha1.JBSConfigConditionReady, metav1.ConditionFalse, v1alpha1.JBSConfigReasonInvalidCacheSettings)
+		g.Expect(meta.FindStatusCondition(jbsConfig.Status.Conditions, v1alpha1.JBSConfigConditionCacheReady).Message).Should(ContainSubstring("limitMemory"))
+		g.Expect(client.Get(ctx, types.NamespacedName{Namespace: metav1.NamespaceDefault, Name: v1alpha1.CacheDeploymentName}, &appsv1.Deployment{})).ShouldNot(Succeed())
+	})
+}
@@ -0,0 +1,81 @@
+package rebuiltartifact
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	"github.com/redhat-appstudio/jvm-build-service/pkg/apis/jvmbuildservice/v1alpha1"
+	"github.com/redhat-appstudio/jvm-build-service/pkg/reconciler/artifactbuild"
+)
+
+func SetupNewReconcilerWithManager(mgr ctrl.Manager) error {
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &v1alpha1.ArtifactBuild{}, ArtifactBuildGAVIndex, artifactBuildGAV); err != nil {
+		return err
+	}
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &v1alpha1.RebuiltArtifact{}, SharedFromIndex, rebuiltArtifactSharedFrom); err != nil {
+		return err
+	}
+	r := newReconciler(mgr)
+	return ctrl.NewControllerManagedBy(mgr).For(&v1alpha1.RebuiltArtifact{}).
+		//the rebuilt artifact has the same name as the ArtifactBuilds created for its GAV
+		Watches(&source.Kind{Type: &v1alpha1.ArtifactBuild{}}, handler.EnqueueRequestsFromMapFunc(func(o client.Object) []reconcile.Request {
+			abr := o.(*v1alpha1.ArtifactBuild)
+			return []reconcile.Request{
+				{
+					NamespacedName: types.NamespacedName{
+						Name:      artifactbuild.CreateABRName(abr.Spec.GAV),
+						Namespace: abr.Namespace,
+					},
+				},
+			}
+		})).
+		Watches(&source.Kind{Type: &v1alpha1.DependencyBuild{}}, handler.EnqueueRequestsFromMapFunc(func(o client.Object) []reconcile.Request {
+			db := o.(*v1alpha1.DependencyBuild)
+			requests := []reconcile.Request{}
+			for _, gav := range db.Status.DeployedArtifacts {
+				requests = append(requests, reconcile.Request{
+					NamespacedName: types.NamespacedName{
+						Name:      artifactbuild.CreateABRName(gav),
+						Namespace: db.Namespace,
+					},
+				})
+			}
+			return requests
+		})).
+		//the copies of a shared artifact follow the artifact in the trusted namespace when it is rebuilt
+		Watches(&source.Kind{Type: &v1alpha1.RebuiltArtifact{}}, handler.EnqueueRequestsFromMapFunc(func(o client.Object) []reconcile.Request {
+			copies := v1alpha1.RebuiltArtifactList{}
+			if err := mgr.GetClient().List(context.Background(), &copies, client.MatchingFields{SharedFromIndex: o.GetNamespace() + "/" + o.GetName()}); err != nil {
+				return []reconcile.Request{}
+			}
+			requests := []reconcile.Request{}
+			for _, ra := range copies.Items {
+				requests = append(requests, reconcile.Request{
+					NamespacedName: types.NamespacedName{
+						Name:      ra.Name,
+						Namespace: ra.Namespace,
+					},
+				})
+			}
+			return requests
+		})).
+		Complete(r)
+}
+
+func artifactBuildGAV(o client.Object) []string {
+	return []string{o.(*v1alpha1.ArtifactBuild).Spec.GAV}
+}
+
+func rebuiltArtifactSharedFrom(o client.Object) []string {
+	ra := o.(*v1alpha1.RebuiltArtifact)
+	if from, ok := sharedFrom(ra); ok {
+		return []string{from.Namespace + "/" + ra.Name}
+	}
+	return nil
+}
@@ -0,0 +1,374 @@
+package rebuiltartifact
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	"github.com/redhat-appstudio/jvm-build-service/pkg/apis/jvmbuildservice/v1alpha1"
+	"github.com/redhat-appstudio/jvm-build-service/pkg/reconciler/artifactbuild"
+	"github.com/redhat-appstudio/jvm-build-service/pkg/reconciler/util"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	contextTimeout = 300 * time.Second
+	// imageCheckRetryInterval how long to wait before checking the image again if the registry could not be reached
+	imageCheckRetryInterval = 5 * time.Minute
+	// defaultImageCheckInterval how often the image is checked if the JBSConfig does not say
+	defaultImageCheckInterval = 24 * time.Hour
+
+	// ArtifactBuildGAVIndex indexes the ArtifactBuilds by the GAV they request
+	ArtifactBuildGAVIndex = "spec.gav"
+	// SharedFromIndex indexes the shared RebuiltArtifacts by the namespace and name of the artifact they were copied from
+	SharedFromIndex = "metadata.annotations.sharedfrom"
+)
+
+// ImageChecker reports if an image exists in its registry
+type ImageChecker interface {
+	ImageExists(ctx context.Context, ref string, insecure bool, dockerConfig []byte) (bool, error)
+}
+
+type registryImageChecker struct {
+}
+
+func (c *registryImageChecker) ImageExists(ctx context.Context, ref string, insecure bool, dockerConfig []byte) (bool, error) {
+	opts := []name.Option{name.WeakValidation}
+	if insecure {
+		opts = append(opts, name.Insecure)
+	}
+	imageRef, err := name.ParseReference(ref, opts...)
+	if err != nil {
+		return false, err
+	}
+	auth, err := util.RegistryAuth(dockerConfig, imageRef.Context().RegistryStr())
+	if err != nil {
+		return false, err
+	}
+	_, err = remote.Head(imageRef, remote.WithAuth(auth), remote.WithContext(ctx))
+	if err != nil {
+		if terr, ok := err.(*transport.Error); ok && terr.StatusCode == http.StatusNotFound {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+type ReconcileRebuiltArtifact struct {
+	client        client.Client
+	imageChecker  ImageChecker
+	eventRecorder record.EventRecorder
+}
+
+func newReconciler(mgr ctrl.Manager) reconcile.Reconciler {
+	return &ReconcileRebuiltArtifact{
+		client:        mgr.GetClient(),
+		imageChecker:  &registryImageChecker{},
+		eventRecorder: mgr.GetEventRecorderFor("RebuiltArtifact"),
+	}
+}
+
+func (r *ReconcileRebuiltArtifact) Reconcile(ctx context.Context, request reconcile.Request) (reconcile.Result, error) {
+	var cancel context.CancelFunc
+	ctx, cancel = context.WithTimeout(ctx, contextTimeout)
+	defer cancel()
+	log := ctrl.Log.WithName("rebuiltartifact").WithValues("namespace", request.NamespacedName.Namespace, "resource", request.Name, "kind", "RebuiltArtifact")
+
+	ra := v1alpha1.RebuiltArtifact{}
+	err := r.client.Get(ctx, request.NamespacedName, &ra)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, err
+	}
+	if ra.DeletionTimestamp != nil {
+		return reconcile.Result{}, nil
+	}
+	//the image of a shared artifact is in the registry of the trusted namespace, which is also where it is rebuilt
+	imageNamespace := ra.Namespace
+	imageJBSConfig := ra.Spec.JBSConfig
+	if from, ok := sharedFrom(&ra); ok {
+		imageNamespace = from.Namespace
+		producing, err := r.updateSharedArtifact(ctx, log, &ra, from.Namespace)
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+		imageJBSConfig = ""
+		if producing != nil {
+			imageJBSConfig = producing.Spec.JBSConfig
+		}
+	}
+	original := ra.Status.DeepCopy()
+	result := reconcile.Result{}
+
+	if err := r.updateProducingBuild(ctx, &ra); err != nil {
+		return reconcile.Result{}, err
+	}
+	if err := r.updateArtifactBuilds(ctx, &ra); err != nil {
+		return reconcile.Result{}, err
+	}
+	jbsConfig := v1alpha1.JBSConfig{}
+	err = r.client.Get(ctx, types.NamespacedName{Namespace: ra.Namespace, Name: v1alpha1.JBSConfigNameOrDefault(ra.Spec.JBSConfig)}, &jbsConfig)
+	if err != nil && !errors.IsNotFound(err) {
+		return reconcile.Result{}, err
+	}
+	registryConfig := &jbsConfig
+	if imageNamespace != ra.Namespace {
+		registryConfig = &v1alpha1.JBSConfig{}
+		err = r.client.Get(ctx, types.NamespacedName{Namespace: imageNamespace, Name: v1alpha1.JBSConfigNameOrDefault(imageJBSConfig)}, registryConfig)
+		if err != nil && !errors.IsNotFound(err) {
+			return reconcile.Result{}, err
+		}
+	}
+	interval := imageCheckInterval(&jbsConfig)
+	imageCondition := meta.FindStatusCondition(ra.Status.Conditions, v1alpha1.RebuiltArtifactConditionImageAvailable)
+	if imageCondition == nil || imageCondition.ObservedGeneration != ra.Generation || imageCondition.Status == metav1.ConditionUnknown ||
+		(interval > 0 && (ra.Status.LastImageCheck == nil || time.Since(ra.Status.LastImageCheck.Time) >= interval)) {
+		if !r.checkImage(ctx, log, &ra, imageNamespace, registryConfig) {
+			result.RequeueAfter = imageCheckRetryInterval
+		}
+	}
+	if jbsConfig.Spec.ImageCheck.RebuildMissing {
+		if err := r.requestRebuild(ctx, log, &ra, imageNamespace); err != nil {
+			return reconcile.Result{}, err
+		}
+	}
+	updateReady(&ra)
+	if result.RequeueAfter == 0 && interval > 0 && ra.Status.LastImageCheck != nil {
+		//check the image again once the interval has passed
+		result.RequeueAfter = interval - time.Since(ra.Status.LastImageCheck.Time)
+	}
+
+	if !equality.Semantic.DeepEqual(original, &ra.Status) {
+		if err := r.client.Status().Update(ctx, &ra); err != nil {
+			return reconcile.Result{}, err
+		}
+	}
+	return result, nil
+}
+
+// updateProducingBuild records the build that produced the artifact, and if it passed verification. The build may have
+// been cleaned up, in which case what was recorded while it existed is kept.
+func (r *ReconcileRebuiltArtifact) updateProducingBuild(ctx context.Context, ra *v1alpha1.RebuiltArtifact) error {
+	key := types.NamespacedName{}
+	for _, ref := range ra.OwnerReferences {
+		if ref.Kind == "DependencyBuild" {
+			key = types.NamespacedName{Namespace: ra.Namespace, Name: ref.Name}
+		}
+	}
+	if from, ok := sharedFrom(ra); ok {
+		//a shared artifact was produced in the trusted namespace
+		key = from
+	}
+	db := v1alpha1.DependencyBuild{}
+	found := false
+	if key.Name != "" {
+		err := r.client.Get(ctx, key, &db)
+		if err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+		found = err == nil
+	}
+	if !found {
+		if meta.FindStatusCondition(ra.Status.Conditions, v1alpha1.RebuiltArtifactConditionVerified) == nil {
+			setCondition(ra, v1alpha1.RebuiltArtifactConditionVerified, metav1.ConditionUnknown, v1alpha1.RebuiltArtifactReasonDependencyBuildNotFound, "the DependencyBuild that produced the artifact was not found")
+		}
+		return nil
+	}
+	ra.Status.DependencyBuild = db.Namespace + "/" + db.Name
+	ra.Status.SCMURL = db.Spec.ScmInfo.SCMURL
+	ra.Status.CommitHash = db.Spec.ScmInfo.CommitHash
+	verified := meta.FindStatusCondition(db.Status.Conditions, v1alpha1.DependencyBuildConditionVerified)
+	if verified == nil && db.Status.FailedVerification {
+		verified = &metav1.Condition{Status: metav1.ConditionFalse}
+	}
+	switch {
+	case verified == nil || verified.Status == metav1.ConditionUnknown:
+		setCondition(ra, v1alpha1.RebuiltArtifactConditionVerified, metav1.ConditionUnknown, v1alpha1.RebuiltArtifactReasonVerificationNotReported, "DependencyBuild "+db.Name+" did not report a verification result")
+	case verified.Status == metav1.ConditionTrue:
+		setCondition(ra, v1alpha1.RebuiltArtifactConditionVerified, metav1.ConditionTrue, v1alpha1.RebuiltArtifactReasonVerificationPassed, "")
+	default:
+		setCondition(ra, v1alpha1.RebuiltArtifactConditionVerified, metav1.ConditionFalse, v1alpha1.RebuiltArtifactReasonVerificationFailed, "DependencyBuild "+db.Name+" did not match the upstream artifacts")
+	}
+	return nil
+}
+
+func (r *ReconcileRebuiltArtifact) updateArtifactBuilds(ctx context.Context, ra *v1alpha1.RebuiltArtifact) error {
+	abrs := v1alpha1.ArtifactBuildList{}
+	if err := r.client.List(ctx, &abrs, client.InNamespace(ra.Namespace), client.MatchingFields{ArtifactBuildGAVIndex: ra.Spec.GAV}); err != nil {
+		return err
+	}
+	ra.Status.ArtifactBuilds = nil
+	for _, abr := range abrs.Items {
+		if abr.DeletionTimestamp == nil {
+			ra.Status.ArtifactBuilds = append(ra.Status.ArtifactBuilds, abr.Name)
+		}
+	}
+	sort.Strings(ra.Status.ArtifactBuilds)
+	return nil
+}
+
+// sharedFrom the DependencyBuild in the trusted namespace a shared artifact was copied from
+func sharedFrom(ra *v1alpha1.RebuiltArtifact) (types.NamespacedName, bool) {
+	parts := strings.SplitN(ra.Annotations[artifactbuild.SharedFromAnnotation], "/", 2)
+	if len(parts) != 2 {
+		return types.NamespacedName{}, false
+	}
+	return types.NamespacedName{Namespace: parts[0], Name: parts[1]}, true
+}
+
+// updateSharedArtifact keeps a shared artifact pointing at the image of the artifact it was copied from, which changes
+// when the artifact is rebuilt in the trusted namespace. The artifact in the trusted namespace is returned, or nil if
+// it no longer exists.
+func (r *ReconcileRebuiltArtifact) updateSharedArtifact(ctx context.Context, log logr.Logger, ra *v1alpha1.RebuiltArtifact, namespace string) (*v1alpha1.RebuiltArtifact, error) {
+	producing := v1alpha1.RebuiltArtifact{}
+	err := r.client.Get(ctx, types.NamespacedName{Namespace: namespace, Name: ra.Name}, &producing)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if producing.Spec.Image == ra.Spec.Image && producing.Spec.Digest == ra.Spec.Digest {
+		return &producing, nil
+	}
+	log.Info("the shared artifact was rebuilt, updating the image", "image", producing.Spec.Image, "digest", producing.Spec.Digest)
+	ra.Spec.Image = producing.Spec.Image
+	ra.Spec.Digest = producing.Spec.Digest
+	return &producing, r.client.Update(ctx, ra)
+}
+
+// checkImage checks the image and digest of the artifact are still in the registry, and returns false if the
+// registry could not be checked. The registry credentials are read from the namespace the image was deployed from.
+func (r *ReconcileRebuiltArtifact) checkImage(ctx context.Context, log logr.Logger, ra *v1alpha1.RebuiltArtifact, namespace string, jbsConfig *v1alpha1.JBSConfig) bool {
+	if ra.Spec.Image == "" {
+		setCondition(ra, v1alpha1.RebuiltArtifactConditionImageAvailable, metav1.ConditionFalse, v1alpha1.RebuiltArtifactReasonImageMissing, "the artifact does not reference an image")
+		return true
+	}
+	ref := ra.Spec.Image
+	if ra.Spec.Digest != "" {
+		//the tag can be moved, the digest is what was built
+		imageRef, err := name.ParseReference(ra.Spec.Image, name.WeakValidation)
+		if err != nil {
+			setCondition(ra, v1alpha1.RebuiltArtifactConditionImageAvailable, metav1.ConditionFalse, v1alpha1.RebuiltArtifactReasonImageMissing, err.Error())
+			return true
+		}
+		ref = imageRef.Context().Name() + "@" + ra.Spec.Digest
+	}
+	secret := corev1.Secret{}
+	err := r.client.Get(ctx, types.NamespacedName{Namespace: namespace, Name: jbsConfig.ImageSecret()}, &secret)
+	if err != nil && !errors.IsNotFound(err) {
+		log.Error(err, "failed to read the image registry secret")
+		setCondition(ra, v1alpha1.RebuiltArtifactConditionImageAvailable, metav1.ConditionUnknown, v1alpha1.RebuiltArtifactReasonImageCheckFailed, err.Error())
+		return false
+	}
+	exists, err := r.imageChecker.ImageExists(ctx, ref, jbsConfig.ImageRegistry().Insecure, secret.Data[v1alpha1.ImageSecretTokenKey])
+	if err != nil {
+		log.Error(err, "failed to check the image", "image", ref)
+		setCondition(ra, v1alpha1.RebuiltArtifactConditionImageAvailable, metav1.ConditionUnknown, v1alpha1.RebuiltArtifactReasonImageCheckFailed, err.Error())
+		return false
+	}
+	now := metav1.Now()
+	ra.Status.LastImageCheck = &now
+	previous := meta.FindStatusCondition(ra.Status.Conditions, v1alpha1.RebuiltArtifactConditionImageAvailable)
+	if exists {
+		setCondition(ra, v1alpha1.RebuiltArtifactConditionImageAvailable, metav1.ConditionTrue, v1alpha1.RebuiltArtifactReasonImageFound, "")
+	} else if previous == nil || previous.Status != metav1.ConditionFalse || previous.ObservedGeneration != ra.Generation {
+		//the reason is kept if the image was already known to be missing, so a rebuild is only requested once
+		log.Info("the image of the rebuilt artifact is missing", "image", ref)
+		r.eventRecorder.Eventf(ra, corev1.EventTypeWarning, v1alpha1.RebuiltArtifactReasonImageMissing, "The image %s of RebuiltArtifact %s/%s was not found in the registry", ref, ra.Namespace, ra.Name)
+		setCondition(ra, v1alpha1.RebuiltArtifactConditionImageAvailable, metav1.ConditionFalse, v1alpha1.RebuiltArtifactReasonImageMissing, "image "+ref+" was not found in the registry")
+	}
+	return true
+}
+
+// requestRebuild annotates the completed ArtifactBuilds of an artifact whose image is missing to be rebuilt, which
+// deploys the image again and updates the artifact. A shared artifact is rebuilt in the trusted namespace it was
+// copied from, relinking the copy in this namespace would only find the same missing image.
+func (r *ReconcileRebuiltArtifact) requestRebuild(ctx context.Context, log logr.Logger, ra *v1alpha1.RebuiltArtifact, namespace string) error {
+	condition := meta.FindStatusCondition(ra.Status.Conditions, v1alpha1.RebuiltArtifactConditionImageAvailable)
+	if condition == nil || condition.Status != metav1.ConditionFalse || condition.Reason != v1alpha1.RebuiltArtifactReasonImageMissing {
+		return nil
+	}
+	abrs := v1alpha1.ArtifactBuildList{}
+	if err := r.client.List(ctx, &abrs, client.InNamespace(namespace), client.MatchingFields{ArtifactBuildGAVIndex: ra.Spec.GAV}); err != nil {
+		return err
+	}
+	sort.Slice(abrs.Items, func(i, j int) bool {
+		return abrs.Items[i].Name < abrs.Items[j].Name
+	})
+	requested := []string{}
+	for _, abr := range abrs.Items {
+		//a build that has not completed will deploy the image anyway
+		if abr.DeletionTimestamp != nil || abr.Status.State != v1alpha1.ArtifactBuildStateComplete || abr.Annotations[artifactbuild.RebuildAnnotation] != "" {
+			continue
+		}
+		if abr.Annotations == nil {
+			abr.Annotations = map[string]string{}
+		}
+		abr.Annotations[artifactbuild.RebuildAnnotation] = "true"
+		if err := r.client.Update(ctx, &abr); err != nil {
+			return err
+		}
+		requested = append(requested, abr.Name)
+	}
+	if len(requested) == 0 {
+		return nil
+	}
+	log.Info("requested a rebuild of the missing image", "buildnamespace", namespace, "artifactbuilds", requested)
+	message := "the image was not found in the registry, rebuilding ArtifactBuilds " + strings.Join(requested, ", ")
+	if namespace != ra.Namespace {
+		message += " in namespace " + namespace
+	}
+	r.eventRecorder.Eventf(ra, corev1.EventTypeNormal, v1alpha1.RebuiltArtifactReasonRebuildRequested, "RebuiltArtifact %s/%s: %s", ra.Namespace, ra.Name, message)
+	setCondition(ra, v1alpha1.RebuiltArtifactConditionImageAvailable, metav1.ConditionFalse, v1alpha1.RebuiltArtifactReasonRebuildRequested, message)
+	return nil
+}
+
+// imageCheckInterval how often the image of an artifact is checked, 0 means only when the artifact changes
+func imageCheckInterval(jbsConfig *v1alpha1.JBSConfig) time.Duration {
+	if jbsConfig.Spec.ImageCheck.Interval != nil {
+		return jbsConfig.Spec.ImageCheck.Interval.Duration
+	}
+	return defaultImageCheckInterval
+}
+
+// updateReady the artifact is ready if its image is available and its build passed verification, otherwise the
+// first condition that is not met gives the reason
+func updateReady(ra *v1alpha1.RebuiltArtifact) {
+	for _, conditionType := range []string{v1alpha1.RebuiltArtifactConditionImageAvailable, v1alpha1.RebuiltArtifactConditionVerified} {
+		condition := meta.FindStatusCondition(ra.Status.Conditions, conditionType)
+		if condition != nil && condition.Status != metav1.ConditionTrue {
+			setCondition(ra, v1alpha1.RebuiltArtifactConditionReady, condition.Status, condition.Reason, condition.Message)
+			return
+		}
+	}
+	setCondition(ra, v1alpha1.RebuiltArtifactConditionReady, metav1.ConditionTrue, v1alpha1.RebuiltArtifactReasonReady, "")
+}
+
+func setCondition(ra *v1alpha1.RebuiltArtifact, conditionType string, status metav1.ConditionStatus, reason string, message string) {
+	meta.SetStatusCondition(&ra.Status.Conditions, metav1.Condition{
+		Type:               conditionType,
+		Status:             status,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: ra.Generation,
+	})
+}
@@ -0,0 +1,322 @@
+package rebuiltartifact
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/redhat-appstudio/jvm-build-service/pkg/apis/jvmbuildservice/v1alpha1"
+	"github.com/redhat-appstudio/jvm-build-service/pkg/reconciler/artifactbuild"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	gav   = "com.test:test:1.0"
+	image = "quay.io/test/test:1.0"
+)
+
+type fakeImageChecker struct {
+	images      map[string]bool
+	err         error
+	checked     []string
+	credentials []string
+}
+
+func (f *fakeImageChecker) ImageExists(ctx context.Context, ref string, insecure bool, dockerConfig []byte) (bool, error) {
+	f.checked = append(f.checked, ref)
+	f.credentials = append(f.credentials, string(dockerConfig))
+	if f.err != nil {
+		return false, f.err
+	}
+	return f.images[ref], nil
+}
+
+func setupClientAndReconciler(checker ImageChecker, objs ...runtimeclient.Object) (runtimeclient.Client, *ReconcileRebuiltArtifact) {
+	scheme := runtime.NewScheme()
+	_ = v1alpha1.AddToScheme(scheme)
+	_ = corev1.AddToScheme(scheme)
+	client := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).WithIndex(&v1alpha1.ArtifactBuild{}, ArtifactBuildGAVIndex, artifactBuildGAV).Build()
+	reconciler := &ReconcileRebuiltArtifact{
+		client:        client,
+		imageChecker:  checker,
+		eventRecorder: &record.FakeRecorder{},
+	}
+	return client, reconciler
+}
+
+func rebuiltArtifact(owner string) *v1alpha1.RebuiltArtifact {
+	ra := v1alpha1.RebuiltArtifact{}
+	ra.Namespace = metav1.NamespaceDefault
+	ra.Name = artifactbuild.CreateABRName(gav)
+	ra.Spec.GAV = gav
+	ra.Spec.Image = image
+	ra.Spec.Digest = "sha256:1234"
+	if owner != "" {
+		ra.OwnerReferences = []metav1.OwnerReference{{APIVersion: v1alpha1.SchemeGroupVersion.String(), Kind: "DependencyBuild", Name: owner, UID: types.UID(owner)}}
+	}
+	return &ra
+}
+
+func dependencyBuild(namespace string, name string, verified metav1.ConditionStatus) *v1alpha1.DependencyBuild {
+	db := v1alpha1.DependencyBuild{}
+	db.Namespace = namespace
+	db.Name = name
+	db.Spec.ScmInfo = v1alpha1.SCMInfo{SCMURL: "https://github.com/test/test.git", CommitHash: "abcd"}
+	db.Status.State = v1alpha1.DependencyBuildStateComplete
+	db.Status.DeployedArtifacts = []string{gav}
+	if verified != "" {
+		db.Status.Conditions = []metav1.Condition{{Type: v1alpha1.DependencyBuildConditionVerified, Status: verified, Reason: "test"}}
+	}
+	return &db
+}
+
+func abr(name string) *v1alpha1.ArtifactBuild {
+	ab := v1alpha1.ArtifactBuild{}
+	ab.Namespace = metav1.NamespaceDefault
+	ab.Name = name
+	ab.Spec.GAV = gav
+	ab.Status.State = v1alpha1.ArtifactBuildStateComplete
+	return &ab
+}
+
+func jbsConfig(settings v1alpha1.ImageCheckSettings) *v1alpha1.JBSConfig {
+	config := v1alpha1.JBSConfig{}
+	config.Namespace = metav1.NamespaceDefault
+	config.Name = v1alpha1.JBSConfigName
+	config.Spec.ImageCheck = settings
+	return &config
+}
+
+// checkedAgo marks the image as found by a check that ran the given time ago
+func checkedAgo(ra *v1alpha1.RebuiltArtifact, ago time.Duration) *v1alpha1.RebuiltArtifact {
+	lastCheck := metav1.NewTime(time.Now().Add(-ago))
+	ra.Status.LastImageCheck = &lastCheck
+	ra.Status.Conditions = []metav1.Condition{{Type: v1alpha1.RebuiltArtifactConditionImageAvailable, Status: metav1.ConditionTrue, Reason: v1alpha1.RebuiltArtifactReasonImageFound}}
+	return ra
+}
+
+func reconcileAndGet(g *WithT, client runtimeclient.Client, reconciler *ReconcileRebuiltArtifact) (reconcile.Result, *v1alpha1.RebuiltArtifact) {
+	ctx := context.TODO()
+	key := types.NamespacedName{Namespace: metav1.NamespaceDefault, Name: artifactbuild.CreateABRName(gav)}
+	result, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+	g.Expect(err).ShouldNot(HaveOccurred())
+	ra := v1alpha1.RebuiltArtifact{}
+	g.Expect(client.Get(ctx, key, &ra)).Should(Succeed())
+	return result, &ra
+}
+
+func expectCondition(g *WithT, ra *v1alpha1.RebuiltArtifact, conditionType string, status metav1.ConditionStatus, reason string) {
+	condition := meta.FindStatusCondition(ra.Status.Conditions, conditionType)
+	g.Expect(condition).ShouldNot(BeNil())
+	g.Expect(condition.Status).Should(Equal(status))
+	g.Expect(condition.Reason).Should(Equal(reason))
+}
+
+func TestReconcileRebuiltArtifact(t *testing.T) {
+	t.Run("Test a verified artifact with an available image is ready", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		checker := &fakeImageChecker{images: map[string]bool{"quay.io/test/test@sha256:1234": true}}
+		client, reconciler := setupClientAndReconciler(checker, rebuiltArtifact("db1"), dependencyBuild(metav1.NamespaceDefault, "db1", metav1.ConditionTrue), abr("b"), abr("a"))
+		result, ra := reconcileAndGet(g, client, reconciler)
+		g.Expect(result.RequeueAfter).Should(BeNumerically("~", defaultImageCheckInterval, time.Minute))
+		expectCondition(g, ra, v1alpha1.RebuiltArtifactConditionImageAvailable, metav1.ConditionTrue, v1alpha1.RebuiltArtifactReasonImageFound)
+		expectCondition(g, ra, v1alpha1.RebuiltArtifactConditionVerified, metav1.ConditionTrue, v1alpha1.RebuiltArtifactReasonVerificationPassed)
+		expectCondition(g, ra, v1alpha1.RebuiltArtifactConditionReady, metav1.ConditionTrue, v1alpha1.RebuiltArtifactReasonReady)
+		g.Expect(ra.Status.DependencyBuild).Should(Equal("default/db1"))
+		g.Expect(ra.Status.SCMURL).Should(Equal("https://github.com/test/test.git"))
+		g.Expect(ra.Status.CommitHash).Should(Equal("abcd"))
+		g.Expect(ra.Status.ArtifactBuilds).Should(Equal([]string{"a", "b"}))
+
+		//the image is not checked again until the interval has passed
+		result, ra = reconcileAndGet(g, client, reconciler)
+		g.Expect(checker.checked).Should(HaveLen(1))
+		g.Expect(ra.Status.LastImageCheck).ShouldNot(BeNil())
+		g.Expect(result.RequeueAfter).Should(BeNumerically("~", defaultImageCheckInterval, time.Minute))
+	})
+	t.Run("Test a missing image is reported", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		client, reconciler := setupClientAndReconciler(&fakeImageChecker{}, rebuiltArtifact("db1"), dependencyBuild(metav1.NamespaceDefault, "db1", metav1.ConditionTrue))
+		_, ra := reconcileAndGet(g, client, reconciler)
+		expectCondition(g, ra, v1alpha1.RebuiltArtifactConditionImageAvailable, metav1.ConditionFalse, v1alpha1.RebuiltArtifactReasonImageMissing)
+		expectCondition(g, ra, v1alpha1.RebuiltArtifactConditionReady, metav1.ConditionFalse, v1alpha1.RebuiltArtifactReasonImageMissing)
+	})
+	t.Run("Test a failed verification is reported", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		checker := &fakeImageChecker{images: map[string]bool{"quay.io/test/test@sha256:1234": true}}
+		client, reconciler := setupClientAndReconciler(checker, rebuiltArtifact("db1"), dependencyBuild(metav1.NamespaceDefault, "db1", metav1.ConditionFalse))
+		_, ra := reconcileAndGet(g, client, reconciler)
+		expectCondition(g, ra, v1alpha1.RebuiltArtifactConditionVerified, metav1.ConditionFalse, v1alpha1.RebuiltArtifactReasonVerificationFailed)
+		expectCondition(g, ra, v1alpha1.RebuiltArtifactConditionReady, metav1.ConditionFalse, v1alpha1.RebuiltArtifactReasonVerificationFailed)
+	})
+	t.Run("Test the recorded build is kept when the DependencyBuild is deleted", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		checker := &fakeImageChecker{images: map[string]bool{"quay.io/test/test@sha256:1234": true}}
+		db := dependencyBuild(metav1.NamespaceDefault, "db1", metav1.ConditionTrue)
+		client, reconciler := setupClientAndReconciler(checker, rebuiltArtifact("db1"), db)
+		_, _ = reconcileAndGet(g, client, reconciler)
+		g.Expect(client.Delete(context.TODO(), db)).Should(Succeed())
+		_, ra := reconcileAndGet(g, client, reconciler)
+		g.Expect(ra.Status.DependencyBuild).Should(Equal("default/db1"))
+		expectCondition(g, ra, v1alpha1.RebuiltArtifactConditionVerified, metav1.ConditionTrue, v1alpha1.RebuiltArtifactReasonVerificationPassed)
+		expectCondition(g, ra, v1alpha1.RebuiltArtifactConditionReady, metav1.ConditionTrue, v1alpha1.RebuiltArtifactReasonReady)
+	})
+	t.Run("Test a shared artifact reports the build in the trusted namespace", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		checker := &fakeImageChecker{images: map[string]bool{"quay.io/test/test@sha256:1234": true}}
+		ra := rebuiltArtifact("")
+		ra.Annotations = map[string]string{artifactbuild.SharedFromAnnotation: "trusted/db1"}
+		client, reconciler := setupClientAndReconciler(checker, ra, dependencyBuild("trusted", "db1", metav1.ConditionTrue))
+		_, ra = reconcileAndGet(g, client, reconciler)
+		g.Expect(ra.Status.DependencyBuild).Should(Equal("trusted/db1"))
+		expectCondition(g, ra, v1alpha1.RebuiltArtifactConditionReady, metav1.ConditionTrue, v1alpha1.RebuiltArtifactReasonReady)
+	})
+	t.Run("Test the image is checked again if the registry could not be reached", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		checker := &fakeImageChecker{err: fmt.Errorf("connection refused")}
+		client, reconciler := setupClientAndReconciler(checker, rebuiltArtifact("db1"), dependencyBuild(metav1.NamespaceDefault, "db1", metav1.ConditionTrue))
+		result, ra := reconcileAndGet(g, client, reconciler)
+		g.Expect(result.RequeueAfter).Should(Equal(imageCheckRetryInterval))
+		expectCondition(g, ra, v1alpha1.RebuiltArtifactConditionImageAvailable, metav1.ConditionUnknown, v1alpha1.RebuiltArtifactReasonImageCheckFailed)
+		expectCondition(g, ra, v1alpha1.RebuiltArtifactConditionReady, metav1.ConditionUnknown, v1alpha1.RebuiltArtifactReasonImageCheckFailed)
+
+		checker.err = nil
+		checker.images = map[string]bool{"quay.io/test/test@sha256:1234": true}
+		result, ra = reconcileAndGet(g, client, reconciler)
+		g.Expect(result.RequeueAfter).Should(BeNumerically("~", defaultImageCheckInterval, time.Minute))
+		expectCondition(g, ra, v1alpha1.RebuiltArtifactConditionReady, metav1.ConditionTrue, v1alpha1.RebuiltArtifactReasonReady)
+	})
+}
+
+func TestPeriodicImageCheck(t *testing.T) {
+	found := map[string]bool{"quay.io/test/test@sha256:1234": true}
+	t.Run("Test the image is checked again once the interval has passed", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		checker := &fakeImageChecker{}
+		client, reconciler := setupClientAndReconciler(checker, checkedAgo(rebuiltArtifact("db1"), 2*time.Hour), dependencyBuild(metav1.NamespaceDefault, "db1", metav1.ConditionTrue), jbsConfig(v1alpha1.ImageCheckSettings{Interval: &metav1.Duration{Duration: time.Hour}}))
+		result, ra := reconcileAndGet(g, client, reconciler)
+		g.Expect(checker.checked).Should(Equal([]string{"quay.io/test/test@sha256:1234"}))
+		g.Expect(result.RequeueAfter).Should(BeNumerically("~", time.Hour, time.Minute))
+		expectCondition(g, ra, v1alpha1.RebuiltArtifactConditionImageAvailable, metav1.ConditionFalse, v1alpha1.RebuiltArtifactReasonImageMissing)
+		expectCondition(g, ra, v1alpha1.RebuiltArtifactConditionReady, metav1.ConditionFalse, v1alpha1.RebuiltArtifactReasonImageMissing)
+	})
+	t.Run("Test the image is not checked before the interval has passed", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		checker := &fakeImageChecker{}
+		client, reconciler := setupClientAndReconciler(checker, checkedAgo(rebuiltArtifact("db1"), time.Hour), dependencyBuild(metav1.NamespaceDefault, "db1", metav1.ConditionTrue))
+		result, ra := reconcileAndGet(g, client, reconciler)
+		g.Expect(checker.checked).Should(BeEmpty())
+		g.Expect(result.RequeueAfter).Should(BeNumerically("~", defaultImageCheckInterval-time.Hour, time.Minute))
+		expectCondition(g, ra, v1alpha1.RebuiltArtifactConditionReady, metav1.ConditionTrue, v1alpha1.RebuiltArtifactReasonReady)
+	})
+	t.Run("Test periodic checks can be disabled", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		checker := &fakeImageChecker{images: found}
+		client, reconciler := setupClientAndReconciler(checker, checkedAgo(rebuiltArtifact("db1"), 48*time.Hour), dependencyBuild(metav1.NamespaceDefault, "db1", metav1.ConditionTrue), jbsConfig(v1alpha1.ImageCheckSettings{Interval: &metav1.Duration{}}))
+		result, _ := reconcileAndGet(g, client, reconciler)
+		g.Expect(checker.checked).Should(BeEmpty())
+		g.Expect(result.RequeueAfter).Should(BeZero())
+	})
+	t.Run("Test the ArtifactBuilds are not rebuilt by default", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		client, reconciler := setupClientAndReconciler(&fakeImageChecker{}, rebuiltArtifact("db1"), dependencyBuild(metav1.NamespaceDefault, "db1", metav1.ConditionTrue), abr("a"))
+		_, ra := reconcileAndGet(g, client, reconciler)
+		expectCondition(g, ra, v1alpha1.RebuiltArtifactConditionImageAvailable, metav1.ConditionFalse, v1alpha1.RebuiltArtifactReasonImageMissing)
+		ab := v1alpha1.ArtifactBuild{}
+		g.Expect(client.Get(context.TODO(), types.NamespacedName{Namespace: metav1.NamespaceDefault, Name: "a"}, &ab)).Should(Succeed())
+		g.Expect(ab.Annotations).ShouldNot(HaveKey(artifactbuild.RebuildAnnotation))
+	})
+	t.Run("Test a rebuild of a missing image is requested once", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		ctx := context.TODO()
+		building := abr("building")
+		building.Status.State = v1alpha1.ArtifactBuildStateBuilding
+		client, reconciler := setupClientAndReconciler(&fakeImageChecker{}, checkedAgo(rebuiltArtifact("db1"), 48*time.Hour), dependencyBuild(metav1.NamespaceDefault, "db1", metav1.ConditionTrue), abr("a"), building, jbsConfig(v1alpha1.ImageCheckSettings{RebuildMissing: true}))
+		_, ra := reconcileAndGet(g, client, reconciler)
+		expectCondition(g, ra, v1alpha1.RebuiltArtifactConditionImageAvailable, metav1.ConditionFalse, v1alpha1.RebuiltArtifactReasonRebuildRequested)
+		expectCondition(g, ra, v1alpha1.RebuiltArtifactConditionReady, metav1.ConditionFalse, v1alpha1.RebuiltArtifactReasonRebuildRequested)
+		ab := v1alpha1.ArtifactBuild{}
+		g.Expect(client.Get(ctx, types.NamespacedName{Namespace: metav1.NamespaceDefault, Name: "a"}, &ab)).Should(Succeed())
+		g.Expect(ab.Annotations[artifactbuild.RebuildAnnotation]).Should(Equal("true"))
+		g.Expect(client.Get(ctx, types.NamespacedName{Namespace: metav1.NamespaceDefault, Name: "building"}, &ab)).Should(Succeed())
+		g.Expect(ab.Annotations).ShouldNot(HaveKey(artifactbuild.RebuildAnnotation))
+
+		//the rebuild has started, the next check of the still missing image does not request another one
+		g.Expect(client.Get(ctx, types.NamespacedName{Namespace: metav1.NamespaceDefault, Name: "a"}, &ab)).Should(Succeed())
+		delete(ab.Annotations, artifactbuild.RebuildAnnotation)
+		g.Expect(client.Update(ctx, &ab)).Should(Succeed())
+		lastCheck := metav1.NewTime(time.Now().Add(-48 * time.Hour))
+		ra.Status.LastImageCheck = &lastCheck
+		g.Expect(client.Status().Update(ctx, ra)).Should(Succeed())
+		_, ra = reconcileAndGet(g, client, reconciler)
+		g.Expect(ra.Status.LastImageCheck.Time).Should(BeTemporally(">", lastCheck.Time))
+		expectCondition(g, ra, v1alpha1.RebuiltArtifactConditionImageAvailable, metav1.ConditionFalse, v1alpha1.RebuiltArtifactReasonRebuildRequested)
+		g.Expect(client.Get(ctx, types.NamespacedName{Namespace: metav1.NamespaceDefault, Name: "a"}, &ab)).Should(Succeed())
+		g.Expect(ab.Annotations).ShouldNot(HaveKey(artifactbuild.RebuildAnnotation))
+	})
+}
+
+func TestSharedRebuiltArtifact(t *testing.T) {
+	const trusted = "trusted"
+	shared := func() *v1alpha1.RebuiltArtifact {
+		ra := rebuiltArtifact("")
+		ra.Annotations = map[string]string{artifactbuild.SharedFromAnnotation: trusted + "/db1"}
+		return ra
+	}
+	producing := func(image string, digest string) *v1alpha1.RebuiltArtifact {
+		ra := rebuiltArtifact("db1")
+		ra.Namespace = trusted
+		ra.Spec.Image = image
+		ra.Spec.Digest = digest
+		return ra
+	}
+	imageSecret := func(namespace string) *corev1.Secret {
+		secret := corev1.Secret{}
+		secret.Namespace = namespace
+		secret.Name = v1alpha1.ImageSecretName
+		secret.Data = map[string][]byte{v1alpha1.ImageSecretTokenKey: []byte(namespace + "-token")}
+		return &secret
+	}
+	t.Run("Test the image is checked with the credentials of the trusted namespace", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		checker := &fakeImageChecker{images: map[string]bool{"quay.io/test/test@sha256:1234": true}}
+		client, reconciler := setupClientAndReconciler(checker, shared(), producing(image, "sha256:1234"), dependencyBuild(trusted, "db1", metav1.ConditionTrue), imageSecret(trusted), imageSecret(metav1.NamespaceDefault))
+		_, ra := reconcileAndGet(g, client, reconciler)
+		g.Expect(checker.credentials).Should(Equal([]string{trusted + "-token"}))
+		expectCondition(g, ra, v1alpha1.RebuiltArtifactConditionReady, metav1.ConditionTrue, v1alpha1.RebuiltArtifactReasonReady)
+	})
+	t.Run("Test a missing image is rebuilt in the trusted namespace", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		ctx := context.TODO()
+		trustedBuild := abr("a")
+		trustedBuild.Namespace = trusted
+		client, reconciler := setupClientAndReconciler(&fakeImageChecker{}, shared(), producing(image, "sha256:1234"), dependencyBuild(trusted, "db1", metav1.ConditionTrue), abr("a"), trustedBuild, jbsConfig(v1alpha1.ImageCheckSettings{RebuildMissing: true}))
+		_, ra := reconcileAndGet(g, client, reconciler)
+		expectCondition(g, ra, v1alpha1.RebuiltArtifactConditionImageAvailable, metav1.ConditionFalse, v1alpha1.RebuiltArtifactReasonRebuildRequested)
+		ab := v1alpha1.ArtifactBuild{}
+		g.Expect(client.Get(ctx, types.NamespacedName{Namespace: trusted, Name: "a"}, &ab)).Should(Succeed())
+		g.Expect(ab.Annotations[artifactbuild.RebuildAnnotation]).Should(Equal("true"))
+		g.Expect(client.Get(ctx, types.NamespacedName{Namespace: metav1.NamespaceDefault, Name: "a"}, &ab)).Should(Succeed())
+		g.Expect(ab.Annotations).ShouldNot(HaveKey(artifactbuild.RebuildAnnotation))
+	})
+	t.Run("Test a shared artifact follows a rebuild in the trusted namespace", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		checker := &fakeImageChecker{images: map[string]bool{"quay.io/test/test@sha256:5678": true}}
+		missing := shared()
+		missing.Status.Conditions = []metav1.Condition{{Type: v1alpha1.RebuiltArtifactConditionImageAvailable, Status: metav1.ConditionFalse, Reason: v1alpha1.RebuiltArtifactReasonRebuildRequested}}
+		client, reconciler := setupClientAndReconciler(checker, missing, producing(image, "sha256:5678"), dependencyBuild(trusted, "db1", metav1.ConditionTrue))
+		_, ra := reconcileAndGet(g, client, reconciler)
+		g.Expect(ra.Spec.Digest).Should(Equal("sha256:5678"))
+		g.Expect(checker.checked).Should(Equal([]string{"quay.io/test/test@sha256:5678"}))
+		expectCondition(g, ra, v1alpha1.RebuiltArtifactConditionReady, metav1.ConditionTrue, v1alpha1.RebuiltArtifactReasonReady)
+	})
+}
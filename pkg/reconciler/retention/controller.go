@@ -0,0 +1,12 @@
+package retention
+
+import (
+	"github.com/redhat-appstudio/jvm-build-service/pkg/apis/jvmbuildservice/v1alpha1"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+func SetupNewReconcilerWithManager(mgr ctrl.Manager) error {
+	r := newReconciler(mgr)
+	return ctrl.NewControllerManagedBy(mgr).Named("retention").For(&v1alpha1.JBSConfig{}).
+		Complete(r)
+}
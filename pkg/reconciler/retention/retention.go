@@ -0,0 +1,273 @@
+package retention
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/redhat-appstudio/jvm-build-service/pkg/apis/jvmbuildservice/v1alpha1"
+	"github.com/redhat-appstudio/jvm-build-service/pkg/reconciler/artifactbuild"
+	pipelinev1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	contextTimeout = 300 * time.Second
+	// gcInterval how often the finished builds in a namespace are checked, the TTLs are enforced to within this
+	gcInterval = 10 * time.Minute
+)
+
+type ReconcileRetention struct {
+	client        client.Client
+	scheme        *runtime.Scheme
+	eventRecorder record.EventRecorder
+}
+
+func newReconciler(mgr ctrl.Manager) reconcile.Reconciler {
+	return &ReconcileRetention{
+		client:        mgr.GetClient(),
+		scheme:        mgr.GetScheme(),
+		eventRecorder: mgr.GetEventRecorderFor("Retention"),
+	}
+}
+
+func (r *ReconcileRetention) Reconcile(ctx context.Context, request reconcile.Request) (reconcile.Result, error) {
+	var cancel context.CancelFunc
+	ctx, cancel = context.WithTimeout(ctx, contextTimeout)
+	defer cancel()
+	log := ctrl.Log.WithName("retention").WithValues("namespace", request.NamespacedName.Namespace, "resource", request.Name, "kind", "JBSConfig")
+
+	jbsConfig := v1alpha1.JBSConfig{}
+	err := r.client.Get(ctx, request.NamespacedName, &jbsConfig)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, err
+	}
+	if jbsConfig.DeletionTimestamp != nil {
+		return reconcile.Result{}, nil
+	}
+	retention := jbsConfig.Spec.Retention
+	if ttl(retention.CompletedArtifactBuildTTL) == 0 && ttl(retention.FailedArtifactBuildTTL) == 0 && retention.PipelineRunsPerDependencyBuild <= 0 {
+		//nothing to clean up, a change to the config will reconcile again
+		return reconcile.Result{}, nil
+	}
+	now := time.Now()
+	if err := r.deleteExpiredArtifactBuilds(ctx, log, &jbsConfig, now); err != nil {
+		return reconcile.Result{}, err
+	}
+	if err := r.pruneDependencyBuildPipelineRuns(ctx, log, &jbsConfig); err != nil {
+		return reconcile.Result{}, err
+	}
+	return reconcile.Result{RequeueAfter: gcInterval}, nil
+}
+
+func ttl(duration *metav1.Duration) time.Duration {
+	if duration == nil || duration.Duration < 0 {
+		return 0
+	}
+	return duration.Duration
+}
+
+// deleteExpiredArtifactBuilds deletes the finished ArtifactBuilds that are older than their TTL. Deleting the last
+// ArtifactBuild that owns a DependencyBuild also deletes the DependencyBuild and its PipelineRuns, so the
+// RebuiltArtifacts are detached from the DependencyBuild first unless they should be deleted as well.
+func (r *ReconcileRetention) deleteExpiredArtifactBuilds(ctx context.Context, log logr.Logger, jbsConfig *v1alpha1.JBSConfig, now time.Time) error {
+	retention := jbsConfig.Spec.Retention
+	if ttl(retention.CompletedArtifactBuildTTL) == 0 && ttl(retention.FailedArtifactBuildTTL) == 0 {
+		return nil
+	}
+	abrList := v1alpha1.ArtifactBuildList{}
+	if err := r.client.List(ctx, &abrList, client.InNamespace(jbsConfig.Namespace)); err != nil {
+		return err
+	}
+	dbList := v1alpha1.DependencyBuildList{}
+	if err := r.client.List(ctx, &dbList, client.InNamespace(jbsConfig.Namespace)); err != nil {
+		return err
+	}
+	dbsByName := map[string]*v1alpha1.DependencyBuild{}
+	dbsByOwner := map[types.UID][]*v1alpha1.DependencyBuild{}
+	for i := range dbList.Items {
+		db := &dbList.Items[i]
+		dbsByName[db.Name] = db
+		for _, ref := range db.OwnerReferences {
+			dbsByOwner[ref.UID] = append(dbsByOwner[ref.UID], db)
+		}
+	}
+
+	expired := map[types.UID]*v1alpha1.ArtifactBuild{}
+	for i := range abrList.Items {
+		abr := &abrList.Items[i]
+		//each JBSConfig only cleans up the builds that reference it
+		if v1alpha1.JBSConfigNameOrDefault(abr.Spec.JBSConfig) != jbsConfig.Name {
+			continue
+		}
+		if abr.DeletionTimestamp != nil || !artifactBuildExpired(abr, &retention, now) {
+			continue
+		}
+		if artifactBuildInUse(abr, dbsByName, dbsByOwner[abr.UID]) {
+			continue
+		}
+		expired[abr.UID] = abr
+	}
+	if len(expired) == 0 {
+		return nil
+	}
+
+	if !retention.DeleteRebuiltArtifacts {
+		for i := range dbList.Items {
+			db := &dbList.Items[i]
+			if len(db.OwnerReferences) == 0 {
+				continue
+			}
+			orphaned := true
+			for _, ref := range db.OwnerReferences {
+				if expired[ref.UID] == nil {
+					orphaned = false
+					break
+				}
+			}
+			if orphaned {
+				if err := r.detachRebuiltArtifacts(ctx, log, db); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	for _, abr := range expired {
+		log.Info("deleting expired ArtifactBuild", "artifactbuild", abr.Name, "state", abr.Status.State)
+		if err := r.client.Delete(ctx, abr); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+		r.eventRecorder.Eventf(abr, corev1.EventTypeNormal, "ArtifactBuildExpired", "The ArtifactBuild %s/%s in state %s was deleted as it is older than its retention TTL", abr.Namespace, abr.Name, abr.Status.State)
+	}
+	return nil
+}
+
+func artifactBuildExpired(abr *v1alpha1.ArtifactBuild, retention *v1alpha1.RetentionSettings, now time.Time) bool {
+	var timeout time.Duration
+	switch abr.Status.State {
+	case v1alpha1.ArtifactBuildStateComplete:
+		timeout = ttl(retention.CompletedArtifactBuildTTL)
+	case v1alpha1.ArtifactBuildStateFailed, v1alpha1.ArtifactBuildStateMissing:
+		timeout = ttl(retention.FailedArtifactBuildTTL)
+	}
+	if timeout == 0 {
+		return false
+	}
+	return finishedTime(abr).Add(timeout).Before(now)
+}
+
+// finishedTime the time the ArtifactBuild reached its current state, the conditions are updated on every state change
+func finishedTime(abr *v1alpha1.ArtifactBuild) time.Time {
+	finished := abr.CreationTimestamp.Time
+	for _, condition := range abr.Status.Conditions {
+		if condition.LastTransitionTime.After(finished) {
+			finished = condition.LastTransitionTime.Time
+		}
+	}
+	return finished
+}
+
+// artifactBuildInUse an ArtifactBuild is kept while another build may still depend on it. This is the case if it
+// is needed to resolve a contaminated DependencyBuild, or its own DependencyBuild has not finished yet, which includes
+// DependencyBuilds that are waiting for their contamination to be resolved so the ArtifactBuild can complete.
+func artifactBuildInUse(abr *v1alpha1.ArtifactBuild, dbsByName map[string]*v1alpha1.DependencyBuild, owned []*v1alpha1.DependencyBuild) bool {
+	for key, value := range abr.Annotations {
+		if strings.HasPrefix(key, artifactbuild.DependencyBuildContaminatedByAnnotation) {
+			db := dbsByName[value]
+			if db != nil && db.Status.State == v1alpha1.DependencyBuildStateContaminated {
+				return true
+			}
+		}
+	}
+	for _, db := range owned {
+		if db.Status.State != v1alpha1.DependencyBuildStateComplete && db.Status.State != v1alpha1.DependencyBuildStateFailed {
+			return true
+		}
+	}
+	return false
+}
+
+// detachRebuiltArtifacts removes the DependencyBuild owner reference from the RebuiltArtifacts it produced, so they
+// are not garbage collected with it
+func (r *ReconcileRetention) detachRebuiltArtifacts(ctx context.Context, log logr.Logger, db *v1alpha1.DependencyBuild) error {
+	raList := v1alpha1.RebuiltArtifactList{}
+	if err := r.client.List(ctx, &raList, client.InNamespace(db.Namespace)); err != nil {
+		return err
+	}
+	for i := range raList.Items {
+		ra := &raList.Items[i]
+		refs := []metav1.OwnerReference{}
+		for _, ref := range ra.OwnerReferences {
+			if ref.UID != db.UID {
+				refs = append(refs, ref)
+			}
+		}
+		if len(refs) == len(ra.OwnerReferences) {
+			continue
+		}
+		ra.OwnerReferences = refs
+		log.Info("keeping RebuiltArtifact after its DependencyBuild is deleted", "rebuiltartifact", ra.Name, "dependencybuild", db.Name)
+		if err := r.client.Update(ctx, ra); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pruneDependencyBuildPipelineRuns deletes the oldest finished PipelineRuns of each DependencyBuild, so only the
+// configured number is kept. PipelineRuns that still have the finalizer have not been processed yet and are kept.
+func (r *ReconcileRetention) pruneDependencyBuildPipelineRuns(ctx context.Context, log logr.Logger, jbsConfig *v1alpha1.JBSConfig) error {
+	keep := jbsConfig.Spec.Retention.PipelineRunsPerDependencyBuild
+	if keep <= 0 {
+		return nil
+	}
+	prList := pipelinev1beta1.PipelineRunList{}
+	if err := r.client.List(ctx, &prList, client.InNamespace(jbsConfig.Namespace), client.HasLabels{artifactbuild.PipelineRunLabel}); err != nil {
+		return err
+	}
+	byDependencyBuild := map[types.UID][]*pipelinev1beta1.PipelineRun{}
+	for i := range prList.Items {
+		pr := &prList.Items[i]
+		if v1alpha1.JBSConfigNameOrDefault(pr.Labels[artifactbuild.JBSConfigLabel]) != jbsConfig.Name {
+			continue
+		}
+		if pr.DeletionTimestamp != nil || pr.Status.CompletionTime == nil || controllerutil.ContainsFinalizer(pr, artifactbuild.PipelineRunFinalizer) {
+			continue
+		}
+		for _, ref := range pr.OwnerReferences {
+			if ref.Kind == "DependencyBuild" {
+				byDependencyBuild[ref.UID] = append(byDependencyBuild[ref.UID], pr)
+			}
+		}
+	}
+	for _, prs := range byDependencyBuild {
+		if len(prs) <= keep {
+			continue
+		}
+		sort.Slice(prs, func(i, j int) bool {
+			return prs[i].Status.CompletionTime.After(prs[j].Status.CompletionTime.Time)
+		})
+		for _, pr := range prs[keep:] {
+			log.Info("deleting old PipelineRun", "pipelinerun", pr.Name)
+			if err := r.client.Delete(ctx, pr); err != nil && !errors.IsNotFound(err) {
+				return err
+			}
+		}
+	}
+	return nil
+}
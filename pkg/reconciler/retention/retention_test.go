@@ -0,0 +1,187 @@
+package retention
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/redhat-appstudio/jvm-build-service/pkg/apis/jvmbuildservice/v1alpha1"
+	"github.com/redhat-appstudio/jvm-build-service/pkg/reconciler/artifactbuild"
+	pipelinev1beta1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func setupClientAndReconciler(objs ...runtimeclient.Object) (runtimeclient.Client, *ReconcileRetention) {
+	scheme := runtime.NewScheme()
+	_ = v1alpha1.AddToScheme(scheme)
+	_ = pipelinev1beta1.AddToScheme(scheme)
+	client := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
+	reconciler := &ReconcileRetention{
+		client:        client,
+		scheme:        scheme,
+		eventRecorder: &record.FakeRecorder{},
+	}
+	return client, reconciler
+}
+
+func jbsConfig(retention v1alpha1.RetentionSettings) *v1alpha1.JBSConfig {
+	config := v1alpha1.JBSConfig{}
+	config.Namespace = metav1.NamespaceDefault
+	config.Name = v1alpha1.JBSConfigName
+	config.Spec.Retention = retention
+	return &config
+}
+
+func abr(name string, state string, finished time.Time) *v1alpha1.ArtifactBuild {
+	ab := v1alpha1.ArtifactBuild{}
+	ab.Namespace = metav1.NamespaceDefault
+	ab.Name = name
+	ab.UID = types.UID(name)
+	ab.Status.State = state
+	ab.Status.Conditions = []metav1.Condition{{Type: v1alpha1.ArtifactBuildConditionDiscovered, Status: metav1.ConditionTrue, LastTransitionTime: metav1.NewTime(finished)}}
+	return &ab
+}
+
+func db(name string, state string, owners ...*v1alpha1.ArtifactBuild) *v1alpha1.DependencyBuild {
+	d := v1alpha1.DependencyBuild{}
+	d.Namespace = metav1.NamespaceDefault
+	d.Name = name
+	d.UID = types.UID(name)
+	d.Status.State = state
+	for _, owner := range owners {
+		d.OwnerReferences = append(d.OwnerReferences, metav1.OwnerReference{APIVersion: v1alpha1.SchemeGroupVersion.String(), Kind: "ArtifactBuild", Name: owner.Name, UID: owner.UID})
+	}
+	return &d
+}
+
+func ownedBy(d *v1alpha1.DependencyBuild) []metav1.OwnerReference {
+	return []metav1.OwnerReference{{APIVersion: v1alpha1.SchemeGroupVersion.String(), Kind: "DependencyBuild", Name: d.Name, UID: d.UID}}
+}
+
+func pipelineRun(name string, owner *v1alpha1.DependencyBuild, completed time.Time) *pipelinev1beta1.PipelineRun {
+	pr := pipelinev1beta1.PipelineRun{}
+	pr.Namespace = metav1.NamespaceDefault
+	pr.Name = name
+	pr.Labels = map[string]string{artifactbuild.PipelineRunLabel: ""}
+	pr.OwnerReferences = ownedBy(owner)
+	completionTime := metav1.NewTime(completed)
+	pr.Status.CompletionTime = &completionTime
+	return &pr
+}
+
+func reconcileConfig(g *WithT, reconciler *ReconcileRetention) reconcile.Result {
+	result, err := reconciler.Reconcile(context.TODO(), reconcile.Request{NamespacedName: types.NamespacedName{Namespace: metav1.NamespaceDefault, Name: v1alpha1.JBSConfigName}})
+	g.Expect(err).NotTo(HaveOccurred())
+	return result
+}
+
+func exists(g *WithT, client runtimeclient.Client, name string, obj runtimeclient.Object) bool {
+	err := client.Get(context.TODO(), types.NamespacedName{Namespace: metav1.NamespaceDefault, Name: name}, obj)
+	if errors.IsNotFound(err) {
+		return false
+	}
+	g.Expect(err).NotTo(HaveOccurred())
+	return true
+}
+
+func TestArtifactBuildRetention(t *testing.T) {
+	old := time.Now().Add(-48 * time.Hour)
+	recent := time.Now().Add(-time.Minute)
+	retention := v1alpha1.RetentionSettings{
+		CompletedArtifactBuildTTL: &metav1.Duration{Duration: 24 * time.Hour},
+		FailedArtifactBuildTTL:    &metav1.Duration{Duration: time.Hour},
+	}
+
+	t.Run("Test nothing is deleted without retention settings", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		client, reconciler := setupClientAndReconciler(jbsConfig(v1alpha1.RetentionSettings{}), abr("complete", v1alpha1.ArtifactBuildStateComplete, old))
+		g.Expect(reconcileConfig(g, reconciler).RequeueAfter).Should(BeZero())
+		g.Expect(exists(g, client, "complete", &v1alpha1.ArtifactBuild{})).Should(BeTrue())
+	})
+	t.Run("Test expired artifact builds are deleted", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		client, reconciler := setupClientAndReconciler(jbsConfig(retention),
+			abr("complete", v1alpha1.ArtifactBuildStateComplete, old),
+			abr("recent", v1alpha1.ArtifactBuildStateComplete, recent),
+			abr("missing", v1alpha1.ArtifactBuildStateMissing, recent.Add(-time.Hour)),
+			abr("building", v1alpha1.ArtifactBuildStateBuilding, old))
+		g.Expect(reconcileConfig(g, reconciler).RequeueAfter).Should(Equal(gcInterval))
+		g.Expect(exists(g, client, "complete", &v1alpha1.ArtifactBuild{})).Should(BeFalse())
+		g.Expect(exists(g, client, "recent", &v1alpha1.ArtifactBuild{})).Should(BeTrue())
+		g.Expect(exists(g, client, "missing", &v1alpha1.ArtifactBuild{})).Should(BeFalse())
+		g.Expect(exists(g, client, "building", &v1alpha1.ArtifactBuild{})).Should(BeTrue())
+	})
+	t.Run("Test artifact builds needed for contamination are kept", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		contaminant := abr("contaminant", v1alpha1.ArtifactBuildStateComplete, old)
+		contaminant.Annotations = map[string]string{artifactbuild.DependencyBuildContaminatedByAnnotation + "1": "contaminated-db"}
+		failed := abr("failed", v1alpha1.ArtifactBuildStateFailed, old)
+		client, reconciler := setupClientAndReconciler(jbsConfig(retention), contaminant, failed,
+			db("contaminated-db", v1alpha1.DependencyBuildStateContaminated, failed))
+		reconcileConfig(g, reconciler)
+		g.Expect(exists(g, client, "contaminant", &v1alpha1.ArtifactBuild{})).Should(BeTrue())
+		g.Expect(exists(g, client, "failed", &v1alpha1.ArtifactBuild{})).Should(BeTrue())
+	})
+	t.Run("Test rebuilt artifacts are detached from the dependency build", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		complete := abr("complete", v1alpha1.ArtifactBuildStateComplete, old)
+		shared := db("shared-db", v1alpha1.DependencyBuildStateComplete, complete, abr("recent", v1alpha1.ArtifactBuildStateComplete, recent))
+		single := db("single-db", v1alpha1.DependencyBuildStateComplete, complete)
+		sharedRa := v1alpha1.RebuiltArtifact{ObjectMeta: metav1.ObjectMeta{Namespace: metav1.NamespaceDefault, Name: "shared-ra", OwnerReferences: ownedBy(shared)}}
+		singleRa := v1alpha1.RebuiltArtifact{ObjectMeta: metav1.ObjectMeta{Namespace: metav1.NamespaceDefault, Name: "single-ra", OwnerReferences: ownedBy(single)}}
+		client, reconciler := setupClientAndReconciler(jbsConfig(retention), complete, shared, single, &sharedRa, &singleRa)
+		reconcileConfig(g, reconciler)
+		g.Expect(exists(g, client, "complete", &v1alpha1.ArtifactBuild{})).Should(BeFalse())
+		ra := v1alpha1.RebuiltArtifact{}
+		g.Expect(exists(g, client, "single-ra", &ra)).Should(BeTrue())
+		g.Expect(ra.OwnerReferences).Should(BeEmpty())
+		g.Expect(exists(g, client, "shared-ra", &ra)).Should(BeTrue())
+		g.Expect(ra.OwnerReferences).Should(HaveLen(1))
+	})
+	t.Run("Test rebuilt artifacts are deleted with the dependency build", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		complete := abr("complete", v1alpha1.ArtifactBuildStateComplete, old)
+		single := db("single-db", v1alpha1.DependencyBuildStateComplete, complete)
+		singleRa := v1alpha1.RebuiltArtifact{ObjectMeta: metav1.ObjectMeta{Namespace: metav1.NamespaceDefault, Name: "single-ra", OwnerReferences: ownedBy(single)}}
+		config := jbsConfig(retention)
+		config.Spec.Retention.DeleteRebuiltArtifacts = true
+		client, reconciler := setupClientAndReconciler(config, complete, single, &singleRa)
+		reconcileConfig(g, reconciler)
+		ra := v1alpha1.RebuiltArtifact{}
+		g.Expect(exists(g, client, "single-ra", &ra)).Should(BeTrue())
+		g.Expect(ra.OwnerReferences).Should(HaveLen(1))
+	})
+}
+
+func TestPipelineRunRetention(t *testing.T) {
+	t.Run("Test the newest pipeline runs are kept", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		now := time.Now()
+		build := db("db", v1alpha1.DependencyBuildStateBuilding)
+		other := db("other", v1alpha1.DependencyBuildStateComplete)
+		unprocessed := pipelineRun("db-build-0", build, now.Add(-3*time.Hour))
+		unprocessed.Finalizers = []string{artifactbuild.PipelineRunFinalizer}
+		running := pipelineRun("db-build-3", build, now)
+		running.Status.CompletionTime = nil
+		client, reconciler := setupClientAndReconciler(jbsConfig(v1alpha1.RetentionSettings{PipelineRunsPerDependencyBuild: 1}), build, other,
+			unprocessed,
+			pipelineRun("db-build-1", build, now.Add(-2*time.Hour)),
+			pipelineRun("db-build-2", build, now.Add(-time.Hour)),
+			running,
+			pipelineRun("other-build-0", other, now.Add(-2*time.Hour)))
+		g.Expect(reconcileConfig(g, reconciler).RequeueAfter).Should(Equal(gcInterval))
+		g.Expect(exists(g, client, "db-build-0", &pipelinev1beta1.PipelineRun{})).Should(BeTrue())
+		g.Expect(exists(g, client, "db-build-1", &pipelinev1beta1.PipelineRun{})).Should(BeFalse())
+		g.Expect(exists(g, client, "db-build-2", &pipelinev1beta1.PipelineRun{})).Should(BeTrue())
+		g.Expect(exists(g, client, "db-build-3", &pipelinev1beta1.PipelineRun{})).Should(BeTrue())
+		g.Expect(exists(g, client, "other-build-0", &pipelinev1beta1.PipelineRun{})).Should(BeTrue())
+	})
+}
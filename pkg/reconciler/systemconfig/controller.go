@@ -2,11 +2,30 @@ package systemconfig
 
 import (
 	"github.com/redhat-appstudio/jvm-build-service/pkg/apis/jvmbuildservice/v1alpha1"
+	"github.com/redhat-appstudio/jvm-build-service/pkg/registry"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 )
 
-func SetupNewReconcilerWithManager(mgr ctrl.Manager) error {
-	r := newReconciler(mgr)
+func SetupNewReconcilerWithManager(mgr ctrl.Manager, provisioner *registry.ProvisionerRef) error {
+	r := newReconciler(mgr, provisioner)
+	//secrets are not cached by the manager, so they are watched in the controller namespace only
+	secretCache, err := cache.New(mgr.GetConfig(), cache.Options{Scheme: mgr.GetScheme(), Mapper: mgr.GetRESTMapper(), Namespace: v1alpha1.ControllerNamespace})
+	if err != nil {
+		return err
+	}
+	if err := mgr.Add(secretCache); err != nil {
+		return err
+	}
 	return ctrl.NewControllerManagedBy(mgr).For(&v1alpha1.SystemConfig{}).
+		Watches(source.NewKindWithCache(&corev1.Secret{}, secretCache), handler.EnqueueRequestsFromMapFunc(func(o client.Object) []reconcile.Request {
+			return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: SystemConfigKey}}}
+		})).
 		Complete(r)
 }
@@ -6,8 +6,16 @@ import (
 	"strings"
 	"time"
 
+	"github.com/go-logr/logr"
 	"github.com/redhat-appstudio/jvm-build-service/pkg/apis/jvmbuildservice/v1alpha1"
+	"github.com/redhat-appstudio/jvm-build-service/pkg/registry"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/record"
 
@@ -24,15 +32,17 @@ type ReconcilerSystemConfig struct {
 	eventRecorder record.EventRecorder
 	config        *rest.Config
 	mgr           ctrl.Manager
+	provisioner   *registry.ProvisionerRef
 }
 
-func newReconciler(mgr ctrl.Manager) reconcile.Reconciler {
+func newReconciler(mgr ctrl.Manager, provisioner *registry.ProvisionerRef) reconcile.Reconciler {
 	return &ReconcilerSystemConfig{
 		client:        mgr.GetClient(),
 		scheme:        mgr.GetScheme(),
 		eventRecorder: mgr.GetEventRecorderFor("ArtifactBuild"),
 		config:        mgr.GetConfig(),
 		mgr:           mgr,
+		provisioner:   provisioner,
 	}
 }
 
@@ -44,9 +54,22 @@ func (r *ReconcilerSystemConfig) Reconcile(ctx context.Context, request reconcil
 	systemConfig := v1alpha1.SystemConfig{}
 	err := r.client.Get(ctx, request.NamespacedName, &systemConfig)
 	if err != nil {
+		if errors.IsNotFound(err) {
+			//the credentials Secret changed before the SystemConfig was created, or it was deleted. Without a
+			//SystemConfig nothing is provisioned, so the reconcilers waiting for the provisioner can carry on.
+			if request.Name == SystemConfigKey && r.provisioner.Update("", func() (registry.RegistryProvisioner, error) {
+				return nil, nil
+			}) {
+				log.Info("registry provisioning disabled, there is no SystemConfig")
+			}
+			return reconcile.Result{}, nil
+		}
 		return reconcile.Result{}, err
 	}
 	if systemConfig.Name == SystemConfigKey {
+		if err := r.updateProvisioner(ctx, log, &systemConfig); err != nil {
+			return reconcile.Result{}, err
+		}
 		foundJDK7 := false
 		foundJDK8 := false
 		foundJDK11 := false
@@ -91,3 +114,55 @@ func (r *ReconcilerSystemConfig) Reconcile(ctx context.Context, request reconcil
 	}
 	return reconcile.Result{}, nil
 }
+
+// updateProvisioner recreates the registry provisioner when the provisioning settings or the Secret with the
+// credentials change, and reports on the SystemConfig if repositories can be provisioned
+func (r *ReconcilerSystemConfig) updateProvisioner(ctx context.Context, log logr.Logger, systemConfig *v1alpha1.SystemConfig) error {
+	settings := systemConfig.Spec.RegistryProvisioning
+	secretName := settings.CredentialsSecret
+	if secretName == "" {
+		secretName = v1alpha1.DefaultRegistryCredentialsSecret
+	}
+	secret := corev1.Secret{}
+	err := r.client.Get(ctx, types.NamespacedName{Namespace: v1alpha1.ControllerNamespace, Name: secretName}, &secret)
+	secretFound := err == nil
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	version := fmt.Sprintf("%+v %t %s", settings, secretFound, secret.ResourceVersion)
+	if r.provisioner.Update(version, func() (registry.RegistryProvisioner, error) {
+		return registry.NewProvisioner(settings, secret.Data)
+	}) {
+		log.Info("registry provisioner updated", "provider", settings.Provider, "secret", secretName, "secretFound", secretFound)
+	}
+
+	provisioner, err := r.provisioner.Get()
+	condition := metav1.Condition{
+		Type:               v1alpha1.SystemConfigConditionRegistryProvisioning,
+		Status:             metav1.ConditionTrue,
+		Reason:             v1alpha1.SystemConfigReasonProvisionerAvailable,
+		ObservedGeneration: systemConfig.Generation,
+	}
+	switch {
+	case err == nil && provisioner != nil:
+		condition.Message = fmt.Sprintf("repositories are provisioned with the credentials in %s", secretName)
+	case !secretFound && settings.Provider != "":
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = v1alpha1.SystemConfigReasonCredentialsNotFound
+		condition.Message = fmt.Sprintf("the %s Secret was not found in the %s namespace", secretName, v1alpha1.ControllerNamespace)
+	case err != nil:
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = v1alpha1.SystemConfigReasonInvalidProvisioning
+		condition.Message = err.Error()
+	default:
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = v1alpha1.SystemConfigReasonProvisioningDisabled
+		condition.Message = "no registry provider is configured, JBSConfigs have to specify an image registry owner"
+	}
+	original := systemConfig.Status.DeepCopy()
+	meta.SetStatusCondition(&systemConfig.Status.Conditions, condition)
+	if equality.Semantic.DeepEqual(original, &systemConfig.Status) {
+		return nil
+	}
+	return r.client.Status().Update(ctx, systemConfig)
+}
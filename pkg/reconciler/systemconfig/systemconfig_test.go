@@ -6,9 +6,11 @@ import (
 
 	. "github.com/onsi/gomega"
 	"github.com/redhat-appstudio/jvm-build-service/pkg/apis/jvmbuildservice/v1alpha1"
+	"github.com/redhat-appstudio/jvm-build-service/pkg/registry"
 	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
 
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
@@ -24,7 +26,7 @@ func setupClientAndReconciler(objs ...runtimeclient.Object) (runtimeclient.Clien
 	_ = v1beta1.AddToScheme(scheme)
 	_ = v1.AddToScheme(scheme)
 	client := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
-	reconciler := &ReconcilerSystemConfig{client: client, scheme: scheme, eventRecorder: &record.FakeRecorder{}}
+	reconciler := &ReconcilerSystemConfig{client: client, scheme: scheme, eventRecorder: &record.FakeRecorder{}, provisioner: &registry.ProvisionerRef{}}
 	return client, reconciler
 }
 
@@ -310,3 +312,86 @@ func TestMissing17SystemConfig(t *testing.T) {
 	g.Expect(err).To(HaveOccurred())
 	g.Expect(result).NotTo(BeNil())
 }
+
+func TestRegistryProvisioning(t *testing.T) {
+	ctx := context.TODO()
+	request := reconcile.Request{NamespacedName: types.NamespacedName{Name: SystemConfigKey}}
+	builders := map[string]v1alpha1.JavaVersionInfo{
+		v1alpha1.JDK7Builder:  {Image: "foo", Tag: "bar"},
+		v1alpha1.JDK8Builder:  {Image: "foo", Tag: "bar"},
+		v1alpha1.JDK11Builder: {Image: "foo", Tag: "bar"},
+		v1alpha1.JDK17Builder: {Image: "foo", Tag: "bar"},
+	}
+	systemConfig := func(settings v1alpha1.RegistryProvisioningSettings) *v1alpha1.SystemConfig {
+		return &v1alpha1.SystemConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: SystemConfigKey},
+			Spec:       v1alpha1.SystemConfigSpec{Builders: builders, RegistryProvisioning: settings},
+		}
+	}
+	secret := func(name string, data map[string][]byte) *v1.Secret {
+		return &v1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: v1alpha1.ControllerNamespace, Name: name}, Data: data}
+	}
+	expectCondition := func(g *WithT, client runtimeclient.Client, reconciler *ReconcilerSystemConfig, status metav1.ConditionStatus, reason string) {
+		_, err := reconciler.Reconcile(ctx, request)
+		g.Expect(err).NotTo(HaveOccurred())
+		sysConfig := v1alpha1.SystemConfig{}
+		g.Expect(client.Get(ctx, request.NamespacedName, &sysConfig)).Should(Succeed())
+		condition := meta.FindStatusCondition(sysConfig.Status.Conditions, v1alpha1.SystemConfigConditionRegistryProvisioning)
+		g.Expect(condition).ShouldNot(BeNil())
+		g.Expect(condition.Status).Should(Equal(status))
+		g.Expect(condition.Reason).Should(Equal(reason))
+	}
+	harbor := v1alpha1.RegistryProvisioningSettings{Provider: v1alpha1.RegistryProviderHarbor, Host: "harbor.local", Owner: "jbs", CredentialsSecret: "harbor"}
+	credentials := map[string][]byte{registry.UsernameKey: []byte("admin"), registry.PasswordKey: []byte("secret")}
+
+	t.Run("Test provisioning is disabled without a provider", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		client, reconciler := setupClientAndReconciler(systemConfig(v1alpha1.RegistryProvisioningSettings{}))
+		expectCondition(g, client, reconciler, metav1.ConditionFalse, v1alpha1.SystemConfigReasonProvisioningDisabled)
+		provisioner, err := reconciler.provisioner.Get()
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(provisioner).Should(BeNil())
+	})
+	t.Run("Test provisioning is disabled without a SystemConfig", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		_, reconciler := setupClientAndReconciler()
+		_, err := reconciler.Reconcile(ctx, request)
+		g.Expect(err).NotTo(HaveOccurred())
+		provisioner, err := reconciler.provisioner.Get()
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(provisioner).Should(BeNil())
+	})
+	t.Run("Test the provisioner is created from the default secret", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		client, reconciler := setupClientAndReconciler(systemConfig(v1alpha1.RegistryProvisioningSettings{}), secret(v1alpha1.DefaultRegistryCredentialsSecret, map[string][]byte{registry.QuayTokenKey: []byte("token"), registry.QuayOrganizationKey: []byte("org")}))
+		expectCondition(g, client, reconciler, metav1.ConditionTrue, v1alpha1.SystemConfigReasonProvisionerAvailable)
+		provisioner, err := reconciler.provisioner.Get()
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(provisioner).ShouldNot(BeNil())
+	})
+	t.Run("Test the provisioner follows the credentials secret", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		client, reconciler := setupClientAndReconciler(systemConfig(harbor))
+		expectCondition(g, client, reconciler, metav1.ConditionFalse, v1alpha1.SystemConfigReasonCredentialsNotFound)
+		_, err := reconciler.provisioner.Get()
+		g.Expect(err).To(HaveOccurred())
+
+		g.Expect(client.Create(ctx, secret("harbor", map[string][]byte{registry.UsernameKey: []byte("admin")}))).Should(Succeed())
+		expectCondition(g, client, reconciler, metav1.ConditionFalse, v1alpha1.SystemConfigReasonInvalidProvisioning)
+
+		updated := v1.Secret{}
+		g.Expect(client.Get(ctx, types.NamespacedName{Namespace: v1alpha1.ControllerNamespace, Name: "harbor"}, &updated)).Should(Succeed())
+		updated.Data = credentials
+		g.Expect(client.Update(ctx, &updated)).Should(Succeed())
+		expectCondition(g, client, reconciler, metav1.ConditionTrue, v1alpha1.SystemConfigReasonProvisionerAvailable)
+		provisioner, err := reconciler.provisioner.Get()
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(provisioner).ShouldNot(BeNil())
+
+		//the provisioner is only recreated when something changes
+		expectCondition(g, client, reconciler, metav1.ConditionTrue, v1alpha1.SystemConfigReasonProvisionerAvailable)
+		unchanged, err := reconciler.provisioner.Get()
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(unchanged).Should(BeIdenticalTo(provisioner))
+	})
+}
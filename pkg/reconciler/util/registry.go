@@ -0,0 +1,28 @@
+package util
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+)
+
+// RegistryAuth finds the credentials for the registry in a .dockerconfigjson, and uses anonymous access if there are none
+func RegistryAuth(dockerConfig []byte, registry string) (authn.Authenticator, error) {
+	if len(dockerConfig) == 0 {
+		return authn.Anonymous, nil
+	}
+	config := struct {
+		Auths map[string]authn.AuthConfig `json:"auths"`
+	}{}
+	if err := json.Unmarshal(dockerConfig, &config); err != nil {
+		return nil, err
+	}
+	for host, auth := range config.Auths {
+		host = strings.TrimPrefix(strings.TrimPrefix(host, "https://"), "http://")
+		if host == registry || strings.HasPrefix(host, registry+"/") {
+			return authn.FromConfig(auth), nil
+		}
+	}
+	return authn.Anonymous, nil
+}
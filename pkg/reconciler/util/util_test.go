@@ -7,6 +7,8 @@ import (
 	"testing"
 
 	"github.com/go-logr/logr"
+	"github.com/google/go-containerregistry/pkg/authn"
+	. "github.com/onsi/gomega"
 	appsv1 "k8s.io/api/apps/v1"
 	v1core "k8s.io/api/core/v1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -109,3 +111,24 @@ func TestGetImageName(t *testing.T) {
 		})
 	}
 }
+
+func TestRegistryAuth(t *testing.T) {
+	t.Run("Test the registry credentials are read from the docker config", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		config := []byte(`{"auths": {"https://quay.io": {"auth": "dXNlcjpwYXNz"}, "registry.example.com/test": {"username": "other", "password": "secret"}}}`)
+		auth, err := RegistryAuth(config, "quay.io")
+		g.Expect(err).Should(Succeed())
+		cfg, err := auth.Authorization()
+		g.Expect(err).Should(Succeed())
+		g.Expect(cfg.Username).Should(Equal("user"))
+		g.Expect(cfg.Password).Should(Equal("pass"))
+		auth, err = RegistryAuth(config, "registry.example.com")
+		g.Expect(err).Should(Succeed())
+		cfg, err = auth.Authorization()
+		g.Expect(err).Should(Succeed())
+		g.Expect(cfg.Username).Should(Equal("other"))
+		auth, err = RegistryAuth(config, "docker.io")
+		g.Expect(err).Should(Succeed())
+		g.Expect(auth).Should(Equal(authn.Anonymous))
+	})
+}
@@ -0,0 +1,263 @@
+package registry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// harborRobotPrefix the prefix Harbor adds to the names of robot accounts. The robot accounts of a project are also
+// prefixed with the project and a +.
+const harborRobotPrefix = "robot$"
+
+// harborProvisioner gives every namespace its own Harbor project, named after the owner and the namespace, and creates
+// robot accounts that can only push to the project of their namespace. Harbor creates the repositories on the first
+// push. The robot accounts are system robot accounts, so the provisioner needs a Harbor administrator.
+type harborProvisioner struct {
+	client   *http.Client
+	apiURL   string
+	host     string
+	project  string
+	insecure bool
+	username string
+	password string
+}
+
+type harborRobot struct {
+	ID          int64                   `json:"id,omitempty"`
+	Name        string                  `json:"name"`
+	Secret      string                  `json:"secret,omitempty"`
+	Description string                  `json:"description,omitempty"`
+	Level       string                  `json:"level,omitempty"`
+	Duration    int                     `json:"duration,omitempty"`
+	Disable     bool                    `json:"disable,omitempty"`
+	Permissions []harborRobotPermission `json:"permissions,omitempty"`
+}
+
+type harborRobotRequest struct {
+	Name        string                  `json:"name"`
+	Description string                  `json:"description"`
+	Level       string                  `json:"level"`
+	Duration    int                     `json:"duration"`
+	Permissions []harborRobotPermission `json:"permissions"`
+}
+
+type harborRobotPermission struct {
+	Kind      string         `json:"kind"`
+	Namespace string         `json:"namespace"`
+	Access    []harborAccess `json:"access"`
+}
+
+type harborAccess struct {
+	Resource string `json:"resource"`
+	Action   string `json:"action"`
+}
+
+type harborErrors struct {
+	Errors []struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+// do sends a request to the Harbor API and decodes the response into result. The status code is returned for
+// the statuses in allowed, any other status that is not successful is an error.
+func (h *harborProvisioner) do(ctx context.Context, method string, path string, body interface{}, result interface{}, allowed ...int) (int, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return 0, err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, h.apiURL+path, reader)
+	if err != nil {
+		return 0, err
+	}
+	req.SetBasicAuth(h.username, h.password)
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	res, err := h.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+	for _, status := range allowed {
+		if res.StatusCode == status {
+			return res.StatusCode, nil
+		}
+	}
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		return res.StatusCode, err
+	}
+	if res.StatusCode >= 300 {
+		errs := harborErrors{}
+		if json.Unmarshal(data, &errs) == nil && len(errs.Errors) > 0 {
+			return res.StatusCode, fmt.Errorf("%s %s failed with status %d: %s", method, path, res.StatusCode, errs.Errors[0].Message)
+		}
+		return res.StatusCode, fmt.Errorf("%s %s failed with status %d", method, path, res.StatusCode)
+	}
+	if result != nil && len(data) > 0 {
+		if err := json.Unmarshal(data, result); err != nil {
+			return res.StatusCode, err
+		}
+	}
+	return res.StatusCode, nil
+}
+
+// CreateRepository makes sure the project of the namespace exists, the repository is created when the first image is
+// pushed
+func (h *harborProvisioner) CreateRepository(ctx context.Context, name string) (*Repository, error) {
+	project, repository := h.projectRepository(name)
+	status, err := h.do(ctx, http.MethodHead, "/projects?project_name="+url.QueryEscape(project), nil, nil, http.StatusNotFound)
+	if err != nil {
+		return nil, err
+	}
+	if status == http.StatusNotFound {
+		request := map[string]interface{}{"project_name": project, "metadata": map[string]string{"public": "true"}}
+		if _, err := h.do(ctx, http.MethodPost, "/projects", request, nil, http.StatusConflict); err != nil {
+			return nil, err
+		}
+	}
+	return &Repository{Host: h.host, Owner: project, Name: repository, Insecure: h.insecure}, nil
+}
+
+// CreateRobot creates a robot account that can push to the project of the repository. Harbor only returns the secret
+// when the account is created, so the secret of an existing account is refreshed.
+func (h *harborProvisioner) CreateRobot(ctx context.Context, repository *Repository, robot string) (*Credential, error) {
+	request := harborRobotRequest{
+		Name:        h.robotName(robot),
+		Description: "JVM Build Service robot account",
+		Level:       "system",
+		Duration:    -1,
+		Permissions: pushPermissions(repository),
+	}
+	created := harborRobot{}
+	status, err := h.do(ctx, http.MethodPost, "/robots", request, &created, http.StatusConflict)
+	if err != nil {
+		return nil, err
+	}
+	if status == http.StatusConflict {
+		return h.RotateCredential(ctx, repository, robot)
+	}
+	//the robot accounts of earlier versions could push to the repositories of every namespace
+	if _, err := h.deleteRobot(ctx, harborRobotPrefix+h.project+"+"+robot); err != nil {
+		return nil, err
+	}
+	return &Credential{Username: created.Name, Password: created.Secret}, nil
+}
+
+// GrantPush limits the robot account to pushing to the project of the repository, an existing account may have been
+// created with other permissions
+func (h *harborProvisioner) GrantPush(ctx context.Context, repository *Repository, robot string) error {
+	existing, err := h.findRobot(ctx, harborRobotPrefix+h.robotName(robot))
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		return fmt.Errorf("robot account %s does not exist", robot)
+	}
+	path := "/robots/" + strconv.FormatInt(existing.ID, 10)
+	current := harborRobot{}
+	if _, err := h.do(ctx, http.MethodGet, path, nil, &current); err != nil {
+		return err
+	}
+	current.Secret = ""
+	current.Permissions = pushPermissions(repository)
+	_, err = h.do(ctx, http.MethodPut, path, current, nil)
+	return err
+}
+
+func (h *harborProvisioner) RotateCredential(ctx context.Context, repository *Repository, robot string) (*Credential, error) {
+	existing, err := h.findRobot(ctx, harborRobotPrefix+h.robotName(robot))
+	if err != nil {
+		return nil, err
+	}
+	if existing == nil {
+		return nil, fmt.Errorf("robot account %s does not exist", robot)
+	}
+	//an empty secret makes Harbor generate a new one
+	refreshed := harborRobot{}
+	if _, err := h.do(ctx, http.MethodPatch, "/robots/"+strconv.FormatInt(existing.ID, 10), map[string]string{"secret": ""}, &refreshed); err != nil {
+		return nil, err
+	}
+	return &Credential{Username: existing.Name, Password: refreshed.Secret}, nil
+}
+
+// DeleteRobot deletes the robot account, and the account an earlier version created in the shared project
+func (h *harborProvisioner) DeleteRobot(ctx context.Context, robot string) (bool, error) {
+	deleted, err := h.deleteRobot(ctx, harborRobotPrefix+h.robotName(robot))
+	if err != nil {
+		return false, err
+	}
+	legacy, err := h.deleteRobot(ctx, harborRobotPrefix+h.project+"+"+robot)
+	return deleted || legacy, err
+}
+
+func (h *harborProvisioner) DeleteRepository(ctx context.Context, name string) (bool, error) {
+	project, repository := h.projectRepository(name)
+	//the slashes in the repository name have to be encoded twice
+	path := "/projects/" + url.PathEscape(project) + "/repositories/" + url.PathEscape(url.PathEscape(repository))
+	status, err := h.do(ctx, http.MethodDelete, path, nil, nil, http.StatusNotFound)
+	return err == nil && status != http.StatusNotFound, err
+}
+
+// projectRepository splits the repository name into the project of its namespace and the repository in that project
+func (h *harborProvisioner) projectRepository(name string) (string, string) {
+	namespace, repository, found := strings.Cut(name, "/")
+	if !found {
+		return h.project + "-" + name, name
+	}
+	return h.project + "-" + namespace, repository
+}
+
+// robotName system robot accounts share one namespace, so they are prefixed with the owner
+func (h *harborProvisioner) robotName(robot string) string {
+	return h.project + "-" + robot
+}
+
+// pushPermissions the permissions of a robot account that can push to the project of the repository
+func pushPermissions(repository *Repository) []harborRobotPermission {
+	return []harborRobotPermission{{
+		Kind:      "project",
+		Namespace: repository.Owner,
+		Access: []harborAccess{
+			{Resource: "repository", Action: "push"},
+			{Resource: "repository", Action: "pull"},
+		},
+	}}
+}
+
+// deleteRobot deletes a robot account, and returns false if it did not exist
+func (h *harborProvisioner) deleteRobot(ctx context.Context, fullName string) (bool, error) {
+	existing, err := h.findRobot(ctx, fullName)
+	if err != nil || existing == nil {
+		return false, err
+	}
+	status, err := h.do(ctx, http.MethodDelete, "/robots/"+strconv.FormatInt(existing.ID, 10), nil, nil, http.StatusNotFound)
+	return err == nil && status != http.StatusNotFound, err
+}
+
+// findRobot looks up a robot account by its full name, or returns nil if it does not exist
+func (h *harborProvisioner) findRobot(ctx context.Context, fullName string) (*harborRobot, error) {
+	robots := []harborRobot{}
+	if _, err := h.do(ctx, http.MethodGet, "/robots?q="+url.QueryEscape("name="+fullName), nil, &robots); err != nil {
+		return nil, err
+	}
+	for i := range robots {
+		if robots[i].Name == fullName {
+			return &robots[i], nil
+		}
+	}
+	return nil, nil
+}
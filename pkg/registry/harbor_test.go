@@ -0,0 +1,229 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+// fakeHarbor the parts of the Harbor API the provisioner uses
+type fakeHarbor struct {
+	projects     map[string]bool
+	robots       map[int64]*harborRobot
+	repositories map[string]bool
+	nextID       int64
+	requests     []string
+}
+
+func (f *fakeHarbor) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.requests = append(f.requests, r.Method+" "+r.URL.EscapedPath())
+	if user, password, ok := r.BasicAuth(); !ok || user != "admin" || password != "secret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	path := strings.TrimPrefix(r.URL.EscapedPath(), "/api/v2.0")
+	switch {
+	case r.Method == http.MethodHead && path == "/projects":
+		if !f.projects[r.URL.Query().Get("project_name")] {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	case r.Method == http.MethodPost && path == "/projects":
+		project := map[string]interface{}{}
+		_ = json.NewDecoder(r.Body).Decode(&project)
+		f.projects[project["project_name"].(string)] = true
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == http.MethodPost && path == "/robots":
+		request := harborRobotRequest{}
+		_ = json.NewDecoder(r.Body).Decode(&request)
+		name := harborRobotPrefix + request.Name
+		if request.Level == "project" {
+			name = harborRobotPrefix + request.Permissions[0].Namespace + "+" + request.Name
+		}
+		for _, robot := range f.robots {
+			if robot.Name == name {
+				w.WriteHeader(http.StatusConflict)
+				_, _ = w.Write([]byte(`{"errors":[{"code":"CONFLICT","message":"robot already exists"}]}`))
+				return
+			}
+		}
+		f.nextID++
+		f.robots[f.nextID] = &harborRobot{ID: f.nextID, Name: name, Secret: "secret-" + strconv.FormatInt(f.nextID, 10), Level: request.Level, Permissions: request.Permissions}
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(harborRobot{ID: f.nextID, Name: name, Secret: f.robots[f.nextID].Secret})
+	case r.Method == http.MethodGet && path == "/robots":
+		robots := []harborRobot{}
+		for _, robot := range f.robots {
+			if "name="+robot.Name == r.URL.Query().Get("q") {
+				robots = append(robots, harborRobot{ID: robot.ID, Name: robot.Name})
+			}
+		}
+		_ = json.NewEncoder(w).Encode(robots)
+	case strings.HasPrefix(path, "/robots/"):
+		id, _ := strconv.ParseInt(strings.TrimPrefix(path, "/robots/"), 10, 64)
+		robot := f.robots[id]
+		if robot == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		switch r.Method {
+		case http.MethodDelete:
+			delete(f.robots, id)
+		case http.MethodGet:
+			_ = json.NewEncoder(w).Encode(harborRobot{ID: robot.ID, Name: robot.Name, Level: robot.Level, Permissions: robot.Permissions})
+		case http.MethodPut:
+			update := harborRobot{}
+			_ = json.NewDecoder(r.Body).Decode(&update)
+			if update.Name != robot.Name || update.Level != robot.Level || update.Secret != "" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			robot.Permissions = update.Permissions
+		default:
+			robot.Secret = robot.Secret + "-rotated"
+			_ = json.NewEncoder(w).Encode(harborRobot{Secret: robot.Secret})
+		}
+	case r.Method == http.MethodDelete && strings.HasPrefix(path, "/projects/") && strings.Contains(path, "/repositories/"):
+		name := strings.TrimPrefix(path, "/projects/")
+		if !f.repositories[name] {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		delete(f.repositories, name)
+	default:
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+func setupHarbor() (*fakeHarbor, *httptest.Server, *harborProvisioner) {
+	harbor := &fakeHarbor{projects: map[string]bool{}, robots: map[int64]*harborRobot{}, repositories: map[string]bool{}}
+	server := httptest.NewServer(harbor)
+	provisioner := &harborProvisioner{
+		client:   server.Client(),
+		apiURL:   server.URL + "/api/v2.0",
+		host:     "harbor.local",
+		project:  "jbs",
+		username: "admin",
+		password: "secret",
+	}
+	return harbor, server, provisioner
+}
+
+// pushTo the permissions of a robot account that can push to the project
+func pushTo(project string) []harborRobotPermission {
+	return pushPermissions(&Repository{Owner: project})
+}
+
+func TestHarborProvisioner(t *testing.T) {
+	ctx := context.TODO()
+	t.Run("Test the project and a robot account are created", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		harbor, server, provisioner := setupHarbor()
+		defer server.Close()
+		repo, err := provisioner.CreateRepository(ctx, "test/jvm-build-service-artifacts")
+		g.Expect(err).ShouldNot(HaveOccurred())
+		g.Expect(repo.URL()).Should(Equal("harbor.local/jbs-test/jvm-build-service-artifacts"))
+		g.Expect(harbor.projects).Should(HaveKey("jbs-test"))
+
+		credential, err := provisioner.CreateRobot(ctx, repo, "test_jvm_build_config")
+		g.Expect(err).ShouldNot(HaveOccurred())
+		g.Expect(*credential).Should(Equal(Credential{Username: "robot$jbs-test_jvm_build_config", Password: "secret-1"}))
+		g.Expect(provisioner.GrantPush(ctx, repo, "test_jvm_build_config")).Should(Succeed())
+		g.Expect(harbor.robots[1].Permissions).Should(Equal(pushTo("jbs-test")))
+
+		//the project is only created once
+		other, err := provisioner.CreateRepository(ctx, "test/jvm-build-service-artifacts-other")
+		g.Expect(err).ShouldNot(HaveOccurred())
+		g.Expect(other.URL()).Should(Equal("harbor.local/jbs-test/jvm-build-service-artifacts-other"))
+		g.Expect(harbor.projects).Should(HaveLen(1))
+	})
+	t.Run("Test the robot accounts can only push to the project of their namespace", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		harbor, server, provisioner := setupHarbor()
+		defer server.Close()
+		a, err := provisioner.CreateRepository(ctx, "a/jvm-build-service-artifacts")
+		g.Expect(err).ShouldNot(HaveOccurred())
+		b, err := provisioner.CreateRepository(ctx, "b/jvm-build-service-artifacts")
+		g.Expect(err).ShouldNot(HaveOccurred())
+		g.Expect(harbor.projects).Should(Equal(map[string]bool{"jbs-a": true, "jbs-b": true}))
+		_, err = provisioner.CreateRobot(ctx, a, "a")
+		g.Expect(err).ShouldNot(HaveOccurred())
+		_, err = provisioner.CreateRobot(ctx, b, "b")
+		g.Expect(err).ShouldNot(HaveOccurred())
+		g.Expect(harbor.robots[1].Permissions).Should(Equal(pushTo("jbs-a")))
+		g.Expect(harbor.robots[2].Permissions).Should(Equal(pushTo("jbs-b")))
+
+		//an existing robot account loses the permissions it should not have
+		harbor.robots[1].Permissions = append(harbor.robots[1].Permissions, pushTo("jbs-b")...)
+		g.Expect(provisioner.GrantPush(ctx, a, "a")).Should(Succeed())
+		g.Expect(harbor.robots[1].Permissions).Should(Equal(pushTo("jbs-a")))
+	})
+	t.Run("Test the robot account of the shared project is replaced", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		harbor, server, provisioner := setupHarbor()
+		defer server.Close()
+		harbor.robots[1] = &harborRobot{ID: 1, Name: "robot$jbs+test", Level: "project", Permissions: pushTo("jbs")}
+		harbor.nextID = 1
+		repo, err := provisioner.CreateRepository(ctx, "test/jvm-build-service-artifacts")
+		g.Expect(err).ShouldNot(HaveOccurred())
+		credential, err := provisioner.CreateRobot(ctx, repo, "test")
+		g.Expect(err).ShouldNot(HaveOccurred())
+		g.Expect(credential.Username).Should(Equal("robot$jbs-test"))
+		g.Expect(harbor.robots).Should(HaveLen(1))
+		g.Expect(harbor.robots).ShouldNot(HaveKey(int64(1)))
+	})
+	t.Run("Test the secret of an existing robot account is rotated", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		harbor, server, provisioner := setupHarbor()
+		defer server.Close()
+		repo := &Repository{Host: "harbor.local", Owner: "jbs-test", Name: "jvm-build-service-artifacts"}
+		_, err := provisioner.CreateRobot(ctx, repo, "test")
+		g.Expect(err).ShouldNot(HaveOccurred())
+		credential, err := provisioner.CreateRobot(ctx, repo, "test")
+		g.Expect(err).ShouldNot(HaveOccurred())
+		g.Expect(*credential).Should(Equal(Credential{Username: "robot$jbs-test", Password: "secret-1-rotated"}))
+		credential, err = provisioner.RotateCredential(ctx, repo, "test")
+		g.Expect(err).ShouldNot(HaveOccurred())
+		g.Expect(credential.Password).Should(Equal("secret-1-rotated-rotated"))
+		g.Expect(harbor.robots).Should(HaveLen(1))
+
+		_, err = provisioner.RotateCredential(ctx, repo, "missing")
+		g.Expect(err).Should(HaveOccurred())
+	})
+	t.Run("Test the robot account and repository are deleted", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		harbor, server, provisioner := setupHarbor()
+		defer server.Close()
+		_, err := provisioner.CreateRobot(ctx, &Repository{Owner: "jbs-test"}, "test")
+		g.Expect(err).ShouldNot(HaveOccurred())
+		harbor.repositories["jbs-test/repositories/jvm-build-service-artifacts"] = true
+
+		deleted, err := provisioner.DeleteRobot(ctx, "test")
+		g.Expect(err).ShouldNot(HaveOccurred())
+		g.Expect(deleted).Should(BeTrue())
+		g.Expect(harbor.robots).Should(BeEmpty())
+		deleted, err = provisioner.DeleteRobot(ctx, "test")
+		g.Expect(err).ShouldNot(HaveOccurred())
+		g.Expect(deleted).Should(BeFalse())
+
+		deleted, err = provisioner.DeleteRepository(ctx, "test/jvm-build-service-artifacts")
+		g.Expect(err).ShouldNot(HaveOccurred())
+		g.Expect(deleted).Should(BeTrue())
+		deleted, err = provisioner.DeleteRepository(ctx, "test/jvm-build-service-artifacts")
+		g.Expect(err).ShouldNot(HaveOccurred())
+		g.Expect(deleted).Should(BeFalse())
+	})
+	t.Run("Test API errors are returned", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		_, server, provisioner := setupHarbor()
+		defer server.Close()
+		provisioner.password = "wrong"
+		_, err := provisioner.CreateRobot(ctx, &Repository{Owner: "jbs-test"}, "test")
+		g.Expect(err).Should(MatchError(ContainSubstring("401")))
+	})
+}
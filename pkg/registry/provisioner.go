@@ -0,0 +1,185 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redhat-appstudio/image-controller/pkg/quay"
+	"github.com/redhat-appstudio/jvm-build-service/pkg/apis/jvmbuildservice/v1alpha1"
+)
+
+const (
+	// QuayTokenKey the credentials key of the OAuth token of a Quay application that can administer the organization
+	QuayTokenKey = "quaytoken"
+	// QuayOrganizationKey the credentials key of the Quay organization, used if the SystemConfig does not set an owner
+	QuayOrganizationKey = "organization"
+	// UsernameKey the credentials key of the Harbor account that can administer the project, or the static user
+	UsernameKey = "username"
+	// PasswordKey the credentials key of the password of UsernameKey
+	PasswordKey = "password" //#nosec
+
+	DefaultQuayHost   = "quay.io"
+	DefaultQuayAPIURL = "https://quay.io/api/v1"
+
+	requestTimeout = 30 * time.Second
+)
+
+// Repository an image repository that was provisioned for a namespace
+type Repository struct {
+	// Host the registry host, with the port if it is not the default
+	Host string
+	// Owner the organization or project the repository belongs to
+	Owner string
+	// Name the repository name under the owner
+	Name     string
+	Insecure bool
+}
+
+// URL the repository without a scheme, as it is used in image references and docker config files
+func (r *Repository) URL() string {
+	return r.Host + "/" + r.Owner + "/" + r.Name
+}
+
+// Credential a robot account that can push to provisioned repositories
+type Credential struct {
+	Username string
+	Password string
+}
+
+// RegistryProvisioner creates the image repositories and robot accounts for JBSConfigs that do not specify an image
+// registry owner, and deletes them again when the JBSConfig is deleted
+type RegistryProvisioner interface {
+	// CreateRepository creates the repository if it does not exist
+	CreateRepository(ctx context.Context, name string) (*Repository, error)
+	// CreateRobot creates a robot account that can push to the repository, or returns the credential of the existing one
+	CreateRobot(ctx context.Context, repository *Repository, robot string) (*Credential, error)
+	// GrantPush lets the robot account push to the repository
+	GrantPush(ctx context.Context, repository *Repository, robot string) error
+	// RotateCredential replaces the password of the robot account, it can still push to the repository afterwards
+	RotateCredential(ctx context.Context, repository *Repository, robot string) (*Credential, error)
+	// DeleteRobot deletes the robot account, and returns false if it did not exist
+	DeleteRobot(ctx context.Context, robot string) (bool, error)
+	// DeleteRepository deletes the repository and its images, and returns false if it did not exist
+	DeleteRepository(ctx context.Context, name string) (bool, error)
+}
+
+// ErrProvisionerNotConfigured the SystemConfig has not been reconciled yet, so it is not known if repositories can be
+// provisioned
+var ErrProvisionerNotConfigured = errors.New("the registry provisioner has not been configured yet")
+
+// ProvisionerRef holds the provisioner that is currently configured. The SystemConfig reconciler replaces it when the
+// SystemConfig or the credentials change, so the reconcilers that provision repositories always use the latest.
+type ProvisionerRef struct {
+	lock        sync.RWMutex
+	configured  bool
+	version     string
+	provisioner RegistryProvisioner
+	err         error
+}
+
+// Get returns the current provisioner, nil if repositories are not provisioned, or the error that prevented it from
+// being created
+func (p *ProvisionerRef) Get() (RegistryProvisioner, error) {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	if !p.configured {
+		return nil, ErrProvisionerNotConfigured
+	}
+	return p.provisioner, p.err
+}
+
+// Update creates a new provisioner if the version of the settings and credentials it is created from has changed, and
+// returns true if it did
+func (p *ProvisionerRef) Update(version string, create func() (RegistryProvisioner, error)) bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.configured && p.version == version {
+		return false
+	}
+	p.provisioner, p.err = create()
+	p.version = version
+	p.configured = true
+	return true
+}
+
+// NewProvisioner creates the provisioner selected in the SystemConfig, with the credentials it administers the
+// registry with. It returns nil if repositories are not provisioned.
+func NewProvisioner(settings v1alpha1.RegistryProvisioningSettings, credentials map[string][]byte) (RegistryProvisioner, error) {
+	provider := settings.Provider
+	if provider == "" {
+		//before the provider could be selected Quay was used if there was a token
+		if credential(credentials, QuayTokenKey) == "" {
+			return nil, nil
+		}
+		provider = v1alpha1.RegistryProviderQuay
+	}
+	httpClient := &http.Client{Transport: &http.Transport{}, Timeout: requestTimeout}
+	switch provider {
+	case v1alpha1.RegistryProviderQuay:
+		token := credential(credentials, QuayTokenKey)
+		if token == "" {
+			return nil, fmt.Errorf("the %s provisioner requires the %s credential", provider, QuayTokenKey)
+		}
+		owner := settings.Owner
+		if owner == "" {
+			owner = credential(credentials, QuayOrganizationKey)
+		}
+		if owner == "" {
+			return nil, fmt.Errorf("the %s provisioner requires an owner", provider)
+		}
+		apiURL := settingOrDefault(settings.APIURL, DefaultQuayAPIURL)
+		client := quay.NewQuayClient(httpClient, token, strings.TrimSuffix(apiURL, "/"))
+		return &quayProvisioner{client: &client, host: settingOrDefault(settings.Host, DefaultQuayHost), organization: owner, insecure: settings.Insecure}, nil
+	case v1alpha1.RegistryProviderHarbor, v1alpha1.RegistryProviderStatic:
+		if settings.Host == "" || settings.Owner == "" {
+			return nil, fmt.Errorf("the %s provisioner requires a host and an owner", provider)
+		}
+		username := credential(credentials, UsernameKey)
+		password := credential(credentials, PasswordKey)
+		if username == "" || password == "" {
+			return nil, fmt.Errorf("the %s provisioner requires the %s and %s credentials", provider, UsernameKey, PasswordKey)
+		}
+		if provider == v1alpha1.RegistryProviderStatic {
+			return &staticProvisioner{
+				host:       settings.Host,
+				owner:      settings.Owner,
+				insecure:   settings.Insecure,
+				credential: Credential{Username: username, Password: password},
+			}, nil
+		}
+		apiURL := settings.APIURL
+		if apiURL == "" {
+			scheme := "https://"
+			if settings.Insecure {
+				scheme = "http://"
+			}
+			apiURL = scheme + settings.Host + "/api/v2.0"
+		}
+		return &harborProvisioner{
+			client:   httpClient,
+			apiURL:   strings.TrimSuffix(apiURL, "/"),
+			host:     settings.Host,
+			project:  settings.Owner,
+			insecure: settings.Insecure,
+			username: username,
+			password: password,
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown registry provider %s", provider)
+}
+
+func credential(credentials map[string][]byte, key string) string {
+	return strings.TrimSpace(string(credentials[key]))
+}
+
+func settingOrDefault(setting, def string) string {
+	if setting == "" {
+		return def
+	}
+	return setting
+}
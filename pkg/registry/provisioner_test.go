@@ -0,0 +1,82 @@
+package registry
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/redhat-appstudio/jvm-build-service/pkg/apis/jvmbuildservice/v1alpha1"
+)
+
+func TestNewProvisioner(t *testing.T) {
+	userCredentials := map[string][]byte{UsernameKey: []byte("admin"), PasswordKey: []byte("secret\n")}
+	t.Run("Test provisioning is disabled without a provider or Quay token", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		provisioner, err := NewProvisioner(v1alpha1.RegistryProvisioningSettings{}, map[string][]byte{})
+		g.Expect(err).ShouldNot(HaveOccurred())
+		g.Expect(provisioner).Should(BeNil())
+	})
+	t.Run("Test Quay is used if a token is mounted", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		provisioner, err := NewProvisioner(v1alpha1.RegistryProvisioningSettings{}, map[string][]byte{QuayTokenKey: []byte("token\n"), QuayOrganizationKey: []byte("org")})
+		g.Expect(err).ShouldNot(HaveOccurred())
+		quay := provisioner.(*quayProvisioner)
+		g.Expect(quay.organization).Should(Equal("org"))
+		g.Expect(quay.host).Should(Equal(DefaultQuayHost))
+		g.Expect(quay.client.AuthToken).Should(Equal("token"))
+	})
+	t.Run("Test the SystemConfig owner overrides the mounted organization", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		provisioner, err := NewProvisioner(v1alpha1.RegistryProvisioningSettings{Provider: v1alpha1.RegistryProviderQuay, Owner: "other"}, map[string][]byte{QuayTokenKey: []byte("token"), QuayOrganizationKey: []byte("org")})
+		g.Expect(err).ShouldNot(HaveOccurred())
+		g.Expect(provisioner.(*quayProvisioner).organization).Should(Equal("other"))
+	})
+	t.Run("Test Quay requires an owner", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		_, err := NewProvisioner(v1alpha1.RegistryProvisioningSettings{}, map[string][]byte{QuayTokenKey: []byte("token")})
+		g.Expect(err).Should(HaveOccurred())
+	})
+	t.Run("Test the Harbor API defaults to the host", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		provisioner, err := NewProvisioner(v1alpha1.RegistryProvisioningSettings{Provider: v1alpha1.RegistryProviderHarbor, Host: "harbor.local:8443", Owner: "jbs"}, userCredentials)
+		g.Expect(err).ShouldNot(HaveOccurred())
+		harbor := provisioner.(*harborProvisioner)
+		g.Expect(harbor.apiURL).Should(Equal("https://harbor.local:8443/api/v2.0"))
+		g.Expect(harbor.password).Should(Equal("secret"))
+
+		provisioner, err = NewProvisioner(v1alpha1.RegistryProvisioningSettings{Provider: v1alpha1.RegistryProviderHarbor, Host: "harbor.local", Owner: "jbs", Insecure: true}, userCredentials)
+		g.Expect(err).ShouldNot(HaveOccurred())
+		g.Expect(provisioner.(*harborProvisioner).apiURL).Should(Equal("http://harbor.local/api/v2.0"))
+	})
+	t.Run("Test Harbor requires credentials", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		_, err := NewProvisioner(v1alpha1.RegistryProvisioningSettings{Provider: v1alpha1.RegistryProviderHarbor, Host: "harbor.local", Owner: "jbs"}, map[string][]byte{UsernameKey: []byte("admin")})
+		g.Expect(err).Should(HaveOccurred())
+	})
+	t.Run("Test an unknown provider", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		_, err := NewProvisioner(v1alpha1.RegistryProvisioningSettings{Provider: "other"}, userCredentials)
+		g.Expect(err).Should(HaveOccurred())
+	})
+}
+
+func TestStaticProvisioner(t *testing.T) {
+	g := NewGomegaWithT(t)
+	ctx := context.TODO()
+	provisioner, err := NewProvisioner(v1alpha1.RegistryProvisioningSettings{Provider: v1alpha1.RegistryProviderStatic, Host: "registry.local:5000", Owner: "jbs", Insecure: true}, map[string][]byte{UsernameKey: []byte("pusher"), PasswordKey: []byte("secret")})
+	g.Expect(err).ShouldNot(HaveOccurred())
+	repo, err := provisioner.CreateRepository(ctx, "test/jvm-build-service-artifacts")
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(repo.URL()).Should(Equal("registry.local:5000/jbs/test/jvm-build-service-artifacts"))
+	g.Expect(repo.Insecure).Should(BeTrue())
+	credential, err := provisioner.CreateRobot(ctx, repo, "test")
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(*credential).Should(Equal(Credential{Username: "pusher", Password: "secret"}))
+	g.Expect(provisioner.GrantPush(ctx, repo, "test")).Should(Succeed())
+	rotated, err := provisioner.RotateCredential(ctx, repo, "test")
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(rotated).Should(Equal(credential))
+	deleted, err := provisioner.DeleteRepository(ctx, repo.Name)
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(deleted).Should(BeFalse())
+}
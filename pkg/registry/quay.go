@@ -0,0 +1,64 @@
+package registry
+
+import (
+	"context"
+
+	"github.com/redhat-appstudio/image-controller/pkg/quay"
+)
+
+// quayProvisioner creates public repositories and robot accounts in a Quay organization
+type quayProvisioner struct {
+	client       *quay.QuayClient
+	host         string
+	organization string
+	insecure     bool
+}
+
+func (q *quayProvisioner) CreateRepository(ctx context.Context, name string) (*Repository, error) {
+	repo, err := q.client.CreateRepository(quay.RepositoryRequest{
+		Namespace:   q.organization,
+		Visibility:  "public",
+		Description: "JVM Build Service repository for the user",
+		Repository:  name,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Repository{Host: q.host, Owner: q.organization, Name: repo.Name, Insecure: q.insecure}, nil
+}
+
+func (q *quayProvisioner) CreateRobot(ctx context.Context, repository *Repository, robot string) (*Credential, error) {
+	account, err := q.client.CreateRobotAccount(q.organization, robot)
+	if err != nil {
+		return nil, err
+	}
+	return &Credential{Username: account.Name, Password: account.Token}, nil
+}
+
+func (q *quayProvisioner) GrantPush(ctx context.Context, repository *Repository, robot string) error {
+	return q.client.AddWritePermissionsToRobotAccount(q.organization, repository.Name, robot)
+}
+
+// RotateCredential the client can't regenerate the token of a robot account, so the account is recreated, which
+// also removes its permissions
+func (q *quayProvisioner) RotateCredential(ctx context.Context, repository *Repository, robot string) (*Credential, error) {
+	if _, err := q.client.DeleteRobotAccount(q.organization, robot); err != nil {
+		return nil, err
+	}
+	credential, err := q.CreateRobot(ctx, repository, robot)
+	if err != nil {
+		return nil, err
+	}
+	if err := q.GrantPush(ctx, repository, robot); err != nil {
+		return nil, err
+	}
+	return credential, nil
+}
+
+func (q *quayProvisioner) DeleteRobot(ctx context.Context, robot string) (bool, error) {
+	return q.client.DeleteRobotAccount(q.organization, robot)
+}
+
+func (q *quayProvisioner) DeleteRepository(ctx context.Context, name string) (bool, error) {
+	return q.client.DeleteRepository(q.organization, name)
+}
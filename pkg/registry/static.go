@@ -0,0 +1,40 @@
+package registry
+
+import (
+	"context"
+)
+
+// staticProvisioner gives every namespace its own path under the owner, and the same credentials to push to it. The
+// registry creates the repositories on the first push, and the credentials are managed outside the operator.
+type staticProvisioner struct {
+	host       string
+	owner      string
+	insecure   bool
+	credential Credential
+}
+
+func (s *staticProvisioner) CreateRepository(ctx context.Context, name string) (*Repository, error) {
+	return &Repository{Host: s.host, Owner: s.owner, Name: name, Insecure: s.insecure}, nil
+}
+
+func (s *staticProvisioner) CreateRobot(ctx context.Context, repository *Repository, robot string) (*Credential, error) {
+	credential := s.credential
+	return &credential, nil
+}
+
+func (s *staticProvisioner) GrantPush(ctx context.Context, repository *Repository, robot string) error {
+	return nil
+}
+
+// RotateCredential the static credentials are rotated by updating them, this returns the current ones
+func (s *staticProvisioner) RotateCredential(ctx context.Context, repository *Repository, robot string) (*Credential, error) {
+	return s.CreateRobot(ctx, repository, robot)
+}
+
+func (s *staticProvisioner) DeleteRobot(ctx context.Context, robot string) (bool, error) {
+	return false, nil
+}
+
+func (s *staticProvisioner) DeleteRepository(ctx context.Context, name string) (bool, error) {
+	return false, nil
+}
@@ -0,0 +1,57 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/redhat-appstudio/jvm-build-service/pkg/apis/jvmbuildservice/v1alpha1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+type artifactBuildValidator struct {
+}
+
+func (v *artifactBuildValidator) ValidateCreate(ctx context.Context, obj runtime.Object) error {
+	return validateArtifactBuild(obj)
+}
+
+func (v *artifactBuildValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) error {
+	return validateArtifactBuild(newObj)
+}
+
+func (v *artifactBuildValidator) ValidateDelete(ctx context.Context, obj runtime.Object) error {
+	return nil
+}
+
+func validateArtifactBuild(obj runtime.Object) error {
+	abr, ok := obj.(*v1alpha1.ArtifactBuild)
+	if !ok {
+		return fmt.Errorf("expected an ArtifactBuild but got a %T", obj)
+	}
+	return invalid("ArtifactBuild", abr.Name, validateGAV(field.NewPath("spec", "gav"), abr.Spec.GAV))
+}
+
+// validateGAV checks that a GAV is in the groupId:artifactId[:packaging[:classifier]]:version form, the version
+// is always taken from the last segment
+func validateGAV(path *field.Path, gav string) field.ErrorList {
+	errs := field.ErrorList{}
+	if len(gav) == 0 {
+		return append(errs, field.Required(path, "a GAV in the form groupId:artifactId:version is required"))
+	}
+	if strings.IndexFunc(gav, unicode.IsSpace) >= 0 {
+		errs = append(errs, field.Invalid(path, gav, "must not contain whitespace"))
+	}
+	parts := strings.Split(gav, ":")
+	if len(parts) < 3 || len(parts) > 5 {
+		return append(errs, field.Invalid(path, gav, "must be in the form groupId:artifactId[:packaging[:classifier]]:version"))
+	}
+	for _, part := range parts {
+		if len(part) == 0 {
+			return append(errs, field.Invalid(path, gav, "groupId, artifactId and version must not be empty"))
+		}
+	}
+	return errs
+}
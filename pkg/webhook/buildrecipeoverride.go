@@ -0,0 +1,61 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+
+	"github.com/redhat-appstudio/jvm-build-service/pkg/apis/jvmbuildservice/v1alpha1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+type buildRecipeOverrideValidator struct {
+}
+
+func (v *buildRecipeOverrideValidator) ValidateCreate(ctx context.Context, obj runtime.Object) error {
+	return validateBuildRecipeOverride(obj)
+}
+
+func (v *buildRecipeOverrideValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) error {
+	return validateBuildRecipeOverride(newObj)
+}
+
+func (v *buildRecipeOverrideValidator) ValidateDelete(ctx context.Context, obj runtime.Object) error {
+	return nil
+}
+
+func validateBuildRecipeOverride(obj runtime.Object) error {
+	override, ok := obj.(*v1alpha1.BuildRecipeOverride)
+	if !ok {
+		return fmt.Errorf("expected a BuildRecipeOverride but got a %T", obj)
+	}
+	spec := field.NewPath("spec")
+	errs := field.ErrorList{}
+	match := override.Spec.Match
+	matchPath := spec.Child("match")
+	if len(match.SCMURL) == 0 && len(match.TagPattern) == 0 && len(match.VersionPattern) == 0 && len(match.GAV) == 0 {
+		errs = append(errs, field.Required(matchPath, "at least one of scmURL, tagPattern, versionPattern or gav must be set"))
+	}
+	validatePattern(matchPath.Child("tagPattern"), match.TagPattern, &errs)
+	validatePattern(matchPath.Child("versionPattern"), match.VersionPattern, &errs)
+	if len(match.GAV) > 0 {
+		errs = append(errs, validateGAV(matchPath.Child("gav"), match.GAV)...)
+	}
+	if override.Spec.AdditionalMemory < 0 {
+		errs = append(errs, field.Invalid(spec.Child("additionalMemory"), override.Spec.AdditionalMemory, "must not be negative"))
+	}
+	validateTimeout(spec.Child("pipelineTimeout"), override.Spec.PipelineTimeout, &errs)
+	validateTimeout(spec.Child("stepTimeout"), override.Spec.StepTimeout, &errs)
+	errs = append(errs, ValidateAdditionalDownloads(spec.Child("additionalDownloads"), override.Spec.AdditionalDownloads)...)
+	return invalid("BuildRecipeOverride", override.Name, errs)
+}
+
+func validatePattern(path *field.Path, pattern string, errs *field.ErrorList) {
+	if len(pattern) == 0 {
+		return
+	}
+	if _, err := regexp.Compile(pattern); err != nil {
+		*errs = append(*errs, field.Invalid(path, pattern, "must be a valid regular expression: "+err.Error()))
+	}
+}
@@ -0,0 +1,90 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/redhat-appstudio/jvm-build-service/pkg/apis/jvmbuildservice/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+type dependencyBuildValidator struct {
+}
+
+func (v *dependencyBuildValidator) ValidateCreate(ctx context.Context, obj runtime.Object) error {
+	return validateDependencyBuild(obj)
+}
+
+// ValidateUpdate only validates a changed spec, the controller adds owner references to existing builds and those
+// updates must not be rejected
+func (v *dependencyBuildValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) error {
+	oldDb, ok := oldObj.(*v1alpha1.DependencyBuild)
+	if !ok {
+		return fmt.Errorf("expected a DependencyBuild but got a %T", oldObj)
+	}
+	newDb, ok := newObj.(*v1alpha1.DependencyBuild)
+	if !ok {
+		return fmt.Errorf("expected a DependencyBuild but got a %T", newObj)
+	}
+	if equality.Semantic.DeepEqual(oldDb.Spec, newDb.Spec) {
+		return nil
+	}
+	return validateDependencyBuild(newObj)
+}
+
+func (v *dependencyBuildValidator) ValidateDelete(ctx context.Context, obj runtime.Object) error {
+	return nil
+}
+
+// validateDependencyBuild checks the spec, the status is written through the status subresource which is not
+// validated. The recipes in the status are checked where they come from, the BuildRecipeOverride webhook and the
+// build info analysis.
+func validateDependencyBuild(obj runtime.Object) error {
+	db, ok := obj.(*v1alpha1.DependencyBuild)
+	if !ok {
+		return fmt.Errorf("expected a DependencyBuild but got a %T", obj)
+	}
+	errs := field.ErrorList{}
+	spec := field.NewPath("spec")
+	if len(db.Spec.ScmInfo.SCMURL) == 0 {
+		errs = append(errs, field.Required(spec.Child("scm", "scmURL"), ""))
+	}
+	if len(db.Spec.Version) == 0 {
+		errs = append(errs, field.Required(spec.Child("version"), ""))
+	}
+	return invalid("DependencyBuild", db.Name, errs)
+}
+
+// ValidateAdditionalDownloads checks the parts of a recipe that would otherwise only fail once the build pipeline is
+// running, it is also used by the controller for the recipes found by the build info analysis
+func ValidateAdditionalDownloads(path *field.Path, downloads []v1alpha1.AdditionalDownload) field.ErrorList {
+	errs := field.ErrorList{}
+	for i, download := range downloads {
+		downloadPath := path.Index(i)
+		switch download.FileType {
+		case v1alpha1.AdditionalDownloadTypeTar:
+			if len(download.Uri) == 0 {
+				errs = append(errs, field.Required(downloadPath.Child("uri"), "uri is required for tar downloads"))
+			}
+			if len(download.BinaryPath) == 0 {
+				errs = append(errs, field.Required(downloadPath.Child("binaryPath"), "binaryPath is required for tar downloads"))
+			}
+		case v1alpha1.AdditionalDownloadTypeExecutable:
+			if len(download.Uri) == 0 {
+				errs = append(errs, field.Required(downloadPath.Child("uri"), "uri is required for executable downloads"))
+			}
+			if len(download.FileName) == 0 {
+				errs = append(errs, field.Required(downloadPath.Child("fileName"), "fileName is required for executable downloads"))
+			}
+		case v1alpha1.AdditionalDownloadTypeRpm:
+			if len(download.PackageName) == 0 {
+				errs = append(errs, field.Required(downloadPath.Child("packageName"), "packageName is required for rpm downloads"))
+			}
+		default:
+			errs = append(errs, field.NotSupported(downloadPath.Child("type"), download.FileType, []string{v1alpha1.AdditionalDownloadTypeTar, v1alpha1.AdditionalDownloadTypeExecutable, v1alpha1.AdditionalDownloadTypeRpm}))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,269 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/redhat-appstudio/jvm-build-service/pkg/apis/jvmbuildservice/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+const defaultBuildPolicy = "default"
+
+var (
+	mavenRepositoryKeyRegex = regexp.MustCompile("^" + v1alpha1.MavenRepositoryKeyPattern + "$")
+	buildPolicyRegex        = regexp.MustCompile(`^[\w-]+$`)
+)
+
+type jbsConfigDefaulter struct {
+}
+
+// Default fills in the cache settings the controller would otherwise default, so the effective values are visible
+// on the resource
+func (d *jbsConfigDefaulter) Default(ctx context.Context, obj runtime.Object) error {
+	jbsConfig, ok := obj.(*v1alpha1.JBSConfig)
+	if !ok {
+		return fmt.Errorf("expected a JBSConfig but got a %T", obj)
+	}
+	cache := &jbsConfig.Spec.CacheSettings
+	defaultString(&cache.RequestMemory, v1alpha1.ConfigArtifactCacheRequestMemoryDefault)
+	defaultString(&cache.RequestCPU, v1alpha1.ConfigArtifactCacheRequestCPUDefault)
+	defaultString(&cache.LimitMemory, v1alpha1.ConfigArtifactCacheLimitMemoryDefault)
+	defaultString(&cache.LimitCPU, v1alpha1.ConfigArtifactCacheLimitCPUDefault)
+	defaultString(&cache.IOThreads, v1alpha1.ConfigArtifactCacheIOThreadsDefault)
+	defaultString(&cache.WorkerThreads, v1alpha1.ConfigArtifactCacheWorkerThreadsDefault)
+	defaultString(&cache.Storage, v1alpha1.ConfigArtifactCacheStorageDefault)
+	for i := range jbsConfig.Spec.RelocationPatterns {
+		defaultString(&jbsConfig.Spec.RelocationPatterns[i].RelocationPattern.BuildPolicy, defaultBuildPolicy)
+	}
+	return nil
+}
+
+func defaultString(setting *string, def string) {
+	if len(*setting) == 0 {
+		*setting = def
+	}
+}
+
+type jbsConfigValidator struct {
+}
+
+func (v *jbsConfigValidator) ValidateCreate(ctx context.Context, obj runtime.Object) error {
+	return validateJBSConfig(obj)
+}
+
+func (v *jbsConfigValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) error {
+	return validateJBSConfig(newObj)
+}
+
+func (v *jbsConfigValidator) ValidateDelete(ctx context.Context, obj runtime.Object) error {
+	return nil
+}
+
+func validateJBSConfig(obj runtime.Object) error {
+	jbsConfig, ok := obj.(*v1alpha1.JBSConfig)
+	if !ok {
+		return fmt.Errorf("expected a JBSConfig but got a %T", obj)
+	}
+	spec := field.NewPath("spec")
+	errs := field.ErrorList{}
+	errs = append(errs, validateCacheSettings(spec.Child("cacheSettings"), &jbsConfig.Spec.CacheSettings)...)
+	errs = append(errs, validateBuildSettings(spec.Child("buildSettings"), &jbsConfig.Spec.BuildSettings)...)
+	errs = append(errs, validateMavenBaseLocations(spec.Child("mavenBaseLocations"), jbsConfig.Spec.MavenBaseLocations)...)
+	errs = append(errs, validateRelocationPatterns(spec.Child("relocationPatterns"), jbsConfig.Spec.RelocationPatterns)...)
+	errs = append(errs, validateRetention(spec.Child("retention"), &jbsConfig.Spec.Retention)...)
+	if jbsConfig.Spec.EventSink.URL != "" {
+		parsed, err := url.ParseRequestURI(jbsConfig.Spec.EventSink.URL)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || len(parsed.Host) == 0 {
+			errs = append(errs, field.Invalid(spec.Child("eventSink", "url"), jbsConfig.Spec.EventSink.URL, "must be an absolute http or https URL"))
+		}
+	}
+	return invalid("JBSConfig", jbsConfig.Name, errs)
+}
+
+func validateCacheSettings(path *field.Path, cache *v1alpha1.CacheSettings) field.ErrorList {
+	errs := field.ErrorList{}
+	requestMemory := validateQuantity(path.Child("requestMemory"), cache.RequestMemory, &errs)
+	limitMemory := validateQuantity(path.Child("limitMemory"), cache.LimitMemory, &errs)
+	requestCPU := validateQuantity(path.Child("requestCPU"), cache.RequestCPU, &errs)
+	limitCPU := validateQuantity(path.Child("limitCPU"), cache.LimitCPU, &errs)
+	validateQuantity(path.Child("storage"), cache.Storage, &errs)
+	validateRequestWithinLimit(path.Child("requestMemory"), cache.RequestMemory, requestMemory, limitMemory, &errs)
+	validateRequestWithinLimit(path.Child("requestCPU"), cache.RequestCPU, requestCPU, limitCPU, &errs)
+	validatePositiveInteger(path.Child("ioThreads"), cache.IOThreads, &errs)
+	validatePositiveInteger(path.Child("workerThreads"), cache.WorkerThreads, &errs)
+	return errs
+}
+
+func validateBuildSettings(path *field.Path, build *v1alpha1.BuildSettings) field.ErrorList {
+	errs := field.ErrorList{}
+	validateQuantity(path.Child("buildRequestMemory"), build.BuildRequestMemory, &errs)
+	validateQuantity(path.Child("buildRequestCPU"), build.BuildRequestCPU, &errs)
+	validateQuantity(path.Child("taskRequestMemory"), build.TaskRequestMemory, &errs)
+	validateQuantity(path.Child("taskRequestCPU"), build.TaskRequestCPU, &errs)
+	validateQuantity(path.Child("taskLimitMemory"), build.TaskLimitMemory, &errs)
+	validateQuantity(path.Child("taskLimitCPU"), build.TaskLimitCPU, &errs)
+	if build.MaxConcurrentBuilds < 0 {
+		errs = append(errs, field.Invalid(path.Child("maxConcurrentBuilds"), build.MaxConcurrentBuilds, "must not be negative"))
+	}
+	validateTimeout(path.Child("pipelineTimeout"), build.PipelineTimeout, &errs)
+	validateTimeout(path.Child("stepTimeout"), build.StepTimeout, &errs)
+	validateTimeout(path.Child("maxPipelineTimeout"), build.MaxPipelineTimeout, &errs)
+	if build.PipelineTimeout != nil && build.MaxPipelineTimeout != nil && build.PipelineTimeout.Duration > build.MaxPipelineTimeout.Duration {
+		errs = append(errs, field.Invalid(path.Child("pipelineTimeout"), build.PipelineTimeout.Duration.String(), "must not be longer than maxPipelineTimeout"))
+	}
+	switch build.TimeoutPolicy {
+	case "", v1alpha1.BuildTimeoutPolicyNextRecipe, v1alpha1.BuildTimeoutPolicyRetryWithLongerTimeout:
+	default:
+		errs = append(errs, field.NotSupported(path.Child("timeoutPolicy"), build.TimeoutPolicy, []string{v1alpha1.BuildTimeoutPolicyNextRecipe, v1alpha1.BuildTimeoutPolicyRetryWithLongerTimeout}))
+	}
+	errs = append(errs, validateRetryPolicy(path.Child("retryPolicy"), &build.RetryPolicy)...)
+	return errs
+}
+
+var retryCauses = []string{v1alpha1.BuildFailureClassOOM, v1alpha1.RetryCauseCacheRestart, v1alpha1.BuildFailureClassGitClone, v1alpha1.BuildFailureClassDeploy, v1alpha1.BuildFailureClassTimeout}
+
+func validateRetryPolicy(path *field.Path, policy *v1alpha1.RetryPolicy) field.ErrorList {
+	errs := field.ErrorList{}
+	for cause, max := range policy.MaxRetries {
+		known := false
+		for _, c := range retryCauses {
+			known = known || c == cause
+		}
+		if !known {
+			errs = append(errs, field.NotSupported(path.Child("maxRetries"), cause, retryCauses))
+		} else if max < 0 {
+			errs = append(errs, field.Invalid(path.Child("maxRetries").Key(cause), max, "must not be negative"))
+		}
+	}
+	if policy.MemoryIncrement < 0 {
+		errs = append(errs, field.Invalid(path.Child("memoryIncrement"), policy.MemoryIncrement, "must not be negative"))
+	}
+	if policy.MemoryGrowthFactor < 0 {
+		errs = append(errs, field.Invalid(path.Child("memoryGrowthFactor"), policy.MemoryGrowthFactor, "must not be negative"))
+	}
+	if policy.MemoryCeiling < 0 {
+		errs = append(errs, field.Invalid(path.Child("memoryCeiling"), policy.MemoryCeiling, "must not be negative"))
+	}
+	if policy.InitialBackoff != nil && policy.InitialBackoff.Duration < 0 {
+		errs = append(errs, field.Invalid(path.Child("initialBackoff"), policy.InitialBackoff.Duration.String(), "must not be negative"))
+	}
+	validateTimeout(path.Child("maxBackoff"), policy.MaxBackoff, &errs)
+	return errs
+}
+
+func validateRetention(path *field.Path, retention *v1alpha1.RetentionSettings) field.ErrorList {
+	errs := field.ErrorList{}
+	validateTimeout(path.Child("completedArtifactBuildTTL"), retention.CompletedArtifactBuildTTL, &errs)
+	validateTimeout(path.Child("failedArtifactBuildTTL"), retention.FailedArtifactBuildTTL, &errs)
+	if retention.PipelineRunsPerDependencyBuild < 0 {
+		errs = append(errs, field.Invalid(path.Child("pipelineRunsPerDependencyBuild"), retention.PipelineRunsPerDependencyBuild, "must not be negative"))
+	}
+	return errs
+}
+
+func validateTimeout(path *field.Path, timeout *metav1.Duration, errs *field.ErrorList) {
+	if timeout != nil && timeout.Duration <= 0 {
+		*errs = append(*errs, field.Invalid(path, timeout.Duration.String(), "must be positive"))
+	}
+}
+
+// validateQuantity returns the parsed quantity, or nil if it is not set or is invalid
+func validateQuantity(path *field.Path, value string, errs *field.ErrorList) *resource.Quantity {
+	if len(value) == 0 {
+		return nil
+	}
+	qty, err := resource.ParseQuantity(value)
+	if err != nil {
+		*errs = append(*errs, field.Invalid(path, value, "must be a valid quantity such as 512Mi or 500m"))
+		return nil
+	}
+	return &qty
+}
+
+func validateRequestWithinLimit(path *field.Path, value string, request *resource.Quantity, limit *resource.Quantity, errs *field.ErrorList) {
+	if request != nil && limit != nil && request.Cmp(*limit) > 0 {
+		*errs = append(*errs, field.Invalid(path, value, "must be less than or equal to the limit of "+limit.String()))
+	}
+}
+
+func validatePositiveInteger(path *field.Path, value string, errs *field.ErrorList) {
+	if len(value) == 0 {
+		return
+	}
+	i, err := strconv.Atoi(value)
+	if err != nil || i <= 0 {
+		*errs = append(*errs, field.Invalid(path, value, "must be a positive integer"))
+	}
+}
+
+func validateMavenBaseLocations(path *field.Path, locations map[string]string) field.ErrorList {
+	errs := field.ErrorList{}
+	names := map[string]string{}
+	for key, location := range locations {
+		keyPath := path.Key(key)
+		results := mavenRepositoryKeyRegex.FindStringSubmatch(key)
+		if results == nil {
+			errs = append(errs, field.Invalid(keyPath, key, "key must be in the form maven-repository-<position>-<name>, for example maven-repository-300-jboss"))
+			continue
+		}
+		name := results[2]
+		if existing, ok := names[name]; ok {
+			errs = append(errs, field.Duplicate(keyPath, "repository "+name+" is already defined by "+existing))
+		} else {
+			names[name] = key
+		}
+		parsed, err := url.ParseRequestURI(location)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || len(parsed.Host) == 0 {
+			errs = append(errs, field.Invalid(keyPath, location, "must be an absolute http or https URL"))
+		}
+	}
+	return errs
+}
+
+func validateRelocationPatterns(path *field.Path, relocationPatterns []v1alpha1.RelocationPatternElement) field.ErrorList {
+	errs := field.ErrorList{}
+	buildPolicies := map[string]bool{}
+	for i, element := range relocationPatterns {
+		elementPath := path.Index(i).Child("relocationPattern")
+		buildPolicy := element.RelocationPattern.BuildPolicy
+		if len(buildPolicy) == 0 {
+			buildPolicy = defaultBuildPolicy
+		}
+		if !buildPolicyRegex.MatchString(buildPolicy) {
+			errs = append(errs, field.Invalid(elementPath.Child("buildPolicy"), buildPolicy, "must only contain letters, digits, '_' or '-'"))
+		} else if buildPolicies[buildPolicy] {
+			//the patterns for a build policy are passed to the cache as a single environment variable
+			errs = append(errs, field.Duplicate(elementPath.Child("buildPolicy"), buildPolicy))
+		}
+		buildPolicies[buildPolicy] = true
+		for j, patternElement := range element.RelocationPattern.Patterns {
+			patternPath := elementPath.Child("patterns").Index(j).Child("pattern")
+			errs = append(errs, validateRelocationValue(patternPath.Child("from"), patternElement.Pattern.From)...)
+			errs = append(errs, validateRelocationValue(patternPath.Child("to"), patternElement.Pattern.To)...)
+			to := strings.Split(patternElement.Pattern.To, ":")
+			if len(patternElement.Pattern.To) > 0 && len(to) != 3 {
+				errs = append(errs, field.Invalid(patternPath.Child("to"), patternElement.Pattern.To, "must be in the form groupId:artifactId:version"))
+			}
+		}
+	}
+	return errs
+}
+
+// validateRelocationValue checks for the characters that are used to join the patterns when they are passed to the cache
+func validateRelocationValue(path *field.Path, value string) field.ErrorList {
+	if len(value) == 0 {
+		return field.ErrorList{field.Required(path, "")}
+	}
+	if strings.ContainsAny(value, "=,") {
+		return field.ErrorList{field.Invalid(path, value, "must not contain '=' or ','")}
+	}
+	return nil
+}
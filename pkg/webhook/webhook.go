@@ -0,0 +1,49 @@
+package webhook
+
+import (
+	"github.com/redhat-appstudio/jvm-build-service/pkg/apis/jvmbuildservice/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+var (
+	webhookLog = ctrl.Log.WithName("webhook")
+)
+
+// SetupWebhooksWithManager registers the validating and defaulting admission webhooks for the jvmbuildservice
+// resources with the managers webhook server
+func SetupWebhooksWithManager(mgr ctrl.Manager) error {
+	if err := ctrl.NewWebhookManagedBy(mgr).
+		For(&v1alpha1.ArtifactBuild{}).
+		WithValidator(&artifactBuildValidator{}).
+		Complete(); err != nil {
+		return err
+	}
+	if err := ctrl.NewWebhookManagedBy(mgr).
+		For(&v1alpha1.DependencyBuild{}).
+		WithValidator(&dependencyBuildValidator{}).
+		Complete(); err != nil {
+		return err
+	}
+	if err := ctrl.NewWebhookManagedBy(mgr).
+		For(&v1alpha1.BuildRecipeOverride{}).
+		WithValidator(&buildRecipeOverrideValidator{}).
+		Complete(); err != nil {
+		return err
+	}
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(&v1alpha1.JBSConfig{}).
+		WithDefaulter(&jbsConfigDefaulter{}).
+		WithValidator(&jbsConfigValidator{}).
+		Complete()
+}
+
+// invalid converts a list of field errors into the error returned to the API server, or nil if there are no errors
+func invalid(kind string, name string, errs field.ErrorList) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	webhookLog.Info("rejecting invalid object", "kind", kind, "name", name, "errors", errs.ToAggregate().Error())
+	return errors.NewInvalid(v1alpha1.Kind(kind), name, errs)
+}
@@ -0,0 +1,255 @@
+package webhook
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+
+	"github.com/redhat-appstudio/jvm-build-service/pkg/apis/jvmbuildservice/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+func TestValidateArtifactBuild(t *testing.T) {
+	ctx := context.TODO()
+	validator := artifactBuildValidator{}
+	abr := func(gav string) *v1alpha1.ArtifactBuild {
+		return &v1alpha1.ArtifactBuild{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: metav1.NamespaceDefault}, Spec: v1alpha1.ArtifactBuildSpec{GAV: gav}}
+	}
+	t.Run("Test valid GAVs are accepted", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		g.Expect(validator.ValidateCreate(ctx, abr("com.test:test:1.0"))).Should(Succeed())
+		g.Expect(validator.ValidateCreate(ctx, abr("com.test:test:jar:tests:1.0"))).Should(Succeed())
+		g.Expect(validator.ValidateUpdate(ctx, abr("com.test:test:1.0"), abr("com.test:test:1.1"))).Should(Succeed())
+	})
+	t.Run("Test invalid GAVs are rejected", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		for _, gav := range []string{"", "com.test", "com.test:test", "com.test::1.0", "com.test:test:1.0:", "com.test:test: 1.0", "a:b:c:d:e:f"} {
+			err := validator.ValidateCreate(ctx, abr(gav))
+			g.Expect(errors.IsInvalid(err)).Should(BeTrue(), "GAV %q should be rejected", gav)
+		}
+		g.Expect(errors.IsInvalid(validator.ValidateUpdate(ctx, abr("com.test:test:1.0"), abr("com.test")))).Should(BeTrue())
+	})
+}
+
+func TestValidateDependencyBuild(t *testing.T) {
+	ctx := context.TODO()
+	validator := dependencyBuildValidator{}
+	db := func(scmURL string, version string) *v1alpha1.DependencyBuild {
+		return &v1alpha1.DependencyBuild{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: metav1.NamespaceDefault}, Spec: v1alpha1.DependencyBuildSpec{ScmInfo: v1alpha1.SCMInfo{SCMURL: scmURL}, Version: version}}
+	}
+	t.Run("Test a valid spec is accepted", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		g.Expect(validator.ValidateCreate(ctx, db("https://github.com/test/test.git", "1.0"))).Should(Succeed())
+		g.Expect(validator.ValidateUpdate(ctx, db("https://github.com/test/test.git", "1.0"), db("https://github.com/test/test.git", "1.1"))).Should(Succeed())
+	})
+	t.Run("Test an invalid spec is rejected", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		g.Expect(errors.IsInvalid(validator.ValidateCreate(ctx, db("", "1.0")))).Should(BeTrue())
+		g.Expect(errors.IsInvalid(validator.ValidateCreate(ctx, db("https://github.com/test/test.git", "")))).Should(BeTrue())
+		g.Expect(errors.IsInvalid(validator.ValidateUpdate(ctx, db("https://github.com/test/test.git", "1.0"), db("https://github.com/test/test.git", "")))).Should(BeTrue())
+	})
+	t.Run("Test updates that do not change the spec are accepted", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		old := db("", "")
+		updated := db("", "")
+		updated.OwnerReferences = []metav1.OwnerReference{{Name: "test"}}
+		//recipes are validated where they come from, the status is never validated
+		updated.Status.CurrentBuildRecipe = &v1alpha1.BuildRecipe{AdditionalDownloads: []v1alpha1.AdditionalDownload{{FileType: "zip"}}}
+		g.Expect(validator.ValidateUpdate(ctx, old, updated)).Should(Succeed())
+	})
+}
+
+func TestValidateAdditionalDownloads(t *testing.T) {
+	path := field.NewPath("additionalDownloads")
+	t.Run("Test valid additional downloads are accepted", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		g.Expect(ValidateAdditionalDownloads(path, []v1alpha1.AdditionalDownload{
+			{FileType: v1alpha1.AdditionalDownloadTypeTar, Uri: "https://example.com/tool.tar.gz", BinaryPath: "bin"},
+			{FileType: v1alpha1.AdditionalDownloadTypeExecutable, Uri: "https://example.com/tool", FileName: "tool"},
+			{FileType: v1alpha1.AdditionalDownloadTypeRpm, PackageName: "glibc-devel"},
+		})).Should(BeEmpty())
+	})
+	t.Run("Test invalid additional downloads are rejected", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		for _, download := range []v1alpha1.AdditionalDownload{
+			{FileType: "zip", Uri: "https://example.com/tool.zip"},
+			{FileType: v1alpha1.AdditionalDownloadTypeTar, Uri: "https://example.com/tool.tar.gz"},
+			{FileType: v1alpha1.AdditionalDownloadTypeTar, BinaryPath: "bin"},
+			{FileType: v1alpha1.AdditionalDownloadTypeExecutable, Uri: "https://example.com/tool"},
+			{FileType: v1alpha1.AdditionalDownloadTypeRpm},
+		} {
+			g.Expect(ValidateAdditionalDownloads(path, []v1alpha1.AdditionalDownload{download})).ShouldNot(BeEmpty(), "download %v should be rejected", download)
+		}
+	})
+}
+
+func TestJBSConfigWebhook(t *testing.T) {
+	ctx := context.TODO()
+	validator := jbsConfigValidator{}
+	config := func() *v1alpha1.JBSConfig {
+		return &v1alpha1.JBSConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: v1alpha1.JBSConfigName, Namespace: metav1.NamespaceDefault},
+			Spec: v1alpha1.JBSConfigSpec{
+				MavenBaseLocations: map[string]string{
+					"maven-repository-300-jboss":   "https://repository.jboss.org/nexus/content/groups/public/",
+					"maven-repository-303-jitpack": "https://jitpack.io",
+				},
+				RelocationPatterns: []v1alpha1.RelocationPatternElement{{RelocationPattern: v1alpha1.RelocationPattern{
+					Patterns: []v1alpha1.PatternElement{{Pattern: v1alpha1.Pattern{From: "(com.test):(test):(1.0)", To: "com.test:test:1.1"}}},
+				}}},
+			},
+		}
+	}
+	t.Run("Test defaults are applied", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		jbsConfig := config()
+		jbsConfig.Spec.CacheSettings.LimitMemory = "1Gi"
+		g.Expect((&jbsConfigDefaulter{}).Default(ctx, jbsConfig)).Should(Succeed())
+		g.Expect(jbsConfig.Spec.CacheSettings.RequestMemory).Should(Equal(v1alpha1.ConfigArtifactCacheRequestMemoryDefault))
+		g.Expect(jbsConfig.Spec.CacheSettings.LimitMemory).Should(Equal("1Gi"))
+		g.Expect(jbsConfig.Spec.CacheSettings.Storage).Should(Equal(v1alpha1.ConfigArtifactCacheStorageDefault))
+		g.Expect(jbsConfig.Spec.RelocationPatterns[0].RelocationPattern.BuildPolicy).Should(Equal("default"))
+		g.Expect(validator.ValidateCreate(ctx, jbsConfig)).Should(Succeed())
+	})
+	t.Run("Test valid config is accepted", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		g.Expect(validator.ValidateCreate(ctx, config())).Should(Succeed())
+	})
+	t.Run("Test invalid quantities are rejected", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		jbsConfig := config()
+		jbsConfig.Spec.CacheSettings.RequestMemory = "512MB"
+		g.Expect(errors.IsInvalid(validator.ValidateCreate(ctx, jbsConfig))).Should(BeTrue())
+		jbsConfig = config()
+		jbsConfig.Spec.BuildSettings.TaskLimitCPU = "lots"
+		g.Expect(errors.IsInvalid(validator.ValidateCreate(ctx, jbsConfig))).Should(BeTrue())
+		jbsConfig = config()
+		jbsConfig.Spec.CacheSettings.RequestMemory = "2Gi"
+		jbsConfig.Spec.CacheSettings.LimitMemory = "1Gi"
+		g.Expect(errors.IsInvalid(validator.ValidateCreate(ctx, jbsConfig))).Should(BeTrue())
+		jbsConfig = config()
+		jbsConfig.Spec.CacheSettings.WorkerThreads = "0"
+		g.Expect(errors.IsInvalid(validator.ValidateCreate(ctx, jbsConfig))).Should(BeTrue())
+		jbsConfig = config()
+		jbsConfig.Spec.BuildSettings.MaxConcurrentBuilds = -1
+		g.Expect(errors.IsInvalid(validator.ValidateCreate(ctx, jbsConfig))).Should(BeTrue())
+	})
+	t.Run("Test build timeouts are validated", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		jbsConfig := config()
+		jbsConfig.Spec.BuildSettings.PipelineTimeout = &metav1.Duration{Duration: time.Hour * 4}
+		jbsConfig.Spec.BuildSettings.StepTimeout = &metav1.Duration{Duration: time.Hour}
+		jbsConfig.Spec.BuildSettings.TimeoutPolicy = v1alpha1.BuildTimeoutPolicyRetryWithLongerTimeout
+		g.Expect(validator.ValidateCreate(ctx, jbsConfig)).Should(Succeed())
+		jbsConfig.Spec.BuildSettings.MaxPipelineTimeout = &metav1.Duration{Duration: time.Hour * 2}
+		g.Expect(errors.IsInvalid(validator.ValidateCreate(ctx, jbsConfig))).Should(BeTrue())
+		jbsConfig = config()
+		jbsConfig.Spec.BuildSettings.StepTimeout = &metav1.Duration{}
+		g.Expect(errors.IsInvalid(validator.ValidateCreate(ctx, jbsConfig))).Should(BeTrue())
+		jbsConfig = config()
+		jbsConfig.Spec.BuildSettings.TimeoutPolicy = "GiveUp"
+		g.Expect(errors.IsInvalid(validator.ValidateCreate(ctx, jbsConfig))).Should(BeTrue())
+	})
+	t.Run("Test retry policy is validated", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		jbsConfig := config()
+		jbsConfig.Spec.BuildSettings.RetryPolicy = v1alpha1.RetryPolicy{
+			MaxRetries:         map[string]int{v1alpha1.BuildFailureClassOOM: 5, v1alpha1.RetryCauseCacheRestart: 0},
+			MemoryIncrement:    1024,
+			MemoryGrowthFactor: 1,
+			InitialBackoff:     &metav1.Duration{},
+			MaxBackoff:         &metav1.Duration{Duration: time.Minute},
+		}
+		g.Expect(validator.ValidateCreate(ctx, jbsConfig)).Should(Succeed())
+		jbsConfig.Spec.BuildSettings.RetryPolicy.MaxRetries[v1alpha1.BuildFailureClassCompilation] = 1
+		g.Expect(errors.IsInvalid(validator.ValidateCreate(ctx, jbsConfig))).Should(BeTrue())
+		jbsConfig = config()
+		jbsConfig.Spec.BuildSettings.RetryPolicy.MaxRetries = map[string]int{v1alpha1.BuildFailureClassGitClone: -1}
+		g.Expect(errors.IsInvalid(validator.ValidateCreate(ctx, jbsConfig))).Should(BeTrue())
+		jbsConfig = config()
+		jbsConfig.Spec.BuildSettings.RetryPolicy.MemoryCeiling = -1
+		g.Expect(errors.IsInvalid(validator.ValidateCreate(ctx, jbsConfig))).Should(BeTrue())
+		jbsConfig = config()
+		jbsConfig.Spec.BuildSettings.RetryPolicy.MaxBackoff = &metav1.Duration{}
+		g.Expect(errors.IsInvalid(validator.ValidateCreate(ctx, jbsConfig))).Should(BeTrue())
+	})
+	t.Run("Test retention settings are validated", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		jbsConfig := config()
+		jbsConfig.Spec.Retention = v1alpha1.RetentionSettings{
+			CompletedArtifactBuildTTL:      &metav1.Duration{Duration: 24 * time.Hour},
+			FailedArtifactBuildTTL:         &metav1.Duration{Duration: time.Hour},
+			PipelineRunsPerDependencyBuild: 2,
+		}
+		g.Expect(validator.ValidateCreate(ctx, jbsConfig)).Should(Succeed())
+		jbsConfig.Spec.Retention.CompletedArtifactBuildTTL = &metav1.Duration{}
+		g.Expect(errors.IsInvalid(validator.ValidateCreate(ctx, jbsConfig))).Should(BeTrue())
+		jbsConfig = config()
+		jbsConfig.Spec.Retention.PipelineRunsPerDependencyBuild = -1
+		g.Expect(errors.IsInvalid(validator.ValidateCreate(ctx, jbsConfig))).Should(BeTrue())
+	})
+	t.Run("Test event sink is validated", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		jbsConfig := config()
+		jbsConfig.Spec.EventSink.URL = "https://events.example.com/jvm-build-service"
+		g.Expect(validator.ValidateCreate(ctx, jbsConfig)).Should(Succeed())
+		jbsConfig.Spec.EventSink.URL = "events.example.com"
+		g.Expect(errors.IsInvalid(validator.ValidateCreate(ctx, jbsConfig))).Should(BeTrue())
+	})
+	t.Run("Test invalid repositories are rejected", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		jbsConfig := config()
+		jbsConfig.Spec.MavenBaseLocations["maven-repo-304-test"] = "https://example.com"
+		g.Expect(errors.IsInvalid(validator.ValidateCreate(ctx, jbsConfig))).Should(BeTrue())
+		jbsConfig = config()
+		jbsConfig.Spec.MavenBaseLocations["maven-repository-304-jboss"] = "https://example.com"
+		g.Expect(errors.IsInvalid(validator.ValidateCreate(ctx, jbsConfig))).Should(BeTrue())
+		jbsConfig = config()
+		jbsConfig.Spec.MavenBaseLocations["maven-repository-304-test"] = "example.com/maven"
+		g.Expect(errors.IsInvalid(validator.ValidateCreate(ctx, jbsConfig))).Should(BeTrue())
+	})
+	t.Run("Test invalid relocation patterns are rejected", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		jbsConfig := config()
+		jbsConfig.Spec.RelocationPatterns[0].RelocationPattern.Patterns[0].Pattern.To = "com.test:test"
+		g.Expect(errors.IsInvalid(validator.ValidateCreate(ctx, jbsConfig))).Should(BeTrue())
+		jbsConfig = config()
+		jbsConfig.Spec.RelocationPatterns[0].RelocationPattern.Patterns[0].Pattern.From = "com.test:test:1.0=1.1"
+		g.Expect(errors.IsInvalid(validator.ValidateCreate(ctx, jbsConfig))).Should(BeTrue())
+		jbsConfig = config()
+		jbsConfig.Spec.RelocationPatterns = append(jbsConfig.Spec.RelocationPatterns, jbsConfig.Spec.RelocationPatterns[0])
+		g.Expect(errors.IsInvalid(validator.ValidateCreate(ctx, jbsConfig))).Should(BeTrue())
+		jbsConfig = config()
+		jbsConfig.Spec.RelocationPatterns[0].RelocationPattern.BuildPolicy = "my policy"
+		g.Expect(errors.IsInvalid(validator.ValidateCreate(ctx, jbsConfig))).Should(BeTrue())
+	})
+}
+
+func TestValidateBuildRecipeOverride(t *testing.T) {
+	ctx := context.TODO()
+	validator := buildRecipeOverrideValidator{}
+	override := func(match v1alpha1.BuildRecipeOverrideMatch) *v1alpha1.BuildRecipeOverride {
+		return &v1alpha1.BuildRecipeOverride{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: metav1.NamespaceDefault}, Spec: v1alpha1.BuildRecipeOverrideSpec{Match: match, JavaVersion: "17"}}
+	}
+	t.Run("Test valid overrides are accepted", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		g.Expect(validator.ValidateCreate(ctx, override(v1alpha1.BuildRecipeOverrideMatch{SCMURL: "https://github.com/test/test.git", TagPattern: "test-1\\..*"}))).Should(Succeed())
+		g.Expect(validator.ValidateCreate(ctx, override(v1alpha1.BuildRecipeOverrideMatch{GAV: "com.test:test:1.0"}))).Should(Succeed())
+	})
+	t.Run("Test invalid overrides are rejected", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		g.Expect(errors.IsInvalid(validator.ValidateCreate(ctx, override(v1alpha1.BuildRecipeOverrideMatch{})))).Should(BeTrue())
+		g.Expect(errors.IsInvalid(validator.ValidateCreate(ctx, override(v1alpha1.BuildRecipeOverrideMatch{VersionPattern: "1.(0"})))).Should(BeTrue())
+		g.Expect(errors.IsInvalid(validator.ValidateCreate(ctx, override(v1alpha1.BuildRecipeOverrideMatch{GAV: "com.test"})))).Should(BeTrue())
+		invalidDownload := override(v1alpha1.BuildRecipeOverrideMatch{GAV: "com.test:test:1.0"})
+		invalidDownload.Spec.AdditionalDownloads = []v1alpha1.AdditionalDownload{{FileType: "zip"}}
+		g.Expect(errors.IsInvalid(validator.ValidateCreate(ctx, invalidDownload))).Should(BeTrue())
+		invalidTimeout := override(v1alpha1.BuildRecipeOverrideMatch{GAV: "com.test:test:1.0"})
+		invalidTimeout.Spec.PipelineTimeout = &metav1.Duration{Duration: -time.Hour}
+		g.Expect(errors.IsInvalid(validator.ValidateCreate(ctx, invalidTimeout))).Should(BeTrue())
+	})
+}